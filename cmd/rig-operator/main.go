@@ -16,6 +16,7 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/rigdev/rig-go-api/operator/api/v1/capabilities/capabilitiesconnect"
 	"github.com/rigdev/rig-go-api/operator/api/v1/cluster/clusterconnect"
+	"github.com/rigdev/rig-go-api/operator/api/v1/jobs/jobsconnect"
 	"github.com/rigdev/rig-go-api/operator/api/v1/pipeline/pipelineconnect"
 	"github.com/rigdev/rig/cmd/rig-operator/apichecker"
 	"github.com/rigdev/rig/cmd/rig-operator/certgen"
@@ -25,12 +26,15 @@ import (
 	"github.com/rigdev/rig/pkg/controller/plugin"
 	"github.com/rigdev/rig/pkg/handler/api/capabilities"
 	"github.com/rigdev/rig/pkg/handler/api/cluster"
+	apijobs "github.com/rigdev/rig/pkg/handler/api/jobs"
 	"github.com/rigdev/rig/pkg/handler/api/pipeline"
+	"github.com/rigdev/rig/pkg/handler/jobs"
 	"github.com/rigdev/rig/pkg/manager"
 	"github.com/rigdev/rig/pkg/scheme"
 	svccapabilities "github.com/rigdev/rig/pkg/service/capabilities"
 	svccluster "github.com/rigdev/rig/pkg/service/cluster"
 	"github.com/rigdev/rig/pkg/service/config"
+	svcjobs "github.com/rigdev/rig/pkg/service/jobs"
 	"github.com/rigdev/rig/pkg/service/objectstatus"
 	svcpipeline "github.com/rigdev/rig/pkg/service/pipeline"
 	"github.com/spf13/afero"
@@ -41,6 +45,7 @@ import (
 	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -92,13 +97,16 @@ func run(cmd *cobra.Command, _ []string) error {
 				ctrl.SetLogger(log)
 				return log
 			},
-			func(fs afero.Fs, scheme *runtime.Scheme) (*v1alpha1.OperatorConfig, error) {
+			func(fs afero.Fs, scheme *runtime.Scheme) (*config.OperatorConfigSource, error) {
 				cfgFile, err := cmd.Flags().GetString(flagConfigFile)
 				if err != nil {
 					return nil, err
 				}
 
-				return config.NewOperatorConfig(fs, scheme, config.WithFilePaths(cfgFile))
+				return config.NewOperatorConfigSource(fs, scheme, cfgFile), nil
+			},
+			func(source *config.OperatorConfigSource) (*v1alpha1.OperatorConfig, error) {
+				return source.Load()
 			},
 			func(lc fx.Lifecycle, log logr.Logger) context.Context {
 				ctx, cancel := context.WithCancel(cmd.Context())
@@ -126,10 +134,15 @@ func run(cmd *cobra.Command, _ []string) error {
 			func(restConfig *rest.Config) (clientset.Interface, error) {
 				return clientset.NewForConfig(restConfig)
 			},
+			func(restConfig *rest.Config) (kubernetes.Interface, error) {
+				return kubernetes.NewForConfig(restConfig)
+			},
 			func(cc clientset.Interface) discovery.DiscoveryInterface {
 				return cc.Discovery()
 			},
-			plugin.NewManager,
+			func(restConfig *rest.Config, cfg *v1alpha1.OperatorConfig) (*plugin.Manager, error) {
+				return plugin.NewManager(restConfig, plugin.SetPluginSandboxesOption(cfg.Pipeline.PluginSandboxes))
+			},
 			svccapabilities.NewService,
 			capabilities.NewHandler,
 			svcpipeline.NewService,
@@ -137,6 +150,9 @@ func run(cmd *cobra.Command, _ []string) error {
 			pipeline.NewHandler,
 			svccluster.New,
 			cluster.NewHandler,
+			svcjobs.NewService,
+			jobs.NewHandler,
+			apijobs.NewHandler,
 			manager.New,
 		),
 		fx.Invoke(
@@ -149,11 +165,15 @@ func run(cmd *cobra.Command, _ []string) error {
 				cap capabilitiesconnect.ServiceHandler,
 				pip pipelineconnect.ServiceHandler,
 				cluster clusterconnect.ServiceHandler,
+				jobsAPI jobsconnect.ServiceHandler,
+				jobsHandler http.Handler,
 			) {
 				mux := http.NewServeMux()
 				mux.Handle(capabilitiesconnect.NewServiceHandler(cap))
 				mux.Handle(pipelineconnect.NewServiceHandler(pip))
 				mux.Handle(clusterconnect.NewServiceHandler(cluster))
+				mux.Handle(jobsconnect.NewServiceHandler(jobsAPI))
+				mux.Handle(jobs.Pattern, jobsHandler)
 				mux.Handle(grpcreflect.NewHandlerV1(grpcreflect.NewStaticReflector(
 					capabilitiesconnect.ServiceName,
 					pipelineconnect.ServiceName,
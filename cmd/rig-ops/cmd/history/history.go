@@ -0,0 +1,94 @@
+package history
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/rigdev/rig/cmd/common"
+	"github.com/rigdev/rig/cmd/rig-ops/cmd/base"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rodaine/table"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (c *Cmd) list(ctx context.Context, _ *cobra.Command, args []string) error {
+	records, err := c.getRecords(ctx, args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	if base.Flags.OutputType != common.OutputTypePretty {
+		return common.FormatPrint(records, base.Flags.OutputType)
+	}
+
+	headerFmt := color.New(color.FgBlue, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+	tbl := table.New("ID", "Started", "Generation", "Duration", "Changed objects", "Error")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, r := range records {
+		tbl.AddRow(
+			r.ID,
+			r.StartedAt.Format(time.RFC3339),
+			strconv.FormatInt(r.Generation, 10),
+			r.Duration.Round(time.Millisecond),
+			len(r.Objects),
+			r.Error,
+		)
+	}
+	tbl.Print()
+
+	return nil
+}
+
+func (c *Cmd) inspect(ctx context.Context, _ *cobra.Command, args []string) error {
+	records, err := c.getRecords(ctx, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return errors.NotFoundErrorf("capsule '%s' has no recorded executions", args[1])
+	}
+
+	record := records[0]
+	if len(args) == 3 {
+		found := false
+		for _, r := range records {
+			if r.ID == args[2] {
+				record, found = r, true
+				break
+			}
+		}
+		if !found {
+			return errors.NotFoundErrorf("execution '%s' of capsule '%s' not found", args[2], args[1])
+		}
+	}
+
+	outputType := base.Flags.OutputType
+	if outputType == common.OutputTypePretty {
+		outputType = common.OutputTypeYAML
+	}
+	return common.FormatPrint(record, outputType)
+}
+
+func (c *Cmd) getRecords(ctx context.Context, namespace, capsule string) ([]pipeline.ExecutionRecord, error) {
+	cm := &corev1.ConfigMap{}
+	if err := c.K8sReader.Get(ctx, client.ObjectKey{
+		Namespace: namespace,
+		Name:      pipeline.ExecutionHistoryName(capsule),
+	}, cm); err != nil {
+		if err := errors.FromK8sClient(err); errors.IsNotFound(err) {
+			return nil, errors.NotFoundErrorf(
+				"no execution history of capsule '%s' in namespace '%s', is executionHistory enabled in the operator?",
+				capsule, namespace,
+			)
+		}
+		return nil, err
+	}
+
+	return pipeline.DecodeExecutionHistory(cm)
+}
@@ -0,0 +1,48 @@
+package history
+
+import (
+	"github.com/rigdev/rig/pkg/cli"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type Cmd struct {
+	fx.In
+
+	K8sReader client.Reader
+}
+
+var cmd Cmd
+
+func initCmd(c Cmd) {
+	cmd = c
+}
+
+func Setup(parent *cobra.Command, s *cli.SetupContext) {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Inspect the execution history of the pipeline of capsules",
+		Long: `Inspect the execution history of the pipeline of capsules.
+The history is recorded by the operator when executionHistory is enabled in its config.`,
+		PersistentPreRunE: s.MakeInvokePreRunE(initCmd),
+	}
+
+	list := &cobra.Command{
+		Use:   "list namespace capsule",
+		Short: "Lists the recorded executions of the pipeline of a capsule, newest first",
+		Args:  cobra.ExactArgs(2),
+		RunE:  cli.CtxWrap(cmd.list),
+	}
+	historyCmd.AddCommand(list)
+
+	inspect := &cobra.Command{
+		Use:   "inspect namespace capsule [id]",
+		Short: "Shows the steps, plugin configs and changed objects of an execution. Defaults to the newest",
+		Args:  cobra.RangeArgs(2, 3),
+		RunE:  cli.CtxWrap(cmd.inspect),
+	}
+	historyCmd.AddCommand(inspect)
+
+	parent.AddCommand(historyCmd)
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/rigdev/rig/pkg/controller/plugin"
 	"github.com/rodaine/table"
 	"github.com/spf13/cobra"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (c *Cmd) check(ctx context.Context, _ *cobra.Command, _ []string) error {
@@ -21,6 +23,11 @@ func (c *Cmd) check(ctx context.Context, _ *cobra.Command, _ []string) error {
 		return err
 	}
 
+	order, err := plugin.OrderSteps(cfg.Pipeline.Steps)
+	if err != nil {
+		return fmt.Errorf("invalid ordering of steps: %w", err)
+	}
+
 	var matchers []plugin.Matcher
 	for _, step := range cfg.Pipeline.Steps {
 		matcher, err := plugin.NewMatcher(plugin.MatchFromStep(step))
@@ -30,7 +37,7 @@ func (c *Cmd) check(ctx context.Context, _ *cobra.Command, _ []string) error {
 		matchers = append(matchers, matcher)
 	}
 
-	var objects []capsuleNamespace
+	var objects []*v1alpha2.Capsule
 
 	if len(capsules) == 0 || len(namespaces) == 0 {
 		capsuleList := v1alpha2.CapsuleList{}
@@ -44,27 +51,24 @@ func (c *Cmd) check(ctx context.Context, _ *cobra.Command, _ []string) error {
 			if len(namespaces) != 0 && !slices.Contains(namespaces, c.Namespace) {
 				continue
 			}
-			objects = append(objects, capsuleNamespace{
-				namespace:   c.Namespace,
-				capsule:     c.Name,
-				annotations: c.Annotations,
-			})
+			objects = append(objects, &c)
 		}
 	} else {
-		for _, c := range capsules {
+		for _, name := range capsules {
 			for _, ns := range namespaces {
-				objects = append(objects, capsuleNamespace{
-					namespace: ns,
-					capsule:   c,
-				})
+				capsule := &v1alpha2.Capsule{}
+				if err := c.K8s.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, capsule); err != nil {
+					if kerrors.IsNotFound(err) {
+						continue
+					}
+					return err
+				}
+				objects = append(objects, capsule)
 			}
 		}
 	}
 
-	results, err := getResults(matchers, objects)
-	if err != nil {
-		return err
-	}
+	results := getResults(matchers, order, objects)
 
 	if base.Flags.OutputType != common.OutputTypePretty {
 		return common.FormatPrint(&results, base.Flags.OutputType)
@@ -72,39 +76,46 @@ func (c *Cmd) check(ctx context.Context, _ *cobra.Command, _ []string) error {
 
 	headerFmt := color.New(color.FgBlue, color.Underline).SprintfFunc()
 	tbl := table.
-		New("Namespace", "Capsule", "StepIndex").
+		New("Namespace", "Capsule", "StepIndex", "Error").
 		WithHeaderFormatter(headerFmt)
 	for _, r := range results {
-		tbl.AddRow(r.Namespace, r.CapsuleID, r.StepIndex)
+		tbl.AddRow(r.Namespace, r.CapsuleID, r.StepIndex, r.Error)
 	}
 	tbl.Print()
 
 	return nil
 }
 
-type capsuleNamespace struct {
-	namespace   string
-	capsule     string
-	annotations map[string]string
-}
-
 type result struct {
 	Namespace string `json:"namespace"`
 	CapsuleID string `json:"capsule_id"`
 	StepIndex int    `json:"step_index"`
+	Error     string `json:"error,omitempty"`
+	position  int
 }
 
-func getResults(matchers []plugin.Matcher, objects []capsuleNamespace) ([]result, error) {
+// getResults returns the steps which will be run for each capsule, in the order
+// they are executed. Steps with conditions which fail to evaluate are included
+// with the error.
+func getResults(matchers []plugin.Matcher, order []int, objects []*v1alpha2.Capsule) []result {
 	var results []result
 	for _, obj := range objects {
-		for idx, matcher := range matchers {
-			if matcher.Match(obj.namespace, obj.capsule, obj.annotations) {
-				results = append(results, result{
-					Namespace: obj.namespace,
-					CapsuleID: obj.capsule,
-					StepIndex: idx,
-				})
+		for position, idx := range order {
+			ok, err := matchers[idx].MatchCapsule(obj)
+			if !ok && err == nil {
+				continue
+			}
+
+			r := result{
+				Namespace: obj.GetNamespace(),
+				CapsuleID: obj.GetName(),
+				StepIndex: idx,
+				position:  position,
+			}
+			if err != nil {
+				r.Error = err.Error()
 			}
+			results = append(results, r)
 		}
 	}
 
@@ -115,8 +126,8 @@ func getResults(matchers []plugin.Matcher, objects []capsuleNamespace) ([]result
 		if r1.CapsuleID != r2.CapsuleID {
 			return strings.Compare(r1.CapsuleID, r2.CapsuleID)
 		}
-		return r1.StepIndex - r2.StepIndex
+		return r1.position - r2.position
 	})
 
-	return results, nil
+	return results
 }
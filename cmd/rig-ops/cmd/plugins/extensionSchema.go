@@ -0,0 +1,62 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rigdev/rig/cmd/common"
+	"github.com/rigdev/rig/cmd/rig-ops/cmd/base"
+	"github.com/rigdev/rig/pkg/api/config/v1alpha1"
+	"github.com/rigdev/rig/pkg/controller/plugin"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/plugins/allplugins"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+)
+
+func extensionSchema(_ context.Context, _ *cobra.Command, args []string) error {
+	pluginName := args[0]
+	names := extensiblePlugins()
+	p, ok := allplugins.Plugins[pluginName].(plugin.ExtensionPlugin)
+	if !ok {
+		return errors.InvalidArgumentErrorf(
+			"plugin '%s' doesn't support capsule extensions, must be one of %s",
+			pluginName, strings.Join(names, ", "),
+		)
+	}
+
+	schema, err := p.ExtensionSchema()
+	if err != nil {
+		return err
+	}
+	schema.Title = fmt.Sprintf("Configuration of %s", pluginName)
+
+	name := extensionName
+	if name == "" {
+		name = strings.TrimPrefix(pluginName, "rigdev.")
+	}
+
+	outputType := base.Flags.OutputType
+	if outputType == common.OutputTypePretty {
+		outputType = common.OutputTypeYAML
+	}
+
+	return common.FormatPrint(map[string]any{
+		"capsuleExtensions": map[string]v1alpha1.Extension{
+			name: {Schema: schema},
+		},
+	}, outputType)
+}
+
+// extensiblePlugins returns the names of the builtin plugins supporting capsule extensions.
+func extensiblePlugins() []string {
+	names := maps.Keys(allplugins.Plugins)
+	names = slices.DeleteFunc(names, func(name string) bool {
+		_, ok := allplugins.Plugins[name].(plugin.ExtensionPlugin)
+		return !ok
+	})
+	slices.Sort(names)
+	return names
+}
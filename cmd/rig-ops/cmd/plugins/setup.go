@@ -26,6 +26,8 @@ var (
 	appends      []string
 	dry          bool
 	interactive  bool
+
+	extensionName string
 )
 
 type Cmd struct {
@@ -136,5 +138,26 @@ The dry run will be executed with the resulting list of plugins.`,
 	computeConfig.Flags().StringVar(&specPath, "spec", "", "If given, will read the capsule spec at the path instead of using the capsule spec of an existing capsule from the platform")
 	pluginsCmd.AddCommand(computeConfig)
 
+	extensionSchema := &cobra.Command{
+		Use: "extension-schema plugin",
+		//nolint:lll
+		Short: "Prints the platform configuration of a capsule extension overriding the configuration of a builtin plugin",
+		Long: `Prints the platform configuration of a capsule extension overriding the configuration of a builtin plugin.
+The JSON schema of the extension is generated from the configuration of the plugin. To use the extension,
+add it to the capsuleExtensions of both the platform and the operator configuration, and set the
+capsuleExtension field of the plugin configuration to its name.`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: extensiblePlugins(),
+		// The schema is generated from the builtin plugins, so no clients are needed.
+		PersistentPreRunE: func(*cobra.Command, []string) error { return nil },
+		RunE:              cli.CtxWrap(extensionSchema),
+	}
+	//nolint:lll
+	extensionSchema.Flags().StringVar(
+		&extensionName, "name", "",
+		"Name of the capsule extension. Defaults to the name of the plugin without the 'rigdev.' prefix.",
+	)
+	pluginsCmd.AddCommand(extensionSchema)
+
 	parent.AddCommand(pluginsCmd)
 }
@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 
 	"github.com/rigdev/rig/cmd/rig-ops/cmd/base"
+	"github.com/rigdev/rig/cmd/rig-ops/cmd/history"
 	"github.com/rigdev/rig/cmd/rig-ops/cmd/migrate"
 	"github.com/rigdev/rig/cmd/rig-ops/cmd/plugins"
 	"github.com/rigdev/rig/cmd/rig/services/auth"
@@ -54,6 +55,7 @@ func Run(s *cli.SetupContext) error {
 	version.Setup(rootCmd, s, "")
 	migrate.Setup(rootCmd, s)
 	plugins.Setup(rootCmd, s)
+	history.Setup(rootCmd, s)
 	if s.Args != nil {
 		rootCmd.SetArgs(s.Args)
 	}
@@ -0,0 +1,154 @@
+package activator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	platformv1 "github.com/rigdev/rig-go-api/platform/v1"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/tunnel"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// activityInterval is how often requests to an awake capsule are recorded.
+	activityInterval  = time.Minute
+	readyPollInterval = 250 * time.Millisecond
+)
+
+// Server is the activator of capsules which scale to zero. Their routes are
+// sent through the activator while they are asleep or on standby, and the
+// activator records their requests on the Capsule, wakes them up if asleep and
+// holds requests until an instance is ready.
+type Server struct {
+	logger *zap.Logger
+	client client.Client
+}
+
+func New(logger *zap.Logger, cc client.Client) *Server {
+	return &Server{
+		logger: logger,
+		client: cc,
+	}
+}
+
+func (s *Server) Serve(port uint32) error {
+	s.logger.Info("activator listening", zap.Uint32("port", port))
+	return http.ListenAndServe(fmt.Sprintf(":%d", port), s)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The capsule a request is for is found by the route it was sent through,
+	// so requests can't be sent to arbitrary capsules or ports.
+	capsules := &v1alpha2.CapsuleList{}
+	if err := s.client.List(r.Context(), capsules); err != nil {
+		s.logger.Warn("could not list capsules", zap.Error(err))
+		http.Error(w, "capsules unavailable", http.StatusBadGateway)
+		return
+	}
+
+	capsule, port, ok := findTarget(capsules.Items, r.Host, r.URL.Path)
+	if !ok {
+		http.Error(w, "no capsule scaling to zero has a route for the request", http.StatusNotFound)
+		return
+	}
+	logger := s.logger.With(zap.String("namespace", capsule.Namespace), zap.String("capsule", capsule.Name))
+
+	ready, err := s.isReady(r.Context(), capsule)
+	if err != nil {
+		logger.Warn("could not get capsule workload", zap.Error(err))
+		http.Error(w, "capsule unavailable", http.StatusBadGateway)
+		return
+	}
+
+	if err := s.recordRequest(r.Context(), capsule, !ready); err != nil {
+		logger.Warn("could not record request", zap.Error(err))
+	}
+
+	if !ready {
+		timeout := capsule.Spec.Scale.Horizontal.ScaleToZero.WakeupTimeout()
+
+		logger.Info("waking up capsule", zap.Duration("timeout", timeout))
+		if err := s.waitForReady(r.Context(), capsule, timeout); err != nil {
+			logger.Warn("capsule did not wake up", zap.Error(err))
+			http.Error(w, "capsule did not wake up in time", http.StatusGatewayTimeout)
+			return
+		}
+	}
+
+	tunnel.NewReverseProxy(tunnel.Target{
+		Host:    fmt.Sprintf("%s.%s.svc.cluster.local:%d", capsule.Name, capsule.Namespace, port),
+		Options: &platformv1.InterfaceOptions{},
+	}).ServeHTTP(w, r)
+}
+
+// recordRequest sets the last request of the capsule, which wakes it up if
+// asleep and takes it off standby. For awake capsules, it is recorded at most
+// once per activityInterval.
+func (s *Server) recordRequest(ctx context.Context, capsule *v1alpha2.Capsule, wakeUp bool) error {
+	now := time.Now()
+	last, _ := time.Parse(time.RFC3339, capsule.GetAnnotations()[pipeline.AnnotationLastRequest])
+	if !wakeUp && now.Sub(last) < activityInterval {
+		return nil
+	}
+
+	patch := client.MergeFrom(capsule.DeepCopy())
+	annotations := capsule.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[pipeline.AnnotationLastRequest] = now.UTC().Format(time.RFC3339)
+	capsule.SetAnnotations(annotations)
+
+	return s.client.Patch(ctx, capsule, patch)
+}
+
+// isReady returns true if the capsule has an instance ready to serve requests.
+func (s *Server) isReady(ctx context.Context, capsule *v1alpha2.Capsule) (bool, error) {
+	key := client.ObjectKeyFromObject(capsule)
+	if pipeline.IsStateful(capsule) {
+		sts := &appsv1.StatefulSet{}
+		if err := s.client.Get(ctx, key, sts); kerrors.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return sts.Status.ReadyReplicas > 0, nil
+	}
+
+	d := &appsv1.Deployment{}
+	if err := s.client.Get(ctx, key, d); kerrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return d.Status.ReadyReplicas > 0, nil
+}
+
+func (s *Server) waitForReady(ctx context.Context, capsule *v1alpha2.Capsule, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if ready, err := s.isReady(ctx, capsule); err != nil {
+			return err
+		} else if ready {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package activator
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/plugins/capsulesteps/ingress_routes"
+)
+
+// findTarget returns the capsule and the port of its interface which a request
+// to the given host and path is for. Only capsules which scale to zero are
+// considered, as only their routes are sent through the activator. If several
+// routes match, the one with the longest matching path is used.
+//
+// Requests are received after the path rewrites of their route, so a route
+// rewriting the path prefix matches requests with the rewritten prefix.
+func findTarget(capsules []v1alpha2.Capsule, host, path string) (*v1alpha2.Capsule, int32, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	var (
+		target *v1alpha2.Capsule
+		port   int32
+		best   = -1
+	)
+	for i := range capsules {
+		c := &capsules[i]
+		if c.Spec.Scale.Horizontal.ScaleToZero == nil {
+			continue
+		}
+
+		for _, inf := range c.AllInterfaces() {
+			for _, route := range ingress_routes.GetRoutes(inf) {
+				if !matchHost(route.Host, host) {
+					continue
+				}
+				if length := matchPath(route, path); length > best {
+					target, port, best = c, inf.Port, length
+				}
+			}
+		}
+	}
+
+	return target, port, target != nil
+}
+
+// matchHost returns true if the host matches the host of a route, which can
+// have a wildcard as its first label.
+func matchHost(routeHost, host string) bool {
+	if suffix, ok := strings.CutPrefix(routeHost, "*."); ok {
+		prefix, ok := strings.CutSuffix(host, "."+suffix)
+		return ok && prefix != "" && !strings.Contains(prefix, ".")
+	}
+	return strings.EqualFold(routeHost, host)
+}
+
+// matchPath returns the length of the longest path of the route matching the
+// path, or -1 if none match.
+func matchPath(route v1alpha2.HostRoute, path string) int {
+	if route.RewritePathPrefix != "" {
+		if !hasPathPrefix(path, route.RewritePathPrefix) {
+			return -1
+		}
+		return len(route.RewritePathPrefix)
+	}
+
+	if len(route.Paths) == 0 {
+		return 0
+	}
+
+	best := -1
+	for _, p := range route.Paths {
+		var ok bool
+		switch p.Match {
+		case v1alpha2.Exact:
+			ok = path == p.Path
+		case v1alpha2.RegularExpression:
+			re, err := regexp.Compile("^(?:" + p.Path + ")")
+			ok = err == nil && re.MatchString(path)
+		default:
+			ok = hasPathPrefix(path, p.Path)
+		}
+		if ok && len(p.Path) > best {
+			best = len(p.Path)
+		}
+	}
+	return best
+}
+
+// hasPathPrefix returns true if the prefix matches the path element-wise.
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/") || prefix == ""
+}
@@ -0,0 +1,92 @@
+package activator
+
+import (
+	"testing"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newCapsule(name string, scaleToZero bool, interfaces ...v1alpha2.CapsuleInterface) v1alpha2.Capsule {
+	c := v1alpha2.Capsule{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prod"},
+		Spec: v1alpha2.CapsuleSpec{
+			Interfaces: interfaces,
+		},
+	}
+	if scaleToZero {
+		c.Spec.Scale.Horizontal.ScaleToZero = &v1alpha2.ScaleToZero{}
+	}
+	return c
+}
+
+func Test_findTarget(t *testing.T) {
+	capsules := []v1alpha2.Capsule{
+		newCapsule("web", true, v1alpha2.CapsuleInterface{
+			Name: "http",
+			Port: 8080,
+			Routes: []v1alpha2.HostRoute{{
+				ID:   "web",
+				Host: "example.com",
+			}},
+		}),
+		newCapsule("api", true,
+			v1alpha2.CapsuleInterface{
+				Name: "http",
+				Port: 8000,
+				Routes: []v1alpha2.HostRoute{{
+					ID:    "api",
+					Host:  "example.com",
+					Paths: []v1alpha2.HTTPPathRoute{{Path: "/api"}},
+				}},
+			},
+			v1alpha2.CapsuleInterface{
+				Name: "admin",
+				Port: 9000,
+				Routes: []v1alpha2.HostRoute{{
+					ID:           "admin",
+					Host:         "*.admin.example.com",
+					Paths:        []v1alpha2.HTTPPathRoute{{Path: "/admin"}},
+					RouteOptions: v1alpha2.RouteOptions{RewritePathPrefix: "/internal"},
+				}},
+			},
+		),
+		newCapsule("users", false, v1alpha2.CapsuleInterface{
+			Name: "http",
+			Port: 8080,
+			Routes: []v1alpha2.HostRoute{{
+				ID:   "users",
+				Host: "users.example.com",
+			}},
+		}),
+	}
+
+	tests := []struct {
+		name    string
+		host    string
+		path    string
+		capsule string
+		port    int32
+	}{
+		{name: "catch-all route", host: "example.com", path: "/index.html", capsule: "web", port: 8080},
+		{name: "longest path", host: "example.com:443", path: "/api/users", capsule: "api", port: 8000},
+		{name: "path prefix is element-wise", host: "example.com", path: "/apis", capsule: "web", port: 8080},
+		{name: "rewritten path", host: "eu.admin.example.com", path: "/internal/users", capsule: "api", port: 9000},
+		{name: "rewritten path not matching", host: "eu.admin.example.com", path: "/admin/users"},
+		{name: "wildcard matches one label", host: "a.eu.admin.example.com", path: "/internal"},
+		{name: "capsule not scaling to zero", host: "users.example.com", path: "/"},
+		{name: "unknown host", host: "other.com", path: "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, port, ok := findTarget(capsules, tt.host, tt.path)
+			assert.Equal(t, tt.capsule != "", ok)
+			if ok {
+				assert.Equal(t, tt.capsule, c.Name)
+				assert.Equal(t, tt.port, port)
+			}
+		})
+	}
+}
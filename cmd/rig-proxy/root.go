@@ -1,18 +1,27 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	platformv1 "github.com/rigdev/rig-go-api/platform/v1"
+	"github.com/rigdev/rig/cmd/rig-proxy/activator"
 	"github.com/rigdev/rig/cmd/rig-proxy/tunnel"
 	"github.com/rigdev/rig/pkg/build"
+	"github.com/rigdev/rig/pkg/scheme"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 )
 
-var verbose = false
+var (
+	verbose       = false
+	activatorPort uint32
+)
 
 func createRootCMD() *cobra.Command {
 	cmd := &cobra.Command{
@@ -59,6 +68,57 @@ func createRootCMD() *cobra.Command {
 	pflags.BoolVarP(&verbose, "verbose", "v", false, "enable verbose error logging")
 
 	cmd.AddCommand(build.VersionCommand())
+	cmd.AddCommand(createActivatorCMD())
+
+	return cmd
+}
+
+func createActivatorCMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "activator",
+		Short: "Run the activator, which wakes up capsules scaled to zero on incoming requests",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			core := zapcore.NewCore(
+				zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+				zapcore.AddSync(os.Stderr),
+				zapcore.DebugLevel,
+			)
+			logger := zap.New(core)
+
+			cfg, err := ctrl.GetConfig()
+			if err != nil {
+				return err
+			}
+
+			// Capsules and their workloads are read through an informer cache, as
+			// they are looked up for every request.
+			s := scheme.New()
+			c, err := cache.New(cfg, cache.Options{Scheme: s})
+			if err != nil {
+				return err
+			}
+			cc, err := client.New(cfg, client.Options{
+				Scheme: s,
+				Cache:  &client.CacheOptions{Reader: c},
+			})
+			if err != nil {
+				return err
+			}
+
+			go func() {
+				if err := c.Start(cmd.Context()); err != nil {
+					logger.Error("error running cache", zap.Error(err))
+				}
+			}()
+			if !c.WaitForCacheSync(cmd.Context()) {
+				return errors.New("could not sync cache")
+			}
+
+			return activator.New(logger, cc).Serve(activatorPort)
+		},
+	}
+
+	cmd.Flags().Uint32VarP(&activatorPort, "port", "p", 8080, "port to listen on")
 
 	return cmd
 }
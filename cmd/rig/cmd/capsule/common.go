@@ -199,39 +199,48 @@ func AbortAndDeploy(
 
 func PrintLogs(stream *connect.ServerStreamForClient[capsule.LogsResponse]) error {
 	for stream.Receive() {
-		switch v := stream.Msg().GetLog().GetMessage().GetMessage().(type) {
-		case *capsule.LogMessage_Stdout:
-			if err := printInstanceID(stream.Msg().GetLog().GetInstanceId(), os.Stdout); err != nil {
-				return err
-			}
-			os.Stdout.WriteString(stream.Msg().GetLog().GetTimestamp().AsTime().Format(cli.RFC3339NanoFixed))
-			os.Stdout.WriteString(": ")
-			if _, err := os.Stdout.Write(v.Stdout); err != nil {
-				return err
-			}
-		case *capsule.LogMessage_Stderr:
-			if err := printInstanceID(stream.Msg().GetLog().GetInstanceId(), os.Stderr); err != nil {
-				return err
-			}
-			os.Stderr.WriteString(stream.Msg().GetLog().GetTimestamp().AsTime().Format(cli.RFC3339NanoFixed))
-			os.Stderr.WriteString(": ")
-			if _, err := os.Stderr.Write(v.Stderr); err != nil {
-				return err
-			}
-		case *capsule.LogMessage_ContainerTermination_:
-			if err := printInstanceID(stream.Msg().GetLog().GetInstanceId(), os.Stderr); err != nil {
-				return err
-			}
-			os.Stdout.WriteString(stream.Msg().GetLog().GetTimestamp().AsTime().Format(cli.RFC3339NanoFixed))
-			os.Stdout.WriteString(" Container Terminated.\n\n")
-		default:
-			return errors.InvalidArgumentErrorf("invalid log message")
+		if err := PrintLog(stream.Msg().GetLog()); err != nil {
+			return err
 		}
 	}
 
 	return stream.Err()
 }
 
+// PrintLog prints a single log line of an instance.
+func PrintLog(log *capsule.Log) error {
+	switch v := log.GetMessage().GetMessage().(type) {
+	case *capsule.LogMessage_Stdout:
+		if err := printInstanceID(log.GetInstanceId(), os.Stdout); err != nil {
+			return err
+		}
+		os.Stdout.WriteString(log.GetTimestamp().AsTime().Format(cli.RFC3339NanoFixed))
+		os.Stdout.WriteString(": ")
+		if _, err := os.Stdout.Write(v.Stdout); err != nil {
+			return err
+		}
+	case *capsule.LogMessage_Stderr:
+		if err := printInstanceID(log.GetInstanceId(), os.Stderr); err != nil {
+			return err
+		}
+		os.Stderr.WriteString(log.GetTimestamp().AsTime().Format(cli.RFC3339NanoFixed))
+		os.Stderr.WriteString(": ")
+		if _, err := os.Stderr.Write(v.Stderr); err != nil {
+			return err
+		}
+	case *capsule.LogMessage_ContainerTermination_:
+		if err := printInstanceID(log.GetInstanceId(), os.Stderr); err != nil {
+			return err
+		}
+		os.Stdout.WriteString(log.GetTimestamp().AsTime().Format(cli.RFC3339NanoFixed))
+		os.Stdout.WriteString(" Container Terminated.\n\n")
+	default:
+		return errors.InvalidArgumentErrorf("invalid log message")
+	}
+
+	return nil
+}
+
 func SelectCapsule(ctx context.Context, rc rig.Client, prompter common.Prompter, scope scope.Scope) (string, error) {
 	resp, err := rc.Capsule().List(ctx, connect.NewRequest(&capsule.ListRequest{
 		Pagination: &model.Pagination{},
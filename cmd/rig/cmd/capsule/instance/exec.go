@@ -72,6 +72,7 @@ func (c *Cmd) exec(ctx context.Context, cmd *cobra.Command, args []string) error
 				Arguments:   arguments,
 				Tty:         resize,
 				Interactive: interactive,
+				Container:   container,
 			},
 		},
 		ProjectId:     c.Scope.GetCurrentContext().GetProject(),
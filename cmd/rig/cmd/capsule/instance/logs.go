@@ -30,6 +30,7 @@ func (c *Cmd) logs(ctx context.Context, cmd *cobra.Command, args []string) error
 			ProjectId:          c.Scope.GetCurrentContext().GetProject(),
 			EnvironmentId:      c.Scope.GetCurrentContext().GetEnvironment(),
 			PreviousContainers: previousContainers,
+			Container:          container,
 		},
 	}
 
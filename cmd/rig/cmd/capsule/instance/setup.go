@@ -34,7 +34,10 @@ var (
 	previousContainers bool
 )
 
-var since string
+var (
+	since     string
+	container string
+)
 
 type Cmd struct {
 	fx.In
@@ -128,6 +131,9 @@ func Setup(parent *cobra.Command, s *cli.SetupContext) {
 		"Return logs from previous container terminations of the instance.",
 	)
 	logs.Flags().StringVarP(&since, "since", "s", "", "do not show logs older than 'since'")
+	logs.Flags().StringVarP(
+		&container, "container", "c", "", "the container of the instance to read logs from. Defaults to the main container",
+	)
 	if err := logs.RegisterFlagCompletionFunc("follow", common.BoolCompletions); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -146,6 +152,9 @@ func Setup(parent *cobra.Command, s *cli.SetupContext) {
 	}
 	exec.Flags().BoolVarP(&tty, "tty", "t", false, "allocate a TTY")
 	exec.Flags().BoolVarP(&interactive, "interactive", "i", false, "Keep STDIN open")
+	exec.Flags().StringVarP(
+		&container, "container", "c", "", "the container of the instance to execute in. Defaults to the main container",
+	)
 	if err := exec.RegisterFlagCompletionFunc("tty", common.BoolCompletions); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -0,0 +1,114 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/cmd/common"
+	capsule_cmd "github.com/rigdev/rig/cmd/rig/cmd/capsule"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func (c *Cmd) run(ctx context.Context, cmd *cobra.Command, args []string) error {
+	rollout, err := capsule_cmd.GetCurrentRollout(ctx, c.Rig, c.Scope)
+	if errors.IsNotFound(err) {
+		return errors.FailedPreconditionErrorf("capsule '%s' has no jobs", capsule_cmd.CapsuleID)
+	} else if err != nil {
+		return err
+	}
+
+	var jobNames []string
+	for _, j := range rollout.GetConfig().GetCronJobs() {
+		jobNames = append(jobNames, j.GetJobName())
+	}
+	if len(jobNames) == 0 {
+		return errors.FailedPreconditionErrorf("capsule '%s' has no jobs", capsule_cmd.CapsuleID)
+	}
+
+	var job string
+	if len(args) < 2 {
+		_, job, err = c.Prompter.Select("Job to run:", jobNames, common.SelectEnableFilterOpt)
+		if err != nil {
+			return err
+		}
+	} else {
+		job = args[1]
+	}
+
+	if !slices.Contains(jobNames, job) {
+		return fmt.Errorf("no job with name %s", job)
+	}
+
+	req := &capsule.RunJobRequest{
+		CapsuleId:       capsule_cmd.CapsuleID,
+		JobName:         job,
+		ProjectId:       c.Scope.GetCurrentContext().GetProject(),
+		EnvironmentId:   c.Scope.GetCurrentContext().GetEnvironment(),
+		Env:             runEnv,
+		QueryParameters: runQueryParameters,
+		Follow:          runFollow,
+	}
+	if cmd.Flags().Changed("arg") {
+		req.Args = runArgs
+	}
+	if cmd.Flags().Changed("max-retries") {
+		req.MaxRetries = &runMaxRetries
+	}
+	if cmd.Flags().Changed("timeout") {
+		seconds := uint32(runTimeout / time.Second)
+		req.TimeoutSeconds = &seconds
+	}
+
+	stream, err := c.Rig.Capsule().RunJob(ctx, connect.NewRequest(req))
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	var execution *capsule.JobExecution
+	for stream.Receive() {
+		switch v := stream.Msg().GetResponse().(type) {
+		case *capsule.RunJobResponse_Log:
+			if err := capsule_cmd.PrintLog(v.Log); err != nil {
+				return err
+			}
+		case *capsule.RunJobResponse_Execution:
+			if execution == nil {
+				fmt.Printf("Running job %s as %s\n", job, v.Execution.GetExecutionId())
+			}
+			execution = v.Execution
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return err
+	}
+
+	if execution == nil {
+		return errors.UnavailableErrorf("no execution of job %s was started", job)
+	}
+
+	return executionResult(execution)
+}
+
+// executionResult prints the state of the execution of the job, and returns
+// an error if it didn't complete.
+func executionResult(e *capsule.JobExecution) error {
+	switch e.GetState() {
+	case capsule.JobState_JOB_STATE_COMPLETED:
+		fmt.Printf("Job %s completed after %d retries\n", e.GetExecutionId(), e.GetRetries())
+		return nil
+	case capsule.JobState_JOB_STATE_FAILED:
+		return fmt.Errorf("job %s failed after %d retries with exit code %d",
+			e.GetExecutionId(), e.GetRetries(), e.GetExitCode())
+	case capsule.JobState_JOB_STATE_TERMINATED:
+		return fmt.Errorf("job %s timed out after %d retries", e.GetExecutionId(), e.GetRetries())
+	default:
+		fmt.Printf("Job %s is %s\n", e.GetExecutionId(), stateToStr(e.GetState()))
+		return nil
+	}
+}
@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_executionResult(t *testing.T) {
+	e := &capsule.JobExecution{
+		JobName:     "report",
+		ExecutionId: "api-report-x7k2p",
+		State:       capsule.JobState_JOB_STATE_FAILED,
+		Retries:     2,
+		ExitCode:    3,
+	}
+	assert.EqualError(t, executionResult(e), "job api-report-x7k2p failed after 2 retries with exit code 3")
+
+	e.State = capsule.JobState_JOB_STATE_TERMINATED
+	assert.EqualError(t, executionResult(e), "job api-report-x7k2p timed out after 2 retries")
+
+	e.State = capsule.JobState_JOB_STATE_COMPLETED
+	assert.NoError(t, executionResult(e))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/rigdev/rig-go-sdk"
 	"github.com/rigdev/rig/cmd/common"
@@ -25,6 +26,13 @@ var (
 	limit   uint32
 
 	path string
+
+	runArgs            []string
+	runEnv             map[string]string
+	runQueryParameters map[string]string
+	runMaxRetries      uint32
+	runTimeout         time.Duration
+	runFollow          bool
 )
 
 type Cmd struct {
@@ -81,6 +89,43 @@ func Setup(parent *cobra.Command, s *cli.SetupContext) {
 	}
 	jobs.AddCommand(jobsDelete)
 
+	jobsRun := &cobra.Command{
+		Use:   "run [capsule] [job-name]",
+		Short: "Run a cronjob of the capsule now, outside of its schedule",
+		Long: `Run a cronjob of the capsule now, outside of its schedule. The job is run from the
+template of the cronjob, and the logs and exit status of the job are streamed
+until it has finished.`,
+		Args: cobra.MaximumNArgs(2),
+		RunE: cli.CtxWrap(cmd.run),
+		ValidArgsFunction: common.ChainCompletions(
+			[]int{1, 2},
+			cli.HackCtxWrapCompletion(cmd.capsuleCompletions, s),
+			cli.HackCtxWrapCompletion(cmd.jobCompletions, s),
+		),
+	}
+	jobsRun.Flags().StringArrayVar(
+		&runArgs, "arg", nil,
+		"Argument replacing the arguments of a job running a command. Can be given multiple times",
+	)
+	jobsRun.Flags().StringToStringVarP(
+		&runEnv, "env", "e", nil,
+		"Environment variable added to a job running a command, as KEY=VALUE. Can be given multiple times",
+	)
+	jobsRun.Flags().StringToStringVarP(
+		&runQueryParameters, "param", "q", nil,
+		"Query parameter added to a job calling a URL, as KEY=VALUE. Can be given multiple times",
+	)
+	jobsRun.Flags().Uint32Var(
+		&runMaxRetries, "max-retries", 0, "If set, replaces the maximum number of retries of the job",
+	)
+	jobsRun.Flags().DurationVar(
+		&runTimeout, "timeout", 0, "If set, replaces the timeout of the job",
+	)
+	jobsRun.Flags().BoolVarP(
+		&runFollow, "follow", "f", true, "Stream the logs of the job until it has finished",
+	)
+	jobs.AddCommand(jobsRun)
+
 	executions := &cobra.Command{
 		Use:   "executions [capsule]",
 		Short: "See executions of jobs",
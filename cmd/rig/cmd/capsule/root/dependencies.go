@@ -0,0 +1,167 @@
+package root
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-api/model"
+	"github.com/rigdev/rig/cmd/common"
+	"github.com/rigdev/rig/cmd/rig/cmd/flags"
+	v1 "github.com/rigdev/rig/pkg/api/platform/v1"
+)
+
+// dependencyNode is a capsule of an environment and the capsules it depends on.
+type dependencyNode struct {
+	Capsule      string                 `json:"capsule"`
+	Dependencies []v1.CapsuleDependency `json:"dependencies,omitempty"`
+}
+
+func (c *Cmd) dependencies(ctx context.Context) error {
+	project := c.Scope.GetCurrentContext().GetProject()
+	environment := c.Scope.GetCurrentContext().GetEnvironment()
+
+	capsules, err := c.listAllCapsules(ctx, project)
+	if err != nil {
+		return err
+	}
+
+	var nodes []dependencyNode
+	for _, cc := range capsules {
+		resp, err := c.Rig.Capsule().Get(ctx, connect.NewRequest(&capsule.GetRequest{
+			CapsuleId: cc.GetCapsuleId(),
+			ProjectId: project,
+		}))
+		if err != nil {
+			return err
+		}
+
+		for _, rev := range resp.Msg.GetEnvironmentRevisions() {
+			if rev.GetSpec().GetEnvironment() != environment {
+				continue
+			}
+
+			nodes = append(nodes, dependencyNode{
+				Capsule:      cc.GetCapsuleId(),
+				Dependencies: v1.CapsuleDependenciesFromProto(rev.GetSpec().GetSpec()),
+			})
+		}
+	}
+	slices.SortFunc(nodes, func(n1, n2 dependencyNode) int {
+		return strings.Compare(n1.Capsule, n2.Capsule)
+	})
+
+	if flags.Flags.OutputType != common.OutputTypePretty {
+		return common.FormatPrint(nodes, flags.Flags.OutputType)
+	}
+
+	if len(nodes) == 0 {
+		fmt.Println("No capsules deployed in environment", environment)
+		return nil
+	}
+
+	fmt.Print(renderDependencyGraph(nodes))
+	return nil
+}
+
+// listAllCapsules lists the capsules of the project, a page at a time.
+func (c *Cmd) listAllCapsules(ctx context.Context, project string) ([]*capsule.Capsule, error) {
+	const pageSize = 50
+
+	var capsules []*capsule.Capsule
+	for {
+		resp, err := c.Rig.Capsule().List(ctx, connect.NewRequest(&capsule.ListRequest{
+			Pagination: &model.Pagination{
+				Offset: uint32(len(capsules)),
+				Limit:  pageSize,
+			},
+			ProjectId: project,
+		}))
+		if err != nil {
+			return nil, err
+		}
+
+		capsules = append(capsules, resp.Msg.GetCapsules()...)
+		if len(resp.Msg.GetCapsules()) == 0 || uint64(len(capsules)) >= resp.Msg.GetTotal() {
+			return capsules, nil
+		}
+	}
+}
+
+// renderDependencyGraph renders the capsules as trees of dependencies. The
+// roots are the capsules no other capsule depends on. Capsules only part of
+// a cycle are rendered as roots afterwards.
+func renderDependencyGraph(nodes []dependencyNode) string {
+	byName := map[string]dependencyNode{}
+	dependedOn := map[string]bool{}
+	for _, n := range nodes {
+		byName[n.Capsule] = n
+		for _, d := range n.Dependencies {
+			if d.Namespace == "" {
+				dependedOn[d.Capsule] = true
+			}
+		}
+	}
+
+	builder := &strings.Builder{}
+	rendered := map[string]bool{}
+	var render func(name string, prefix string, path []string)
+	render = func(name string, prefix string, path []string) {
+		rendered[name] = true
+		n := byName[name]
+		for i, d := range n.Dependencies {
+			branch, indent := "├── ", "│   "
+			if i == len(n.Dependencies)-1 {
+				branch, indent = "└── ", "    "
+			}
+
+			var notes []string
+			if d.Namespace != "" {
+				notes = append(notes, "namespace "+d.Namespace)
+			}
+			if d.WaitForReady {
+				notes = append(notes, "wait for ready")
+			}
+
+			_, deployed := byName[d.Capsule]
+			cycle := slices.Contains(path, d.Capsule)
+			switch {
+			case d.Namespace != "":
+			case cycle:
+				notes = append(notes, red.Sprint("cycle"))
+			case !deployed:
+				notes = append(notes, yellow.Sprint("not deployed"))
+			}
+
+			line := fmt.Sprintf("%s (%s)", d.Capsule, d.Interface)
+			if len(notes) > 0 {
+				line += " [" + strings.Join(notes, ", ") + "]"
+			}
+			builder.WriteString(prefix + branch + line + "\n")
+
+			if d.Namespace == "" && deployed && !cycle {
+				render(d.Capsule, prefix+indent, append(path, d.Capsule))
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		if dependedOn[n.Capsule] {
+			continue
+		}
+		builder.WriteString(n.Capsule + "\n")
+		render(n.Capsule, "", []string{n.Capsule})
+	}
+	for _, n := range nodes {
+		if rendered[n.Capsule] {
+			continue
+		}
+		builder.WriteString(n.Capsule + "\n")
+		render(n.Capsule, "", []string{n.Capsule})
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,34 @@
+package root
+
+import (
+	"testing"
+
+	v1 "github.com/rigdev/rig/pkg/api/platform/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_renderDependencyGraph(t *testing.T) {
+	nodes := []dependencyNode{
+		{Capsule: "api", Dependencies: []v1.CapsuleDependency{
+			{Capsule: "users", Interface: "grpc", WaitForReady: true},
+			{Capsule: "db", Interface: "postgres", Namespace: "data"},
+			{Capsule: "cache", Interface: "redis"},
+		}},
+		{Capsule: "frontend", Dependencies: []v1.CapsuleDependency{{Capsule: "api", Interface: "http"}}},
+		{Capsule: "ping", Dependencies: []v1.CapsuleDependency{{Capsule: "pong", Interface: "http"}}},
+		{Capsule: "pong", Dependencies: []v1.CapsuleDependency{{Capsule: "ping", Interface: "http"}}},
+		{Capsule: "users"},
+		{Capsule: "worker"},
+	}
+
+	require.Equal(t, `frontend
+└── api (http)
+    ├── users (grpc) [wait for ready]
+    ├── db (postgres) [namespace data]
+    └── cache (redis) [not deployed]
+worker
+ping
+└── pong (http)
+    └── ping (http) [cycle]
+`, renderDependencyGraph(nodes))
+}
@@ -0,0 +1,238 @@
+package root
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-api/api/v1/settings"
+	platformv1 "github.com/rigdev/rig-go-api/platform/v1"
+	"github.com/rigdev/rig/cmd/common"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+	"golang.org/x/exp/maps"
+	"google.golang.org/protobuf/types/known/structpb"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// promptExtensions prompts for a capsule extension and its new value. Returns the
+// spec of the capsule with the updated extension.
+func (c *Cmd) promptExtensions(
+	ctx context.Context,
+	spec *platformv1.CapsuleSpec,
+) (*platformv1.CapsuleSpec, error) {
+	resp, err := c.Rig.Settings().GetConfiguration(ctx, connect.NewRequest(&settings.GetConfigurationRequest{}))
+	if err != nil {
+		return nil, err
+	}
+
+	extensions := resp.Msg.GetConfiguration().GetCapsuleExtensions()
+	if len(extensions) == 0 {
+		return nil, errors.FailedPreconditionErrorf("the platform has no capsule extensions configured")
+	}
+
+	names := maps.Keys(extensions)
+	slices.Sort(names)
+	_, name, err := c.Prompter.Select("Select the extension to update", names, common.SelectEnableFilterOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &apiextensionsv1.JSONSchemaProps{}
+	if err := json.Unmarshal([]byte(extensions[name].GetJsonSchema()), schema); err != nil {
+		return nil, fmt.Errorf("invalid schema of extension '%s': %w", name, err)
+	}
+
+	value := spec.GetExtensions()[name].AsMap()
+	value, err = promptExtensionValue(c.Prompter, schema, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.Extensions == nil {
+		spec.Extensions = map[string]*structpb.Struct{}
+	}
+	if len(value) == 0 {
+		delete(spec.Extensions, name)
+		return spec, nil
+	}
+
+	s, err := structpb.NewStruct(value)
+	if err != nil {
+		return nil, err
+	}
+	spec.Extensions[name] = s
+
+	return spec, nil
+}
+
+// promptExtensionValue prompts for the properties of an extension value, until the
+// value is valid according to the schema of the extension.
+func promptExtensionValue(
+	prompter common.Prompter,
+	schema *apiextensionsv1.JSONSchemaProps,
+	value map[string]any,
+) (map[string]any, error) {
+	if value == nil {
+		value = map[string]any{}
+	}
+
+	properties := maps.Keys(schema.Properties)
+	slices.Sort(properties)
+
+	for {
+		var choices []string
+		for _, p := range properties {
+			current := "-"
+			if v, ok := value[p]; ok {
+				current = formatExtensionValue(v)
+			}
+			choices = append(choices, fmt.Sprintf("%s: %s", p, current))
+		}
+		choices = append(choices, "Done")
+
+		i, _, err := prompter.Select("Select the property to update (empty input unsets it)", choices)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == len(properties) {
+			if err := validateExtensionValue(schema, value); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			return value, nil
+		}
+
+		property := properties[i]
+		v, err := promptExtensionProperty(prompter, property, schema.Properties[property], value[property])
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			delete(value, property)
+		} else {
+			value[property] = v
+		}
+	}
+}
+
+func promptExtensionProperty(
+	prompter common.Prompter,
+	name string,
+	schema apiextensionsv1.JSONSchemaProps,
+	current any,
+) (any, error) {
+	label := name
+	if schema.Description != "" {
+		label = fmt.Sprintf("%s (%s)", name, schema.Description)
+	}
+
+	def := ""
+	if current != nil {
+		def = formatExtensionValue(current)
+	}
+
+	if len(schema.Enum) > 0 {
+		var choices []string
+		for _, e := range schema.Enum {
+			choices = append(choices, strings.Trim(string(e.Raw), `"`))
+		}
+		choices = append(choices, "-")
+		i, _, err := prompter.Select(label, choices)
+		if err != nil {
+			return nil, err
+		}
+		if i == len(schema.Enum) {
+			return nil, nil
+		}
+		var v any
+		return v, json.Unmarshal(schema.Enum[i].Raw, &v)
+	}
+
+	var validate func(string) error
+	switch schema.Type {
+	case "boolean":
+		validate = common.ValidateBool
+	case "integer":
+		validate = common.ValidateInt
+	case "number":
+		validate = func(s string) error {
+			_, err := strconv.ParseFloat(s, 64)
+			return err
+		}
+	case "string":
+		validate = common.ValidateAll
+	default:
+		label += " as YAML"
+		validate = func(s string) error {
+			var v any
+			return yaml.Unmarshal([]byte(s), &v)
+		}
+	}
+
+	input, err := prompter.Input(label, common.InputDefaultOpt(def), common.ValidateAllowEmptyOpt(validate))
+	if err != nil {
+		return nil, err
+	}
+	if input == "" {
+		return nil, nil
+	}
+
+	switch schema.Type {
+	case "boolean":
+		return strconv.ParseBool(input)
+	case "integer", "number":
+		return strconv.ParseFloat(input, 64)
+	case "string":
+		return input, nil
+	default:
+		var v any
+		return v, yaml.Unmarshal([]byte(input), &v)
+	}
+}
+
+func formatExtensionValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(bs)
+}
+
+func validateExtensionValue(schema *apiextensionsv1.JSONSchemaProps, value map[string]any) error {
+	res, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schema), gojsonschema.NewGoLoader(value))
+	if err != nil {
+		return err
+	}
+	if res.Valid() {
+		return nil
+	}
+
+	var msgs []string
+	for _, e := range res.Errors() {
+		msgs = append(msgs, e.String())
+	}
+	return fmt.Errorf("invalid extension: %s", strings.Join(msgs, ", "))
+}
+
+// environmentSpec returns the spec of the capsule in the current environment.
+func (c *Cmd) environmentSpec(resp *capsule.GetResponse) (*platformv1.CapsuleSpec, error) {
+	environmentID := c.Scope.GetCurrentContext().GetEnvironment()
+	for _, env := range resp.GetEnvironmentRevisions() {
+		if env.GetSpec().GetEnvironment() == environmentID {
+			return env.GetSpec().GetSpec(), nil
+		}
+	}
+
+	return nil, errors.NotFoundErrorf("capsule is not deployed to environment '%s'", environmentID)
+}
@@ -0,0 +1,64 @@
+package root
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rigdev/rig/cmd/common"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// scriptedPrompter answers selects and inputs in the order given.
+type scriptedPrompter struct {
+	common.Prompter
+	selects []int
+	inputs  []string
+}
+
+func (p *scriptedPrompter) Select(_ string, choices []string, _ ...common.SelectInputOption) (int, string, error) {
+	i := p.selects[0]
+	p.selects = p.selects[1:]
+	return i, choices[i], nil
+}
+
+func (p *scriptedPrompter) Input(_ string, _ ...common.GetInputOption) (string, error) {
+	s := p.inputs[0]
+	p.inputs = p.inputs[1:]
+	return s, nil
+}
+
+func Test_promptExtensionValue_complexProperties(t *testing.T) {
+	schema := &apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"args": {
+				Type:  "array",
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"}},
+			},
+			"labels": {
+				Type: "object",
+				AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{
+					Allows: true,
+					Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"},
+				},
+			},
+			"replicas": {Type: "integer"},
+		},
+	}
+
+	// Properties are listed sorted by name, followed by Done. The first value of
+	// labels is rejected by the schema, so it is prompted for again.
+	prompter := &scriptedPrompter{
+		selects: []int{0, 1, 3, 1, 3},
+		inputs:  []string{"[c, d]", "tier: 1", `tier: "1"`},
+	}
+	value, err := promptExtensionValue(prompter, schema, map[string]any{"replicas": float64(2)})
+	require.NoError(t, err)
+	require.Empty(t, prompter.selects)
+	require.Empty(t, prompter.inputs)
+
+	bs, err := json.Marshal(value)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"args": ["c", "d"], "labels": {"tier": "1"}, "replicas": 2}`, string(bs))
+}
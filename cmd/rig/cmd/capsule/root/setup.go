@@ -36,6 +36,7 @@ var (
 	follow             bool
 	previousContainers bool
 	verbose            bool
+	showDependencies   bool
 	force              bool
 	dryRun             bool
 	timeout            time.Duration
@@ -185,6 +186,8 @@ func Setup(parent *cobra.Command, s *cli.SetupContext) {
 		"show more detailed status information. Only valid with --output=pretty (default)")
 	capsuleStatus.Flags().BoolVarP(&follow, "follow", "f", false,
 		"keep the connection open and read status until canceled. Only valid with --verbose")
+	capsuleStatus.Flags().BoolVar(&showDependencies, "dependencies", false,
+		"show the dependency graph of the capsules in the environment instead of the status of a capsule")
 	capsuleCmd.AddCommand(capsuleStatus)
 
 	capsulePortForward := &cobra.Command{
@@ -243,10 +246,13 @@ forwarded traffic.
 			common.MaxArgsCompletionFilter(1)),
 		RunE: cli.CtxWrap(cmd.update),
 	}
-	// TODO Add once we have implemented the backend
-	if false {
-		capsuleCmd.AddCommand(capsuleUpdate)
-	}
+	capsuleUpdate.Flags().DurationVarP(
+		&timeout, "timeout", "t", 0,
+		"timeout for when the deploy command should terminate."+
+			" If not set, the command will wait until the rollout is done",
+	)
+	capsuleUpdate.Flags().BoolVar(&noWait, "no-wait", false, "skip waiting for the changes to be applied.")
+	capsuleCmd.AddCommand(capsuleUpdate)
 
 	capsuleListProposal := &cobra.Command{
 		Use:   "list-proposals",
@@ -324,6 +330,11 @@ func (c *Cmd) persistentPreRunE(ctx context.Context, cmd *cobra.Command, args []
 		return nil
 	}
 
+	// The dependency graph covers all capsules of the environment.
+	if cmd.Name() == "status" && showDependencies {
+		return nil
+	}
+
 	if len(args) > 0 {
 		capsule.CapsuleID = args[0]
 		return nil
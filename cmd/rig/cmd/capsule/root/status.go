@@ -27,6 +27,10 @@ var (
 )
 
 func (c *Cmd) status(ctx context.Context, _ *cobra.Command, _ []string) error {
+	if showDependencies {
+		return c.dependencies(ctx)
+	}
+
 	if !verbose {
 		statusResp, err := c.Rig.Capsule().GetStatus(ctx, &connect.Request[capsule.GetStatusRequest]{
 			Msg: &capsule.GetStatusRequest{
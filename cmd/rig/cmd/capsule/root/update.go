@@ -8,11 +8,15 @@ import (
 	"connectrpc.com/connect"
 	"github.com/rigdev/rig-go-api/api/v1/capsule"
 	"github.com/rigdev/rig-go-api/api/v1/environment"
+	platformv1 "github.com/rigdev/rig-go-api/platform/v1"
 	"github.com/rigdev/rig/cmd/common"
 	capsule_cmd "github.com/rigdev/rig/cmd/rig/cmd/capsule"
 	"github.com/spf13/cobra"
 )
 
+// TODO Enable once we have implemented the backend.
+const gitStoreUpdateEnabled = false
+
 func (c *Cmd) update(ctx context.Context, _ *cobra.Command, _ []string) error {
 	if !c.Scope.IsInteractive() {
 		return errors.New("non-interactive mode is not supported for this command")
@@ -27,6 +31,11 @@ func (c *Cmd) update(ctx context.Context, _ *cobra.Command, _ []string) error {
 	}
 	cc := resp.Msg.GetCapsule()
 
+	spec, err := c.environmentSpec(resp.Msg)
+	if err != nil {
+		return err
+	}
+
 	envResp, err := c.Rig.Environment().List(ctx, connect.NewRequest(&environment.ListRequest{
 		ProjectFilter: c.Scope.GetCurrentContext().GetProject(),
 	}))
@@ -34,10 +43,16 @@ func (c *Cmd) update(ctx context.Context, _ *cobra.Command, _ []string) error {
 		return err
 	}
 
+	options := []string{"Extensions"}
+	if gitStoreUpdateEnabled {
+		options = append(options, "Git store")
+	}
+	options = append(options, "Done")
+
 	var updates []*capsule.Update
+	var newSpec *platformv1.CapsuleSpec
 	for {
-		i, _, err := c.Prompter.Select("Select the setting to update (CTRL + C to cancel)",
-			[]string{"Git store", "Done"})
+		_, setting, err := c.Prompter.Select("Select the setting to update (CTRL + C to cancel)", options)
 		if err != nil {
 			if common.ErrIsAborted(err) {
 				return nil
@@ -46,8 +61,17 @@ func (c *Cmd) update(ctx context.Context, _ *cobra.Command, _ []string) error {
 		}
 
 		done := false
-		switch i {
-		case 0:
+		switch setting {
+		case "Extensions":
+			s, err := c.promptExtensions(ctx, spec)
+			if err != nil {
+				if common.ErrIsAborted(err) {
+					continue
+				}
+				return err
+			}
+			newSpec = s
+		case "Git store":
 			cc.GitStore, err = common.PromptGitStore(c.Prompter, cc.GetGitStore(), envResp.Msg.GetEnvironments())
 			if err != nil {
 				if common.ErrIsAborted(err) {
@@ -61,7 +85,7 @@ func (c *Cmd) update(ctx context.Context, _ *cobra.Command, _ []string) error {
 					SetGitStore: cc.GitStore,
 				},
 			})
-		case 1:
+		case "Done":
 			done = true
 		}
 		if done {
@@ -69,20 +93,41 @@ func (c *Cmd) update(ctx context.Context, _ *cobra.Command, _ []string) error {
 		}
 	}
 
-	if len(updates) == 0 {
+	if len(updates) == 0 && newSpec == nil {
 		fmt.Println("No updates to make")
 		return nil
 	}
 
-	if _, err = c.Rig.Capsule().Update(ctx, connect.NewRequest(&capsule.UpdateRequest{
-		Updates:   updates,
-		ProjectId: c.Scope.GetCurrentContext().GetProject(),
-		CapsuleId: capsuleID,
-	})); err != nil {
-		return err
+	if len(updates) > 0 {
+		if _, err = c.Rig.Capsule().Update(ctx, connect.NewRequest(&capsule.UpdateRequest{
+			Updates:   updates,
+			ProjectId: c.Scope.GetCurrentContext().GetProject(),
+			CapsuleId: capsuleID,
+		})); err != nil {
+			return err
+		}
+		fmt.Println("Capsule updated")
 	}
 
-	fmt.Println("Capsule updated")
+	if newSpec == nil {
+		return nil
+	}
 
-	return nil
+	return capsule_cmd.DeployAndWait(capsule_cmd.DeployAndWaitInput{
+		DeployInput: capsule_cmd.DeployInput{
+			BaseInput: capsule_cmd.BaseInput{
+				Ctx:           ctx,
+				Rig:           c.Rig,
+				ProjectID:     c.Scope.GetCurrentContext().GetProject(),
+				EnvironmentID: c.Scope.GetCurrentContext().GetEnvironment(),
+				CapsuleID:     capsuleID,
+			},
+			Changes: []*capsule.Change{{
+				Field: &capsule.Change_Spec{Spec: newSpec},
+			}},
+			ForceDeploy: true,
+		},
+		Timeout: timeout,
+		NoWait:  noWait,
+	})
 }
@@ -2,12 +2,18 @@ package scale
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
+	"connectrpc.com/connect"
 	"github.com/jedib0t/go-pretty/v6/table"
 	capsule_api "github.com/rigdev/rig-go-api/api/v1/capsule"
 	"github.com/rigdev/rig/cmd/common"
 	"github.com/rigdev/rig/cmd/rig/cmd/capsule"
 	"github.com/rigdev/rig/cmd/rig/cmd/flags"
+	v1 "github.com/rigdev/rig/pkg/api/platform/v1"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
 	"github.com/rigdev/rig/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -25,11 +31,17 @@ func (c *Cmd) get(ctx context.Context, cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
+	schedules, err := c.getScaleSchedules(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
 	if flags.Flags.OutputType != common.OutputTypePretty {
 		obj := scaleObj{
 			Replicas:      rollout.GetConfig().GetReplicas(),
 			ContainerSize: containerSettings.GetResources(),
 			Autoscaler:    rollout.GetConfig().GetHorizontalScale(),
+			Schedules:     schedules,
 		}
 		return common.FormatPrint(obj, flags.Flags.OutputType)
 	}
@@ -56,9 +68,90 @@ func (c *Cmd) get(ctx context.Context, cmd *cobra.Command, _ []string) error {
 	t.AppendRow(table.Row{"Replicas", replicas})
 	cmd.Println(t.Render())
 
+	if len(schedules) == 0 {
+		return nil
+	}
+
+	t = table.NewWriter()
+	t.AppendHeader(table.Row{"Schedule", "Min", "Max", "Active window", "Next window"})
+	for _, s := range schedules {
+		name := s.Name
+		if name == "" {
+			name = s.Schedule
+		}
+		active := "-"
+		if s.Active != nil {
+			active = formatWindow(*s.Active)
+		}
+		t.AppendRow(table.Row{
+			name,
+			formatOverride(s.Min),
+			formatOverride(s.Max),
+			active,
+			formatWindow(s.Next),
+		})
+	}
+	cmd.Println(t.Render())
+
 	return nil
 }
 
+// getScaleSchedules returns the scale schedules of the capsule in the current
+// environment, with their active and next windows at the given time.
+func (c *Cmd) getScaleSchedules(ctx context.Context, now time.Time) ([]scheduleObj, error) {
+	resp, err := c.Rig.Capsule().Get(ctx, connect.NewRequest(&capsule_api.GetRequest{
+		CapsuleId: capsule.CapsuleID,
+		ProjectId: c.Scope.GetCurrentContext().GetProject(),
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	var horizontal v1.HorizontalScale
+	for _, env := range resp.Msg.GetEnvironmentRevisions() {
+		if env.GetSpec().GetEnvironment() != c.Scope.GetCurrentContext().GetEnvironment() {
+			continue
+		}
+		bs, err := json.Marshal(env.GetSpec().GetSpec().GetScale().GetHorizontal())
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(bs, &horizontal); err != nil {
+			return nil, err
+		}
+	}
+
+	var res []scheduleObj
+	for _, s := range horizontal.Schedules {
+		schedule := s.ToK8s()
+		obj := scheduleObj{ScaleSchedule: schedule}
+		if start, end, ok, err := schedule.ActiveWindow(now); err != nil {
+			return nil, err
+		} else if ok {
+			obj.Active = &window{Start: start, End: end}
+		}
+		start, end, err := schedule.NextWindow(now)
+		if err != nil {
+			return nil, err
+		}
+		obj.Next = window{Start: start, End: end}
+		res = append(res, obj)
+	}
+
+	return res, nil
+}
+
+func formatWindow(w window) string {
+	return fmt.Sprintf("%s - %s", w.Start.Format("2006-01-02 15:04"), w.End.Format("2006-01-02 15:04 MST"))
+}
+
+func formatOverride(n *uint32) string {
+	if n == nil {
+		return "-"
+	}
+	return fmt.Sprint(*n)
+}
+
 func formatLimitString(fmt func(uint64) string, n uint64) string {
 	if n == 0 {
 		return "-"
@@ -70,4 +163,16 @@ type scaleObj struct {
 	Replicas      uint32                       `json:"replicas"`
 	ContainerSize *capsule_api.Resources       `json:"resources"`
 	Autoscaler    *capsule_api.HorizontalScale `json:"autoscaler,omitempty"`
+	Schedules     []scheduleObj                `json:"schedules,omitempty"`
+}
+
+type scheduleObj struct {
+	v1alpha2.ScaleSchedule
+	Active *window `json:"active,omitempty"`
+	Next   window  `json:"next"`
+}
+
+type window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
 }
@@ -60,7 +60,7 @@ func Setup(parent *cobra.Command, s *cli.SetupContext) {
 
 	scaleGet := &cobra.Command{
 		Use:   "get [capsule]",
-		Short: "Displays the resources (container size) and replicas of the capsule",
+		Short: "Displays the resources (container size), replicas and scale schedules of the capsule",
 		Args:  cobra.MaximumNArgs(1),
 		ValidArgsFunction: common.Complete(cli.HackCtxWrapCompletion(cmd.completions, s),
 			common.MaxArgsCompletionFilter(1)),
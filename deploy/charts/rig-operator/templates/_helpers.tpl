@@ -103,3 +103,29 @@ Create the name of the service account to use
 {{- default "default" .Values.certgen.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Create the fullname of activator resources
+*/}}
+{{- define "rig-operator.activator.fullname" -}}
+{{- include "rig-operator.fullname" . | printf "%s-activator" -}}
+{{- end }}
+
+{{/*
+Activator selector labels
+*/}}
+{{- define "rig-operator.activator.selectorLabels" -}}
+app.kubernetes.io/name: {{ include "rig-operator.name" . }}-activator
+app.kubernetes.io/instance: {{ .Release.Name }}
+{{- end }}
+
+{{/*
+Create the name of the service account to use
+*/}}
+{{- define "rig-operator.activator.serviceAccountName" -}}
+{{- if .Values.activator.serviceAccount.create }}
+{{- default (include "rig-operator.activator.fullname" .) .Values.activator.serviceAccount.name }}
+{{- else }}
+{{- default "default" .Values.activator.serviceAccount.name }}
+{{- end }}
+{{- end }}
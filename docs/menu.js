@@ -517,6 +517,18 @@ const sidebars = {
               label: "Service Monitor",
               className: "homepage-sidebar-item",
             },
+            {
+              type: "doc",
+              id: "operator-manual/plugins/capsulesteps/rollout",
+              label: "Rollout",
+              className: "homepage-sidebar-item",
+            },
+            {
+              type: "doc",
+              id: "operator-manual/plugins/capsulesteps/network_policy",
+              label: "Network Policy",
+              className: "homepage-sidebar-item",
+            },
           ],
         },
         {
@@ -30,6 +30,61 @@ type OperatorConfig struct {
 
 	// Pipeline defines the capsule controller pipeline
 	Pipeline Pipeline `json:"pipeline,omitempty" protobuf:"7"`
+
+	// DriftDetection periodically compares the resources owned by capsules
+	// against their desired state, to detect changes made outside of the operator.
+	DriftDetection DriftDetection `json:"driftDetection,omitempty" protobuf:"8"`
+
+	// ConfigReload reloads the pipeline when the config file changes, without
+	// restarting the operator.
+	ConfigReload ConfigReload `json:"configReload,omitempty" protobuf:"9"`
+
+	// ExecutionHistory records the executions of the pipeline of each capsule,
+	// in a ConfigMap next to the capsule.
+	ExecutionHistory ExecutionHistory `json:"executionHistory,omitempty" protobuf:"10"`
+}
+
+type ExecutionHistory struct {
+	// Enabled enables recording executions which changed objects, failed or
+	// were of a new generation of the capsule.
+	Enabled bool `json:"enabled,omitempty" protobuf:"1"`
+	// MaxRecords is the maximum number of records kept per capsule.
+	// Defaults to 10.
+	MaxRecords uint32 `json:"maxRecords,omitempty" protobuf:"2"`
+	// MaxAgeSeconds is the maximum age of the records kept. The newest record
+	// is always kept. If 0, records are kept regardless of their age.
+	MaxAgeSeconds uint32 `json:"maxAgeSeconds,omitempty" protobuf:"3"`
+}
+
+type ConfigReload struct {
+	// Enabled enables watching the config file for changes to the pipeline.
+	// Changes to other parts of the config still require a restart.
+	Enabled bool `json:"enabled,omitempty" protobuf:"1"`
+	// DrainTimeoutSeconds is the maximum time to wait for running executions of
+	// the replaced pipeline to finish, before stopping its plugins.
+	// Defaults to 60.
+	DrainTimeoutSeconds uint32 `json:"drainTimeoutSeconds,omitempty" protobuf:"2"`
+}
+
+type DriftDetectionMode string
+
+const (
+	// DriftDetectionModeReport only reports drift in the status of the capsule.
+	DriftDetectionModeReport DriftDetectionMode = "report"
+	// DriftDetectionModeCorrect reports drift and reconciles the capsule to revert it.
+	DriftDetectionModeCorrect DriftDetectionMode = "correct"
+)
+
+type DriftDetection struct {
+	// Enabled enables the drift scanner.
+	Enabled bool `json:"enabled,omitempty" protobuf:"1"`
+	// IntervalSeconds is the time between two scans of all capsules.
+	// Defaults to 300.
+	IntervalSeconds uint32 `json:"intervalSeconds,omitempty" protobuf:"2"`
+	// Mode is either `report`, which only reports drift in the status of the
+	// capsule, or `correct`, which also reconciles capsules with drift.
+	// Defaults to `report`.
+	Mode DriftDetectionMode `json:"mode,omitempty" protobuf:"3"`
 }
 
 type Pipeline struct {
@@ -53,6 +108,12 @@ type Pipeline struct {
 	// rigdev.service_monitor plugin spawns a Prometheus ServiceMonitor per capsule
 	// for use with a Prometheus Operator stack.
 	ServiceMonitorStep CapsuleStep `json:"serviceMonitorStep,omitempty" protobuf:"6"`
+	// How to handle the network policy step of capsules in the cluster.
+	// If left empty, no network policies will be created.
+	// rigdev.network_policy plugin creates a NetworkPolicy per capsule, only
+	// allowing traffic to its interfaces from the ingress controller and the
+	// capsules and namespaces it allows.
+	NetworkPolicyStep CapsuleStep `json:"networkPolicyStep,omitempty" protobuf:"12"`
 	// Steps to perform as part of running the operator.
 	// +patchStrategy=merge
 	Steps []Step `json:"steps,omitempty" protobuf:"7"`
@@ -62,8 +123,53 @@ type Pipeline struct {
 	// CapsuleExtensions supported by the Operator. Each extension supported
 	// should be configured in the map, with an additional plugin name.
 	CapsuleExtensions map[string]CapsuleStep `json:"capsuleExtensions,omitempty" protobuf:"9"`
+	// ServerSideApply applies the objects of capsules using server-side apply.
+	// Each step applies the fields it sets under its own field manager, named
+	// `rig-operator/<step>`, leaving fields managed by others, such as
+	// autoscalers or kubectl, untouched. Fields conflicting with other managers
+	// are not applied, but reported in the status of the capsule, unless the
+	// capsule has the `rig.dev/override-ownership` annotation.
+	ServerSideApply bool `json:"serverSideApply,omitempty" protobuf:"10"`
+	// PluginSandboxes limits what plugins can do, keyed by the name of the plugin.
+	// Intended for third-party plugins, which otherwise run with the
+	// permissions of the operator and without time limits.
+	PluginSandboxes map[string]PluginSandbox `json:"pluginSandboxes,omitempty" protobuf:"11"`
+}
+
+// PluginSandbox limits what a plugin can do. Violations are reported as errors
+// of the plugin in the status of the capsule.
+type PluginSandbox struct {
+	// TimeoutSeconds is the maximum duration of running the plugin for a capsule
+	// or computing its configuration. Defaults to 10.
+	TimeoutSeconds uint32 `json:"timeoutSeconds,omitempty" protobuf:"1"`
+	// AllowedObjects are the types of objects the plugin can set or delete.
+	// If empty, the plugin can set and delete objects of any type.
+	AllowedObjects []ObjectType `json:"allowedObjects,omitempty" protobuf:"2"`
+	// ClientAccess is the access of the Kubernetes client given to the plugin,
+	// either `readWrite`, with the permissions of the operator, or `readOnly`.
+	// Defaults to `readWrite`.
+	ClientAccess PluginClientAccess `json:"clientAccess,omitempty" protobuf:"3"`
 }
 
+// ObjectType is a type of Kubernetes objects.
+type ObjectType struct {
+	// Group of the objects, empty for the core group.
+	Group string `json:"group,omitempty" protobuf:"1"`
+	// Kind of the objects. If `*`, all kinds of the group are matched.
+	Kind string `json:"kind" protobuf:"2"`
+}
+
+type PluginClientAccess string
+
+const (
+	// PluginClientAccessReadWrite gives the plugin a client with the
+	// permissions of the operator.
+	PluginClientAccessReadWrite PluginClientAccess = "readWrite"
+	// PluginClientAccessReadOnly gives the plugin a client which can only
+	// read objects.
+	PluginClientAccessReadOnly PluginClientAccess = "readOnly"
+)
+
 type CapsuleStep struct {
 	// The plugin to use for handling the capsule step.
 	// fx. "rigdev.ingress_routes" for routesStep will create an ingress resource per route.
@@ -92,6 +198,11 @@ type Step struct {
 	// If set, will enable the step for the Rig platform which is a Capsule as well
 	// Deprecated, use Match.EnableForPlatform.
 	EnableForPlatform bool `json:"enableForPlatform,omitempty" protobuf:"6"`
+
+	// After are tags of other steps, which must run before this step.
+	After []string `json:"after,omitempty" protobuf:"7"`
+	// Before are tags of other steps, which must run after this step.
+	Before []string `json:"before,omitempty" protobuf:"8"`
 }
 
 type CapsuleMatch struct {
@@ -103,6 +214,11 @@ type CapsuleMatch struct {
 	Annotations map[string]string `json:"annotations,omitempty" protobuf:"3"`
 	// If set, will enable the step for the Rig platform which is a Capsule as well
 	EnableForPlatform bool `json:"enableForPlatform,omitempty" protobuf:"4"`
+	// If set, only execute the plugin on the capsules for which the CEL
+	// expression evaluates to true. The capsule is available as `capsule`, e.g.
+	// `capsule.spec.image.startsWith("ghcr.io/")` or
+	// `has(capsule.metadata.labels) && capsule.metadata.labels["team"] == "core"`.
+	Condition string `json:"condition,omitempty" protobuf:"5"`
 }
 
 type CustomPlugin struct {
@@ -156,5 +272,17 @@ func (c *OperatorConfig) Default() *OperatorConfig {
 	if c.LeaderElectionEnabled == nil {
 		c.LeaderElectionEnabled = ptr.New(true)
 	}
+	if c.DriftDetection.IntervalSeconds == 0 {
+		c.DriftDetection.IntervalSeconds = 300
+	}
+	if c.DriftDetection.Mode == "" {
+		c.DriftDetection.Mode = DriftDetectionModeReport
+	}
+	if c.ConfigReload.DrainTimeoutSeconds == 0 {
+		c.ConfigReload.DrainTimeoutSeconds = 60
+	}
+	if c.ExecutionHistory.MaxRecords == 0 {
+		c.ExecutionHistory.MaxRecords = 10
+	}
 	return c
 }
@@ -137,16 +137,6 @@ func (e Extension) validate(path *field.Path) field.ErrorList {
 		return errs
 	}
 
-	for key, prop := range e.Schema.Properties {
-		if prop.Type == "object" || prop.Type == "array" {
-			errs = append(errs,
-				field.Invalid(
-					schemaPath.Child("properties").Key(key).Child("type"),
-					prop.Type, "complex child properties are not yet supported"),
-			)
-		}
-	}
-
 	if _, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(e.Schema)); err != nil {
 		errs = append(errs, field.Invalid(schemaPath, e.Schema, err.Error()))
 	}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
 
 func TestPlatformConfigValidate(t *testing.T) {
@@ -102,6 +103,46 @@ func TestPlatformConfigValidate(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "extension with object and array properties",
+			cfg: &PlatformConfig{
+				CapsuleExtensions: map[string]Extension{
+					"placement": {Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"nodeSelector": {
+								Type: "object",
+								AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{
+									Allows: true,
+									Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"},
+								},
+							},
+							"tolerations": {
+								Type: "array",
+								Items: &apiextensionsv1.JSONSchemaPropsOrArray{
+									Schema: &apiextensionsv1.JSONSchemaProps{
+										Type: "object",
+										Properties: map[string]apiextensionsv1.JSONSchemaProps{
+											"key": {Type: "string"},
+										},
+									},
+								},
+							},
+						},
+					}},
+				},
+			},
+		},
+		{
+			name: "extension without object schema",
+			cfg: &PlatformConfig{
+				CapsuleExtensions: map[string]Extension{
+					"placement": {Schema: &apiextensionsv1.JSONSchemaProps{Type: "array"}},
+				},
+			},
+			expectedErr: "capsuleExtensions[placement].schema.type: Invalid value: \"array\": " +
+				"top level schema must be of type 'object'",
+		},
 	}
 
 	for _, tt := range tests {
@@ -278,6 +278,21 @@ func (in *ClusterGit) DeepCopy() *ClusterGit {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigReload) DeepCopyInto(out *ConfigReload) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigReload.
+func (in *ConfigReload) DeepCopy() *ConfigReload {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigReload)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CustomPlugin) DeepCopyInto(out *CustomPlugin) {
 	*out = *in
@@ -328,6 +343,21 @@ func (in *DockerRegistryCredentials) DeepCopy() *DockerRegistryCredentials {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftDetection) DeepCopyInto(out *DriftDetection) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftDetection.
+func (in *DriftDetection) DeepCopy() *DriftDetection {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftDetection)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Email) DeepCopyInto(out *Email) {
 	*out = *in
@@ -343,6 +373,21 @@ func (in *Email) DeepCopy() *Email {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecutionHistory) DeepCopyInto(out *ExecutionHistory) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecutionHistory.
+func (in *ExecutionHistory) DeepCopy() *ExecutionHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecutionHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Extension) DeepCopyInto(out *Extension) {
 	*out = *in
@@ -596,6 +641,21 @@ func (in *OIDCProvider) DeepCopy() *OIDCProvider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectType) DeepCopyInto(out *ObjectType) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectType.
+func (in *ObjectType) DeepCopy() *ObjectType {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectType)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OperatorConfig) DeepCopyInto(out *OperatorConfig) {
 	*out = *in
@@ -611,6 +671,9 @@ func (in *OperatorConfig) DeepCopyInto(out *OperatorConfig) {
 		**out = **in
 	}
 	in.Pipeline.DeepCopyInto(&out.Pipeline)
+	out.DriftDetection = in.DriftDetection
+	out.ConfigReload = in.ConfigReload
+	out.ExecutionHistory = in.ExecutionHistory
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorConfig.
@@ -655,6 +718,7 @@ func (in *Pipeline) DeepCopyInto(out *Pipeline) {
 	out.CronJobsStep = in.CronJobsStep
 	out.VPAStep = in.VPAStep
 	out.ServiceMonitorStep = in.ServiceMonitorStep
+	out.NetworkPolicyStep = in.NetworkPolicyStep
 	if in.Steps != nil {
 		in, out := &in.Steps, &out.Steps
 		*out = make([]Step, len(*in))
@@ -674,6 +738,13 @@ func (in *Pipeline) DeepCopyInto(out *Pipeline) {
 			(*out)[key] = val
 		}
 	}
+	if in.PluginSandboxes != nil {
+		in, out := &in.PluginSandboxes, &out.PluginSandboxes
+		*out = make(map[string]PluginSandbox, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Pipeline.
@@ -752,6 +823,26 @@ func (in *Plugin) DeepCopy() *Plugin {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PluginSandbox) DeepCopyInto(out *PluginSandbox) {
+	*out = *in
+	if in.AllowedObjects != nil {
+		in, out := &in.AllowedObjects, &out.AllowedObjects
+		*out = make([]ObjectType, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PluginSandbox.
+func (in *PluginSandbox) DeepCopy() *PluginSandbox {
+	if in == nil {
+		return nil
+	}
+	out := new(PluginSandbox)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrometheusServiceMonitor) DeepCopyInto(out *PrometheusServiceMonitor) {
 	*out = *in
@@ -838,6 +929,16 @@ func (in *Step) DeepCopyInto(out *Step) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.After != nil {
+		in, out := &in.After, &out.After
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Before != nil {
+		in, out := &in.Before, &out.Before
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Step.
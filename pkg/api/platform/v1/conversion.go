@@ -63,6 +63,8 @@ func RolloutConfigToCapsuleSpec(rc *capsule.RolloutConfig) (*platformv1.CapsuleS
 		spec.CronJobs = append(spec.CronJobs, CronJobConversion(j))
 	}
 
+	spec.Sidecars = makeSidecars(rc.GetSidecars())
+
 	return spec, nil
 }
 
@@ -359,6 +361,7 @@ func CapsuleSpecToRolloutConfig(spec *platformv1.CapsuleSpec) (*capsule.RolloutC
 		HorizontalScale:           HorizontalScaleSpecConversion(spec.GetScale().GetHorizontal()),
 		CronJobs:                  makeCronJobs(spec.GetCronJobs()),
 		Annotations:               maps.Clone(spec.GetAnnotations()),
+		Sidecars:                  makeSidecars(spec.GetSidecars()),
 	}
 	config.Replicas = config.GetHorizontalScale().GetMinReplicas()
 	var err error
@@ -566,6 +569,14 @@ func CronJobSpecConversion(j *platformv1.CronJob) *capsule.CronJob {
 	return job
 }
 
+func makeSidecars(sidecars []*platformv1.Sidecar) []*platformv1.Sidecar {
+	var res []*platformv1.Sidecar
+	for _, s := range sidecars {
+		res = append(res, proto.Clone(s).(*platformv1.Sidecar))
+	}
+	return res
+}
+
 func makeNetworks(spec []*platformv1.CapsuleInterface) *capsule.Network {
 	res := &capsule.Network{}
 	for _, i := range spec {
@@ -865,6 +876,34 @@ func ChangesFromSpecPair(curSpec, newSpec *platformv1.CapsuleSpec) ([]*capsule.C
 		}
 	}
 
+	sc := map[string]*platformv1.Sidecar{}
+	for _, s := range curSpec.GetSidecars() {
+		sc[s.GetName()] = s
+	}
+	for _, s1 := range newSpec.GetSidecars() {
+		if s2, ok := sc[s1.GetName()]; !ok || !proto.Equal(s1, s2) {
+			res = append(res, &capsule.Change{
+				Field: &capsule.Change_SetSidecar{
+					SetSidecar: proto.Clone(s1).(*platformv1.Sidecar),
+				},
+			})
+		}
+	}
+
+	clear(sc)
+	for _, s := range newSpec.GetSidecars() {
+		sc[s.GetName()] = s
+	}
+	for _, s := range curSpec.GetSidecars() {
+		if _, ok := sc[s.GetName()]; !ok {
+			res = append(res, &capsule.Change{
+				Field: &capsule.Change_RemoveSidecar{
+					RemoveSidecar: s.GetName(),
+				},
+			})
+		}
+	}
+
 	if !proto.Equal(curSpec.GetScale().GetHorizontal(), newSpec.GetScale().GetHorizontal()) {
 		res = append(res, &capsule.Change{
 			Field: &capsule.Change_HorizontalScale{
@@ -10,10 +10,23 @@ import (
 	platformv1 "github.com/rigdev/rig-go-api/platform/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 var (
+	sidecar = &platformv1.Sidecar{
+		Name:  "proxy",
+		Image: "envoy",
+		Args:  []string{"-c", "/etc/envoy.yaml"},
+		Env: &platformv1.EnvironmentVariables{
+			Raw: map[string]string{"key": "value"},
+		},
+		Readiness: &platformv1.SidecarProbe{
+			Port: 9901,
+			Tcp:  true,
+		},
+	}
 	rolloutConfig = &capsule.RolloutConfig{
 		ImageId: "image",
 		Network: &capsule.Network{
@@ -148,6 +161,7 @@ var (
 			},
 		}},
 		Annotations: map[string]string{"annotation": "value"},
+		Sidecars:    []*platformv1.Sidecar{sidecar},
 	}
 	spec = &platformv1.CapsuleSpec{
 		Image:   "image",
@@ -266,6 +280,7 @@ var (
 		},
 		Annotations:               map[string]string{"annotation": "value"},
 		AutoAddRigServiceAccounts: true,
+		Sidecars:                  []*platformv1.Sidecar{sidecar},
 	}
 )
 
@@ -295,6 +310,30 @@ func Test_conversion_both_ways(t *testing.T) {
 	require.Equal(t, rolloutConfig, config)
 }
 
+func Test_ChangesFromSpecPair_sidecars(t *testing.T) {
+	updated := proto.Clone(sidecar).(*platformv1.Sidecar)
+	updated.Image = "envoy:v2"
+	added := &platformv1.Sidecar{Name: "exporter", Image: "exporter"}
+
+	changes, err := ChangesFromSpecPair(
+		&platformv1.CapsuleSpec{Sidecars: []*platformv1.Sidecar{sidecar, {Name: "old", Image: "old"}}},
+		&platformv1.CapsuleSpec{Sidecars: []*platformv1.Sidecar{updated, added}},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []*capsule.Change{
+		{Field: &capsule.Change_SetSidecar{SetSidecar: updated}},
+		{Field: &capsule.Change_SetSidecar{SetSidecar: added}},
+		{Field: &capsule.Change_RemoveSidecar{RemoveSidecar: "old"}},
+	}, changes)
+
+	changes, err = ChangesFromSpecPair(
+		&platformv1.CapsuleSpec{Sidecars: []*platformv1.Sidecar{sidecar}},
+		&platformv1.CapsuleSpec{Sidecars: []*platformv1.Sidecar{sidecar}},
+	)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
 func Test_mergeCapsuleSpec(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -152,6 +152,20 @@ func CapsuleSpecCRDToProto(spec CapsuleSpec, scheme *runtime.Scheme) (*platformv
 	return cp.GetSpec(), nil
 }
 
+// CapsuleDependenciesFromProto returns the dependencies of the capsule spec.
+func CapsuleDependenciesFromProto(spec *platformv1.CapsuleSpec) []CapsuleDependency {
+	var deps []CapsuleDependency
+	for _, d := range spec.GetDependencies() {
+		deps = append(deps, CapsuleDependency{
+			Capsule:      d.GetCapsule(),
+			Interface:    d.GetInterface(),
+			Namespace:    d.GetNamespace(),
+			WaitForReady: d.GetWaitForReady(),
+		})
+	}
+	return deps
+}
+
 func NewCapsuleProto(projectID, environmentID, capsuleID string, spec *platformv1.CapsuleSpec) *platformv1.Capsule {
 	res := &platformv1.Capsule{
 		Kind:        CapsuleKind,
@@ -214,7 +228,8 @@ func cleanProto(msg proto.Message) {
 }
 
 func cleanReflectMessage(msg protoreflect.Message) {
-	if msg == nil {
+	// Unset message fields are read-only, and have nothing to clean.
+	if msg == nil || !msg.IsValid() {
 		return
 	}
 
@@ -26,8 +26,9 @@ func Test_initialise(t *testing.T) {
 					},
 					Scale: &platformv1.Scale{
 						Horizontal: &platformv1.HorizontalScale{
-							Instances: &platformv1.Instances{},
-							CpuTarget: &platformv1.CPUTarget{},
+							Instances:   &platformv1.Instances{},
+							CpuTarget:   &platformv1.CPUTarget{},
+							ScaleToZero: &platformv1.ScaleToZero{},
 						},
 						Vertical: &platformv1.VerticalScale{
 							Cpu:    &platformv1.ResourceLimits{},
@@ -36,6 +37,21 @@ func Test_initialise(t *testing.T) {
 						},
 					},
 					Extensions: map[string]*structpb.Struct{},
+					Storage:    &platformv1.Storage{},
+					Rollout: &platformv1.RolloutStrategy{
+						Canary:    &platformv1.CanaryStrategy{},
+						BlueGreen: &platformv1.BlueGreenStrategy{},
+					},
+					RolloutHooks: &platformv1.RolloutHooks{
+						PreRollout:  &platformv1.RolloutHook{Command: &platformv1.JobCommand{}},
+						PostRollout: &platformv1.RolloutHook{Command: &platformv1.JobCommand{}},
+					},
+					Availability: &platformv1.Availability{},
+					Lifecycle: &platformv1.Lifecycle{
+						PreStop:      &platformv1.LifecycleHook{Http: &platformv1.HTTPHook{}},
+						StartupProbe: &platformv1.StartupProbe{Grpc: &platformv1.InterfaceGRPCProbe{}},
+					},
+					NetworkPolicy: &platformv1.NetworkPolicy{},
 				},
 			},
 		},
@@ -77,8 +93,9 @@ func Test_initialise(t *testing.T) {
 					},
 					Scale: &platformv1.Scale{
 						Horizontal: &platformv1.HorizontalScale{
-							Instances: &platformv1.Instances{},
-							CpuTarget: &platformv1.CPUTarget{},
+							Instances:   &platformv1.Instances{},
+							CpuTarget:   &platformv1.CPUTarget{},
+							ScaleToZero: &platformv1.ScaleToZero{},
 						},
 						Vertical: &platformv1.VerticalScale{
 							Cpu:    &platformv1.ResourceLimits{},
@@ -87,6 +104,21 @@ func Test_initialise(t *testing.T) {
 						},
 					},
 					Extensions: map[string]*structpb.Struct{},
+					Storage:    &platformv1.Storage{},
+					Rollout: &platformv1.RolloutStrategy{
+						Canary:    &platformv1.CanaryStrategy{},
+						BlueGreen: &platformv1.BlueGreenStrategy{},
+					},
+					RolloutHooks: &platformv1.RolloutHooks{
+						PreRollout:  &platformv1.RolloutHook{Command: &platformv1.JobCommand{}},
+						PostRollout: &platformv1.RolloutHook{Command: &platformv1.JobCommand{}},
+					},
+					Availability: &platformv1.Availability{},
+					Lifecycle: &platformv1.Lifecycle{
+						PreStop:      &platformv1.LifecycleHook{Http: &platformv1.HTTPHook{}},
+						StartupProbe: &platformv1.StartupProbe{Grpc: &platformv1.InterfaceGRPCProbe{}},
+					},
+					NetworkPolicy: &platformv1.NetworkPolicy{},
 				},
 			},
 		},
@@ -115,8 +147,9 @@ func Test_NewCapsuleProto(t *testing.T) {
 			},
 			Scale: &platformv1.Scale{
 				Horizontal: &platformv1.HorizontalScale{
-					Instances: &platformv1.Instances{},
-					CpuTarget: &platformv1.CPUTarget{},
+					Instances:   &platformv1.Instances{},
+					CpuTarget:   &platformv1.CPUTarget{},
+					ScaleToZero: &platformv1.ScaleToZero{},
 				},
 				Vertical: &platformv1.VerticalScale{
 					Cpu:    &platformv1.ResourceLimits{},
@@ -125,6 +158,21 @@ func Test_NewCapsuleProto(t *testing.T) {
 				},
 			},
 			Extensions: map[string]*structpb.Struct{},
+			Storage:    &platformv1.Storage{},
+			Rollout: &platformv1.RolloutStrategy{
+				Canary:    &platformv1.CanaryStrategy{},
+				BlueGreen: &platformv1.BlueGreenStrategy{},
+			},
+			RolloutHooks: &platformv1.RolloutHooks{
+				PreRollout:  &platformv1.RolloutHook{Command: &platformv1.JobCommand{}},
+				PostRollout: &platformv1.RolloutHook{Command: &platformv1.JobCommand{}},
+			},
+			Availability: &platformv1.Availability{},
+			Lifecycle: &platformv1.Lifecycle{
+				PreStop:      &platformv1.LifecycleHook{Http: &platformv1.HTTPHook{}},
+				StartupProbe: &platformv1.StartupProbe{Grpc: &platformv1.InterfaceGRPCProbe{}},
+			},
+			NetworkPolicy: &platformv1.NetworkPolicy{},
 		},
 	}),
 	)
@@ -222,3 +270,20 @@ scale:
       min: 1
 `, s)
 }
+
+func Test_CapsuleDependenciesFromProto(t *testing.T) {
+	spec := &platformv1.CapsuleSpec{
+		Image: "nginx",
+		Dependencies: []*platformv1.CapsuleDependency{
+			{Capsule: "db", Interface: "postgres", Namespace: "data", WaitForReady: true},
+			{Capsule: "users", Interface: "grpc"},
+		},
+	}
+
+	require.Equal(t, []CapsuleDependency{
+		{Capsule: "db", Interface: "postgres", Namespace: "data", WaitForReady: true},
+		{Capsule: "users", Interface: "grpc"},
+	}, CapsuleDependenciesFromProto(spec))
+
+	require.Empty(t, CapsuleDependenciesFromProto(&platformv1.CapsuleSpec{}))
+}
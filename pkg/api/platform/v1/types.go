@@ -56,6 +56,9 @@ type EnvironmentSource struct {
 	Name string `json:"name" protobuf:"1"`
 	// Kind is the kind of source, either ConfigMap or Secret.
 	Kind EnvironmentSourceKind `json:"kind" protobuf:"2"`
+	// External syncs the Secret from an external secret store. If set, Kind
+	// must be Secret and Name is the name of the Secret the values are synced into.
+	External *ExternalSecretReference `json:"external,omitempty" protobuf:"3"`
 }
 
 type EnvironmentSourceKind string
@@ -132,6 +135,50 @@ type CapsuleSpec struct {
 
 	CronJobs []CronJob `json:"cronJobs,omitempty" protobuf:"10" patchMergeKey:"name" patchStrategy:"replace"`
 
+	// Sidecars is a list of additional containers which run alongside the
+	// main container of the Capsule. They are run as native sidecar
+	// containers, which requires Kubernetes 1.29 or later.
+	Sidecars []Sidecar `json:"sidecars,omitempty" protobuf:"15" patchMergeKey:"name" patchStrategy:"merge"`
+
+	// Storage specifies persistent storage for the Capsule. If any volumes
+	// are given, the Capsule is run as a StatefulSet where each instance
+	// gets its own set of persistent volumes. Storage cannot be changed once
+	// the Capsule is created.
+	Storage *Storage `json:"storage,omitempty" protobuf:"16"`
+
+	// Rollout specifies how new versions of the Capsule are rolled out. If
+	// not set, new versions are rolled out as a regular rolling update.
+	Rollout *RolloutStrategy `json:"rollout,omitempty" protobuf:"17"`
+
+	// RolloutHooks are Jobs run from the image and environment of the
+	// Capsule when a new version of it is rolled out.
+	RolloutHooks *RolloutHooks `json:"rolloutHooks,omitempty" protobuf:"23"`
+
+	// Availability specifies how the Capsule is protected during voluntary
+	// disruptions and spread across the cluster. If not set, and defaults
+	// are enabled in the operator, it is defaulted from the minimum number of
+	// instances the Capsule is scheduled to run.
+	Availability *Availability `json:"availability,omitempty" protobuf:"18"`
+
+	// Lifecycle specifies hooks, graceful shutdown and a startup probe for
+	// the main container of the Capsule.
+	Lifecycle *Lifecycle `json:"lifecycle,omitempty" protobuf:"19"`
+
+	// NetworkPolicy specifies which Capsules and namespaces can connect to
+	// the interfaces of the Capsule, and which the Capsule can connect to.
+	// Only enforced if the operator is configured with a network policy step.
+	NetworkPolicy *NetworkPolicy `json:"networkPolicy,omitempty" protobuf:"20"`
+
+	// Dependencies are the interfaces of other Capsules this Capsule
+	// connects to. The host and port of each dependency are injected as
+	// environment variables, and instances can be held back from starting
+	// until the dependency is ready.
+	Dependencies []CapsuleDependency `json:"dependencies,omitempty" protobuf:"21"`
+
+	// Containers is a list of additional application containers, which run
+	// alongside the main container in each instance of the Capsule.
+	Containers []Container `json:"containers,omitempty" protobuf:"22" patchMergeKey:"name" patchStrategy:"merge"`
+
 	// TODO Move to plugin
 	AutoAddRigServiceAccounts bool `json:"autoAddRigServiceAccounts" protobuf:"13"`
 
@@ -170,6 +217,40 @@ type FileReference struct {
 
 	// Key in reference which holds file contents.
 	Key string `json:"key" protobuf:"3"`
+
+	// External syncs the Secret from an external secret store. If set, Kind
+	// must be Secret and Name is the name of the Secret the values are synced into.
+	External *ExternalSecretReference `json:"external,omitempty" protobuf:"4"`
+}
+
+// ExternalSecretReference references a secret in an external secret store,
+// such as Vault or AWS Secrets Manager.
+type ExternalSecretReference struct {
+	// Store is the name of the SecretStore or ClusterSecretStore holding the secret.
+	Store string `json:"store" protobuf:"1"`
+
+	// StoreKind is the kind of the store, either SecretStore or ClusterSecretStore.
+	// Defaults to ClusterSecretStore.
+	StoreKind string `json:"storeKind,omitempty" protobuf:"2"`
+
+	// Path is the path, or remote key, of the secret in the store.
+	Path string `json:"path" protobuf:"3"`
+
+	// RefreshIntervalSeconds is how often the secret is read from the store.
+	// Defaults to 3600.
+	RefreshIntervalSeconds uint32 `json:"refreshIntervalSeconds,omitempty" protobuf:"4"`
+}
+
+func (r *ExternalSecretReference) ToK8s() *v1alpha2.ExternalSecretReference {
+	if r == nil {
+		return nil
+	}
+	return &v1alpha2.ExternalSecretReference{
+		Store:                  r.Store,
+		StoreKind:              r.StoreKind,
+		Path:                   r.Path,
+		RefreshIntervalSeconds: r.RefreshIntervalSeconds,
+	}
 }
 
 type Scale struct {
@@ -202,6 +283,73 @@ type HorizontalScale struct {
 	// CustomMetrics specifies custom metrics emitted by the custom.metrics.k8s.io API
 	// which the autoscaler should scale on
 	CustomMetrics []CustomMetric `json:"customMetrics,omitempty" protobuf:"3" patchStrategy:"replace"`
+
+	// Schedules overrides the instances of the Capsule in recurring time
+	// windows. If multiple windows are active, the first one is used.
+	Schedules []ScaleSchedule `json:"schedules,omitempty" protobuf:"6"`
+
+	// ScaleToZero scales the Capsule to zero instances when it hasn't
+	// received any requests for a while. The Capsule is woken up again by
+	// the next request through one of its routes.
+	ScaleToZero *ScaleToZero `json:"scaleToZero,omitempty" protobuf:"7"`
+}
+
+// ScaleToZero specifies how a Capsule is scaled to zero when idle.
+type ScaleToZero struct {
+	// IdleSeconds is how long the Capsule must go without requests before
+	// it is scaled to zero. Requests are sent directly to the Capsule while it
+	// is awake, so after IdleSeconds they are sent through the activator again,
+	// and the Capsule is scaled to zero if it receives no requests within
+	// another IdleSeconds. Defaults to 900.
+	IdleSeconds uint32 `json:"idleSeconds,omitempty" protobuf:"1"`
+
+	// WakeupTimeoutSeconds is how long a request waits for the Capsule to
+	// wake up before failing. Defaults to 60.
+	WakeupTimeoutSeconds uint32 `json:"wakeupTimeoutSeconds,omitempty" protobuf:"2"`
+}
+
+func (s *ScaleToZero) ToK8s() *v1alpha2.ScaleToZero {
+	if s == nil {
+		return nil
+	}
+	return &v1alpha2.ScaleToZero{
+		IdleSeconds:          s.IdleSeconds,
+		WakeupTimeoutSeconds: s.WakeupTimeoutSeconds,
+	}
+}
+
+// ScaleSchedule overrides the minimum and maximum number of instances of
+// the Capsule in a recurring time window.
+type ScaleSchedule struct {
+	// Name of the schedule.
+	Name string `json:"name,omitempty" protobuf:"1"`
+
+	// Schedule is a cron expression for when the window starts.
+	Schedule string `json:"schedule" protobuf:"2"`
+
+	// Duration of the window, e.g. `10h`.
+	Duration string `json:"duration" protobuf:"3"`
+
+	// Timezone the schedule is evaluated in, e.g. `Europe/Copenhagen`.
+	// Defaults to UTC.
+	Timezone string `json:"timezone,omitempty" protobuf:"4"`
+
+	// Min overrides the minimum number of instances during the window.
+	Min *uint32 `json:"min,omitempty" protobuf:"5"`
+
+	// Max overrides the maximum number of instances during the window.
+	Max *uint32 `json:"max,omitempty" protobuf:"6"`
+}
+
+func (s ScaleSchedule) ToK8s() v1alpha2.ScaleSchedule {
+	return v1alpha2.ScaleSchedule{
+		Name:     s.Name,
+		Schedule: s.Schedule,
+		Duration: s.Duration,
+		Timezone: s.Timezone,
+		Min:      ptr.Copy(s.Min),
+		Max:      ptr.Copy(s.Max),
+	}
 }
 
 // Instances specifies the minimum and maximum amount of capsule
@@ -430,6 +578,15 @@ type HostRoute struct {
 	// HTTP paths of the host that maps to the interface. If empty, all paths are
 	// automatically matched.
 	Paths []HTTPPathRoute `json:"paths,omitempty" protobuf:"3"`
+	// Headers the requests must have for the route to match. All headers must
+	// match.
+	Headers []HeaderMatch `json:"headers,omitempty" protobuf:"5"`
+	// Methods are the HTTP methods matched by the route. If empty, all
+	// methods are matched.
+	Methods []string `json:"methods,omitempty" protobuf:"6"`
+	// QueryParameters the requests must have for the route to match. All
+	// query parameters must match.
+	QueryParameters []QueryParameterMatch `json:"queryParameters,omitempty" protobuf:"7"`
 	// Options for all paths of this host.
 	RouteOptions `json:",inline"`
 }
@@ -438,14 +595,60 @@ func (h HostRoute) ToK8s() v1alpha2.HostRoute {
 	res := v1alpha2.HostRoute{
 		ID:           h.ID,
 		Host:         h.Host,
+		Methods:      slices.Clone(h.Methods),
 		RouteOptions: h.RouteOptions.ToK8s(),
 	}
 	for _, p := range h.Paths {
 		res.Paths = append(res.Paths, p.ToK8s())
 	}
+	for _, m := range h.Headers {
+		res.Headers = append(res.Headers, v1alpha2.HeaderMatch{
+			Name:  m.Name,
+			Value: m.Value,
+			Match: v1alpha2.ValueMatchType(m.Match),
+		})
+	}
+	for _, m := range h.QueryParameters {
+		res.QueryParameters = append(res.QueryParameters, v1alpha2.QueryParameterMatch{
+			Name:  m.Name,
+			Value: m.Value,
+			Match: v1alpha2.ValueMatchType(m.Match),
+		})
+	}
 	return res
 }
 
+// ValueMatchType specifies the semantics of how header and query parameter
+// values should be compared.
+type ValueMatchType string
+
+const (
+	// ValueExact is for when the value should match exactly.
+	ValueExact ValueMatchType = "Exact"
+	// ValueRegularExpression is for when the value should match a regular expression.
+	ValueRegularExpression ValueMatchType = "RegularExpression"
+)
+
+// HeaderMatch matches requests on the value of an HTTP header.
+type HeaderMatch struct {
+	// Name of the header. Header names are case insensitive.
+	Name string `json:"name" protobuf:"1"`
+	// Value the header must have.
+	Value string `json:"value" protobuf:"2"`
+	// The method of matching. By default, `Exact` is used.
+	Match ValueMatchType `json:"match,omitempty" protobuf:"3"`
+}
+
+// QueryParameterMatch matches requests on the value of a query parameter.
+type QueryParameterMatch struct {
+	// Name of the query parameter.
+	Name string `json:"name" protobuf:"1"`
+	// Value the query parameter must have.
+	Value string `json:"value" protobuf:"2"`
+	// The method of matching. By default, `Exact` is used.
+	Match ValueMatchType `json:"match,omitempty" protobuf:"3"`
+}
+
 // PathMatchType specifies the semantics of how HTTP paths should be compared.
 type PathMatchType string
 
@@ -479,11 +682,45 @@ type RouteOptions struct {
 	// Annotations of the route option. This can be plugin-specific configuration
 	// that allows custom plugins to add non-standard behavior.
 	Annotations map[string]string `json:"annotations,omitempty" protobuf:"4"`
+	// RequestHeaders modifies the headers of requests before they are sent
+	// to the interface.
+	RequestHeaders *HeaderModifier `json:"requestHeaders,omitempty" protobuf:"8"`
+	// ResponseHeaders modifies the headers of responses before they are sent
+	// back to the client.
+	ResponseHeaders *HeaderModifier `json:"responseHeaders,omitempty" protobuf:"9"`
+	// RewritePathPrefix replaces the matched path prefix of requests with
+	// the given prefix before they are sent to the interface. Can only be used
+	// when all paths are matched by `PathPrefix`.
+	RewritePathPrefix string `json:"rewritePathPrefix,omitempty" protobuf:"10"`
 }
 
 func (r RouteOptions) ToK8s() v1alpha2.RouteOptions {
 	return v1alpha2.RouteOptions{
-		Annotations: maps.Clone(r.Annotations),
+		Annotations:       maps.Clone(r.Annotations),
+		RequestHeaders:    r.RequestHeaders.ToK8s(),
+		ResponseHeaders:   r.ResponseHeaders.ToK8s(),
+		RewritePathPrefix: r.RewritePathPrefix,
+	}
+}
+
+// HeaderModifier specifies changes to the headers of a request or response.
+type HeaderModifier struct {
+	// Set overwrites the given headers with the given values.
+	Set map[string]string `json:"set,omitempty" protobuf:"1"`
+	// Add appends the given values to the given headers.
+	Add map[string]string `json:"add,omitempty" protobuf:"2"`
+	// Remove removes the given headers.
+	Remove []string `json:"remove,omitempty" protobuf:"3"`
+}
+
+func (h *HeaderModifier) ToK8s() *v1alpha2.HeaderModifier {
+	if h == nil {
+		return nil
+	}
+	return &v1alpha2.HeaderModifier{
+		Set:    maps.Clone(h.Set),
+		Add:    maps.Clone(h.Add),
+		Remove: slices.Clone(h.Remove),
 	}
 }
 
@@ -571,6 +808,9 @@ type CronJob struct {
 	// Defaults to 6
 	MaxRetries     *uint `json:"maxRetries,omitempty" protobuf:"5"`
 	TimeoutSeconds *uint `json:"timeoutSeconds,omitempty" protobuf:"6"`
+	// Webhook enables running the job outside of its schedule through the
+	// webhook of the operator.
+	Webhook *JobWebhook `json:"webhook,omitempty" protobuf:"7"`
 }
 
 func (c CronJob) ToK8s() v1alpha2.CronJob {
@@ -581,6 +821,60 @@ func (c CronJob) ToK8s() v1alpha2.CronJob {
 		Command:        c.Command.ToK8s(),
 		MaxRetries:     ptr.Copy(c.MaxRetries),
 		TimeoutSeconds: ptr.Copy(c.TimeoutSeconds),
+		Webhook:        c.Webhook.ToK8s(),
+	}
+}
+
+// JobWebhook configures the webhook of the operator running a job outside of
+// its schedule.
+type JobWebhook struct {
+	// SecretName is the name of a Secret in the namespace of the capsule. The
+	// `token` key of the Secret holds the token which requests to the webhook
+	// must present as a bearer token.
+	// +kubebuilder:validation:Required
+	SecretName string `json:"secretName" protobuf:"1"`
+	// AllowedOverrides are the parameters of the job which requests to the
+	// webhook can override. By default, none can be overridden.
+	AllowedOverrides *JobAllowedOverrides `json:"allowedOverrides,omitempty" protobuf:"2"`
+}
+
+// JobAllowedOverrides are the parameters of a job which can be overridden by
+// requests to its webhook.
+type JobAllowedOverrides struct {
+	// Args allows replacing the arguments of a job running a command.
+	Args bool `json:"args,omitempty" protobuf:"1"`
+	// Env are the names of the environment variables which can be set for a
+	// job running a command.
+	Env []string `json:"env,omitempty" protobuf:"2"`
+	// QueryParameters are the names of the query parameters which can be
+	// added to the request of a job calling a URL.
+	QueryParameters []string `json:"queryParameters,omitempty" protobuf:"3"`
+	// MaxRetries allows replacing the number of retries of the job.
+	MaxRetries bool `json:"maxRetries,omitempty" protobuf:"4"`
+	// TimeoutSeconds allows replacing the timeout of the job.
+	TimeoutSeconds bool `json:"timeoutSeconds,omitempty" protobuf:"5"`
+}
+
+func (w *JobWebhook) ToK8s() *v1alpha2.JobWebhook {
+	if w == nil {
+		return nil
+	}
+	return &v1alpha2.JobWebhook{
+		SecretName:       w.SecretName,
+		AllowedOverrides: w.AllowedOverrides.ToK8s(),
+	}
+}
+
+func (o *JobAllowedOverrides) ToK8s() *v1alpha2.JobAllowedOverrides {
+	if o == nil {
+		return nil
+	}
+	return &v1alpha2.JobAllowedOverrides{
+		Args:            o.Args,
+		Env:             slices.Clone(o.Env),
+		QueryParameters: slices.Clone(o.QueryParameters),
+		MaxRetries:      o.MaxRetries,
+		TimeoutSeconds:  o.TimeoutSeconds,
 	}
 }
 
@@ -619,6 +913,540 @@ func (j *JobCommand) ToK8s() *v1alpha2.JobCommand {
 	}
 }
 
+// Storage specifies the persistent storage of a Capsule.
+type Storage struct {
+	// Volumes is a list of persistent volumes which are claimed and mounted
+	// for each instance of the Capsule.
+	// nolint:lll
+	Volumes []PersistentVolume `json:"volumes,omitempty" protobuf:"1" patchMergeKey:"name" patchStrategy:"merge"`
+}
+
+func (s *Storage) ToK8s() *v1alpha2.Storage {
+	if s == nil {
+		return nil
+	}
+	res := &v1alpha2.Storage{}
+	for _, v := range s.Volumes {
+		res.Volumes = append(res.Volumes, v.ToK8s())
+	}
+	return res
+}
+
+// PersistentVolume defines a volume which is claimed for each instance of
+// the Capsule. The volume is claimed with the ReadWriteOnce access mode.
+type PersistentVolume struct {
+	// Name of the volume. Must be unique among the volumes of the Capsule.
+	Name string `json:"name" protobuf:"1"`
+
+	// Path specifies where the volume should be mounted in the container.
+	Path string `json:"path" protobuf:"2"`
+
+	// Size specifies the requested storage size of the volume.
+	Size resource.Quantity `json:"size" protobuf:"3"`
+
+	// StorageClassName is the name of the StorageClass the volume is claimed
+	// from. If empty, the default StorageClass of the cluster is used.
+	StorageClassName string `json:"storageClassName,omitempty" protobuf:"4"`
+}
+
+func (v PersistentVolume) ToK8s() v1alpha2.PersistentVolume {
+	return v1alpha2.PersistentVolume{
+		Name:             v.Name,
+		Path:             v.Path,
+		Size:             v.Size.DeepCopy(),
+		StorageClassName: v.StorageClassName,
+	}
+}
+
+// Availability specifies how the instances of a Capsule are kept available
+// during voluntary disruptions, such as node drains, and how they are spread
+// across the nodes and zones of the cluster.
+type Availability struct {
+	// MinAvailable is the number of instances which must be available during
+	// voluntary disruptions. Either an absolute number or a percentage, e.g.
+	// `50%`. Cannot be set together with MaxUnavailable.
+	MinAvailable string `json:"minAvailable,omitempty" protobuf:"1"`
+
+	// MaxUnavailable is the number of instances which can be unavailable
+	// during voluntary disruptions. Either an absolute number or a percentage,
+	// e.g. `50%`. If neither MinAvailable nor MaxUnavailable is set, and
+	// defaults are enabled in the operator, one instance can be unavailable
+	// at a time when the minimum number of instances is at least 2.
+	MaxUnavailable string `json:"maxUnavailable,omitempty" protobuf:"2"`
+
+	// ZoneSpread specifies how instances are spread across zones. If
+	// defaults are enabled in the operator, defaults to `Preferred` when the
+	// minimum number of instances is at least 2.
+	ZoneSpread SpreadPolicy `json:"zoneSpread,omitempty" protobuf:"3"`
+
+	// NodeSpread specifies how instances are spread across nodes. If
+	// defaults are enabled in the operator, defaults to `Preferred` when the
+	// minimum number of instances is at least 2.
+	NodeSpread SpreadPolicy `json:"nodeSpread,omitempty" protobuf:"4"`
+}
+
+func (a *Availability) ToK8s() *v1alpha2.Availability {
+	if a == nil {
+		return nil
+	}
+	return &v1alpha2.Availability{
+		MinAvailable:   a.MinAvailable,
+		MaxUnavailable: a.MaxUnavailable,
+		ZoneSpread:     v1alpha2.SpreadPolicy(a.ZoneSpread),
+		NodeSpread:     v1alpha2.SpreadPolicy(a.NodeSpread),
+	}
+}
+
+// SpreadPolicy specifies how instances are spread across a topology domain.
+type SpreadPolicy string
+
+const (
+	// SpreadNone doesn't spread the instances.
+	SpreadNone SpreadPolicy = "None"
+	// SpreadPreferred spreads the instances if possible, but still schedules
+	// them if the spread cannot be satisfied.
+	SpreadPreferred SpreadPolicy = "Preferred"
+	// SpreadRequired doesn't schedule instances which would break the spread.
+	SpreadRequired SpreadPolicy = "Required"
+)
+
+// NetworkPolicy specifies the network traffic allowed to and from the
+// instances of a Capsule, besides traffic from the ingress controller to
+// public interfaces.
+type NetworkPolicy struct {
+	// AllowFrom are the Capsules and namespaces which can connect to the
+	// interfaces of the Capsule. Connections from all other pods are denied.
+	AllowFrom []NetworkPeer `json:"allowFrom,omitempty" protobuf:"1"`
+
+	// AllowTo are the Capsules and namespaces the Capsule can connect to, if
+	// the operator restricts egress traffic of Capsules.
+	AllowTo []NetworkPeer `json:"allowTo,omitempty" protobuf:"2"`
+}
+
+// NetworkPeer is a Capsule or all pods of a namespace. At least one of
+// Capsule and Namespace must be set.
+type NetworkPeer struct {
+	// Capsule is the name of a Capsule. If empty, all pods of the namespace
+	// are matched.
+	Capsule string `json:"capsule,omitempty" protobuf:"1"`
+
+	// Namespace of the peer. Defaults to the namespace of the Capsule.
+	Namespace string `json:"namespace,omitempty" protobuf:"2"`
+}
+
+func (n *NetworkPolicy) ToK8s() *v1alpha2.NetworkPolicy {
+	if n == nil {
+		return nil
+	}
+	res := &v1alpha2.NetworkPolicy{}
+	for _, p := range n.AllowFrom {
+		res.AllowFrom = append(res.AllowFrom, v1alpha2.NetworkPeer(p))
+	}
+	for _, p := range n.AllowTo {
+		res.AllowTo = append(res.AllowTo, v1alpha2.NetworkPeer(p))
+	}
+	return res
+}
+
+// CapsuleDependency is an interface of another Capsule, which the Capsule
+// connects to.
+type CapsuleDependency struct {
+	// +kubebuilder:validation:Required
+	// Capsule is the name of the Capsule depended on.
+	Capsule string `json:"capsule" protobuf:"1"`
+
+	// +kubebuilder:validation:Required
+	// Interface is the name of the interface of the Capsule depended on.
+	Interface string `json:"interface" protobuf:"2"`
+
+	// Namespace of the Capsule depended on. Defaults to the namespace of the
+	// Capsule.
+	Namespace string `json:"namespace,omitempty" protobuf:"3"`
+
+	// WaitForReady holds back new instances of the Capsule from starting,
+	// until the interface of the dependency accepts connections. Capsules
+	// cannot wait for each other, directly or through other Capsules.
+	WaitForReady bool `json:"waitForReady,omitempty" protobuf:"4"`
+}
+
+func (d CapsuleDependency) ToK8s() v1alpha2.CapsuleDependency {
+	return v1alpha2.CapsuleDependency(d)
+}
+
+// Lifecycle specifies how the main container of a Capsule is started and
+// stopped.
+type Lifecycle struct {
+	// PreStop is run in the container before it is stopped. If not set,
+	// Capsules with interfaces sleep for 10 seconds before stopping, to let
+	// load balancers stop sending traffic to the instance.
+	PreStop *LifecycleHook `json:"preStop,omitempty" protobuf:"1"`
+
+	// TerminationGracePeriodSeconds is how long the container is given to
+	// stop, including the PreStop hook, before it is killed. Defaults to 30.
+	TerminationGracePeriodSeconds *uint32 `json:"terminationGracePeriodSeconds,omitempty" protobuf:"2"`
+
+	// StartupProbe is probed until it succeeds before the liveness and
+	// readiness probes start. Cannot be used together with the
+	// startupDelay of a liveness probe.
+	StartupProbe *StartupProbe `json:"startupProbe,omitempty" protobuf:"3"`
+}
+
+// LifecycleHook is a command run in the container or an HTTP request sent
+// to it. Exactly one of Command and HTTP must be set.
+type LifecycleHook struct {
+	// Command is the command, with arguments, to run in the container.
+	Command []string `json:"command,omitempty" protobuf:"1"`
+
+	// HTTP is an HTTP GET request to send to the container.
+	HTTP *HTTPHook `json:"http,omitempty" protobuf:"2"`
+}
+
+// HTTPHook is an HTTP GET request sent to the container.
+type HTTPHook struct {
+	// Port is the port of the container to send the request to.
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port" protobuf:"1"`
+
+	// Path is the HTTP path of the request.
+	Path string `json:"path,omitempty" protobuf:"2"`
+}
+
+// StartupProbe specifies a probe which must succeed before the container is
+// considered started.
+type StartupProbe struct {
+	// Port specifies which port of the container to probe.
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port" protobuf:"1"`
+
+	// Path is the HTTP path of the probe. Path is mutually
+	// exclusive with the TCP and GCRP fields.
+	Path string `json:"path,omitempty" protobuf:"2"`
+
+	// TCP specifies that this is a simple TCP listen probe.
+	TCP bool `json:"tcp,omitempty" protobuf:"3"`
+
+	// GRPC specifies that this is a GRCP probe.
+	GRPC *InterfaceGRPCProbe `json:"grpc,omitempty" protobuf:"4"`
+
+	// PeriodSeconds is how often the probe is run. Defaults to 10.
+	PeriodSeconds uint32 `json:"periodSeconds,omitempty" protobuf:"5"`
+
+	// MaxStartupSeconds is how long the container is given to start before
+	// it is restarted. Defaults to 300.
+	MaxStartupSeconds uint32 `json:"maxStartupSeconds,omitempty" protobuf:"6"`
+}
+
+func (l *Lifecycle) ToK8s() *v1alpha2.Lifecycle {
+	if l == nil {
+		return nil
+	}
+	return &v1alpha2.Lifecycle{
+		PreStop:                       l.PreStop.ToK8s(),
+		TerminationGracePeriodSeconds: ptr.Copy(l.TerminationGracePeriodSeconds),
+		StartupProbe:                  l.StartupProbe.ToK8s(),
+	}
+}
+
+func (h *LifecycleHook) ToK8s() *v1alpha2.LifecycleHook {
+	if h == nil {
+		return nil
+	}
+	return &v1alpha2.LifecycleHook{
+		Command: slices.Clone(h.Command),
+		HTTP:    h.HTTP.ToK8s(),
+	}
+}
+
+func (h *HTTPHook) ToK8s() *v1alpha2.HTTPHook {
+	if h == nil {
+		return nil
+	}
+	return &v1alpha2.HTTPHook{
+		Port: h.Port,
+		Path: h.Path,
+	}
+}
+
+func (p *StartupProbe) ToK8s() *v1alpha2.StartupProbe {
+	if p == nil {
+		return nil
+	}
+	return &v1alpha2.StartupProbe{
+		Port:              p.Port,
+		Path:              p.Path,
+		TCP:               p.TCP,
+		GRPC:              p.GRPC.ToK8s(),
+		PeriodSeconds:     p.PeriodSeconds,
+		MaxStartupSeconds: p.MaxStartupSeconds,
+	}
+}
+
+// RolloutStrategy specifies how new versions of a Capsule are rolled out.
+// Exactly one of Canary and BlueGreen must be set.
+type RolloutStrategy struct {
+	// Canary rolls out a new version next to the current one, gradually
+	// shifting traffic to it.
+	Canary *CanaryStrategy `json:"canary,omitempty" protobuf:"1"`
+
+	// BlueGreen rolls out a new version next to the current one, and switches
+	// all traffic to it at once when it has been ready for long enough.
+	BlueGreen *BlueGreenStrategy `json:"blueGreen,omitempty" protobuf:"2"`
+}
+
+func (r *RolloutStrategy) ToK8s() *v1alpha2.RolloutStrategy {
+	if r == nil {
+		return nil
+	}
+	res := &v1alpha2.RolloutStrategy{}
+	if r.Canary != nil {
+		res.Canary = &v1alpha2.CanaryStrategy{}
+		for _, s := range r.Canary.Steps {
+			res.Canary.Steps = append(res.Canary.Steps, v1alpha2.CanaryStep{
+				Weight:       s.Weight,
+				PauseSeconds: s.PauseSeconds,
+			})
+		}
+	}
+	if r.BlueGreen != nil {
+		res.BlueGreen = &v1alpha2.BlueGreenStrategy{
+			PromotionDelaySeconds: r.BlueGreen.PromotionDelaySeconds,
+		}
+	}
+	return res
+}
+
+// CanaryStrategy specifies the steps a canary goes through before it is
+// promoted to be the new version of the Capsule.
+type CanaryStrategy struct {
+	// Steps is the list of steps of the canary. After the last step, the
+	// canary is promoted.
+	Steps []CanaryStep `json:"steps" protobuf:"1" patchStrategy:"replace"`
+}
+
+// CanaryStep is a single step of a canary rollout.
+type CanaryStep struct {
+	// Weight is the percentage of traffic sent to the canary during the step.
+	Weight uint32 `json:"weight" protobuf:"1"`
+
+	// PauseSeconds is how long the canary must stay ready at this step before
+	// moving on to the next one.
+	PauseSeconds uint32 `json:"pauseSeconds,omitempty" protobuf:"2"`
+}
+
+// BlueGreenStrategy specifies how a blue/green rollout is promoted.
+type BlueGreenStrategy struct {
+	// PromotionDelaySeconds is how long all instances of the new version must
+	// stay ready before traffic is switched to it.
+	PromotionDelaySeconds uint32 `json:"promotionDelaySeconds,omitempty" protobuf:"1"`
+}
+
+// RolloutHooks specifies Jobs run when a new version of a Capsule is rolled
+// out, fx. to migrate a database.
+type RolloutHooks struct {
+	// PreRollout is run before a new version is rolled out. The Deployment of
+	// the Capsule keeps running the current version until the Job has
+	// succeeded. If the Job fails, the new version is not rolled out.
+	PreRollout *RolloutHook `json:"preRollout,omitempty" protobuf:"1"`
+
+	// PostRollout is run once all instances of a new version are ready.
+	PostRollout *RolloutHook `json:"postRollout,omitempty" protobuf:"2"`
+}
+
+func (h *RolloutHooks) ToK8s() *v1alpha2.RolloutHooks {
+	if h == nil {
+		return nil
+	}
+	return &v1alpha2.RolloutHooks{
+		PreRollout:  h.PreRollout.ToK8s(),
+		PostRollout: h.PostRollout.ToK8s(),
+	}
+}
+
+// RolloutHook is a Job running a command in the image and environment of the
+// main container of a Capsule.
+type RolloutHook struct {
+	// Command is the command, and its arguments, run by the Job.
+	Command JobCommand `json:"command" protobuf:"1"`
+
+	// MaxRetries is the number of times the Job is retried before it fails.
+	// Defaults to 6.
+	MaxRetries *uint `json:"maxRetries,omitempty" protobuf:"2"`
+
+	// TimeoutSeconds is how long the Job can run before it fails.
+	TimeoutSeconds *uint `json:"timeoutSeconds,omitempty" protobuf:"3"`
+}
+
+func (h *RolloutHook) ToK8s() *v1alpha2.RolloutHook {
+	if h == nil {
+		return nil
+	}
+	return &v1alpha2.RolloutHook{
+		Command:        *h.Command.ToK8s(),
+		MaxRetries:     ptr.Copy(h.MaxRetries),
+		TimeoutSeconds: ptr.Copy(h.TimeoutSeconds),
+	}
+}
+
+// Container defines an additional application container of a Capsule. The
+// container is configured independently of the main container, and doesn't
+// inherit its environment, files or interfaces.
+type Container struct {
+	// Name of the container. Must be unique among the containers and sidecars
+	// of the Capsule and different from the name of the Capsule.
+	Name string `json:"name" protobuf:"1"`
+
+	// Image specifies what image the container should run.
+	Image string `json:"image" protobuf:"2"`
+
+	// Command is run as a command in the shell. If left unspecified, the
+	// container will run using what is specified as ENTRYPOINT in the
+	// Dockerfile.
+	Command string `json:"command,omitempty" protobuf:"3"`
+
+	// Args is a list of arguments either passed to the Command or if Command
+	// is left empty the arguments will be passed to the ENTRYPOINT of the
+	// docker image.
+	Args []string `json:"args,omitempty" protobuf:"4" patchStrategy:"replace"`
+
+	// Env specifies the environment variables of the container.
+	Env EnvironmentVariables `json:"env" protobuf:"5"`
+
+	// Files is a list of files to mount in the container.
+	Files []File `json:"files,omitempty" protobuf:"6" patchMergeKey:"path" patchStrategy:"merge"`
+
+	// Interfaces specifies the interfaces of the container. They are exposed
+	// by the Service of the Capsule together with the interfaces of the main
+	// container, so names and ports must be unique across all containers.
+	Interfaces []CapsuleInterface `json:"interfaces,omitempty" protobuf:"7" patchMergeKey:"port" patchStrategy:"merge"`
+
+	// Resources specifies the resource requests and limits of the container.
+	// If omitted, no requests or limits are set.
+	Resources *VerticalScale `json:"resources,omitempty" protobuf:"8"`
+}
+
+func (c Container) ToK8s() v1alpha2.Container {
+	res := v1alpha2.Container{
+		Name:      c.Name,
+		Image:     c.Image,
+		Command:   c.Command,
+		Args:      slices.Clone(c.Args),
+		Env:       sidecarEnvToK8s(c.Env),
+		Resources: c.Resources.ToK8s(),
+	}
+	// Files with inline contents are stored in ConfigMaps and Secrets by the
+	// platform, after which they are referenced.
+	for _, f := range c.Files {
+		if f.Ref == nil {
+			continue
+		}
+		res.Files = append(res.Files, v1alpha2.File{
+			Path: f.Path,
+			Ref: &v1alpha2.FileContentReference{
+				Kind:     f.Ref.Kind,
+				Name:     f.Ref.Name,
+				Key:      f.Ref.Key,
+				External: f.Ref.External.ToK8s(),
+			},
+		})
+	}
+	for _, inf := range c.Interfaces {
+		res.Interfaces = append(res.Interfaces, inf.ToK8s())
+	}
+	return res
+}
+
+// Sidecar defines an additional container which runs alongside the main
+// container of the Capsule, in the same instances.
+type Sidecar struct {
+	// Name of the sidecar container. Must be unique among the sidecars of
+	// the Capsule and different from the name of the Capsule.
+	Name string `json:"name" protobuf:"1"`
+
+	// Image specifies what image the sidecar should run.
+	Image string `json:"image" protobuf:"2"`
+
+	// Command is run as a command in the shell. If left unspecified, the
+	// container will run using what is specified as ENTRYPOINT in the
+	// Dockerfile.
+	Command string `json:"command,omitempty" protobuf:"3"`
+
+	// Args is a list of arguments either passed to the Command or if Command
+	// is left empty the arguments will be passed to the ENTRYPOINT of the
+	// docker image.
+	Args []string `json:"args,omitempty" protobuf:"4" patchStrategy:"replace"`
+
+	// Env defines the environment variables set in the sidecar.
+	Env EnvironmentVariables `json:"env" protobuf:"5"`
+
+	// Resources specifies the resource requests and limits of the sidecar.
+	Resources *VerticalScale `json:"resources,omitempty" protobuf:"6"`
+
+	// Liveness specifies the liveness probe of the sidecar.
+	Liveness *SidecarProbe `json:"liveness,omitempty" protobuf:"7"`
+
+	// Readiness specifies the readiness probe of the sidecar.
+	Readiness *SidecarProbe `json:"readiness,omitempty" protobuf:"8"`
+}
+
+func (s Sidecar) ToK8s() v1alpha2.Sidecar {
+	return v1alpha2.Sidecar{
+		Name:      s.Name,
+		Image:     s.Image,
+		Command:   s.Command,
+		Args:      slices.Clone(s.Args),
+		Env:       sidecarEnvToK8s(s.Env),
+		Resources: s.Resources.ToK8s(),
+		Liveness:  s.Liveness.ToK8s(),
+		Readiness: s.Readiness.ToK8s(),
+	}
+}
+
+func sidecarEnvToK8s(e EnvironmentVariables) v1alpha2.SidecarEnv {
+	res := v1alpha2.SidecarEnv{
+		Raw: maps.Clone(e.Raw),
+	}
+	for _, s := range e.Sources {
+		res.From = append(res.From, v1alpha2.EnvReference{
+			Kind:     string(s.Kind),
+			Name:     s.Name,
+			External: s.External.ToK8s(),
+		})
+	}
+	return res
+}
+
+// SidecarProbe specifies a probe of a sidecar container.
+type SidecarProbe struct {
+	// Port specifies which port of the sidecar to probe.
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port" protobuf:"1"`
+
+	// Path is the HTTP path of the probe. Path is mutually
+	// exclusive with the TCP and GCRP fields.
+	Path string `json:"path,omitempty" protobuf:"2"`
+
+	// TCP specifies that this is a simple TCP listen probe.
+	TCP bool `json:"tcp,omitempty" protobuf:"3"`
+
+	// GRPC specifies that this is a GRCP probe.
+	GRPC *InterfaceGRPCProbe `json:"grpc,omitempty" protobuf:"4"`
+}
+
+func (p *SidecarProbe) ToK8s() *v1alpha2.SidecarProbe {
+	if p == nil {
+		return nil
+	}
+	return &v1alpha2.SidecarProbe{
+		Port: p.Port,
+		Path: p.Path,
+		TCP:  p.TCP,
+		GRPC: p.GRPC.ToK8s(),
+	}
+}
+
 var (
 	// GroupVersion is group version used to register these objects
 	GroupVersion = schema.GroupVersion{Group: "platform.rig.dev", Version: "v1"}
@@ -45,6 +45,50 @@ type CapsuleSpec struct {
 
 	CronJobs []CronJob `json:"cronJobs,omitempty"`
 
+	// Sidecars is a list of additional containers which run alongside the
+	// main container of the Capsule. They are run as native sidecar
+	// containers, which requires Kubernetes 1.29 or later.
+	Sidecars []Sidecar `json:"sidecars,omitempty"`
+
+	// Containers is a list of additional application containers, which run
+	// alongside the main container in each instance of the Capsule.
+	Containers []Container `json:"containers,omitempty"`
+
+	// Storage specifies persistent storage for the Capsule. If any volumes
+	// are given, the Capsule is run as a StatefulSet where each instance
+	// gets its own set of persistent volumes. Storage cannot be changed once
+	// the Capsule is created.
+	Storage *Storage `json:"storage,omitempty"`
+
+	// Rollout specifies how new versions of the Capsule are rolled out. If
+	// not set, new versions are rolled out as a regular rolling update.
+	Rollout *RolloutStrategy `json:"rollout,omitempty"`
+
+	// RolloutHooks are Jobs run from the image and environment of the
+	// Capsule when a new version of it is rolled out.
+	RolloutHooks *RolloutHooks `json:"rolloutHooks,omitempty"`
+
+	// Availability specifies how the Capsule is protected during voluntary
+	// disruptions and spread across the cluster. If not set, and defaults
+	// are enabled in the operator, it is defaulted from the minimum number of
+	// instances the Capsule is scheduled to run.
+	Availability *Availability `json:"availability,omitempty"`
+
+	// Lifecycle specifies hooks, graceful shutdown and a startup probe for
+	// the main container of the Capsule.
+	Lifecycle *Lifecycle `json:"lifecycle,omitempty"`
+
+	// NetworkPolicy specifies which Capsules and namespaces can connect to
+	// the interfaces of the Capsule, and which the Capsule can connect to.
+	// Only enforced if the operator is configured with a network policy step.
+	NetworkPolicy *NetworkPolicy `json:"networkPolicy,omitempty"`
+
+	// Dependencies are the interfaces of other Capsules this Capsule
+	// connects to. The host and port of each dependency are injected as
+	// environment variables, and instances can be held back from starting
+	// until the dependency is ready.
+	Dependencies []CapsuleDependency `json:"dependencies,omitempty"`
+
 	// +kubebuilder:validation:Schemaless
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +kubebuilder:validation:Type=object
@@ -63,6 +107,39 @@ type CronJob struct {
 	// Defaults to 6
 	MaxRetries     *uint `json:"maxRetries,omitempty" protobuf:"5"`
 	TimeoutSeconds *uint `json:"timeoutSeconds,omitempty" protobuf:"6"`
+	// Webhook enables running the job outside of its schedule through the
+	// webhook of the operator.
+	Webhook *JobWebhook `json:"webhook,omitempty" protobuf:"7"`
+}
+
+// JobWebhook configures the webhook of the operator running a job outside of
+// its schedule.
+type JobWebhook struct {
+	// SecretName is the name of a Secret in the namespace of the capsule. The
+	// `token` key of the Secret holds the token which requests to the webhook
+	// must present as a bearer token.
+	// +kubebuilder:validation:Required
+	SecretName string `json:"secretName" protobuf:"1"`
+	// AllowedOverrides are the parameters of the job which requests to the
+	// webhook can override. By default, none can be overridden.
+	AllowedOverrides *JobAllowedOverrides `json:"allowedOverrides,omitempty" protobuf:"2"`
+}
+
+// JobAllowedOverrides are the parameters of a job which can be overridden by
+// requests to its webhook.
+type JobAllowedOverrides struct {
+	// Args allows replacing the arguments of a job running a command.
+	Args bool `json:"args,omitempty" protobuf:"1"`
+	// Env are the names of the environment variables which can be set for a
+	// job running a command.
+	Env []string `json:"env,omitempty" protobuf:"2"`
+	// QueryParameters are the names of the query parameters which can be
+	// added to the request of a job calling a URL.
+	QueryParameters []string `json:"queryParameters,omitempty" protobuf:"3"`
+	// MaxRetries allows replacing the number of retries of the job.
+	MaxRetries bool `json:"maxRetries,omitempty" protobuf:"4"`
+	// TimeoutSeconds allows replacing the timeout of the job.
+	TimeoutSeconds bool `json:"timeoutSeconds,omitempty" protobuf:"5"`
 }
 
 type URL struct {
@@ -98,6 +175,9 @@ type EnvReference struct {
 	Kind string `json:"kind"`
 	// Name is the name of a ConfigMap or Secret in the same namespace as the Capsule.
 	Name string `json:"name"`
+	// External syncs the Secret from an external secret store. If set, Kind
+	// must be Secret and Name is the name of the Secret the values are synced into.
+	External *ExternalSecretReference `json:"external,omitempty"`
 }
 
 // CapsuleScale specifies the horizontal and vertical scaling of the Capsule.
@@ -123,6 +203,52 @@ type HorizontalScale struct {
 	// CustomMetrics specifies custom metrics emitted by the custom.metrics.k8s.io API
 	// which the autoscaler should scale on
 	CustomMetrics []CustomMetric `json:"customMetrics,omitempty" protobuf:"3" patchStrategy:"replace"`
+
+	// Schedules overrides the instances of the Capsule in recurring time
+	// windows. If multiple windows are active, the first one is used.
+	Schedules []ScaleSchedule `json:"schedules,omitempty" protobuf:"4"`
+
+	// ScaleToZero scales the Capsule to zero instances when it hasn't
+	// received any requests for a while. The Capsule is woken up again by
+	// the next request through one of its routes.
+	ScaleToZero *ScaleToZero `json:"scaleToZero,omitempty" protobuf:"5"`
+}
+
+// ScaleToZero specifies how a Capsule is scaled to zero when idle.
+type ScaleToZero struct {
+	// IdleSeconds is how long the Capsule must go without requests before
+	// it is scaled to zero. Requests are sent directly to the Capsule while it
+	// is awake, so after IdleSeconds they are sent through the activator again,
+	// and the Capsule is scaled to zero if it receives no requests within
+	// another IdleSeconds. Defaults to 900.
+	IdleSeconds uint32 `json:"idleSeconds,omitempty" protobuf:"1"`
+
+	// WakeupTimeoutSeconds is how long a request waits for the Capsule to
+	// wake up before failing. Defaults to 60.
+	WakeupTimeoutSeconds uint32 `json:"wakeupTimeoutSeconds,omitempty" protobuf:"2"`
+}
+
+// ScaleSchedule overrides the minimum and maximum number of instances of
+// the Capsule in a recurring time window.
+type ScaleSchedule struct {
+	// Name of the schedule.
+	Name string `json:"name,omitempty" protobuf:"1"`
+
+	// Schedule is a cron expression for when the window starts.
+	Schedule string `json:"schedule" protobuf:"2"`
+
+	// Duration of the window, e.g. `10h`.
+	Duration string `json:"duration" protobuf:"3"`
+
+	// Timezone the schedule is evaluated in, e.g. `Europe/Copenhagen`.
+	// Defaults to UTC.
+	Timezone string `json:"timezone,omitempty" protobuf:"4"`
+
+	// Min overrides the minimum number of instances during the window.
+	Min *uint32 `json:"min,omitempty" protobuf:"5"`
+
+	// Max overrides the maximum number of instances during the window.
+	Max *uint32 `json:"max,omitempty" protobuf:"6"`
 }
 
 // Instances specifies the minimum and maximum amount of capsule
@@ -251,10 +377,52 @@ type HostRoute struct {
 	// HTTP paths of the host that maps to the interface. If empty, all paths are
 	// automatically matched.
 	Paths []HTTPPathRoute `json:"paths,omitempty" protobuf:"3"`
+	// Headers the requests must have for the route to match. All headers must
+	// match.
+	Headers []HeaderMatch `json:"headers,omitempty" protobuf:"5"`
+	// Methods are the HTTP methods matched by the route. If empty, all
+	// methods are matched.
+	Methods []string `json:"methods,omitempty" protobuf:"6"`
+	// QueryParameters the requests must have for the route to match. All
+	// query parameters must match.
+	QueryParameters []QueryParameterMatch `json:"queryParameters,omitempty" protobuf:"7"`
 	// Options for all paths of this host.
 	RouteOptions `json:",inline"`
 }
 
+// ValueMatchType specifies the semantics of how header and query parameter
+// values should be compared.
+type ValueMatchType string
+
+const (
+	// ValueExact is for when the value should match exactly.
+	ValueExact ValueMatchType = "Exact"
+	// ValueRegularExpression is for when the value should match a regular expression.
+	ValueRegularExpression ValueMatchType = "RegularExpression"
+)
+
+// HeaderMatch matches requests on the value of an HTTP header.
+type HeaderMatch struct {
+	// Name of the header. Header names are case insensitive.
+	Name string `json:"name" protobuf:"1"`
+	// Value the header must have.
+	Value string `json:"value" protobuf:"2"`
+	// The method of matching. By default, `Exact` is used.
+	// +kubebuilder:validation:Enum=Exact;RegularExpression
+	Match ValueMatchType `json:"match,omitempty" protobuf:"3"`
+}
+
+// QueryParameterMatch matches requests on the value of a query parameter.
+type QueryParameterMatch struct {
+	// Name of the query parameter.
+	Name string `json:"name" protobuf:"1"`
+	// Value the query parameter must have.
+	Value string `json:"value" protobuf:"2"`
+	// The method of matching. By default, `Exact` is used.
+	// +kubebuilder:validation:Enum=Exact;RegularExpression
+	Match ValueMatchType `json:"match,omitempty" protobuf:"3"`
+}
+
 // PathMatchType specifies the semantics of how HTTP paths should be compared.
 type PathMatchType string
 
@@ -281,6 +449,26 @@ type RouteOptions struct {
 	// Annotations of the route option. This can be plugin-specific configuration
 	// that allows custom plugins to add non-standard behavior.
 	Annotations map[string]string `json:"annotations,omitempty" protobuf:"4"`
+	// RequestHeaders modifies the headers of requests before they are sent
+	// to the interface.
+	RequestHeaders *HeaderModifier `json:"requestHeaders,omitempty" protobuf:"8"`
+	// ResponseHeaders modifies the headers of responses before they are sent
+	// back to the client.
+	ResponseHeaders *HeaderModifier `json:"responseHeaders,omitempty" protobuf:"9"`
+	// RewritePathPrefix replaces the matched path prefix of requests with
+	// the given prefix before they are sent to the interface. Can only be used
+	// when all paths are matched by `PathPrefix`.
+	RewritePathPrefix string `json:"rewritePathPrefix,omitempty" protobuf:"10"`
+}
+
+// HeaderModifier specifies changes to the headers of a request or response.
+type HeaderModifier struct {
+	// Set overwrites the given headers with the given values.
+	Set map[string]string `json:"set,omitempty" protobuf:"1"`
+	// Add appends the given values to the given headers.
+	Add map[string]string `json:"add,omitempty" protobuf:"2"`
+	// Remove removes the given headers.
+	Remove []string `json:"remove,omitempty" protobuf:"3"`
 }
 
 // InterfaceLivenessProbe specifies an interface probe for liveness checks.
@@ -385,8 +573,384 @@ type FileContentReference struct {
 
 	// Key in reference which holds file contents.
 	Key string `json:"key"`
+
+	// External syncs the Secret from an external secret store. If set, Kind
+	// must be Secret and Name is the name of the Secret the values are synced into.
+	External *ExternalSecretReference `json:"external,omitempty"`
+}
+
+// ExternalSecretReference references a secret in an external secret store,
+// such as Vault or AWS Secrets Manager. The secret is synced into a Secret in
+// the namespace of the Capsule by an ExternalSecret of the External Secrets Operator.
+type ExternalSecretReference struct {
+	// Store is the name of the SecretStore or ClusterSecretStore holding the secret.
+	Store string `json:"store"`
+
+	// StoreKind is the kind of the store, either SecretStore or ClusterSecretStore.
+	// Defaults to ClusterSecretStore.
+	StoreKind string `json:"storeKind,omitempty"`
+
+	// Path is the path, or remote key, of the secret in the store. All properties
+	// of the secret are synced as keys of the Secret.
+	Path string `json:"path"`
+
+	// RefreshIntervalSeconds is how often the secret is read from the store.
+	// Defaults to 3600.
+	RefreshIntervalSeconds uint32 `json:"refreshIntervalSeconds,omitempty"`
+}
+
+// Storage specifies the persistent storage of a Capsule.
+type Storage struct {
+	// Volumes is a list of persistent volumes which are claimed and mounted
+	// for each instance of the Capsule.
+	Volumes []PersistentVolume `json:"volumes,omitempty" protobuf:"1"`
+}
+
+// PersistentVolume defines a volume which is claimed for each instance of
+// the Capsule. The volume of an instance outlives restarts and rollouts of
+// the instance. The volume is claimed with the ReadWriteOnce access mode.
+type PersistentVolume struct {
+	// Name of the volume. Must be unique among the volumes of the Capsule.
+	Name string `json:"name" protobuf:"1"`
+
+	// Path specifies where the volume should be mounted in the container.
+	Path string `json:"path" protobuf:"2"`
+
+	// Size specifies the requested storage size of the volume.
+	Size resource.Quantity `json:"size" protobuf:"3"`
+
+	// StorageClassName is the name of the StorageClass the volume is claimed
+	// from. If empty, the default StorageClass of the cluster is used.
+	StorageClassName string `json:"storageClassName,omitempty" protobuf:"4"`
+}
+
+// Availability specifies how the instances of a Capsule are kept available
+// during voluntary disruptions, such as node drains, and how they are spread
+// across the nodes and zones of the cluster.
+type Availability struct {
+	// MinAvailable is the number of instances which must be available during
+	// voluntary disruptions. Either an absolute number or a percentage, e.g.
+	// `50%`. Cannot be set together with MaxUnavailable.
+	MinAvailable string `json:"minAvailable,omitempty" protobuf:"1"`
+
+	// MaxUnavailable is the number of instances which can be unavailable
+	// during voluntary disruptions. Either an absolute number or a percentage,
+	// e.g. `50%`. If neither MinAvailable nor MaxUnavailable is set, and
+	// defaults are enabled in the operator, one instance can be unavailable
+	// at a time when the minimum number of instances is at least 2.
+	MaxUnavailable string `json:"maxUnavailable,omitempty" protobuf:"2"`
+
+	// ZoneSpread specifies how instances are spread across zones. If
+	// defaults are enabled in the operator, defaults to `Preferred` when the
+	// minimum number of instances is at least 2.
+	// +kubebuilder:validation:Enum=None;Preferred;Required
+	ZoneSpread SpreadPolicy `json:"zoneSpread,omitempty" protobuf:"3"`
+
+	// NodeSpread specifies how instances are spread across nodes. If
+	// defaults are enabled in the operator, defaults to `Preferred` when the
+	// minimum number of instances is at least 2.
+	// +kubebuilder:validation:Enum=None;Preferred;Required
+	NodeSpread SpreadPolicy `json:"nodeSpread,omitempty" protobuf:"4"`
+}
+
+// SpreadPolicy specifies how instances are spread across a topology domain.
+type SpreadPolicy string
+
+const (
+	// SpreadNone doesn't spread the instances.
+	SpreadNone SpreadPolicy = "None"
+	// SpreadPreferred spreads the instances if possible, but still schedules
+	// them if the spread cannot be satisfied.
+	SpreadPreferred SpreadPolicy = "Preferred"
+	// SpreadRequired doesn't schedule instances which would break the spread.
+	SpreadRequired SpreadPolicy = "Required"
+)
+
+// NetworkPolicy specifies the network traffic allowed to and from the
+// instances of a Capsule, besides traffic from the ingress controller to
+// public interfaces.
+type NetworkPolicy struct {
+	// AllowFrom are the Capsules and namespaces which can connect to the
+	// interfaces of the Capsule. Connections from all other pods are denied.
+	AllowFrom []NetworkPeer `json:"allowFrom,omitempty" protobuf:"1"`
+
+	// AllowTo are the Capsules and namespaces the Capsule can connect to, if
+	// the operator restricts egress traffic of Capsules.
+	AllowTo []NetworkPeer `json:"allowTo,omitempty" protobuf:"2"`
+}
+
+// NetworkPeer is a Capsule or all pods of a namespace. At least one of
+// Capsule and Namespace must be set.
+type NetworkPeer struct {
+	// Capsule is the name of a Capsule. If empty, all pods of the namespace
+	// are matched.
+	Capsule string `json:"capsule,omitempty" protobuf:"1"`
+
+	// Namespace of the peer. Defaults to the namespace of the Capsule.
+	Namespace string `json:"namespace,omitempty" protobuf:"2"`
+}
+
+// CapsuleDependency is an interface of another Capsule, which the Capsule
+// connects to.
+type CapsuleDependency struct {
+	// +kubebuilder:validation:Required
+	// Capsule is the name of the Capsule depended on.
+	Capsule string `json:"capsule" protobuf:"1"`
+
+	// +kubebuilder:validation:Required
+	// Interface is the name of the interface of the Capsule depended on.
+	Interface string `json:"interface" protobuf:"2"`
+
+	// Namespace of the Capsule depended on. Defaults to the namespace of the
+	// Capsule.
+	Namespace string `json:"namespace,omitempty" protobuf:"3"`
+
+	// WaitForReady holds back new instances of the Capsule from starting,
+	// until the interface of the dependency accepts connections. Capsules
+	// cannot wait for each other, directly or through other Capsules.
+	WaitForReady bool `json:"waitForReady,omitempty" protobuf:"4"`
+}
+
+// Lifecycle specifies how the main container of a Capsule is started and
+// stopped.
+type Lifecycle struct {
+	// PreStop is run in the container before it is stopped. If not set,
+	// Capsules with interfaces sleep for 10 seconds before stopping, to let
+	// load balancers stop sending traffic to the instance.
+	PreStop *LifecycleHook `json:"preStop,omitempty" protobuf:"1"`
+
+	// TerminationGracePeriodSeconds is how long the container is given to
+	// stop, including the PreStop hook, before it is killed. Defaults to 30.
+	TerminationGracePeriodSeconds *uint32 `json:"terminationGracePeriodSeconds,omitempty" protobuf:"2"`
+
+	// StartupProbe is probed until it succeeds before the liveness and
+	// readiness probes start. Cannot be used together with the
+	// startupDelay of a liveness probe.
+	StartupProbe *StartupProbe `json:"startupProbe,omitempty" protobuf:"3"`
+}
+
+// LifecycleHook is a command run in the container or an HTTP request sent
+// to it. Exactly one of Command and HTTP must be set.
+type LifecycleHook struct {
+	// Command is the command, with arguments, to run in the container.
+	Command []string `json:"command,omitempty" protobuf:"1"`
+
+	// HTTP is an HTTP GET request to send to the container.
+	HTTP *HTTPHook `json:"http,omitempty" protobuf:"2"`
+}
+
+// HTTPHook is an HTTP GET request sent to the container.
+type HTTPHook struct {
+	// Port is the port of the container to send the request to.
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port" protobuf:"1"`
+
+	// Path is the HTTP path of the request.
+	Path string `json:"path,omitempty" protobuf:"2"`
+}
+
+// StartupProbe specifies a probe which must succeed before the container is
+// considered started.
+type StartupProbe struct {
+	// Port specifies which port of the container to probe.
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port" protobuf:"1"`
+
+	// Path is the HTTP path of the probe. Path is mutually
+	// exclusive with the TCP and GCRP fields.
+	Path string `json:"path,omitempty" protobuf:"2"`
+
+	// TCP specifies that this is a simple TCP listen probe.
+	TCP bool `json:"tcp,omitempty" protobuf:"3"`
+
+	// GRPC specifies that this is a GRCP probe.
+	GRPC *InterfaceGRPCProbe `json:"grpc,omitempty" protobuf:"4"`
+
+	// PeriodSeconds is how often the probe is run. Defaults to 10.
+	PeriodSeconds uint32 `json:"periodSeconds,omitempty" protobuf:"5"`
+
+	// MaxStartupSeconds is how long the container is given to start before
+	// it is restarted. Defaults to 300.
+	MaxStartupSeconds uint32 `json:"maxStartupSeconds,omitempty" protobuf:"6"`
+}
+
+func (p StartupProbe) GetPath() string              { return p.Path }
+func (p StartupProbe) GetTCP() bool                 { return p.TCP }
+func (p StartupProbe) GetGRPC() *InterfaceGRPCProbe { return p.GRPC }
+
+// RolloutStrategy specifies how new versions of a Capsule are rolled out.
+// Exactly one of Canary and BlueGreen must be set.
+type RolloutStrategy struct {
+	// Canary rolls out a new version next to the current one, gradually
+	// shifting traffic to it.
+	Canary *CanaryStrategy `json:"canary,omitempty" protobuf:"1"`
+
+	// BlueGreen rolls out a new version next to the current one, and switches
+	// all traffic to it at once when it has been ready for long enough.
+	BlueGreen *BlueGreenStrategy `json:"blueGreen,omitempty" protobuf:"2"`
+}
+
+// CanaryStrategy specifies the steps a canary goes through before it is
+// promoted to be the new version of the Capsule.
+type CanaryStrategy struct {
+	// Steps is the list of steps of the canary. The canary moves on to the
+	// next step when all its instances have been ready for the pause of the
+	// current step. After the last step, the canary is promoted.
+	Steps []CanaryStep `json:"steps" protobuf:"1"`
+}
+
+// CanaryStep is a single step of a canary rollout.
+type CanaryStep struct {
+	// Weight is the percentage of traffic sent to the canary during the step.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Weight uint32 `json:"weight" protobuf:"1"`
+
+	// PauseSeconds is how long the canary must stay ready at this step before
+	// moving on to the next one.
+	PauseSeconds uint32 `json:"pauseSeconds,omitempty" protobuf:"2"`
+}
+
+// BlueGreenStrategy specifies how a blue/green rollout is promoted.
+type BlueGreenStrategy struct {
+	// PromotionDelaySeconds is how long all instances of the new version must
+	// stay ready before traffic is switched to it.
+	PromotionDelaySeconds uint32 `json:"promotionDelaySeconds,omitempty" protobuf:"1"`
+}
+
+// RolloutHooks specifies Jobs run when a new version of a Capsule is rolled
+// out, fx. to migrate a database.
+type RolloutHooks struct {
+	// PreRollout is run before a new version is rolled out. The Deployment of
+	// the Capsule keeps running the current version until the Job has
+	// succeeded. If the Job fails, the new version is not rolled out.
+	PreRollout *RolloutHook `json:"preRollout,omitempty" protobuf:"1"`
+
+	// PostRollout is run once all instances of a new version are ready.
+	PostRollout *RolloutHook `json:"postRollout,omitempty" protobuf:"2"`
+}
+
+// RolloutHook is a Job running a command in the image and environment of the
+// main container of a Capsule.
+type RolloutHook struct {
+	// Command is the command, and its arguments, run by the Job.
+	// +kubebuilder:validation:Required
+	Command JobCommand `json:"command" protobuf:"1"`
+
+	// MaxRetries is the number of times the Job is retried before it fails.
+	// Defaults to 6.
+	MaxRetries *uint `json:"maxRetries,omitempty" protobuf:"2"`
+
+	// TimeoutSeconds is how long the Job can run before it fails.
+	TimeoutSeconds *uint `json:"timeoutSeconds,omitempty" protobuf:"3"`
 }
 
+// Container defines an additional application container of a Capsule. The
+// container is configured independently of the main container, and doesn't
+// inherit its environment, files or interfaces.
+type Container struct {
+	// Name of the container. Must be unique among the containers and sidecars
+	// of the Capsule and different from the name of the Capsule.
+	Name string `json:"name" protobuf:"1"`
+
+	// Image specifies what image the container should run.
+	Image string `json:"image" protobuf:"2"`
+
+	// Command is run as a command in the shell. If left unspecified, the
+	// container will run using what is specified as ENTRYPOINT in the
+	// Dockerfile.
+	Command string `json:"command,omitempty" protobuf:"3"`
+
+	// Args is a list of arguments either passed to the Command or if Command
+	// is left empty the arguments will be passed to the ENTRYPOINT of the
+	// docker image.
+	Args []string `json:"args,omitempty" protobuf:"4"`
+
+	// Env specifies the environment variables of the container.
+	Env SidecarEnv `json:"env,omitempty" protobuf:"5"`
+
+	// Files is a list of files to mount in the container.
+	Files []File `json:"files,omitempty" protobuf:"6"`
+
+	// Interfaces specifies the interfaces of the container. They are exposed
+	// by the Service of the Capsule together with the interfaces of the main
+	// container, so names and ports must be unique across all containers.
+	Interfaces []CapsuleInterface `json:"interfaces,omitempty" protobuf:"7"`
+
+	// Resources specifies the resource requests and limits of the container.
+	// If omitted, no requests or limits are set.
+	Resources *VerticalScale `json:"resources,omitempty" protobuf:"8"`
+}
+
+// Sidecar defines an additional container which runs alongside the main
+// container of the Capsule, in the same instances.
+type Sidecar struct {
+	// Name of the sidecar container. Must be unique among the sidecars of
+	// the Capsule and different from the name of the Capsule.
+	Name string `json:"name" protobuf:"1"`
+
+	// Image specifies what image the sidecar should run.
+	Image string `json:"image" protobuf:"2"`
+
+	// Command is run as a command in the shell. If left unspecified, the
+	// container will run using what is specified as ENTRYPOINT in the
+	// Dockerfile.
+	Command string `json:"command,omitempty" protobuf:"3"`
+
+	// Args is a list of arguments either passed to the Command or if Command
+	// is left empty the arguments will be passed to the ENTRYPOINT of the
+	// docker image.
+	Args []string `json:"args,omitempty" protobuf:"4"`
+
+	// Env specifies the environment variables of the sidecar. The sidecar
+	// does not inherit the environment of the Capsule.
+	Env SidecarEnv `json:"env,omitempty" protobuf:"5"`
+
+	// Resources specifies the resource requests and limits of the sidecar.
+	// If omitted, no requests or limits are set.
+	Resources *VerticalScale `json:"resources,omitempty" protobuf:"6"`
+
+	// Liveness specifies the liveness probe of the sidecar.
+	Liveness *SidecarProbe `json:"liveness,omitempty" protobuf:"7"`
+
+	// Readiness specifies the readiness probe of the sidecar.
+	Readiness *SidecarProbe `json:"readiness,omitempty" protobuf:"8"`
+}
+
+// SidecarEnv defines the environment variables of a sidecar.
+type SidecarEnv struct {
+	// Raw is a list of environment variables as key-value pairs.
+	Raw map[string]string `json:"raw,omitempty" protobuf:"1"`
+
+	// From holds a list of references to secrets and configmaps which should
+	// be mounted as environment variables.
+	From []EnvReference `json:"from,omitempty" protobuf:"2"`
+}
+
+// SidecarProbe specifies a probe of a sidecar container.
+type SidecarProbe struct {
+	// Port specifies which port of the sidecar to probe.
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port" protobuf:"1"`
+
+	// Path is the HTTP path of the probe. Path is mutually
+	// exclusive with the TCP and GCRP fields.
+	Path string `json:"path,omitempty" protobuf:"2"`
+
+	// TCP specifies that this is a simple TCP listen probe.
+	TCP bool `json:"tcp,omitempty" protobuf:"3"`
+
+	// GRPC specifies that this is a GRCP probe.
+	GRPC *InterfaceGRPCProbe `json:"grpc,omitempty" protobuf:"4"`
+}
+
+func (p SidecarProbe) GetPath() string              { return p.Path }
+func (p SidecarProbe) GetTCP() bool                 { return p.TCP }
+func (p SidecarProbe) GetGRPC() *InterfaceGRPCProbe { return p.GRPC }
+
 // CapsuleStatus defines the observed state of Capsule
 type CapsuleStatus struct {
 	Replicas           uint32            `json:"replicas,omitempty"`
@@ -395,6 +959,16 @@ type CapsuleStatus struct {
 	UsedResources      []UsedResource    `json:"usedResources,omitempty"`
 	Deployment         *DeploymentStatus `json:"deploymentStatus,omitempty"`
 	Errors             []string          `json:"errors,omitempty"`
+	// Conditions of the Capsule. The Drifted condition is maintained when
+	// drift detection is enabled in the operator.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Drift are the owned resources which differed from their desired state
+	// in the latest drift scan.
+	Drift []ObjectDrift `json:"drift,omitempty"`
+	// AppliedAt is the time of the reconciliation which last applied changes
+	// to the owned resources. Drift scans compute the desired state as of this
+	// time, so changes made by time-based steps are not reported as drift.
+	AppliedAt *metav1.Time `json:"appliedAt,omitempty"`
 }
 
 type DeploymentStatus struct {
@@ -408,6 +982,36 @@ type OwnedResource struct {
 	// +kubebuilder:validation:Enum=created;failed;alreadyExists;unchanged;updated;changePending;deleted
 	State   string `json:"state,omitempty"`
 	Message string `json:"message,omitempty"`
+	// Conflicts are the fields of the resource which were not applied, as
+	// they are managed by another field manager. Only set when using server-side apply.
+	Conflicts []FieldConflict `json:"conflicts,omitempty"`
+}
+
+// FieldConflict is a field which conflicts with another field manager.
+type FieldConflict struct {
+	// Field is the path of the conflicting field.
+	Field string `json:"field"`
+	// Manager is the field manager which owns the field.
+	Manager string `json:"manager,omitempty"`
+	// Message describes the conflict.
+	Message string `json:"message,omitempty"`
+}
+
+// ObjectDrift is an owned resource which has been changed outside of the operator.
+type ObjectDrift struct {
+	Ref *v1.TypedLocalObjectReference `json:"ref"`
+	// +kubebuilder:validation:Enum=missing;changed;unexpected
+	State string `json:"state"`
+	// Fields are the fields of the resource which differ from the desired state.
+	Fields []FieldDrift `json:"fields,omitempty"`
+}
+
+// FieldDrift is a field of a resource which differs from the desired state.
+type FieldDrift struct {
+	// Field is the path of the field.
+	Field string `json:"field"`
+	// Manager is the field manager which last changed the field, if known.
+	Manager string `json:"manager,omitempty"`
 }
 
 type UsedResource struct {
@@ -1,17 +1,28 @@
 package v1alpha2
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"net/http"
 	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rigdev/rig/pkg/utils"
 	"github.com/robfig/cron/v3"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -23,6 +34,7 @@ var capsulelog = logf.Log.WithName("capsule-resource")
 func (r *Capsule) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
+		WithValidator(&capsuleValidator{reader: mgr.GetClient()}).
 		Complete()
 }
 
@@ -46,9 +58,20 @@ func (r *Capsule) ValidateCreate() (admission.Warnings, error) {
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
-func (r *Capsule) ValidateUpdate(_ runtime.Object) (admission.Warnings, error) {
+func (r *Capsule) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
 	capsulelog.Info("validate update", "name", r.Name)
-	return r.validate()
+	warns, err := r.validate()
+	if err != nil {
+		return warns, err
+	}
+
+	if oldCapsule, ok := old.(*Capsule); ok {
+		if errs := r.validateStorageUpdate(oldCapsule); len(errs) > 0 {
+			return warns, errs.ToAggregate()
+		}
+	}
+
+	return warns, nil
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -56,6 +79,52 @@ func (r *Capsule) ValidateDelete() (admission.Warnings, error) {
 	return nil, nil
 }
 
+// capsuleValidator validates capsules like the validation of Capsule, and
+// additionally validates the dependencies between capsules, which requires
+// looking up the capsules depended on.
+type capsuleValidator struct {
+	reader client.Reader
+}
+
+func (v *capsuleValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	r := obj.(*Capsule)
+	warns, err := r.ValidateCreate()
+	if err != nil {
+		return warns, err
+	}
+
+	return warns, v.validateDependencyCycles(ctx, r)
+}
+
+func (v *capsuleValidator) ValidateUpdate(
+	ctx context.Context,
+	oldObj, newObj runtime.Object,
+) (admission.Warnings, error) {
+	r := newObj.(*Capsule)
+	warns, err := r.ValidateUpdate(oldObj)
+	if err != nil {
+		return warns, err
+	}
+
+	return warns, v.validateDependencyCycles(ctx, r)
+}
+
+func (v *capsuleValidator) ValidateDelete(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return obj.(*Capsule).ValidateDelete()
+}
+
+func (v *capsuleValidator) validateDependencyCycles(ctx context.Context, r *Capsule) error {
+	return r.validateDependencyCycles(func(key types.NamespacedName) (*Capsule, error) {
+		var c Capsule
+		if err := v.reader.Get(ctx, key, &c); kerrors.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return &c, nil
+	}).ToAggregate()
+}
+
 func (r *Capsule) validate() (admission.Warnings, error) {
 	var (
 		allWarns admission.Warnings
@@ -82,6 +151,17 @@ func (r *Capsule) validate() (admission.Warnings, error) {
 
 	allErrs = append(allErrs, r.Spec.Scale.Horizontal.validate(field.NewPath("scale").Child("horizontal"))...)
 	allErrs = append(allErrs, r.validateCronJobs()...)
+	allErrs = append(allErrs, r.validateSidecars()...)
+	allErrs = append(allErrs, r.validateContainers()...)
+	allErrs = append(allErrs, r.validateStorage()...)
+	allErrs = append(allErrs, r.validateRollout()...)
+	allErrs = append(allErrs, r.validateRolloutHooks()...)
+	allErrs = append(allErrs, r.validateAvailability()...)
+	allErrs = append(allErrs, r.validateScaleToZero()...)
+	allErrs = append(allErrs, r.validateLifecycle()...)
+	allErrs = append(allErrs, r.validateNetworkPolicy()...)
+	allErrs = append(allErrs, r.validateDependencies()...)
+	allErrs = append(allErrs, r.validateExternalSecrets()...)
 
 	return allWarns, allErrs.ToAggregate()
 }
@@ -96,19 +176,36 @@ func (r *Capsule) validateSpec() (admission.Warnings, field.ErrorList) {
 }
 
 func (r *Capsule) validateInterfaces() (admission.Warnings, field.ErrorList) {
-	if len(r.Spec.Interfaces) == 0 {
-		return nil, nil
+	// The containers of an instance share its network, and all interfaces
+	// are exposed by the same Service, so names and ports must be unique
+	// across all containers.
+	names := map[string]struct{}{}
+	ports := map[int32]struct{}{}
+
+	errs := validateInterfaceList(r.Spec.Interfaces, field.NewPath("spec").Child("interfaces"), names, ports)
+	for i, c := range r.Spec.Containers {
+		errs = append(errs, validateInterfaceList(
+			c.Interfaces, field.NewPath("spec").Child("containers").Index(i).Child("interfaces"), names, ports,
+		)...)
 	}
 
+	return nil, errs
+}
+
+// validateInterfaceList validates the interfaces of a single container. The
+// names and ports of the interfaces are added to the given maps.
+func validateInterfaceList(
+	interfaces []CapsuleInterface,
+	infsPath *field.Path,
+	names map[string]struct{},
+	ports map[int32]struct{},
+) field.ErrorList {
 	hasLiveness := false
 	hasReadiness := false
 
 	var errs field.ErrorList
 
-	names := map[string]struct{}{}
-	ports := map[int32]struct{}{}
-	infsPath := field.NewPath("spec").Child("interfaces")
-	for i, inf := range r.Spec.Interfaces {
+	for i, inf := range interfaces {
 		infPath := infsPath.Index(i)
 
 		if inf.Name == "" {
@@ -157,9 +254,119 @@ func (r *Capsule) validateInterfaces() (admission.Warnings, field.ErrorList) {
 
 			hasReadiness = true
 		}
+
+		for j, route := range inf.Routes {
+			errs = append(errs, route.validate(infPath.Child("routes").Index(j))...)
+		}
 	}
 
-	return nil, errs
+	return errs
+}
+
+var httpMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+func (h HostRoute) validate(rPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	for i, m := range h.Headers {
+		hPath := rPath.Child("headers").Index(i)
+		if m.Name == "" {
+			errs = append(errs, field.Required(hPath.Child("name"), ""))
+		} else if nameErrs := validation.IsHTTPHeaderName(m.Name); nameErrs != nil {
+			errs = append(errs, field.Invalid(hPath.Child("name"), m.Name, strings.Join(nameErrs, "; ")))
+		}
+		errs = append(errs, validateValueMatch(m.Value, m.Match, hPath)...)
+	}
+
+	for i, m := range h.QueryParameters {
+		qPath := rPath.Child("queryParameters").Index(i)
+		if m.Name == "" {
+			errs = append(errs, field.Required(qPath.Child("name"), ""))
+		}
+		errs = append(errs, validateValueMatch(m.Value, m.Match, qPath)...)
+	}
+
+	methods := map[string]struct{}{}
+	for i, m := range h.Methods {
+		mPath := rPath.Child("methods").Index(i)
+		if !slices.Contains(httpMethods, m) {
+			errs = append(errs, field.NotSupported(mPath, m, httpMethods))
+		}
+		if _, ok := methods[m]; ok {
+			errs = append(errs, field.Duplicate(mPath, m))
+		}
+		methods[m] = struct{}{}
+	}
+
+	if h.RewritePathPrefix != "" {
+		if !strings.HasPrefix(h.RewritePathPrefix, "/") {
+			errs = append(errs, field.Invalid(
+				rPath.Child("rewritePathPrefix"), h.RewritePathPrefix, "must start with '/'",
+			))
+		}
+		for i, p := range h.Paths {
+			if p.Match != "" && p.Match != PathPrefix {
+				errs = append(errs, field.Invalid(
+					rPath.Child("paths").Index(i).Child("match"), p.Match,
+					"only PathPrefix paths can be used with rewritePathPrefix",
+				))
+			}
+		}
+	}
+
+	errs = append(errs, h.RequestHeaders.validate(rPath.Child("requestHeaders"))...)
+	errs = append(errs, h.ResponseHeaders.validate(rPath.Child("responseHeaders"))...)
+
+	return errs
+}
+
+func validateValueMatch(value string, match ValueMatchType, mPath *field.Path) field.ErrorList {
+	switch match {
+	case "", ValueExact:
+	case ValueRegularExpression:
+		if _, err := regexp.Compile(value); err != nil {
+			return field.ErrorList{field.Invalid(mPath.Child("value"), value, err.Error())}
+		}
+	default:
+		return field.ErrorList{field.NotSupported(
+			mPath.Child("match"), match, []string{string(ValueExact), string(ValueRegularExpression)},
+		)}
+	}
+
+	return nil
+}
+
+func (h *HeaderModifier) validate(hPath *field.Path) field.ErrorList {
+	if h == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+	for _, key := range slices.Sorted(maps.Keys(h.Set)) {
+		if nameErrs := validation.IsHTTPHeaderName(key); nameErrs != nil {
+			errs = append(errs, field.Invalid(hPath.Child("set").Key(key), key, strings.Join(nameErrs, "; ")))
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(h.Add)) {
+		if nameErrs := validation.IsHTTPHeaderName(key); nameErrs != nil {
+			errs = append(errs, field.Invalid(hPath.Child("add").Key(key), key, strings.Join(nameErrs, "; ")))
+		}
+	}
+	for i, name := range h.Remove {
+		if nameErrs := validation.IsHTTPHeaderName(name); nameErrs != nil {
+			errs = append(errs, field.Invalid(hPath.Child("remove").Index(i), name, strings.Join(nameErrs, "; ")))
+		}
+		if _, ok := h.Set[name]; ok {
+			errs = append(errs, field.Invalid(
+				hPath.Child("remove").Index(i), name, "header cannot be both set and removed",
+			))
+		}
+	}
+
+	return errs
 }
 
 type interfaceProbe interface {
@@ -194,10 +401,13 @@ func validateProbe(p interfaceProbe, pPath *field.Path) field.ErrorList {
 }
 
 func (r *Capsule) validateEnv() (admission.Warnings, field.ErrorList) {
+	return nil, validateEnvReferences(r.Spec.Env.From, field.NewPath("spec").Child("env").Child("from"))
+}
+
+func validateEnvReferences(refs []EnvReference, fromPath *field.Path) field.ErrorList {
 	var errs field.ErrorList
 
-	fromPath := field.NewPath("spec").Child("env").Child("from")
-	for i, r := range r.Spec.Env.From {
+	for i, r := range refs {
 		fPath := fromPath.Index(i)
 
 		if r.Kind == "" {
@@ -209,17 +419,28 @@ func (r *Capsule) validateEnv() (admission.Warnings, field.ErrorList) {
 		if r.Name == "" {
 			errs = append(errs, field.Required(fPath.Child("name"), "missing env name"))
 		}
+
+		if r.External != nil {
+			if r.Kind != "Secret" {
+				errs = append(errs, field.Invalid(fPath.Child("kind"), r.Kind, "external env references must be of kind Secret"))
+			}
+			errs = append(errs, validateExternalSecretReference(r.External, fPath.Child("external"))...)
+		}
 	}
 
-	return nil, errs
+	return errs
 }
 
 func (r *Capsule) validateFiles() (admission.Warnings, field.ErrorList) {
+	return nil, validateFileList(r.Spec.Files, field.NewPath("spec").Child("files"))
+}
+
+// validateFileList validates the files mounted in a single container.
+func validateFileList(files []File, filesPath *field.Path) field.ErrorList {
 	var errs field.ErrorList
 
 	paths := map[string]struct{}{}
-	filesPath := field.NewPath("spec").Child("files")
-	for i, f := range r.Spec.Files {
+	for i, f := range files {
 		fPath := filesPath.Index(i)
 
 		if f.Path == "" {
@@ -254,10 +475,519 @@ func (r *Capsule) validateFiles() (admission.Warnings, field.ErrorList) {
 			if f.Ref.Key == "" {
 				errs = append(errs, field.Required(fPath.Child("ref").Child("key"), ""))
 			}
+
+			if f.Ref.External != nil {
+				if f.Ref.Kind != "Secret" {
+					errs = append(errs, field.Invalid(
+						fPath.Child("ref").Child("kind"),
+						f.Ref.Kind,
+						"external file references must be of kind Secret",
+					))
+				}
+				errs = append(errs, validateExternalSecretReference(f.Ref.External, fPath.Child("ref").Child("external"))...)
+			}
 		}
 	}
 
-	return nil, errs
+	return errs
+}
+
+func validateExternalSecretReference(ref *ExternalSecretReference, fPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if ref.Store == "" {
+		errs = append(errs, field.Required(fPath.Child("store"), ""))
+	}
+
+	switch ref.StoreKind {
+	case "", ExternalSecretStoreKindSecretStore, ExternalSecretStoreKindClusterSecretStore:
+	default:
+		errs = append(errs, field.NotSupported(
+			fPath.Child("storeKind"),
+			ref.StoreKind,
+			[]string{ExternalSecretStoreKindSecretStore, ExternalSecretStoreKindClusterSecretStore},
+		))
+	}
+
+	if ref.Path == "" {
+		errs = append(errs, field.Required(fPath.Child("path"), ""))
+	}
+
+	return errs
+}
+
+// validateExternalSecrets validates that all references to a Secret agree on
+// whether, and from where, the Secret is synced from an external secret store.
+func (r *Capsule) validateExternalSecrets() field.ErrorList {
+	var errs field.ErrorList
+
+	type reference struct {
+		path     *field.Path
+		external *ExternalSecretReference
+	}
+	secrets := map[string]reference{}
+	check := func(kind, name string, external *ExternalSecretReference, fPath *field.Path) {
+		if kind != "Secret" || name == "" {
+			return
+		}
+		other, ok := secrets[name]
+		if !ok {
+			secrets[name] = reference{path: fPath, external: external}
+			return
+		}
+		if !reflect.DeepEqual(other.external, external) {
+			errs = append(errs, field.Invalid(
+				fPath.Child("external"),
+				external,
+				fmt.Sprintf("secret '%s' is referenced differently by %s", name, other.path),
+			))
+		}
+	}
+
+	for i, e := range r.Spec.Env.From {
+		check(e.Kind, e.Name, e.External, field.NewPath("spec").Child("env").Child("from").Index(i))
+	}
+	for i, f := range r.Spec.Files {
+		if f.Ref != nil {
+			check(f.Ref.Kind, f.Ref.Name, f.Ref.External, field.NewPath("spec").Child("files").Index(i).Child("ref"))
+		}
+	}
+	for i, sc := range r.Spec.Sidecars {
+		for j, e := range sc.Env.From {
+			check(e.Kind, e.Name, e.External,
+				field.NewPath("spec").Child("sidecars").Index(i).Child("env").Child("from").Index(j))
+		}
+	}
+	for i, c := range r.Spec.Containers {
+		cPath := field.NewPath("spec").Child("containers").Index(i)
+		for j, e := range c.Env.From {
+			check(e.Kind, e.Name, e.External, cPath.Child("env").Child("from").Index(j))
+		}
+		for j, f := range c.Files {
+			if f.Ref != nil {
+				check(f.Ref.Kind, f.Ref.Name, f.Ref.External, cPath.Child("files").Index(j).Child("ref"))
+			}
+		}
+	}
+
+	return errs
+}
+
+func (r *Capsule) validateStorage() field.ErrorList {
+	if r.Spec.Storage == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+
+	filePaths := map[string]struct{}{}
+	for _, f := range r.Spec.Files {
+		filePaths[f.Path] = struct{}{}
+	}
+
+	names := map[string]struct{}{}
+	paths := map[string]struct{}{}
+	volumesPath := field.NewPath("spec").Child("storage").Child("volumes")
+	for i, v := range r.Spec.Storage.Volumes {
+		vPath := volumesPath.Index(i)
+
+		if v.Name == "" {
+			errs = append(errs, field.Required(vPath.Child("name"), ""))
+		} else if dnsErrs := validation.IsDNS1123Label(v.Name); dnsErrs != nil {
+			errs = append(errs, field.Invalid(vPath.Child("name"), v.Name, strings.Join(dnsErrs, "; ")))
+		}
+
+		if _, ok := names[v.Name]; ok {
+			errs = append(errs, field.Duplicate(vPath.Child("name"), v.Name))
+		} else {
+			names[v.Name] = struct{}{}
+		}
+
+		if v.Path == "" {
+			errs = append(errs, field.Required(vPath.Child("path"), ""))
+		} else if !path.IsAbs(v.Path) {
+			errs = append(errs, field.Invalid(vPath.Child("path"), v.Path, "path must be an absolute path"))
+		}
+
+		if _, ok := paths[v.Path]; ok {
+			errs = append(errs, field.Duplicate(vPath.Child("path"), v.Path))
+		} else {
+			paths[v.Path] = struct{}{}
+		}
+
+		if _, ok := filePaths[v.Path]; ok {
+			errs = append(errs, field.Invalid(vPath.Child("path"), v.Path, "path is already used by a file"))
+		}
+
+		if v.Size.Sign() <= 0 {
+			errs = append(errs, field.Invalid(vPath.Child("size"), v.Size.String(), "size must be positive"))
+		}
+	}
+
+	return errs
+}
+
+// validateStorageUpdate rejects changes to the storage of a capsule. The
+// volume claim templates of a StatefulSet are immutable, and adding or
+// removing all volumes switches the capsule between a Deployment and a
+// StatefulSet, which would replace all of its instances at once.
+func (r *Capsule) validateStorageUpdate(old *Capsule) field.ErrorList {
+	var volumes, oldVolumes []PersistentVolume
+	if r.Spec.Storage != nil {
+		volumes = r.Spec.Storage.Volumes
+	}
+	if old.Spec.Storage != nil {
+		oldVolumes = old.Spec.Storage.Volumes
+	}
+
+	if slices.EqualFunc(volumes, oldVolumes, func(a, b PersistentVolume) bool {
+		return a.Name == b.Name &&
+			a.Path == b.Path &&
+			a.StorageClassName == b.StorageClassName &&
+			a.Size.Cmp(b.Size) == 0
+	}) {
+		return nil
+	}
+
+	return field.ErrorList{field.Forbidden(
+		field.NewPath("spec").Child("storage"),
+		"storage cannot be changed, delete and recreate the capsule to change its storage",
+	)}
+}
+
+func (r *Capsule) validateRollout() field.ErrorList {
+	if r.Spec.Rollout == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+
+	rPath := field.NewPath("spec").Child("rollout")
+	rollout := r.Spec.Rollout
+	switch {
+	case rollout.Canary == nil && rollout.BlueGreen == nil:
+		errs = append(errs, field.Required(rPath, "one of canary or blueGreen must be set"))
+	case rollout.Canary != nil && rollout.BlueGreen != nil:
+		errs = append(errs, field.Invalid(rPath, "", "only one of canary or blueGreen can be set"))
+	}
+
+	if r.Spec.Storage != nil && len(r.Spec.Storage.Volumes) > 0 {
+		errs = append(errs, field.Forbidden(rPath, "rollout strategies are not supported for capsules with storage"))
+	}
+
+	if rollout.Canary != nil {
+		stepsPath := rPath.Child("canary").Child("steps")
+		if len(rollout.Canary.Steps) == 0 {
+			errs = append(errs, field.Required(stepsPath, ""))
+		}
+		for i, s := range rollout.Canary.Steps {
+			if s.Weight < 1 || s.Weight > 100 {
+				errs = append(errs, field.Invalid(
+					stepsPath.Index(i).Child("weight"), s.Weight, "weight must be 1 <= weight <= 100",
+				))
+			}
+		}
+	}
+
+	return errs
+}
+
+// MaxAllowedRolloutHookCapsuleName is the longest name of a Capsule with
+// rollout hooks. The Jobs of the hooks are named
+// '{capsulename}-post-rollout-{hash}', and Job names can be at most 63
+// characters.
+const MaxAllowedRolloutHookCapsuleName = 63 - len("-post-rollout-") - 8
+
+func (r *Capsule) validateRolloutHooks() field.ErrorList {
+	hooks := r.Spec.RolloutHooks
+	if hooks == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+
+	hPath := field.NewPath("spec").Child("rolloutHooks")
+	if r.Spec.Storage != nil && len(r.Spec.Storage.Volumes) > 0 {
+		errs = append(errs, field.Forbidden(hPath, "rollout hooks are not supported for capsules with storage"))
+	}
+
+	if (hooks.PreRollout != nil || hooks.PostRollout != nil) && len(r.Name) > MaxAllowedRolloutHookCapsuleName {
+		errs = append(errs, field.Forbidden(hPath, fmt.Sprintf(
+			"rollout hooks are not supported for capsules with names longer than %d", MaxAllowedRolloutHookCapsuleName,
+		)))
+	}
+
+	if h := hooks.PreRollout; h != nil && h.Command.Command == "" {
+		errs = append(errs, field.Required(hPath.Child("preRollout").Child("command").Child("command"), ""))
+	}
+	if h := hooks.PostRollout; h != nil && h.Command.Command == "" {
+		errs = append(errs, field.Required(hPath.Child("postRollout").Child("command").Child("command"), ""))
+	}
+
+	return errs
+}
+
+func (r *Capsule) validateScaleToZero() field.ErrorList {
+	if r.Spec.Scale.Horizontal.ScaleToZero == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+
+	sPath := field.NewPath("spec").Child("scale").Child("horizontal").Child("scaleToZero")
+	hasRoutes := false
+	for _, inf := range r.AllInterfaces() {
+		if len(inf.Routes) > 0 {
+			hasRoutes = true
+		}
+	}
+	if !hasRoutes {
+		errs = append(errs, field.Invalid(sPath, "", "scale to zero requires at least one route to wake up the capsule"))
+	}
+
+	if r.Spec.Rollout != nil {
+		errs = append(errs, field.Forbidden(sPath, "scale to zero is not supported together with rollout strategies"))
+	}
+
+	return errs
+}
+
+func (r *Capsule) validateLifecycle() field.ErrorList {
+	lc := r.Spec.Lifecycle
+	if lc == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+
+	lPath := field.NewPath("spec").Child("lifecycle")
+	if h := lc.PreStop; h != nil {
+		hPath := lPath.Child("preStop")
+		switch {
+		case len(h.Command) == 0 && h.HTTP == nil:
+			errs = append(errs, field.Required(hPath, "one of command or http must be set"))
+		case len(h.Command) > 0 && h.HTTP != nil:
+			errs = append(errs, field.Invalid(hPath, "", "only one of command or http can be set"))
+		}
+		if h.HTTP != nil {
+			if !(1 <= h.HTTP.Port && h.HTTP.Port <= 65535) {
+				errs = append(errs, field.Invalid(
+					hPath.Child("http").Child("port"), h.HTTP.Port, "port must be 1 <= port <= 65535",
+				))
+			}
+			if h.HTTP.Path != "" && !path.IsAbs(h.HTTP.Path) {
+				errs = append(errs, field.Invalid(
+					hPath.Child("http").Child("path"), h.HTTP.Path, "path must be an absolute path",
+				))
+			}
+		}
+	}
+
+	if p := lc.StartupProbe; p != nil {
+		pPath := lPath.Child("startupProbe")
+		if !(1 <= p.Port && p.Port <= 65535) {
+			errs = append(errs, field.Invalid(pPath.Child("port"), p.Port, "port must be 1 <= port <= 65535"))
+		}
+		errs = append(errs, validateProbe(p, pPath)...)
+		if p.MaxStartupSeconds != 0 && p.MaxStartupSeconds < p.PeriodSeconds {
+			errs = append(errs, field.Invalid(
+				pPath.Child("maxStartupSeconds"), p.MaxStartupSeconds, "cannot be smaller than periodSeconds",
+			))
+		}
+
+		for i, inf := range r.Spec.Interfaces {
+			if inf.Liveness != nil && inf.Liveness.StartupDelay > 0 {
+				errs = append(errs, field.Forbidden(
+					field.NewPath("spec").Child("interfaces").Index(i).Child("liveness").Child("startupDelay"),
+					"startupDelay cannot be used together with a startup probe",
+				))
+			}
+		}
+	}
+
+	return errs
+}
+
+func (r *Capsule) validateAvailability() field.ErrorList {
+	a := r.Spec.Availability
+	if a == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+
+	aPath := field.NewPath("spec").Child("availability")
+	if a.MinAvailable != "" && a.MaxUnavailable != "" {
+		errs = append(errs, field.Invalid(aPath, "", "only one of minAvailable or maxUnavailable can be set"))
+	}
+	errs = append(errs, validateIntOrPercent(a.MinAvailable, aPath.Child("minAvailable"))...)
+	errs = append(errs, validateIntOrPercent(a.MaxUnavailable, aPath.Child("maxUnavailable"))...)
+
+	return errs
+}
+
+func (r *Capsule) validateNetworkPolicy() field.ErrorList {
+	np := r.Spec.NetworkPolicy
+	if np == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+
+	npPath := field.NewPath("spec").Child("networkPolicy")
+	for i, p := range np.AllowFrom {
+		errs = append(errs, p.validate(npPath.Child("allowFrom").Index(i))...)
+	}
+	for i, p := range np.AllowTo {
+		errs = append(errs, p.validate(npPath.Child("allowTo").Index(i))...)
+	}
+
+	return errs
+}
+
+func (p NetworkPeer) validate(fPath *field.Path) field.ErrorList {
+	if p.Capsule == "" && p.Namespace == "" {
+		return field.ErrorList{field.Invalid(fPath, "", "at least one of capsule or namespace must be set")}
+	}
+
+	var errs field.ErrorList
+	if p.Capsule != "" {
+		if dnsErrs := validation.IsDNS1123Label(p.Capsule); dnsErrs != nil {
+			errs = append(errs, field.Invalid(fPath.Child("capsule"), p.Capsule, strings.Join(dnsErrs, "; ")))
+		}
+	}
+	if p.Namespace != "" {
+		if dnsErrs := validation.IsDNS1123Label(p.Namespace); dnsErrs != nil {
+			errs = append(errs, field.Invalid(fPath.Child("namespace"), p.Namespace, strings.Join(dnsErrs, "; ")))
+		}
+	}
+
+	return errs
+}
+
+func (r *Capsule) validateDependencies() field.ErrorList {
+	var errs field.ErrorList
+
+	seen := map[CapsuleDependency]struct{}{}
+	for i, d := range r.Spec.Dependencies {
+		dPath := field.NewPath("spec").Child("dependencies").Index(i)
+
+		if d.Capsule == "" {
+			errs = append(errs, field.Required(dPath.Child("capsule"), ""))
+		} else if dnsErrs := validation.IsDNS1123Label(d.Capsule); dnsErrs != nil {
+			errs = append(errs, field.Invalid(dPath.Child("capsule"), d.Capsule, strings.Join(dnsErrs, "; ")))
+		}
+		if d.Interface == "" {
+			errs = append(errs, field.Required(dPath.Child("interface"), ""))
+		}
+		if d.Namespace != "" {
+			if dnsErrs := validation.IsDNS1123Label(d.Namespace); dnsErrs != nil {
+				errs = append(errs, field.Invalid(dPath.Child("namespace"), d.Namespace, strings.Join(dnsErrs, "; ")))
+			}
+		}
+
+		if d.Capsule == r.Name && (d.Namespace == "" || d.Namespace == r.Namespace) {
+			errs = append(errs, field.Invalid(dPath.Child("capsule"), d.Capsule, "a capsule cannot depend on itself"))
+		}
+
+		key := CapsuleDependency{Capsule: d.Capsule, Interface: d.Interface, Namespace: d.Namespace}
+		if d.Namespace == r.Namespace {
+			key.Namespace = ""
+		}
+		if _, ok := seen[key]; ok {
+			errs = append(errs, field.Duplicate(dPath, d.Capsule+"/"+d.Interface))
+		}
+		seen[key] = struct{}{}
+	}
+
+	return errs
+}
+
+// validateDependencyCycles rejects dependencies waiting for the readiness of
+// a capsule which, directly or through other capsules, waits for the
+// readiness of the capsule itself, as the instances of the capsules would
+// never start. Capsules depended on are looked up with get, which returns nil
+// if the capsule doesn't exist.
+func (r *Capsule) validateDependencyCycles(
+	get func(key types.NamespacedName) (*Capsule, error),
+) field.ErrorList {
+	self := types.NamespacedName{Namespace: r.Namespace, Name: r.Name}
+	visited := map[types.NamespacedName]struct{}{}
+
+	// findCycle returns the capsules from key leading back to the capsule
+	// itself, through dependencies waiting for readiness.
+	var findCycle func(key types.NamespacedName) ([]string, error)
+	findCycle = func(key types.NamespacedName) ([]string, error) {
+		if key == self {
+			return []string{key.String()}, nil
+		}
+		if _, ok := visited[key]; ok {
+			return nil, nil
+		}
+		visited[key] = struct{}{}
+
+		c, err := get(key)
+		if err != nil || c == nil {
+			return nil, err
+		}
+
+		for _, d := range c.Spec.Dependencies {
+			if !d.WaitForReady {
+				continue
+			}
+			cycle, err := findCycle(dependencyKey(d, c.Namespace))
+			if err != nil || cycle != nil {
+				return append([]string{key.String()}, cycle...), err
+			}
+		}
+		return nil, nil
+	}
+
+	var errs field.ErrorList
+	for i, d := range r.Spec.Dependencies {
+		if !d.WaitForReady {
+			continue
+		}
+
+		dPath := field.NewPath("spec").Child("dependencies").Index(i).Child("waitForReady")
+		cycle, err := findCycle(dependencyKey(d, r.Namespace))
+		if err != nil {
+			errs = append(errs, field.InternalError(dPath, err))
+		} else if cycle != nil {
+			errs = append(errs, field.Invalid(dPath, d.WaitForReady, fmt.Sprintf(
+				"capsules cannot wait for each other, %s -> %s", self.String(), strings.Join(cycle, " -> "),
+			)))
+		}
+	}
+
+	return errs
+}
+
+func dependencyKey(d CapsuleDependency, namespace string) types.NamespacedName {
+	if d.Namespace != "" {
+		namespace = d.Namespace
+	}
+	return types.NamespacedName{Namespace: namespace, Name: d.Capsule}
+}
+
+// validateIntOrPercent validates that the value is either a non-negative
+// integer or a percentage between 0% and 100%.
+func validateIntOrPercent(value string, fPath *field.Path) field.ErrorList {
+	if value == "" {
+		return nil
+	}
+
+	n, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	switch {
+	case err != nil:
+		return field.ErrorList{field.Invalid(fPath, value, "must be an integer or a percentage")}
+	case n < 0:
+		return field.ErrorList{field.Invalid(fPath, value, "must not be negative")}
+	case strings.HasSuffix(value, "%") && n > 100:
+		return field.ErrorList{field.Invalid(fPath, value, "must not be more than 100%")}
+	}
+
+	return nil
 }
 
 func (h *HorizontalScale) validate(fPath *field.Path) field.ErrorList {
@@ -340,6 +1070,32 @@ func (h *HorizontalScale) validate(fPath *field.Path) field.ErrorList {
 		}
 	}
 
+	for idx, s := range h.Schedules {
+		fPath := fPath.Child("schedules").Index(idx)
+		if _, err := cron.ParseStandard(s.Schedule); err != nil {
+			errs = append(errs, field.Invalid(fPath.Child("schedule"), s.Schedule, err.Error()))
+		}
+		if d, err := time.ParseDuration(s.Duration); err != nil {
+			errs = append(errs, field.Invalid(fPath.Child("duration"), s.Duration, err.Error()))
+		} else if d <= 0 {
+			errs = append(errs, field.Invalid(fPath.Child("duration"), s.Duration, "must be positive"))
+		}
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			errs = append(errs, field.Invalid(fPath.Child("timezone"), s.Timezone, err.Error()))
+		}
+
+		minInstances, maxInstances := h.Instances.Min, h.Instances.Max
+		if s.Min != nil {
+			minInstances = *s.Min
+		}
+		if s.Max != nil {
+			maxInstances = s.Max
+		}
+		if maxInstances != nil && *maxInstances < minInstances {
+			errs = append(errs, field.Invalid(fPath, s, "max cannot be smaller than min during the window"))
+		}
+	}
+
 	return errs
 }
 
@@ -399,7 +1155,130 @@ func (r *Capsule) validateCronJobs() field.ErrorList {
 				errs = append(errs, field.Invalid(uPath.Child("path"), job.URL.Path, err.Error()))
 			}
 		}
+
+		if job.Webhook != nil {
+			wPath := jPath.Child("webhook")
+			if job.Webhook.SecretName == "" {
+				errs = append(errs, field.Required(wPath.Child("secretName"), ""))
+			}
+			if o := job.Webhook.AllowedOverrides; o != nil {
+				oPath := wPath.Child("allowedOverrides")
+				if job.URL != nil && (o.Args || len(o.Env) > 0) {
+					errs = append(errs, field.Invalid(oPath, o, "a job calling a URL can only override query parameters"))
+				}
+				if job.Command != nil && len(o.QueryParameters) > 0 {
+					errs = append(errs, field.Invalid(
+						oPath.Child("queryParameters"), o.QueryParameters, "a job running a command has no query parameters",
+					))
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+func (r *Capsule) validateSidecars() field.ErrorList {
+	var errs field.ErrorList
+
+	names := map[string]struct{}{}
+
+	path := field.NewPath("spec").Child("sidecars")
+	for idx, sc := range r.Spec.Sidecars {
+		sPath := path.Index(idx)
+
+		if sc.Name == "" {
+			errs = append(errs, field.Required(sPath.Child("name"), ""))
+		} else if dnsErrs := validation.IsDNS1123Label(sc.Name); dnsErrs != nil {
+			errs = append(errs, field.Invalid(sPath.Child("name"), sc.Name, strings.Join(dnsErrs, "; ")))
+		}
+
+		if _, ok := names[sc.Name]; ok {
+			errs = append(errs, field.Duplicate(sPath.Child("name"), sc.Name))
+		} else {
+			names[sc.Name] = struct{}{}
+		}
+
+		if sc.Name != "" && sc.Name == r.Name {
+			errs = append(errs, field.Invalid(sPath.Child("name"), sc.Name, "name cannot be the same as the capsule"))
+		}
+
+		if sc.Image == "" {
+			errs = append(errs, field.Required(sPath.Child("image"), ""))
+		}
+
+		for key := range sc.Env.Raw {
+			if envErrs := validation.IsEnvVarName(key); envErrs != nil {
+				errs = append(errs, field.Invalid(
+					sPath.Child("env").Child("raw").Key(key), key, strings.Join(envErrs, "; "),
+				))
+			}
+		}
+		errs = append(errs, validateEnvReferences(sc.Env.From, sPath.Child("env").Child("from"))...)
+
+		if sc.Liveness != nil {
+			errs = append(errs, sc.Liveness.validate(sPath.Child("liveness"))...)
+		}
+		if sc.Readiness != nil {
+			errs = append(errs, sc.Readiness.validate(sPath.Child("readiness"))...)
+		}
+	}
+
+	return errs
+}
+
+func (r *Capsule) validateContainers() field.ErrorList {
+	var errs field.ErrorList
+
+	names := map[string]struct{}{}
+	for _, sc := range r.Spec.Sidecars {
+		names[sc.Name] = struct{}{}
+	}
+
+	path := field.NewPath("spec").Child("containers")
+	for idx, c := range r.Spec.Containers {
+		cPath := path.Index(idx)
+
+		if c.Name == "" {
+			errs = append(errs, field.Required(cPath.Child("name"), ""))
+		} else if dnsErrs := validation.IsDNS1123Label(c.Name); dnsErrs != nil {
+			errs = append(errs, field.Invalid(cPath.Child("name"), c.Name, strings.Join(dnsErrs, "; ")))
+		}
+
+		if _, ok := names[c.Name]; ok {
+			errs = append(errs, field.Duplicate(cPath.Child("name"), c.Name))
+		} else {
+			names[c.Name] = struct{}{}
+		}
+
+		if c.Name != "" && c.Name == r.Name {
+			errs = append(errs, field.Invalid(cPath.Child("name"), c.Name, "name cannot be the same as the capsule"))
+		}
+
+		if c.Image == "" {
+			errs = append(errs, field.Required(cPath.Child("image"), ""))
+		}
+
+		for key := range c.Env.Raw {
+			if envErrs := validation.IsEnvVarName(key); envErrs != nil {
+				errs = append(errs, field.Invalid(
+					cPath.Child("env").Child("raw").Key(key), key, strings.Join(envErrs, "; "),
+				))
+			}
+		}
+		errs = append(errs, validateEnvReferences(c.Env.From, cPath.Child("env").Child("from"))...)
+		errs = append(errs, validateFileList(c.Files, cPath.Child("files"))...)
 	}
 
 	return errs
 }
+
+func (p *SidecarProbe) validate(pPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if !(1 <= p.Port && p.Port <= 65535) {
+		errs = append(errs, field.Invalid(pPath.Child("port"), p.Port, "port must be 1 <= port <= 65535"))
+	}
+
+	return append(errs, validateProbe(p, pPath)...)
+}
@@ -1,11 +1,15 @@
 package v1alpha2
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	v2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"github.com/rigdev/rig/pkg/ptr"
@@ -66,11 +70,29 @@ func TestValidateInterfaces(t *testing.T) {
 	tests := []struct {
 		name         string
 		interfaces   []CapsuleInterface
+		containers   []Container
 		expectedErrs field.ErrorList
 	}{
 		{
 			name: "no interfaces returns no errors",
 		},
+		{
+			name:       "interfaces must be unique across containers",
+			interfaces: []CapsuleInterface{{Name: "http", Port: 8080}},
+			containers: []Container{{
+				Name:       "worker",
+				Interfaces: []CapsuleInterface{{Name: "metrics", Port: 9090}, {Name: "http", Port: 8080}},
+			}},
+			expectedErrs: field.ErrorList{
+				field.Duplicate(
+					field.NewPath("spec").Child("containers").Index(0).Child("interfaces").Index(1).Child("name"), "http",
+				),
+				field.Duplicate(
+					field.NewPath("spec").Child("containers").Index(0).Child("interfaces").Index(1).Child("port"),
+					int32(8080),
+				),
+			},
+		},
 		{
 			name:       "name is required",
 			interfaces: []CapsuleInterface{{}},
@@ -213,6 +235,7 @@ func TestValidateInterfaces(t *testing.T) {
 			c := &Capsule{
 				Spec: CapsuleSpec{
 					Interfaces: test.interfaces,
+					Containers: test.containers,
 				},
 			}
 
@@ -263,6 +286,25 @@ func TestValidateEnv(t *testing.T) {
 					"env reference kind must be either ConfigMap or Secret"),
 			},
 		},
+		{
+			name: "external env references",
+			from: []EnvReference{
+				{Kind: "Secret", Name: "db", External: &ExternalSecretReference{Store: "vault", Path: "app/db"}},
+				{Kind: "ConfigMap", Name: "cfg", External: &ExternalSecretReference{
+					StoreKind: "Store",
+				}},
+			},
+			expectedErrs: field.ErrorList{
+				field.Invalid(path.Index(1).Child("kind"), "ConfigMap", "external env references must be of kind Secret"),
+				field.Required(path.Index(1).Child("external").Child("store"), ""),
+				field.NotSupported(
+					path.Index(1).Child("external").Child("storeKind"),
+					"Store",
+					[]string{"SecretStore", "ClusterSecretStore"},
+				),
+				field.Required(path.Index(1).Child("external").Child("path"), ""),
+			},
+		},
 	}
 
 	for i := range tests {
@@ -537,6 +579,53 @@ func Test_HorizontalScaleValidate(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "good, with schedule",
+			h: HorizontalScale{
+				Instances: Instances{Min: 2},
+				Schedules: []ScaleSchedule{{
+					Schedule: "0 8 * * 1-5",
+					Duration: "10h",
+					Timezone: "Europe/Copenhagen",
+					Min:      ptr.New(uint32(5)),
+				}},
+			},
+		},
+		{
+			name: "invalid schedule",
+			h: HorizontalScale{
+				Instances: Instances{Min: 2, Max: ptr.New(uint32(4))},
+				Schedules: []ScaleSchedule{{
+					Schedule: "every morning",
+					Duration: "-1h",
+					Timezone: "Mars/Olympus",
+					Min:      ptr.New(uint32(5)),
+				}},
+			},
+			expectedErrs: []*field.Error{
+				field.Invalid(
+					path.Child("schedules").Index(0).Child("schedule"),
+					"every morning",
+					"expected exactly 5 fields, found 2: [every morning]",
+				),
+				field.Invalid(path.Child("schedules").Index(0).Child("duration"), "-1h", "must be positive"),
+				field.Invalid(
+					path.Child("schedules").Index(0).Child("timezone"),
+					"Mars/Olympus",
+					"unknown time zone Mars/Olympus",
+				),
+				field.Invalid(
+					path.Child("schedules").Index(0),
+					ScaleSchedule{
+						Schedule: "every morning",
+						Duration: "-1h",
+						Timezone: "Mars/Olympus",
+						Min:      ptr.New(uint32(5)),
+					},
+					"max cannot be smaller than min during the window",
+				),
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -632,6 +721,40 @@ func Test_validateCronJobs(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "webhook without secret",
+			job: CronJob{
+				Name:     "job",
+				Schedule: "* * * * *",
+				Command:  &JobCommand{Command: "report"},
+				Webhook:  &JobWebhook{},
+			},
+			err: field.ErrorList{
+				field.Required(field.NewPath("spec").Child("cronJobs").Index(0).Child("webhook").Child("secretName"), ""),
+			},
+		},
+		{
+			name: "webhook of url job overriding env",
+			job: CronJob{
+				Name:     "job",
+				Schedule: "* * * * *",
+				URL: &URL{
+					Port: 1234,
+					Path: "/some/path",
+				},
+				Webhook: &JobWebhook{
+					SecretName:       "job-webhook",
+					AllowedOverrides: &JobAllowedOverrides{Env: []string{"LEVEL"}},
+				},
+			},
+			err: field.ErrorList{
+				field.Invalid(
+					field.NewPath("spec").Child("cronJobs").Index(0).Child("webhook").Child("allowedOverrides"),
+					&JobAllowedOverrides{Env: []string{"LEVEL"}},
+					"a job calling a URL can only override query parameters",
+				),
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -649,3 +772,985 @@ func Test_validateCronJobs(t *testing.T) {
 		})
 	}
 }
+
+func Test_validateSidecars(t *testing.T) {
+	tests := []struct {
+		name     string
+		sidecars []Sidecar
+		err      field.ErrorList
+	}{
+		{
+			name: "good sidecar",
+			sidecars: []Sidecar{{
+				Name:  "proxy",
+				Image: "envoy",
+				Env: SidecarEnv{
+					Raw:  map[string]string{"LOG_LEVEL": "debug"},
+					From: []EnvReference{{Kind: "ConfigMap", Name: "proxy-config"}},
+				},
+				Liveness: &SidecarProbe{Port: 9901, Path: "/ready"},
+			}},
+		},
+		{
+			name:     "missing name and image",
+			sidecars: []Sidecar{{}},
+			err: field.ErrorList{
+				field.Required(field.NewPath("spec").Child("sidecars").Index(0).Child("name"), ""),
+				field.Required(field.NewPath("spec").Child("sidecars").Index(0).Child("image"), ""),
+			},
+		},
+		{
+			name: "duplicate name",
+			sidecars: []Sidecar{
+				{Name: "proxy", Image: "envoy"},
+				{Name: "proxy", Image: "envoy"},
+			},
+			err: field.ErrorList{
+				field.Duplicate(field.NewPath("spec").Child("sidecars").Index(1).Child("name"), "proxy"),
+			},
+		},
+		{
+			name: "same name as capsule",
+			sidecars: []Sidecar{
+				{Name: "somename", Image: "envoy"},
+			},
+			err: field.ErrorList{
+				field.Invalid(
+					field.NewPath("spec").Child("sidecars").Index(0).Child("name"),
+					"somename",
+					"name cannot be the same as the capsule",
+				),
+			},
+		},
+		{
+			name: "bad env reference",
+			sidecars: []Sidecar{{
+				Name:  "proxy",
+				Image: "envoy",
+				Env: SidecarEnv{
+					From: []EnvReference{{Kind: "Deployment", Name: "proxy-config"}},
+				},
+			}},
+			err: field.ErrorList{
+				field.Invalid(
+					field.NewPath("spec").Child("sidecars").Index(0).Child("env").Child("from").Index(0).Child("kind"),
+					EnvReference{Kind: "Deployment", Name: "proxy-config"},
+					"env reference kind must be either ConfigMap or Secret",
+				),
+			},
+		},
+		{
+			name: "bad probe",
+			sidecars: []Sidecar{{
+				Name:      "proxy",
+				Image:     "envoy",
+				Readiness: &SidecarProbe{TCP: true},
+			}},
+			err: field.ErrorList{
+				field.Invalid(
+					field.NewPath("spec").Child("sidecars").Index(0).Child("readiness").Child("port"),
+					int32(0),
+					"port must be 1 <= port <= 65535",
+				),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capsule{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "somename",
+				},
+				Spec: CapsuleSpec{
+					Sidecars: tt.sidecars,
+				},
+			}
+			err := c.validateSidecars()
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func Test_validateContainers(t *testing.T) {
+	cPath := field.NewPath("spec").Child("containers")
+	tests := []struct {
+		name       string
+		containers []Container
+		err        field.ErrorList
+	}{
+		{
+			name: "good container",
+			containers: []Container{{
+				Name:  "worker",
+				Image: "worker:1.0",
+				Env: SidecarEnv{
+					Raw:  map[string]string{"QUEUE": "jobs"},
+					From: []EnvReference{{Kind: "Secret", Name: "worker"}},
+				},
+				Files: []File{{
+					Path: "/etc/worker/config.yaml",
+					Ref:  &FileContentReference{Kind: "ConfigMap", Name: "worker", Key: "config.yaml"},
+				}},
+			}},
+		},
+		{
+			name:       "missing name and image",
+			containers: []Container{{}},
+			err: field.ErrorList{
+				field.Required(cPath.Index(0).Child("name"), ""),
+				field.Required(cPath.Index(0).Child("image"), ""),
+			},
+		},
+		{
+			name: "same name as sidecar, capsule or other container",
+			containers: []Container{
+				{Name: "proxy", Image: "worker"},
+				{Name: "somename", Image: "worker"},
+				{Name: "somename", Image: "worker"},
+			},
+			err: field.ErrorList{
+				field.Duplicate(cPath.Index(0).Child("name"), "proxy"),
+				field.Invalid(cPath.Index(1).Child("name"), "somename", "name cannot be the same as the capsule"),
+				field.Duplicate(cPath.Index(2).Child("name"), "somename"),
+				field.Invalid(cPath.Index(2).Child("name"), "somename", "name cannot be the same as the capsule"),
+			},
+		},
+		{
+			name: "bad env and files",
+			containers: []Container{{
+				Name:  "worker",
+				Image: "worker",
+				Env: SidecarEnv{
+					Raw: map[string]string{"1QUEUE": "jobs"},
+				},
+				Files: []File{{Path: "/etc/worker/config.yaml"}},
+			}},
+			err: field.ErrorList{
+				field.Invalid(cPath.Index(0).Child("env").Child("raw").Key("1QUEUE"), "1QUEUE",
+					strings.Join(validation.IsEnvVarName("1QUEUE"), "; ")),
+				field.Required(cPath.Index(0).Child("files").Index(0).Child("ref"), "file reference is required"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capsule{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "somename",
+				},
+				Spec: CapsuleSpec{
+					Sidecars:   []Sidecar{{Name: "proxy", Image: "envoy"}},
+					Containers: tt.containers,
+				},
+			}
+			err := c.validateContainers()
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func Test_validateStorage(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []File
+		volumes []PersistentVolume
+		err     field.ErrorList
+	}{
+		{
+			name: "good volume",
+			volumes: []PersistentVolume{{
+				Name: "data",
+				Path: "/var/lib/data",
+				Size: resource.MustParse("10Gi"),
+			}},
+		},
+		{
+			name:    "missing fields",
+			volumes: []PersistentVolume{{}},
+			err: field.ErrorList{
+				field.Required(field.NewPath("spec").Child("storage").Child("volumes").Index(0).Child("name"), ""),
+				field.Required(field.NewPath("spec").Child("storage").Child("volumes").Index(0).Child("path"), ""),
+				field.Invalid(
+					field.NewPath("spec").Child("storage").Child("volumes").Index(0).Child("size"),
+					"0",
+					"size must be positive",
+				),
+			},
+		},
+		{
+			name: "duplicate name and path",
+			volumes: []PersistentVolume{
+				{Name: "data", Path: "/data", Size: resource.MustParse("1Gi")},
+				{Name: "data", Path: "/data", Size: resource.MustParse("1Gi")},
+			},
+			err: field.ErrorList{
+				field.Duplicate(field.NewPath("spec").Child("storage").Child("volumes").Index(1).Child("name"), "data"),
+				field.Duplicate(field.NewPath("spec").Child("storage").Child("volumes").Index(1).Child("path"), "/data"),
+			},
+		},
+		{
+			name: "path used by file",
+			files: []File{{
+				Path: "/etc/config.yaml",
+				Ref:  &FileContentReference{Kind: "ConfigMap", Name: "config", Key: "config.yaml"},
+			}},
+			volumes: []PersistentVolume{
+				{Name: "data", Path: "/etc/config.yaml", Size: resource.MustParse("1Gi")},
+			},
+			err: field.ErrorList{
+				field.Invalid(
+					field.NewPath("spec").Child("storage").Child("volumes").Index(0).Child("path"),
+					"/etc/config.yaml",
+					"path is already used by a file",
+				),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capsule{
+				Spec: CapsuleSpec{
+					Files:   tt.files,
+					Storage: &Storage{Volumes: tt.volumes},
+				},
+			}
+			err := c.validateStorage()
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func Test_validateStorageUpdate(t *testing.T) {
+	data := PersistentVolume{Name: "data", Path: "/data", Size: resource.MustParse("1Gi")}
+	forbidden := field.ErrorList{field.Forbidden(
+		field.NewPath("spec").Child("storage"),
+		"storage cannot be changed, delete and recreate the capsule to change its storage",
+	)}
+
+	tests := []struct {
+		name    string
+		old     *Storage
+		storage *Storage
+		err     field.ErrorList
+	}{
+		{
+			name: "no storage",
+		},
+		{
+			name:    "unchanged volumes",
+			old:     &Storage{Volumes: []PersistentVolume{data}},
+			storage: &Storage{Volumes: []PersistentVolume{{Name: "data", Path: "/data", Size: resource.MustParse("1024Mi")}}},
+		},
+		{
+			name:    "empty storage",
+			storage: &Storage{},
+		},
+		{
+			name:    "switch to StatefulSet",
+			storage: &Storage{Volumes: []PersistentVolume{data}},
+			err:     forbidden,
+		},
+		{
+			name: "switch to Deployment",
+			old:  &Storage{Volumes: []PersistentVolume{data}},
+			err:  forbidden,
+		},
+		{
+			name:    "resized volume",
+			old:     &Storage{Volumes: []PersistentVolume{data}},
+			storage: &Storage{Volumes: []PersistentVolume{{Name: "data", Path: "/data", Size: resource.MustParse("2Gi")}}},
+			err:     forbidden,
+		},
+		{
+			name: "added volume",
+			old:  &Storage{Volumes: []PersistentVolume{data}},
+			storage: &Storage{Volumes: []PersistentVolume{
+				data,
+				{Name: "logs", Path: "/logs", Size: resource.MustParse("1Gi")},
+			}},
+			err: forbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := &Capsule{Spec: CapsuleSpec{Storage: tt.old}}
+			c := &Capsule{Spec: CapsuleSpec{Storage: tt.storage}}
+			assert.Equal(t, tt.err, c.validateStorageUpdate(old))
+		})
+	}
+}
+
+func Test_validateRollout(t *testing.T) {
+	rPath := field.NewPath("spec").Child("rollout")
+	tests := []struct {
+		name    string
+		rollout *RolloutStrategy
+		storage *Storage
+		err     field.ErrorList
+	}{
+		{
+			name: "no rollout",
+		},
+		{
+			name: "valid canary",
+			rollout: &RolloutStrategy{Canary: &CanaryStrategy{Steps: []CanaryStep{
+				{Weight: 10, PauseSeconds: 60},
+				{Weight: 50},
+			}}},
+		},
+		{
+			name:    "valid blue/green",
+			rollout: &RolloutStrategy{BlueGreen: &BlueGreenStrategy{PromotionDelaySeconds: 30}},
+		},
+		{
+			name:    "no strategy",
+			rollout: &RolloutStrategy{},
+			err: field.ErrorList{
+				field.Required(rPath, "one of canary or blueGreen must be set"),
+			},
+		},
+		{
+			name: "both strategies",
+			rollout: &RolloutStrategy{
+				Canary:    &CanaryStrategy{Steps: []CanaryStep{{Weight: 10}}},
+				BlueGreen: &BlueGreenStrategy{},
+			},
+			err: field.ErrorList{
+				field.Invalid(rPath, "", "only one of canary or blueGreen can be set"),
+			},
+		},
+		{
+			name:    "canary without steps",
+			rollout: &RolloutStrategy{Canary: &CanaryStrategy{}},
+			err: field.ErrorList{
+				field.Required(rPath.Child("canary").Child("steps"), ""),
+			},
+		},
+		{
+			name:    "invalid weight",
+			rollout: &RolloutStrategy{Canary: &CanaryStrategy{Steps: []CanaryStep{{Weight: 0}, {Weight: 101}}}},
+			err: field.ErrorList{
+				field.Invalid(
+					rPath.Child("canary").Child("steps").Index(0).Child("weight"),
+					uint32(0), "weight must be 1 <= weight <= 100",
+				),
+				field.Invalid(
+					rPath.Child("canary").Child("steps").Index(1).Child("weight"),
+					uint32(101), "weight must be 1 <= weight <= 100",
+				),
+			},
+		},
+		{
+			name:    "with storage",
+			rollout: &RolloutStrategy{BlueGreen: &BlueGreenStrategy{}},
+			storage: &Storage{Volumes: []PersistentVolume{
+				{Name: "data", Path: "/data", Size: resource.MustParse("1Gi")},
+			}},
+			err: field.ErrorList{
+				field.Forbidden(rPath, "rollout strategies are not supported for capsules with storage"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capsule{
+				Spec: CapsuleSpec{
+					Rollout: tt.rollout,
+					Storage: tt.storage,
+				},
+			}
+			err := c.validateRollout()
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func Test_HostRouteValidate(t *testing.T) {
+	rPath := field.NewPath("route")
+	tests := []struct {
+		name  string
+		route HostRoute
+		err   field.ErrorList
+	}{
+		{
+			name: "valid route",
+			route: HostRoute{
+				Paths:           []HTTPPathRoute{{Path: "/api", Match: PathPrefix}},
+				Headers:         []HeaderMatch{{Name: "X-Version", Value: "v[0-9]+", Match: ValueRegularExpression}},
+				Methods:         []string{"GET", "POST"},
+				QueryParameters: []QueryParameterMatch{{Name: "debug", Value: "true"}},
+				RouteOptions: RouteOptions{
+					RequestHeaders:    &HeaderModifier{Set: map[string]string{"X-Env": "prod"}},
+					ResponseHeaders:   &HeaderModifier{Remove: []string{"Server"}},
+					RewritePathPrefix: "/",
+				},
+			},
+		},
+		{
+			name: "invalid matches",
+			route: HostRoute{
+				Headers:         []HeaderMatch{{Value: "v1"}, {Name: "X-Version", Value: "(", Match: ValueRegularExpression}},
+				Methods:         []string{"GET", "FETCH", "GET"},
+				QueryParameters: []QueryParameterMatch{{Name: "q", Match: "Prefix"}},
+			},
+			err: field.ErrorList{
+				field.Required(rPath.Child("headers").Index(0).Child("name"), ""),
+				field.Invalid(
+					rPath.Child("headers").Index(1).Child("value"), "(",
+					"error parsing regexp: missing closing ): `(`",
+				),
+				field.NotSupported(
+					rPath.Child("queryParameters").Index(0).Child("match"), ValueMatchType("Prefix"),
+					[]string{"Exact", "RegularExpression"},
+				),
+				field.NotSupported(rPath.Child("methods").Index(1), "FETCH", httpMethods),
+				field.Duplicate(rPath.Child("methods").Index(2), "GET"),
+			},
+		},
+		{
+			name: "rewrite requires prefix paths",
+			route: HostRoute{
+				Paths: []HTTPPathRoute{{Path: "/api"}, {Path: "/exact", Match: Exact}},
+				RouteOptions: RouteOptions{
+					RewritePathPrefix: "v1",
+				},
+			},
+			err: field.ErrorList{
+				field.Invalid(rPath.Child("rewritePathPrefix"), "v1", "must start with '/'"),
+				field.Invalid(
+					rPath.Child("paths").Index(1).Child("match"), Exact,
+					"only PathPrefix paths can be used with rewritePathPrefix",
+				),
+			},
+		},
+		{
+			name: "header both set and removed",
+			route: HostRoute{
+				RouteOptions: RouteOptions{
+					RequestHeaders: &HeaderModifier{
+						Set:    map[string]string{"X-Env": "prod"},
+						Remove: []string{"X-Env"},
+					},
+				},
+			},
+			err: field.ErrorList{
+				field.Invalid(
+					rPath.Child("requestHeaders").Child("remove").Index(0), "X-Env",
+					"header cannot be both set and removed",
+				),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.route.validate(rPath)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func Test_validateAvailability(t *testing.T) {
+	aPath := field.NewPath("spec").Child("availability")
+	tests := []struct {
+		name         string
+		availability *Availability
+		err          field.ErrorList
+	}{
+		{
+			name: "no availability",
+		},
+		{
+			name:         "valid min available",
+			availability: &Availability{MinAvailable: "2", ZoneSpread: SpreadRequired},
+		},
+		{
+			name:         "valid max unavailable",
+			availability: &Availability{MaxUnavailable: "25%"},
+		},
+		{
+			name:         "both set",
+			availability: &Availability{MinAvailable: "1", MaxUnavailable: "1"},
+			err: field.ErrorList{
+				field.Invalid(aPath, "", "only one of minAvailable or maxUnavailable can be set"),
+			},
+		},
+		{
+			name:         "invalid values",
+			availability: &Availability{MinAvailable: "half", MaxUnavailable: "150%"},
+			err: field.ErrorList{
+				field.Invalid(aPath, "", "only one of minAvailable or maxUnavailable can be set"),
+				field.Invalid(aPath.Child("minAvailable"), "half", "must be an integer or a percentage"),
+				field.Invalid(aPath.Child("maxUnavailable"), "150%", "must not be more than 100%"),
+			},
+		},
+		{
+			name:         "negative value",
+			availability: &Availability{MaxUnavailable: "-1"},
+			err: field.ErrorList{
+				field.Invalid(aPath.Child("maxUnavailable"), "-1", "must not be negative"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capsule{
+				Spec: CapsuleSpec{
+					Availability: tt.availability,
+				},
+			}
+			err := c.validateAvailability()
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func Test_validateNetworkPolicy(t *testing.T) {
+	npPath := field.NewPath("spec").Child("networkPolicy")
+	tests := []struct {
+		name          string
+		networkPolicy *NetworkPolicy
+		err           field.ErrorList
+	}{
+		{
+			name: "no network policy",
+		},
+		{
+			name: "valid peers",
+			networkPolicy: &NetworkPolicy{
+				AllowFrom: []NetworkPeer{{Capsule: "frontend"}, {Namespace: "monitoring"}},
+				AllowTo:   []NetworkPeer{{Capsule: "db", Namespace: "data"}},
+			},
+		},
+		{
+			name: "empty peer",
+			networkPolicy: &NetworkPolicy{
+				AllowFrom: []NetworkPeer{{}},
+			},
+			err: field.ErrorList{
+				field.Invalid(npPath.Child("allowFrom").Index(0), "", "at least one of capsule or namespace must be set"),
+			},
+		},
+		{
+			name: "invalid names",
+			networkPolicy: &NetworkPolicy{
+				AllowTo: []NetworkPeer{{Capsule: "Db", Namespace: "data_ns"}},
+			},
+			err: field.ErrorList{
+				field.Invalid(npPath.Child("allowTo").Index(0).Child("capsule"), "Db",
+					strings.Join(validation.IsDNS1123Label("Db"), "; ")),
+				field.Invalid(npPath.Child("allowTo").Index(0).Child("namespace"), "data_ns",
+					strings.Join(validation.IsDNS1123Label("data_ns"), "; ")),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capsule{
+				Spec: CapsuleSpec{
+					NetworkPolicy: tt.networkPolicy,
+				},
+			}
+			err := c.validateNetworkPolicy()
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func Test_validateDependencies(t *testing.T) {
+	dPath := field.NewPath("spec").Child("dependencies")
+	tests := []struct {
+		name         string
+		dependencies []CapsuleDependency
+		err          field.ErrorList
+	}{
+		{
+			name: "no dependencies",
+		},
+		{
+			name: "valid dependencies",
+			dependencies: []CapsuleDependency{
+				{Capsule: "db", Interface: "postgres", WaitForReady: true},
+				{Capsule: "api", Interface: "http", Namespace: "other"},
+				{Capsule: "users", Interface: "grpc"},
+			},
+		},
+		{
+			name:         "missing fields",
+			dependencies: []CapsuleDependency{{}},
+			err: field.ErrorList{
+				field.Required(dPath.Index(0).Child("capsule"), ""),
+				field.Required(dPath.Index(0).Child("interface"), ""),
+			},
+		},
+		{
+			name:         "invalid names",
+			dependencies: []CapsuleDependency{{Capsule: "Db", Interface: "postgres", Namespace: "data_ns"}},
+			err: field.ErrorList{
+				field.Invalid(dPath.Index(0).Child("capsule"), "Db",
+					strings.Join(validation.IsDNS1123Label("Db"), "; ")),
+				field.Invalid(dPath.Index(0).Child("namespace"), "data_ns",
+					strings.Join(validation.IsDNS1123Label("data_ns"), "; ")),
+			},
+		},
+		{
+			name: "self dependency",
+			dependencies: []CapsuleDependency{
+				{Capsule: "api", Interface: "http", Namespace: "prod"},
+			},
+			err: field.ErrorList{
+				field.Invalid(dPath.Index(0).Child("capsule"), "api", "a capsule cannot depend on itself"),
+			},
+		},
+		{
+			name: "duplicate dependency",
+			dependencies: []CapsuleDependency{
+				{Capsule: "db", Interface: "postgres"},
+				{Capsule: "db", Interface: "postgres", Namespace: "prod", WaitForReady: true},
+			},
+			err: field.ErrorList{
+				field.Duplicate(dPath.Index(1), "db/postgres"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capsule{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"},
+				Spec: CapsuleSpec{
+					Dependencies: tt.dependencies,
+				},
+			}
+			err := c.validateDependencies()
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func Test_validateDependencyCycles(t *testing.T) {
+	capsule := func(namespace, name string, deps ...CapsuleDependency) *Capsule {
+		return &Capsule{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       CapsuleSpec{Dependencies: deps},
+		}
+	}
+	existing := map[types.NamespacedName]*Capsule{}
+	for _, c := range []*Capsule{
+		capsule("prod", "b", CapsuleDependency{Capsule: "a", Interface: "http", WaitForReady: true}),
+		capsule("prod", "c", CapsuleDependency{Capsule: "d", Interface: "http", WaitForReady: true}),
+		capsule("prod", "d", CapsuleDependency{Capsule: "a", Interface: "http", Namespace: "prod", WaitForReady: true}),
+		capsule("prod", "e", CapsuleDependency{Capsule: "a", Interface: "http"}),
+		capsule("other", "f", CapsuleDependency{Capsule: "a", Interface: "http", WaitForReady: true}),
+	} {
+		existing[types.NamespacedName{Namespace: c.Namespace, Name: c.Name}] = c
+	}
+	get := func(key types.NamespacedName) (*Capsule, error) {
+		return existing[key], nil
+	}
+
+	dPath := field.NewPath("spec").Child("dependencies")
+	tests := []struct {
+		name         string
+		dependencies []CapsuleDependency
+		err          field.ErrorList
+	}{
+		{
+			name: "no dependencies",
+		},
+		{
+			name: "direct cycle",
+			dependencies: []CapsuleDependency{
+				{Capsule: "b", Interface: "http", WaitForReady: true},
+			},
+			err: field.ErrorList{
+				field.Invalid(dPath.Index(0).Child("waitForReady"), true,
+					"capsules cannot wait for each other, prod/a -> prod/b -> prod/a"),
+			},
+		},
+		{
+			name: "indirect cycle",
+			dependencies: []CapsuleDependency{
+				{Capsule: "e", Interface: "http", WaitForReady: true},
+				{Capsule: "c", Interface: "http", WaitForReady: true},
+			},
+			err: field.ErrorList{
+				field.Invalid(dPath.Index(1).Child("waitForReady"), true,
+					"capsules cannot wait for each other, prod/a -> prod/c -> prod/d -> prod/a"),
+			},
+		},
+		{
+			name: "cycle without waiting for readiness",
+			dependencies: []CapsuleDependency{
+				{Capsule: "b", Interface: "http"},
+				{Capsule: "e", Interface: "http", WaitForReady: true},
+			},
+		},
+		{
+			name: "dependency in other namespace",
+			dependencies: []CapsuleDependency{
+				{Capsule: "f", Interface: "http", WaitForReady: true},
+				{Capsule: "missing", Interface: "http", WaitForReady: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := capsule("prod", "a", tt.dependencies...)
+			assert.Equal(t, tt.err, c.validateDependencyCycles(get))
+		})
+	}
+}
+
+func Test_validateScaleToZero(t *testing.T) {
+	sPath := field.NewPath("spec").Child("scale").Child("horizontal").Child("scaleToZero")
+	routes := []CapsuleInterface{{
+		Name:   "http",
+		Port:   8080,
+		Routes: []HostRoute{{Host: "example.com"}},
+	}}
+	tests := []struct {
+		name string
+		spec CapsuleSpec
+		err  field.ErrorList
+	}{
+		{
+			name: "no scale to zero",
+		},
+		{
+			name: "valid",
+			spec: CapsuleSpec{
+				Interfaces: routes,
+				Scale:      CapsuleScale{Horizontal: HorizontalScale{ScaleToZero: &ScaleToZero{IdleSeconds: 60}}},
+			},
+		},
+		{
+			name: "no routes",
+			spec: CapsuleSpec{
+				Interfaces: []CapsuleInterface{{Name: "http", Port: 8080}},
+				Scale:      CapsuleScale{Horizontal: HorizontalScale{ScaleToZero: &ScaleToZero{}}},
+			},
+			err: field.ErrorList{
+				field.Invalid(sPath, "", "scale to zero requires at least one route to wake up the capsule"),
+			},
+		},
+		{
+			name: "with rollout",
+			spec: CapsuleSpec{
+				Interfaces: routes,
+				Scale:      CapsuleScale{Horizontal: HorizontalScale{ScaleToZero: &ScaleToZero{}}},
+				Rollout:    &RolloutStrategy{BlueGreen: &BlueGreenStrategy{}},
+			},
+			err: field.ErrorList{
+				field.Forbidden(sPath, "scale to zero is not supported together with rollout strategies"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capsule{Spec: tt.spec}
+			err := c.validateScaleToZero()
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func Test_validateLifecycle(t *testing.T) {
+	lPath := field.NewPath("spec").Child("lifecycle")
+	tests := []struct {
+		name       string
+		lifecycle  *Lifecycle
+		interfaces []CapsuleInterface
+		err        field.ErrorList
+	}{
+		{
+			name: "no lifecycle",
+		},
+		{
+			name: "valid",
+			lifecycle: &Lifecycle{
+				PreStop:                       &LifecycleHook{Command: []string{"sleep", "30"}},
+				TerminationGracePeriodSeconds: ptr.New(uint32(60)),
+				StartupProbe:                  &StartupProbe{Port: 8080, Path: "/health", MaxStartupSeconds: 600},
+			},
+		},
+		{
+			name:      "empty pre stop",
+			lifecycle: &Lifecycle{PreStop: &LifecycleHook{}},
+			err: field.ErrorList{
+				field.Required(lPath.Child("preStop"), "one of command or http must be set"),
+			},
+		},
+		{
+			name: "invalid http pre stop",
+			lifecycle: &Lifecycle{PreStop: &LifecycleHook{
+				Command: []string{"drain"},
+				HTTP:    &HTTPHook{Port: 0, Path: "drain"},
+			}},
+			err: field.ErrorList{
+				field.Invalid(lPath.Child("preStop"), "", "only one of command or http can be set"),
+				field.Invalid(lPath.Child("preStop").Child("http").Child("port"), int32(0), "port must be 1 <= port <= 65535"),
+				field.Invalid(lPath.Child("preStop").Child("http").Child("path"), "drain", "path must be an absolute path"),
+			},
+		},
+		{
+			name: "startup probe with startup delay",
+			lifecycle: &Lifecycle{
+				StartupProbe: &StartupProbe{Port: 8080, TCP: true, PeriodSeconds: 20, MaxStartupSeconds: 10},
+			},
+			interfaces: []CapsuleInterface{{
+				Name:     "http",
+				Port:     8080,
+				Liveness: &InterfaceLivenessProbe{Path: "/health", StartupDelay: 30},
+			}},
+			err: field.ErrorList{
+				field.Invalid(
+					lPath.Child("startupProbe").Child("maxStartupSeconds"), uint32(10), "cannot be smaller than periodSeconds",
+				),
+				field.Forbidden(
+					field.NewPath("spec").Child("interfaces").Index(0).Child("liveness").Child("startupDelay"),
+					"startupDelay cannot be used together with a startup probe",
+				),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capsule{
+				Spec: CapsuleSpec{
+					Lifecycle:  tt.lifecycle,
+					Interfaces: tt.interfaces,
+				},
+			}
+			err := c.validateLifecycle()
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func Test_validateExternalSecrets(t *testing.T) {
+	vault := &ExternalSecretReference{Store: "vault", Path: "app/db"}
+	tests := []struct {
+		name string
+		spec CapsuleSpec
+		err  field.ErrorList
+	}{
+		{
+			name: "no external secrets",
+			spec: CapsuleSpec{
+				Env: Env{From: []EnvReference{{Kind: "Secret", Name: "db"}}},
+			},
+		},
+		{
+			name: "same external secret in env and files",
+			spec: CapsuleSpec{
+				Env: Env{From: []EnvReference{{Kind: "Secret", Name: "db", External: vault}}},
+				Files: []File{{
+					Path: "/etc/db/password",
+					Ref:  &FileContentReference{Kind: "Secret", Name: "db", Key: "password", External: vault},
+				}},
+			},
+		},
+		{
+			name: "secret referenced both as external and existing",
+			spec: CapsuleSpec{
+				Env: Env{From: []EnvReference{{Kind: "Secret", Name: "db", External: vault}}},
+				Sidecars: []Sidecar{{
+					Env: SidecarEnv{From: []EnvReference{{Kind: "Secret", Name: "db"}}},
+				}},
+			},
+			err: field.ErrorList{
+				field.Invalid(
+					field.NewPath("spec").Child("sidecars").Index(0).Child("env").Child("from").Index(0).Child("external"),
+					(*ExternalSecretReference)(nil),
+					"secret 'db' is referenced differently by spec.env.from[0]",
+				),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capsule{Spec: tt.spec}
+			assert.Equal(t, tt.err, c.validateExternalSecrets())
+		})
+	}
+}
+
+func Test_validateRolloutHooks(t *testing.T) {
+	hPath := field.NewPath("spec").Child("rolloutHooks")
+	tests := []struct {
+		name    string
+		capsule string
+		spec    CapsuleSpec
+		err     field.ErrorList
+	}{
+		{
+			name:    "no hooks",
+			capsule: "api",
+		},
+		{
+			name:    "valid hooks",
+			capsule: "api",
+			spec: CapsuleSpec{RolloutHooks: &RolloutHooks{
+				PreRollout:  &RolloutHook{Command: JobCommand{Command: "migrate", Args: []string{"up"}}},
+				PostRollout: &RolloutHook{Command: JobCommand{Command: "warm-cache"}},
+			}},
+		},
+		{
+			name:    "missing command",
+			capsule: "api",
+			spec: CapsuleSpec{RolloutHooks: &RolloutHooks{
+				PreRollout:  &RolloutHook{},
+				PostRollout: &RolloutHook{Command: JobCommand{Args: []string{"up"}}},
+			}},
+			err: field.ErrorList{
+				field.Required(hPath.Child("preRollout").Child("command").Child("command"), ""),
+				field.Required(hPath.Child("postRollout").Child("command").Child("command"), ""),
+			},
+		},
+		{
+			name:    "storage",
+			capsule: "api",
+			spec: CapsuleSpec{
+				Storage: &Storage{Volumes: []PersistentVolume{{Name: "data", Path: "/data"}}},
+				RolloutHooks: &RolloutHooks{
+					PreRollout: &RolloutHook{Command: JobCommand{Command: "migrate"}},
+				},
+			},
+			err: field.ErrorList{
+				field.Forbidden(hPath, "rollout hooks are not supported for capsules with storage"),
+			},
+		},
+		{
+			name:    "name too long",
+			capsule: strings.Repeat("a", MaxAllowedRolloutHookCapsuleName+1),
+			spec: CapsuleSpec{RolloutHooks: &RolloutHooks{
+				PostRollout: &RolloutHook{Command: JobCommand{Command: "warm-cache"}},
+			}},
+			err: field.ErrorList{
+				field.Forbidden(hPath, "rollout hooks are not supported for capsules with names longer than 41"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capsule{
+				ObjectMeta: metav1.ObjectMeta{Name: tt.capsule, Namespace: "prod"},
+				Spec:       tt.spec,
+			}
+			assert.Equal(t, tt.err, c.validateRolloutHooks())
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package v1alpha2
+
+// AllInterfaces returns the interfaces of the main container of the Capsule,
+// followed by the interfaces of its additional containers. All interfaces are
+// exposed by the Service of the Capsule.
+func (c *Capsule) AllInterfaces() []CapsuleInterface {
+	if len(c.Spec.Containers) == 0 {
+		return c.Spec.Interfaces
+	}
+
+	res := append([]CapsuleInterface{}, c.Spec.Interfaces...)
+	for _, container := range c.Spec.Containers {
+		res = append(res, container.Interfaces...)
+	}
+	return res
+}
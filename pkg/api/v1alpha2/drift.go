@@ -0,0 +1,38 @@
+package v1alpha2
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// CapsuleConditionDrifted is true when resources owned by the Capsule have
+	// been changed outside of the operator.
+	CapsuleConditionDrifted = "Drifted"
+
+	ObjectDriftStateMissing    = "missing"
+	ObjectDriftStateChanged    = "changed"
+	ObjectDriftStateUnexpected = "unexpected"
+)
+
+// SetDrift records the drifted resources in the status and updates the
+// Drifted condition accordingly.
+func (s *CapsuleStatus) SetDrift(drift []ObjectDrift, generation int64) {
+	s.Drift = drift
+
+	cond := metav1.Condition{
+		Type:               CapsuleConditionDrifted,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "InSync",
+		Message:            "All owned resources match their desired state",
+	}
+	if len(drift) > 0 {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = "DriftDetected"
+		cond.Message = fmt.Sprintf("%d owned resources differ from their desired state", len(drift))
+	}
+	meta.SetStatusCondition(&s.Conditions, cond)
+}
@@ -0,0 +1,64 @@
+package v1alpha2
+
+import "time"
+
+const (
+	ExternalSecretStoreKindSecretStore        = "SecretStore"
+	ExternalSecretStoreKindClusterSecretStore = "ClusterSecretStore"
+
+	defaultExternalSecretRefreshIntervalSeconds = 3600
+)
+
+// GetStoreKind returns the kind of the store, defaulting to ClusterSecretStore.
+func (r *ExternalSecretReference) GetStoreKind() string {
+	if r.StoreKind == "" {
+		return ExternalSecretStoreKindClusterSecretStore
+	}
+	return r.StoreKind
+}
+
+// RefreshInterval returns how often the secret is read from the store.
+func (r *ExternalSecretReference) RefreshInterval() time.Duration {
+	if r.RefreshIntervalSeconds == 0 {
+		return defaultExternalSecretRefreshIntervalSeconds * time.Second
+	}
+	return time.Duration(r.RefreshIntervalSeconds) * time.Second
+}
+
+// ExternalSecrets returns the external secret references of the Capsule,
+// keyed by the name of the Secret they are synced into. This includes the
+// references of the environment, the files, the sidecars and the containers
+// of the Capsule.
+func (c *Capsule) ExternalSecrets() map[string]*ExternalSecretReference {
+	res := map[string]*ExternalSecretReference{}
+	for _, e := range c.Spec.Env.From {
+		if e.External != nil {
+			res[e.Name] = e.External
+		}
+	}
+	for _, f := range c.Spec.Files {
+		if f.Ref != nil && f.Ref.External != nil {
+			res[f.Ref.Name] = f.Ref.External
+		}
+	}
+	for _, sc := range c.Spec.Sidecars {
+		for _, e := range sc.Env.From {
+			if e.External != nil {
+				res[e.Name] = e.External
+			}
+		}
+	}
+	for _, container := range c.Spec.Containers {
+		for _, e := range container.Env.From {
+			if e.External != nil {
+				res[e.Name] = e.External
+			}
+		}
+		for _, f := range container.Files {
+			if f.Ref != nil && f.Ref.External != nil {
+				res[f.Ref.Name] = f.Ref.External
+			}
+		}
+	}
+	return res
+}
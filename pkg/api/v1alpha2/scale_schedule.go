@@ -0,0 +1,105 @@
+package v1alpha2
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func (s ScaleSchedule) parse() (cron.Schedule, time.Duration, *time.Location, error) {
+	sched, err := cron.ParseStandard(s.Schedule)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	d, err := time.ParseDuration(s.Duration)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("invalid duration: %w", err)
+	}
+	if d <= 0 {
+		return nil, 0, nil, fmt.Errorf("duration must be positive")
+	}
+
+	loc := time.UTC
+	if s.Timezone != "" {
+		if loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return nil, 0, nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+	}
+
+	return sched, d, loc, nil
+}
+
+// ActiveWindow returns the start and end of the earliest window of the schedule
+// which is active at the given time. Only windows starting at most one duration
+// before the given time can be active. If windows overlap, later windows keep
+// the schedule active after the returned end. False is returned if no window is
+// active.
+func (s ScaleSchedule) ActiveWindow(now time.Time) (time.Time, time.Time, bool, error) {
+	sched, d, loc, err := s.parse()
+	if err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+
+	now = now.In(loc)
+	start := sched.Next(now.Add(-d))
+	if start.IsZero() || start.After(now) {
+		return time.Time{}, time.Time{}, false, nil
+	}
+
+	return start, start.Add(d), true, nil
+}
+
+// NextWindow returns the start and end of the first window of the schedule
+// starting after the given time.
+func (s ScaleSchedule) NextWindow(now time.Time) (time.Time, time.Time, error) {
+	sched, d, loc, err := s.parse()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	start := sched.Next(now.In(loc))
+	return start, start.Add(d), nil
+}
+
+// InstancesAt returns the instances at the given time, where the overrides of
+// the first active schedule are applied. The time at which the instances
+// can next change is returned as well, which is zero if there are no
+// schedules.
+func (h HorizontalScale) InstancesAt(now time.Time) (Instances, time.Time, error) {
+	instances := h.Instances
+	applied := false
+	var next time.Time
+	for _, s := range h.Schedules {
+		_, end, active, err := s.ActiveWindow(now)
+		if err != nil {
+			return Instances{}, time.Time{}, err
+		}
+		nextStart, _, err := s.NextWindow(now)
+		if err != nil {
+			return Instances{}, time.Time{}, err
+		}
+
+		change := nextStart
+		if active {
+			change = end
+		}
+		if next.IsZero() || (!change.IsZero() && change.Before(next)) {
+			next = change
+		}
+
+		if !active || applied {
+			continue
+		}
+		applied = true
+		if s.Min != nil {
+			instances.Min = *s.Min
+		}
+		if s.Max != nil {
+			instances.Max = s.Max
+		}
+	}
+
+	return instances, next, nil
+}
@@ -0,0 +1,108 @@
+package v1alpha2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_HorizontalScaleInstancesAt(t *testing.T) {
+	cph, err := time.LoadLocation("Europe/Copenhagen")
+	require.NoError(t, err)
+
+	h := HorizontalScale{
+		Instances: Instances{Min: 1, Max: ptr.New(uint32(3))},
+		Schedules: []ScaleSchedule{
+			{
+				Name:     "business-hours",
+				Schedule: "0 8 * * 1-5",
+				Duration: "10h",
+				Timezone: "Europe/Copenhagen",
+				Min:      ptr.New(uint32(4)),
+				Max:      ptr.New(uint32(10)),
+			},
+			{
+				Name:     "monday-morning",
+				Schedule: "0 7 * * 1",
+				Duration: "4h",
+				Timezone: "Europe/Copenhagen",
+				Min:      ptr.New(uint32(8)),
+				Max:      ptr.New(uint32(10)),
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		instances Instances
+		next      time.Time
+	}{
+		{
+			name:      "before all windows",
+			now:       time.Date(2024, 1, 1, 6, 0, 0, 0, cph), // Monday
+			instances: Instances{Min: 1, Max: ptr.New(uint32(3))},
+			next:      time.Date(2024, 1, 1, 7, 0, 0, 0, cph),
+		},
+		{
+			name:      "first active window wins",
+			now:       time.Date(2024, 1, 1, 9, 0, 0, 0, cph),
+			instances: Instances{Min: 4, Max: ptr.New(uint32(10))},
+			next:      time.Date(2024, 1, 1, 11, 0, 0, 0, cph),
+		},
+		{
+			name:      "only second window active",
+			now:       time.Date(2024, 1, 1, 7, 30, 0, 0, cph),
+			instances: Instances{Min: 8, Max: ptr.New(uint32(10))},
+			next:      time.Date(2024, 1, 1, 8, 0, 0, 0, cph),
+		},
+		{
+			name:      "weekend",
+			now:       time.Date(2024, 1, 6, 12, 0, 0, 0, cph),
+			instances: Instances{Min: 1, Max: ptr.New(uint32(3))},
+			next:      time.Date(2024, 1, 8, 7, 0, 0, 0, cph),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instances, next, err := h.InstancesAt(tt.now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.instances, instances)
+			assert.True(t, tt.next.Equal(next), "expected %s, got %s", tt.next, next)
+		})
+	}
+}
+
+func Test_ScaleScheduleActiveWindow(t *testing.T) {
+	s := ScaleSchedule{Schedule: "0 22 * * *", Duration: "8h"}
+
+	start, end, active, err := s.ActiveWindow(time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.True(t, active)
+	assert.True(t, start.Equal(time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)))
+	assert.True(t, end.Equal(time.Date(2024, 1, 2, 6, 0, 0, 0, time.UTC)))
+
+	_, _, active, err = s.ActiveWindow(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	s = ScaleSchedule{Schedule: "0 * * * *", Duration: "90m"}
+	start, end, active, err = s.ActiveWindow(time.Date(2024, 1, 2, 10, 45, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.True(t, active)
+	assert.True(t, start.Equal(time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)))
+	assert.True(t, end.Equal(time.Date(2024, 1, 2, 11, 30, 0, 0, time.UTC)))
+
+	// Windows starting every minute and lasting a week only look back a week.
+	s = ScaleSchedule{Schedule: "* * * * *", Duration: "168h"}
+	now := time.Date(2024, 1, 8, 12, 0, 30, 0, time.UTC)
+	start, end, active, err = s.ActiveWindow(now)
+	require.NoError(t, err)
+	assert.True(t, active)
+	assert.True(t, start.Equal(time.Date(2024, 1, 1, 12, 1, 0, 0, time.UTC)))
+	assert.True(t, end.Equal(time.Date(2024, 1, 8, 12, 1, 0, 0, time.UTC)))
+}
@@ -0,0 +1,25 @@
+package v1alpha2
+
+import "time"
+
+const (
+	defaultIdleSeconds          = 900
+	defaultWakeupTimeoutSeconds = 60
+)
+
+// IdleTimeout returns how long the Capsule must go without requests before it
+// is scaled to zero.
+func (s *ScaleToZero) IdleTimeout() time.Duration {
+	if s.IdleSeconds == 0 {
+		return defaultIdleSeconds * time.Second
+	}
+	return time.Duration(s.IdleSeconds) * time.Second
+}
+
+// WakeupTimeout returns how long a request waits for the Capsule to wake up.
+func (s *ScaleToZero) WakeupTimeout() time.Duration {
+	if s.WakeupTimeoutSeconds == 0 {
+		return defaultWakeupTimeoutSeconds * time.Second
+	}
+	return time.Duration(s.WakeupTimeoutSeconds) * time.Second
+}
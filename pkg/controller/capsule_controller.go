@@ -19,6 +19,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
@@ -36,6 +37,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -47,6 +49,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 // CapsuleReconciler reconciles a Capsule object
@@ -58,6 +62,9 @@ type CapsuleReconciler struct {
 	PipelineService     svc_pipeline.Service
 	ObjectStatusService objectstatus.Service
 	Lifecycle           fx.Lifecycle
+	DriftScanner        *DriftScanner
+	ConfigReloader      *ConfigReloader
+	ExecutionHistory    *ExecutionHistory
 	initialize          sync.WaitGroup
 	mgr                 ctrl.Manager
 }
@@ -69,6 +76,7 @@ const (
 	fieldFilesSecretName    = ".spec.files.secret.name"
 	fieldEnvConfigMapName   = ".spec.env.from.configMapName"
 	fieldEnvSecretName      = ".spec.env.from.secretName"
+	fieldDependencies       = ".spec.dependencies"
 )
 
 // SetupWithManager sets up the controller with the Manager.
@@ -87,7 +95,7 @@ func (r *CapsuleReconciler) SetupWithManager(mgr ctrl.Manager, name string) erro
 		func(o client.Object) []string {
 			capsule := o.(*v1alpha2.Capsule)
 			var cms []string
-			for _, f := range capsule.Spec.Files {
+			for _, f := range capsuleFiles(capsule) {
 				if f.Ref != nil && f.Ref.Kind == "ConfigMap" {
 					cms = append(cms, f.Ref.Name)
 				}
@@ -105,7 +113,7 @@ func (r *CapsuleReconciler) SetupWithManager(mgr ctrl.Manager, name string) erro
 		func(o client.Object) []string {
 			capsule := o.(*v1alpha2.Capsule)
 			var ss []string
-			for _, f := range capsule.Spec.Files {
+			for _, f := range capsuleFiles(capsule) {
 				if f.Ref != nil && f.Ref.Kind == "Secret" {
 					ss = append(ss, f.Ref.Name)
 				}
@@ -123,7 +131,7 @@ func (r *CapsuleReconciler) SetupWithManager(mgr ctrl.Manager, name string) erro
 		func(o client.Object) []string {
 			capsule := o.(*v1alpha2.Capsule)
 			var cms []string
-			for _, from := range capsule.Spec.Env.From {
+			for _, from := range capsuleEnvReferences(capsule) {
 				if from.Kind == "ConfigMap" {
 					cms = append(cms, from.Name)
 				}
@@ -141,7 +149,7 @@ func (r *CapsuleReconciler) SetupWithManager(mgr ctrl.Manager, name string) erro
 		func(o client.Object) []string {
 			capsule := o.(*v1alpha2.Capsule)
 			var ss []string
-			for _, from := range capsule.Spec.Env.From {
+			for _, from := range capsuleEnvReferences(capsule) {
 				if from.Kind == "Secret" {
 					ss = append(ss, from.Name)
 				}
@@ -152,6 +160,22 @@ func (r *CapsuleReconciler) SetupWithManager(mgr ctrl.Manager, name string) erro
 		return fmt.Errorf("could not setup indexer for %s: %w", fieldEnvSecretName, err)
 	}
 
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&v1alpha2.Capsule{},
+		fieldDependencies,
+		func(o client.Object) []string {
+			capsule := o.(*v1alpha2.Capsule)
+			var deps []string
+			for _, d := range capsule.Spec.Dependencies {
+				deps = append(deps, dependencyKey(capsule.Namespace, d))
+			}
+			return deps
+		},
+	); err != nil {
+		return fmt.Errorf("could not setup indexer for %s: %w", fieldDependencies, err)
+	}
+
 	capabilities, err := r.CapabilitiesService.Get(ctx)
 	if err != nil {
 		return err
@@ -168,13 +192,28 @@ func (r *CapsuleReconciler) SetupWithManager(mgr ctrl.Manager, name string) erro
 		b = b.Owns(&vpav1.VerticalPodAutoscaler{})
 	}
 
+	if r.Config.Pipeline.NetworkPolicyStep.Plugin != "" {
+		b = b.Owns(&netv1.NetworkPolicy{})
+	}
+
+	if capabilities.GetHasHttpRoutes() {
+		b = b.Owns(&gatewayv1.HTTPRoute{})
+	}
+
+	if capabilities.GetHasGrpcRoutes() {
+		b = b.Owns(&gatewayv1.GRPCRoute{})
+	}
+
 	b = b.
 		For(&v1alpha2.Capsule{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
 		Owns(&v1.Service{}).
 		Owns(&netv1.Ingress{}).
 		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
 		Owns(&batchv1.CronJob{}).
+		Owns(&batchv1.Job{}).
 		Watches(
 			&v1.ConfigMap{},
 			configEventHandler,
@@ -184,13 +223,83 @@ func (r *CapsuleReconciler) SetupWithManager(mgr ctrl.Manager, name string) erro
 			&v1.Secret{},
 			configEventHandler,
 			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		).
+		Watches(
+			&v1alpha2.Capsule{},
+			handler.EnqueueRequestsFromMapFunc(findDependentCapsules(mgr)),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
 		)
+	if r.DriftScanner != nil {
+		b = b.WatchesRawSource(source.Channel(r.DriftScanner.reconciles, &handler.EnqueueRequestForObject{}))
+	}
+	if r.ConfigReloader != nil {
+		b = b.WatchesRawSource(source.Channel(r.ConfigReloader.reconciles, &handler.EnqueueRequestForObject{}))
+	}
 	if name != "" {
 		b.Named(name)
 	}
 	return b.Complete(r)
 }
 
+// capsuleFiles returns the files of the main container and the additional
+// containers of the capsule. Sidecars don't have files.
+func capsuleFiles(capsule *v1alpha2.Capsule) []v1alpha2.File {
+	files := slices.Clone(capsule.Spec.Files)
+	for _, c := range capsule.Spec.Containers {
+		files = append(files, c.Files...)
+	}
+	return files
+}
+
+// capsuleEnvReferences returns the env references of the main container, the
+// sidecars and the additional containers of the capsule.
+func capsuleEnvReferences(capsule *v1alpha2.Capsule) []v1alpha2.EnvReference {
+	refs := slices.Clone(capsule.Spec.Env.From)
+	for _, sc := range capsule.Spec.Sidecars {
+		refs = append(refs, sc.Env.From...)
+	}
+	for _, c := range capsule.Spec.Containers {
+		refs = append(refs, c.Env.From...)
+	}
+	return refs
+}
+
+// dependencyKey returns the key of the capsule depended on, as indexed by
+// fieldDependencies.
+func dependencyKey(namespace string, d v1alpha2.CapsuleDependency) string {
+	if d.Namespace != "" {
+		namespace = d.Namespace
+	}
+	return namespace + "/" + d.Capsule
+}
+
+// findDependentCapsules returns the capsules depending on a capsule, which
+// are reconciled when the interfaces of the capsule may have changed.
+func findDependentCapsules(mgr ctrl.Manager) handler.MapFunc {
+	log := mgr.GetLogger().WithName("dependencyEventHandler")
+	c := mgr.GetClient()
+
+	return func(ctx context.Context, o client.Object) []ctrl.Request {
+		var dependents v1alpha2.CapsuleList
+		if err := c.List(ctx, &dependents, &client.ListOptions{
+			FieldSelector: fields.SelectorFromSet(fields.Set{
+				fieldDependencies: o.GetNamespace() + "/" + o.GetName(),
+			}),
+		}); err != nil {
+			log.Error(err, "could not list capsules depending on capsule", "capsule", o.GetName())
+			return nil
+		}
+
+		var requests []ctrl.Request
+		for _, capsule := range dependents.Items {
+			requests = append(requests, ctrl.Request{
+				NamespacedName: client.ObjectKeyFromObject(&capsule),
+			})
+		}
+		return requests
+	}
+}
+
 func findCapsulesForConfig(mgr ctrl.Manager) handler.MapFunc {
 	scheme := mgr.GetScheme()
 	log := mgr.GetLogger().WithName("configEventHandler")
@@ -309,12 +418,17 @@ func (r *CapsuleReconciler) initializeReconciler(ctx context.Context) {
 //+kubebuilder:rbac:groups=rig.dev,resources=capsules,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rig.dev,resources=capsules/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=rig.dev,resources=capsules/finalizers,verbs=update
-//+kubebuilder:rbac:groups="apps",resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="apps",resources=deployments;statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=services;serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=external-secrets.io,resources=externalsecrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses;networkpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes;grpcroutes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=configmaps;secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=create;update
 
 // Reconcile compares the state specified by the Capsule object against the
 // actual cluster state, and then performs operations to make the cluster state
@@ -348,7 +462,10 @@ func (r *CapsuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 	} else {
 		log.Info("capsule should be deleted")
-		if _, err := r.PipelineService.GetDefaultPipeline().DeleteCapsule(ctx, capsule, r.Client); err != nil {
+		p, release := r.PipelineService.AcquirePipeline()
+		_, err := p.DeleteCapsule(ctx, capsule, r.Client)
+		release()
+		if err != nil {
 			log.Error(err, "delete ended with error")
 			return ctrl.Result{}, err
 		}
@@ -374,12 +491,19 @@ func (r *CapsuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		options = append(options, pipeline.WithForce())
 	}
 
-	if _, err := r.PipelineService.GetDefaultPipeline().RunCapsule(ctx, capsule, r.Client, options...); err != nil {
+	p, release := r.PipelineService.AcquirePipeline()
+	start := time.Now()
+	res, err := p.RunCapsule(ctx, capsule, r.Client, options...)
+	release()
+	if r.ExecutionHistory != nil {
+		r.ExecutionHistory.Record(capsule, start, res, err)
+	}
+	if err != nil {
 		log.Error(err, "reconciliation ended with error")
 		return ctrl.Result{}, err
 	}
 
 	log.Info("reconciliation completed successfully")
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: pipeline.ReconcileAfter(res, time.Now())}, nil
 }
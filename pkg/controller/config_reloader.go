@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+	configv1alpha1 "github.com/rigdev/rig/pkg/api/config/v1alpha1"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/service/objectstatus"
+	svc_pipeline "github.com/rigdev/rig/pkg/service/pipeline"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// configReloadDelay is the time to wait after a change of the config files, before
+// reloading them. Updates of mounted ConfigMaps and Secrets consist of multiple changes.
+const configReloadDelay = time.Second
+
+// ConfigSource reads the operator config.
+type ConfigSource interface {
+	FilePaths() []string
+	Load() (*configv1alpha1.OperatorConfig, error)
+}
+
+// ConfigReloader watches the files of the operator config and reloads the pipeline
+// when they change. Capsules whose matched steps changed are reconciled with the new
+// pipeline. Invalid configs, and configs changing the plugin manager, are rejected,
+// keeping the current pipeline.
+type ConfigReloader struct {
+	client       client.Client
+	source       ConfigSource
+	cfg          *configv1alpha1.OperatorConfig
+	pipeline     svc_pipeline.Service
+	objectStatus objectstatus.Service
+	recorder     record.EventRecorder
+	eventObject  runtime.Object
+	elected      <-chan struct{}
+	logger       logr.Logger
+	reconciles   chan event.GenericEvent
+}
+
+// NewConfigReloader creates a new ConfigReloader. Events about reloads are recorded
+// on the eventObject, if not nil. Capsules are only reconciled after elected is closed.
+func NewConfigReloader(
+	client client.Client,
+	source ConfigSource,
+	cfg *configv1alpha1.OperatorConfig,
+	pipeline svc_pipeline.Service,
+	objectStatus objectstatus.Service,
+	recorder record.EventRecorder,
+	eventObject runtime.Object,
+	elected <-chan struct{},
+	logger logr.Logger,
+) *ConfigReloader {
+	return &ConfigReloader{
+		client:       client,
+		source:       source,
+		cfg:          cfg,
+		pipeline:     pipeline,
+		objectStatus: objectStatus,
+		recorder:     recorder,
+		eventObject:  eventObject,
+		elected:      elected,
+		logger:       logger.WithName("config-reloader"),
+		reconciles:   make(chan event.GenericEvent),
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. All replicas of the
+// operator run the pipeline, so all of them reload it.
+func (r *ConfigReloader) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable.
+func (r *ConfigReloader) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Mounted files are replaced by swapping symlinks in their directory, so the
+	// directories are watched instead of the files.
+	var dirs []string
+	for _, path := range r.source.FilePaths() {
+		dir := filepath.Dir(path)
+		if slices.Contains(dirs, dir) {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+		dirs = append(dirs, dir)
+	}
+
+	timer := time.NewTimer(configReloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.Errors:
+			r.logger.Error(err, "error watching config files")
+		case <-watcher.Events:
+			timer.Reset(configReloadDelay)
+		case <-timer.C:
+			r.Reload(ctx)
+		}
+	}
+}
+
+// Reload reads the config and reloads the pipeline, if it changed.
+func (r *ConfigReloader) Reload(ctx context.Context) {
+	cfg, err := r.source.Load()
+	if err != nil {
+		r.reject(err)
+		return
+	}
+
+	// The plugins of a new pipeline would be run by the current plugin manager, so
+	// the whole config is rejected rather than partially applied.
+	if svc_pipeline.PluginManagerChanged(r.cfg, cfg) {
+		r.logger.Info("custom plugins or plugin sandboxes changed, keeping the current pipeline until restart")
+		r.event(v1.EventTypeWarning, "ConfigRequiresRestart",
+			"Changes of customPlugins or pluginSandboxes are applied when the operator restarts, "+
+				"keeping the current pipeline")
+		return
+	}
+
+	if svc_pipeline.RequiresRestart(r.cfg, cfg) {
+		r.logger.Info("config changed outside of the pipeline, changes are applied on restart")
+		r.event(v1.EventTypeWarning, "ConfigRequiresRestart",
+			"Changes outside of the pipeline are applied when the operator restarts")
+	}
+
+	if equality.Semantic.DeepEqual(r.cfg.Pipeline, cfg.Pipeline) {
+		r.cfg = cfg
+		return
+	}
+
+	start := time.Now()
+	drainTimeout := time.Duration(cfg.ConfigReload.DrainTimeoutSeconds) * time.Second
+	if err := r.pipeline.ReloadPipeline(cfg, drainTimeout); err != nil {
+		r.reject(err)
+		return
+	}
+	r.objectStatus.PipelineReloaded()
+
+	previous := r.cfg
+	r.cfg = cfg
+
+	r.logger.Info("pipeline reloaded", "duration", time.Since(start))
+	r.event(v1.EventTypeNormal, "ConfigReloaded", "The pipeline has been reloaded")
+
+	select {
+	case <-r.elected:
+	default:
+		// Capsules are reconciled when the operator is elected.
+		return
+	}
+
+	if err := r.reconcileChanged(ctx, previous, cfg); err != nil {
+		r.logger.Error(err, "could not reconcile capsules with changed steps")
+	}
+}
+
+func (r *ConfigReloader) reconcileChanged(ctx context.Context, from, to *configv1alpha1.OperatorConfig) error {
+	var capsules v1alpha2.CapsuleList
+	if err := r.client.List(ctx, &capsules); err != nil {
+		return err
+	}
+
+	for _, capsule := range capsules.Items {
+		changed, err := stepsChanged(from, to, &capsule)
+		if err != nil {
+			r.logger.Error(err, "could not match steps, reconciling capsule",
+				"namespace", capsule.GetNamespace(), "capsule", capsule.GetName())
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+
+		select {
+		case r.reconciles <- event.GenericEvent{Object: &capsule}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func stepsChanged(from, to *configv1alpha1.OperatorConfig, capsule *v1alpha2.Capsule) (bool, error) {
+	fromSteps, err := svc_pipeline.MatchedSteps(from, capsule)
+	if err != nil {
+		return false, err
+	}
+	toSteps, err := svc_pipeline.MatchedSteps(to, capsule)
+	if err != nil {
+		return false, err
+	}
+	return !slices.Equal(fromSteps, toSteps), nil
+}
+
+func (r *ConfigReloader) reject(err error) {
+	r.logger.Error(err, "invalid config, keeping the current pipeline")
+	r.event(v1.EventTypeWarning, "InvalidConfig", "Invalid config, keeping the current pipeline: "+err.Error())
+}
+
+func (r *ConfigReloader) event(eventType, reason, message string) {
+	if r.eventObject == nil {
+		return
+	}
+	r.recorder.Event(r.eventObject, eventType, reason, message)
+}
@@ -0,0 +1,239 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	apiplugin "github.com/rigdev/rig-go-api/operator/api/v1/plugin"
+	configv1alpha1 "github.com/rigdev/rig/pkg/api/config/v1alpha1"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/service/objectstatus"
+	svc_pipeline "github.com/rigdev/rig/pkg/service/pipeline"
+	"github.com/rigdev/rig/pkg/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// DriftScanner periodically dry-runs the pipeline of all capsules, to detect
+// owned resources which have been changed outside of the operator. Drift is
+// reported in the status of the capsule and in the object status stream, and
+// optionally corrected by reconciling the capsule.
+type DriftScanner struct {
+	client       client.Client
+	scheme       *runtime.Scheme
+	config       configv1alpha1.DriftDetection
+	pipeline     svc_pipeline.Service
+	objectStatus objectstatus.Service
+	logger       logr.Logger
+	id           uuid.UUID
+	reconciles   chan event.GenericEvent
+}
+
+func NewDriftScanner(
+	client client.Client,
+	scheme *runtime.Scheme,
+	cfg *configv1alpha1.OperatorConfig,
+	pipeline svc_pipeline.Service,
+	objectStatus objectstatus.Service,
+	logger logr.Logger,
+) *DriftScanner {
+	return &DriftScanner{
+		client:       client,
+		scheme:       scheme,
+		config:       cfg.DriftDetection,
+		pipeline:     pipeline,
+		objectStatus: objectStatus,
+		logger:       logger.WithName("drift-scanner"),
+		id:           uuid.New(),
+		reconciles:   make(chan event.GenericEvent),
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (s *DriftScanner) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable.
+func (s *DriftScanner) Start(ctx context.Context) error {
+	ticker := time.NewTicker(time.Duration(s.config.IntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		var capsules v1alpha2.CapsuleList
+		if err := s.client.List(ctx, &capsules); err != nil {
+			s.logger.Error(err, "could not list capsules")
+			continue
+		}
+
+		for _, capsule := range capsules.Items {
+			if err := s.scanCapsule(ctx, &capsule); err != nil {
+				s.logger.Error(err, "could not scan capsule for drift",
+					"namespace", capsule.GetNamespace(), "capsule", capsule.GetName())
+			}
+		}
+	}
+}
+
+func (s *DriftScanner) scanCapsule(ctx context.Context, capsule *v1alpha2.Capsule) error {
+	// Only capsules which are successfully reconciled at their current generation are
+	// expected to be in sync. Others have changes pending, which are not drift.
+	if !capsule.GetDeletionTimestamp().IsZero() ||
+		capsule.Status == nil ||
+		capsule.Status.ObservedGeneration != capsule.GetGeneration() ||
+		len(capsule.Status.Errors) > 0 {
+		return nil
+	}
+
+	var opts []pipeline.CapsuleRequestOption
+	if v, _ := strconv.ParseBool(capsule.Annotations[pipeline.AnnotationOverrideOwnership]); v {
+		opts = append(opts, pipeline.WithForce())
+	}
+	// Time-based steps, fx. rollouts and scale schedules, change the objects over time. The
+	// pipeline is run at the time of the last reconciliation, so those changes are not drift.
+	if t := capsule.Status.AppliedAt; t != nil {
+		opts = append(opts, pipeline.WithNow(t.Time))
+	}
+
+	res, err := s.pipeline.DryRun(ctx, nil, capsule.GetNamespace(), capsule.GetName(), nil, opts...)
+	if err != nil {
+		return err
+	}
+
+	drift, err := pipeline.ComputeDrift(res, s.scheme)
+	if err != nil {
+		return err
+	}
+
+	s.publishObjectStatus(capsule, res, drift)
+
+	if err := s.updateStatus(ctx, capsule, drift); err != nil {
+		return err
+	}
+
+	if len(drift) > 0 {
+		s.logger.Info("drift detected", "namespace", capsule.GetNamespace(),
+			"capsule", capsule.GetName(), "objects", len(drift))
+		if s.config.Mode == configv1alpha1.DriftDetectionModeCorrect {
+			select {
+			case s.reconciles <- event.GenericEvent{Object: capsule}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *DriftScanner) updateStatus(
+	ctx context.Context,
+	capsule *v1alpha2.Capsule,
+	drift []v1alpha2.ObjectDrift,
+) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := &v1alpha2.Capsule{}
+		if err := s.client.Get(ctx, client.ObjectKeyFromObject(capsule), current); err != nil {
+			return err
+		}
+
+		// The capsule changed since the scan, the result no longer applies.
+		if current.Status == nil || current.GetGeneration() != capsule.GetGeneration() {
+			return nil
+		}
+
+		status := current.Status.DeepCopy()
+		status.SetDrift(drift, current.GetGeneration())
+		if equality.Semantic.DeepEqual(status, current.Status) {
+			return nil
+		}
+
+		current.Status = status
+		return s.client.Status().Update(ctx, current)
+	})
+}
+
+// publishObjectStatus adds a Drift condition to the status of each drifted object,
+// replacing the conditions of the previous scan.
+func (s *DriftScanner) publishObjectStatus(
+	capsule *v1alpha2.Capsule,
+	res *pipeline.Result,
+	drift []v1alpha2.ObjectDrift,
+) {
+	keys := map[schema.GroupKind]map[string]pipeline.ObjectKey{}
+	for _, oo := range res.OutputObjects {
+		gk := oo.ObjectKey.GroupKind()
+		if keys[gk] == nil {
+			keys[gk] = map[string]pipeline.ObjectKey{}
+		}
+		keys[gk][oo.ObjectKey.Name] = oo.ObjectKey
+	}
+
+	all := &apiplugin.ObjectStatusChange_AllObjects{}
+	for _, d := range drift {
+		key, ok := keys[schema.GroupKind{Group: *d.Ref.APIGroup, Kind: d.Ref.Kind}][d.Ref.Name]
+		if !ok {
+			continue
+		}
+
+		all.Objects = append(all.Objects, &apipipeline.ObjectStatus{
+			ObjectRef: &apipipeline.ObjectRef{
+				Gvk: &apipipeline.GVK{
+					Group:   key.Group,
+					Version: key.Version,
+					Kind:    key.Kind,
+				},
+				Namespace: key.Namespace,
+				Name:      key.Name,
+			},
+			Info: &apipipeline.ObjectStatusInfo{
+				Conditions: []*apipipeline.ObjectCondition{{
+					Name:      "Drift",
+					UpdatedAt: timestamppb.Now(),
+					State:     apipipeline.ObjectState_OBJECT_STATE_ERROR,
+					Message:   driftMessage(d),
+				}},
+			},
+		})
+	}
+
+	s.objectStatus.UpdateStatus(capsule.GetNamespace(), capsule.GetName(), s.id, &apiplugin.ObjectStatusChange{
+		Change: &apiplugin.ObjectStatusChange_AllObjects_{AllObjects: all},
+	})
+}
+
+func driftMessage(d v1alpha2.ObjectDrift) string {
+	switch d.State {
+	case v1alpha2.ObjectDriftStateMissing:
+		return "Object has been deleted outside of the operator"
+	case v1alpha2.ObjectDriftStateUnexpected:
+		return "Object is no longer part of the capsule, but has not been deleted"
+	}
+
+	var fields []string
+	for _, f := range d.Fields {
+		if f.Manager != "" {
+			fields = append(fields, fmt.Sprintf("%s (by %s)", f.Field, f.Manager))
+		} else {
+			fields = append(fields, f.Field)
+		}
+	}
+	return fmt.Sprintf("Object has been changed outside of the operator: %s", strings.Join(fields, ", "))
+}
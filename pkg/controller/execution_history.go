@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1alpha1 "github.com/rigdev/rig/pkg/api/config/v1alpha1"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/pipeline"
+	svc_pipeline "github.com/rigdev/rig/pkg/service/pipeline"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// executionHistoryQueueSize is the number of executions which can be waiting
+// to be recorded before new executions are dropped.
+const executionHistoryQueueSize = 256
+
+// ExecutionHistory records the executions of the pipeline of capsules in a
+// ConfigMap per capsule, owned by the capsule. Executions are recorded
+// asynchronously, in the order they are reported, to keep reconciliation from
+// waiting on the plugin configs of the record.
+type ExecutionHistory struct {
+	client   client.Client
+	scheme   *runtime.Scheme
+	config   configv1alpha1.ExecutionHistory
+	pipeline svc_pipeline.Service
+	logger   logr.Logger
+	queue    chan execution
+}
+
+type execution struct {
+	capsule *v1alpha2.Capsule
+	record  pipeline.ExecutionRecord
+}
+
+func NewExecutionHistory(
+	client client.Client,
+	scheme *runtime.Scheme,
+	cfg *configv1alpha1.OperatorConfig,
+	pipeline svc_pipeline.Service,
+	logger logr.Logger,
+) *ExecutionHistory {
+	return &ExecutionHistory{
+		client:   client,
+		scheme:   scheme,
+		config:   cfg.ExecutionHistory,
+		pipeline: pipeline,
+		logger:   logger.WithName("execution-history"),
+		queue:    make(chan execution, executionHistoryQueueSize),
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (h *ExecutionHistory) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable.
+func (h *ExecutionHistory) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-h.queue:
+			if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				return h.record(ctx, e.capsule, e.record)
+			}); err != nil {
+				h.logger.Error(err, "could not record execution",
+					"namespace", e.capsule.GetNamespace(), "capsule", e.capsule.GetName())
+			}
+		}
+	}
+}
+
+// Record queues an execution of the pipeline for the capsule, started at start,
+// to be recorded. Executions which didn't change any objects are only recorded if
+// they are of a new generation of the capsule or failed differently than the
+// previous execution.
+func (h *ExecutionHistory) Record(
+	capsule *v1alpha2.Capsule,
+	start time.Time,
+	result *pipeline.Result,
+	execErr error,
+) {
+	e := execution{
+		capsule: capsule.DeepCopy(),
+		record:  pipeline.NewExecutionRecord(capsule, start, result, execErr),
+	}
+
+	select {
+	case h.queue <- e:
+	default:
+		h.logger.Info("execution history queue is full, dropping execution",
+			"namespace", capsule.GetNamespace(), "capsule", capsule.GetName())
+	}
+}
+
+func (h *ExecutionHistory) record(
+	ctx context.Context,
+	capsule *v1alpha2.Capsule,
+	record pipeline.ExecutionRecord,
+) error {
+	cm := &v1.ConfigMap{}
+	err := h.client.Get(ctx, client.ObjectKey{
+		Namespace: capsule.GetNamespace(),
+		Name:      pipeline.ExecutionHistoryName(capsule.GetName()),
+	}, cm)
+	exists := err == nil
+	if errors.IsNotFound(errors.FromK8sClient(err)) {
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: capsule.GetNamespace(),
+				Name:      pipeline.ExecutionHistoryName(capsule.GetName()),
+				Labels: map[string]string{
+					pipeline.LabelExecutionHistory: capsule.GetName(),
+				},
+			},
+		}
+		if err := controllerutil.SetOwnerReference(capsule, cm, h.scheme); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	} else if !isExecutionHistoryOf(cm, capsule) {
+		return errors.FailedPreconditionErrorf(
+			"ConfigMap %s is not the execution history of the capsule", cm.GetName())
+	}
+
+	records, err := pipeline.DecodeExecutionHistory(cm)
+	if err == nil && len(records) > 0 && !shouldRecord(records[0], record) {
+		return nil
+	}
+
+	// Plugin configs are only computed for executions which are recorded.
+	if record.Error == "" {
+		p, release := h.pipeline.AcquirePipeline()
+		configs, err := p.ComputeConfig(ctx, capsule, h.client)
+		release()
+		if err != nil {
+			h.logger.Error(err, "could not compute plugin configs",
+				"namespace", capsule.GetNamespace(), "capsule", capsule.GetName())
+		}
+		record.SetPluginConfigs(configs)
+	}
+
+	if err := pipeline.AppendExecutionRecord(
+		cm, record,
+		int(h.config.MaxRecords),
+		time.Duration(h.config.MaxAgeSeconds)*time.Second,
+	); err != nil {
+		return err
+	}
+
+	if exists {
+		return h.client.Update(ctx, cm)
+	}
+	return h.client.Create(ctx, cm)
+}
+
+func shouldRecord(previous, record pipeline.ExecutionRecord) bool {
+	return len(record.Objects) > 0 ||
+		previous.Generation != record.Generation ||
+		previous.Error != record.Error
+}
+
+// isExecutionHistoryOf returns whether the ConfigMap was created by the operator
+// to hold the execution history of the capsule, to never overwrite a ConfigMap
+// of the user.
+func isExecutionHistoryOf(cm *v1.ConfigMap, capsule *v1alpha2.Capsule) bool {
+	if cm.GetLabels()[pipeline.LabelExecutionHistory] != capsule.GetName() {
+		return false
+	}
+	for _, ref := range cm.GetOwnerReferences() {
+		if ref.UID == capsule.GetUID() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// conditionEnv is the CEL environment of step conditions. The capsule is
+// available as the `capsule` variable, in the same structure as its YAML.
+var conditionEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("capsule", cel.MapType(cel.StringType, cel.DynType)),
+	)
+})
+
+type condition struct {
+	expression string
+	program    cel.Program
+}
+
+func newCondition(expression string) (*condition, error) {
+	env, err := conditionEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("invalid condition '%s': %w", expression, issues.Err())
+	}
+
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("condition '%s' must evaluate to a bool, not %s", expression, ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition '%s': %w", expression, err)
+	}
+
+	return &condition{
+		expression: expression,
+		program:    program,
+	}, nil
+}
+
+// Eval evaluates the condition for the capsule. An error is returned if the
+// condition fails to evaluate, e.g. by accessing a field which is not set, which
+// fails the step of the condition.
+func (c *condition) Eval(capsule *v1alpha2.Capsule) (bool, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(capsule)
+	if err != nil {
+		return false, err
+	}
+
+	out, _, err := c.program.Eval(map[string]any{"capsule": obj})
+	if err != nil {
+		return false, fmt.Errorf("could not evaluate condition '%s': %w", c.expression, err)
+	}
+
+	res, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("condition '%s' evaluated to %v, not a bool", c.expression, out.Value())
+	}
+
+	return res, nil
+}
@@ -0,0 +1,178 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ExtensibleConfig is implemented by the configuration of plugins, which can be
+// overridden per Capsule through a Capsule extension.
+type ExtensibleConfig interface {
+	// CapsuleExtensionName returns the name of the Capsule extension to merge over
+	// the configuration. If empty, the configuration isn't extended.
+	CapsuleExtensionName() string
+}
+
+// ExtensionPlugin is implemented by plugins with an ExtensibleConfig.
+type ExtensionPlugin interface {
+	// ExtensionSchema returns the JSON schema of the Capsule extension
+	// overriding the configuration of the plugin.
+	ExtensionSchema() (*apiextensionsv1.JSONSchemaProps, error)
+}
+
+// capsuleExtensionField is the field of an ExtensibleConfig naming the Capsule extension.
+const capsuleExtensionField = "capsuleExtension"
+
+// MergeCapsuleExtension merges the value of the Capsule extension named by the
+// configuration over the configuration. Fields set by the extension replace the
+// fields of the configuration, except for maps which are merged key by key.
+func MergeCapsuleExtension[T any](config T, req pipeline.CapsuleRequest) (T, error) {
+	ec, ok := any(config).(ExtensibleConfig)
+	if !ok {
+		ec, ok = any(&config).(ExtensibleConfig)
+	}
+	if !ok || ec.CapsuleExtensionName() == "" {
+		return config, nil
+	}
+
+	name := ec.CapsuleExtensionName()
+	value, ok := req.Capsule().Spec.Extensions[name]
+	if !ok {
+		return config, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(value, &fields); err != nil {
+		return config, fmt.Errorf("invalid capsule extension '%s': %w", name, err)
+	}
+	if _, ok := fields[capsuleExtensionField]; ok {
+		return config, fmt.Errorf("capsule extension '%s' cannot set '%s'", name, capsuleExtensionField)
+	}
+
+	if err := json.Unmarshal(value, &config); err != nil {
+		return config, fmt.Errorf("invalid capsule extension '%s': %w", name, err)
+	}
+
+	return config, nil
+}
+
+// ConfigSchema generates the JSON schema of a Capsule extension from the configuration
+// of a plugin, by the JSON names and types of its fields.
+func ConfigSchema(config any) (*apiextensionsv1.JSONSchemaProps, error) {
+	t := reflect.TypeOf(config)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config must be a struct, got %v", t)
+	}
+
+	schema := schemaForType(t, map[reflect.Type]bool{})
+	delete(schema.Properties, capsuleExtensionField)
+	return &schema, nil
+}
+
+var (
+	quantityType    = reflect.TypeOf(resource.Quantity{})
+	intOrStringType = reflect.TypeOf(intstr.IntOrString{})
+	timeType        = reflect.TypeOf(metav1.Time{})
+	durationType    = reflect.TypeOf(metav1.Duration{})
+	rawMessageType  = reflect.TypeOf(json.RawMessage{})
+)
+
+func schemaForType(t reflect.Type, visiting map[reflect.Type]bool) apiextensionsv1.JSONSchemaProps {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case quantityType, intOrStringType:
+		return apiextensionsv1.JSONSchemaProps{XIntOrString: true}
+	case timeType:
+		return apiextensionsv1.JSONSchemaProps{Type: "string", Format: "date-time"}
+	case durationType:
+		return apiextensionsv1.JSONSchemaProps{Type: "string"}
+	case rawMessageType:
+		return apiextensionsv1.JSONSchemaProps{XPreserveUnknownFields: ptr.New(true)}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return apiextensionsv1.JSONSchemaProps{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return apiextensionsv1.JSONSchemaProps{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return apiextensionsv1.JSONSchemaProps{Type: "number"}
+	case reflect.String:
+		return apiextensionsv1.JSONSchemaProps{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return apiextensionsv1.JSONSchemaProps{Type: "string", Format: "byte"}
+		}
+		items := schemaForType(t.Elem(), visiting)
+		return apiextensionsv1.JSONSchemaProps{
+			Type:  "array",
+			Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &items},
+		}
+	case reflect.Map:
+		values := schemaForType(t.Elem(), visiting)
+		return apiextensionsv1.JSONSchemaProps{
+			Type:                 "object",
+			AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{Allows: true, Schema: &values},
+		}
+	case reflect.Struct:
+		if visiting[t] {
+			// Recursive types are not expanded further.
+			return apiextensionsv1.JSONSchemaProps{Type: "object", XPreserveUnknownFields: ptr.New(true)}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		schema := apiextensionsv1.JSONSchemaProps{
+			Type:                 "object",
+			Properties:           map[string]apiextensionsv1.JSONSchemaProps{},
+			AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{Allows: false},
+		}
+		addStructProperties(t, &schema, visiting)
+		return schema
+	default:
+		return apiextensionsv1.JSONSchemaProps{XPreserveUnknownFields: ptr.New(true)}
+	}
+}
+
+func addStructProperties(t reflect.Type, schema *apiextensionsv1.JSONSchemaProps, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			addStructProperties(ft, schema, visiting)
+			continue
+		}
+		if !f.IsExported() || opts == "inline" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		schema.Properties[name] = schemaForType(f.Type, visiting)
+	}
+}
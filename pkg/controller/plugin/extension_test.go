@@ -0,0 +1,200 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/scheme"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+	"golang.org/x/exp/maps"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type extensibleConfig struct {
+	Name             string            `json:"name,omitempty"`
+	Replicas         int               `json:"replicas,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Args             []string          `json:"args,omitempty"`
+	CapsuleExtension string            `json:"capsuleExtension,omitempty"`
+}
+
+func (c extensibleConfig) CapsuleExtensionName() string {
+	return c.CapsuleExtension
+}
+
+func newExtensionRequest(extensions map[string]string) pipeline.CapsuleRequest {
+	vm := scheme.NewVersionMapperFromScheme(scheme.New())
+	p := pipeline.NewCapsulePipeline(nil, scheme.New(), vm, logr.FromContextOrDiscard(context.Background()))
+
+	capsule := &v1alpha2.Capsule{
+		ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "namespace"},
+		Spec: v1alpha2.CapsuleSpec{
+			Extensions: map[string]json.RawMessage{},
+		},
+	}
+	for name, value := range extensions {
+		capsule.Spec.Extensions[name] = json.RawMessage(value)
+	}
+
+	return pipeline.NewCapsuleRequest(p, capsule, nil)
+}
+
+func Test_MergeCapsuleExtension(t *testing.T) {
+	config := `
+name: operator
+replicas: 2
+labels:
+  team: platform
+  tier: backend
+args: [a, b]
+capsuleExtension: ext`
+
+	tests := []struct {
+		name       string
+		extensions map[string]string
+		config     string
+		expected   extensibleConfig
+		err        string
+	}{
+		{
+			name:   "no extension value",
+			config: config,
+			expected: extensibleConfig{
+				Name:             "operator",
+				Replicas:         2,
+				Labels:           map[string]string{"team": "platform", "tier": "backend"},
+				Args:             []string{"a", "b"},
+				CapsuleExtension: "ext",
+			},
+		},
+		{
+			name:       "not extended",
+			extensions: map[string]string{"ext": `{"name": "capsule"}`},
+			config:     "name: operator",
+			expected:   extensibleConfig{Name: "operator"},
+		},
+		{
+			name: "merged",
+			extensions: map[string]string{
+				"ext": `{"replicas": 5, "labels": {"tier": "frontend"}, "args": ["c"]}`,
+			},
+			config: config,
+			expected: extensibleConfig{
+				Name:             "operator",
+				Replicas:         5,
+				Labels:           map[string]string{"team": "platform", "tier": "frontend"},
+				Args:             []string{"c"},
+				CapsuleExtension: "ext",
+			},
+		},
+		{
+			name:       "cannot set extension name",
+			extensions: map[string]string{"ext": `{"capsuleExtension": "other"}`},
+			config:     config,
+			err:        "capsule extension 'ext' cannot set 'capsuleExtension'",
+		},
+		{
+			name:       "invalid value",
+			extensions: map[string]string{"ext": `{"replicas": "many"}`},
+			config:     config,
+			err:        "invalid capsule extension 'ext'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newExtensionRequest(tt.extensions)
+			conf, err := ParseCapsuleTemplatedConfig[extensibleConfig]([]byte(tt.config), req)
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, conf)
+		})
+	}
+}
+
+func Test_ConfigSchema(t *testing.T) {
+	type nested struct {
+		Value    string `json:"value"`
+		Children []nested
+	}
+	type inline struct {
+		Inlined bool `json:"inlined"`
+	}
+	type schemaConfig struct {
+		inline
+		extensibleConfig `json:",inline"`
+		Nested           nested                       `json:"nested"`
+		Quantity         *resource.Quantity           `json:"quantity"`
+		Limits           map[string]resource.Quantity `json:"limits"`
+		Ignored          string                       `json:"-"`
+		Ratio            float64                      `json:"ratio"`
+	}
+
+	schema, err := ConfigSchema(schemaConfig{})
+	require.NoError(t, err)
+
+	require.Equal(t, "object", schema.Type)
+	require.False(t, schema.AdditionalProperties.Allows)
+	require.ElementsMatch(t, []string{
+		"inlined", "name", "replicas", "labels", "args", "nested", "quantity", "limits", "ratio",
+	}, maps.Keys(schema.Properties))
+
+	require.Equal(t, "boolean", schema.Properties["inlined"].Type)
+	require.Equal(t, "number", schema.Properties["ratio"].Type)
+	require.True(t, schema.Properties["quantity"].XIntOrString)
+	require.True(t, schema.Properties["limits"].AdditionalProperties.Schema.XIntOrString)
+
+	n := schema.Properties["nested"]
+	require.Equal(t, "string", n.Properties["value"].Type)
+	require.Equal(t, "array", n.Properties["Children"].Type)
+	require.True(t, *n.Properties["Children"].Items.Schema.XPreserveUnknownFields)
+
+	schema, err = ConfigSchema(&extensibleConfig{})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"name", "replicas", "labels", "args"}, maps.Keys(schema.Properties))
+	require.Equal(t, "string", schema.Properties["labels"].AdditionalProperties.Schema.Type)
+	require.Equal(t, "string", schema.Properties["args"].Items.Schema.Type)
+
+	_, err = ConfigSchema("config")
+	require.ErrorContains(t, err, "config must be a struct")
+}
+
+func Test_MergeCapsuleExtension_complexProperties(t *testing.T) {
+	schema, err := ConfigSchema(extensibleConfig{})
+	require.NoError(t, err)
+	require.Equal(t, "object", schema.Properties["labels"].Type)
+	require.Equal(t, "array", schema.Properties["args"].Type)
+
+	value := `{"labels": {"tier": "frontend", "zone": "a"}, "args": ["c", "d"]}`
+	res, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schema), gojsonschema.NewStringLoader(value))
+	require.NoError(t, err)
+	require.True(t, res.Valid(), res.Errors())
+
+	res, err = gojsonschema.Validate(
+		gojsonschema.NewGoLoader(schema), gojsonschema.NewStringLoader(`{"labels": {"tier": 1}}`),
+	)
+	require.NoError(t, err)
+	require.False(t, res.Valid())
+
+	req := newExtensionRequest(map[string]string{"ext": value})
+	conf, err := MergeCapsuleExtension(extensibleConfig{
+		Labels:           map[string]string{"team": "platform"},
+		Args:             []string{"a"},
+		CapsuleExtension: "ext",
+	}, req)
+	require.NoError(t, err)
+	require.Equal(t, extensibleConfig{
+		Labels:           map[string]string{"team": "platform", "tier": "frontend", "zone": "a"},
+		Args:             []string{"c", "d"},
+		CapsuleExtension: "ext",
+	}, conf)
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -13,12 +14,15 @@ import (
 	"github.com/hashicorp/go-plugin"
 	apiplugin "github.com/rigdev/rig-go-api/operator/api/v1/plugin"
 	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/errors"
 	"github.com/rigdev/rig/pkg/obj"
 	"github.com/rigdev/rig/pkg/pipeline"
 	"github.com/rigdev/rig/pkg/scheme"
 	"github.com/rigdev/rig/pkg/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -50,22 +54,38 @@ func (c *executionContext) Context() context.Context {
 	return c.ctx
 }
 
+// pluginRunner runs the requests of a plugin, which is either a go-plugin
+// subprocess or a WASM module.
+type pluginRunner interface {
+	Run(ctx context.Context, req pipeline.CapsuleRequest, opts pipeline.Options) error
+	WatchObjectStatus(
+		ctx context.Context,
+		capsule *v1alpha2.Capsule,
+		callback pipeline.ObjectStatusCallback,
+		pluginID uuid.UUID,
+	) error
+	ComputeConfig(ctx context.Context, req pipeline.CapsuleRequest) (string, error)
+}
+
 type pluginExecutor struct {
-	context      ExecutionContext
-	name         string
-	logger       logr.Logger
-	client       *plugin.Client
-	pluginClient *pluginClient
-	binaryPath   string
-	args         []string
-	tag          string
-	id           uuid.UUID
+	context    ExecutionContext
+	name       string
+	step       string
+	logger     logr.Logger
+	client     *plugin.Client
+	runner     pluginRunner
+	binaryPath string
+	args       []string
+	tag        string
+	id         uuid.UUID
+	sandbox    sandbox
 }
 
 func newPluginExecutor(
 	context ExecutionContext,
-	name, stepTag, pluginTag, pluginConfig, path string,
+	name, step, stepTag, pluginTag, pluginConfig, path string,
 	args []string,
+	sandbox sandbox,
 	logger logr.Logger,
 	restConfig *rest.Config,
 ) (*pluginExecutor, error) {
@@ -76,11 +96,13 @@ func newPluginExecutor(
 	p := &pluginExecutor{
 		context:    context,
 		name:       name,
+		step:       step,
 		logger:     logger.WithValues("plugin", name),
 		binaryPath: path,
 		args:       args,
 		tag:        tag,
 		id:         uuid.New(),
+		sandbox:    sandbox,
 	}
 
 	return p, p.start(context.Context(), pluginConfig, restConfig)
@@ -99,6 +121,22 @@ func (l *loggerSink) Accept(name string, level hclog.Level, msg string, args ...
 }
 
 func (p *pluginExecutor) start(ctx context.Context, pluginConfig string, restConfig *rest.Config) error {
+	if strings.HasSuffix(p.binaryPath, wasmExtension) {
+		runner, err := newWASMPlugin(ctx, p.context, p.name, p.binaryPath, pluginConfig, p.tag, p.sandbox, p.logger)
+		if err != nil {
+			return err
+		}
+		p.runner = runner
+		return nil
+	}
+
+	if p.sandbox.readOnly {
+		var err error
+		if restConfig, err = startReadOnlyProxy(ctx, restConfig, p.name, p.logger); err != nil {
+			return err
+		}
+	}
+
 	pLogger := hclog.NewInterceptLogger(&hclog.LoggerOptions{
 		Name:       p.name,
 		Output:     io.Discard,
@@ -160,15 +198,25 @@ func (p *pluginExecutor) start(ctx context.Context, pluginConfig string, restCon
 		return err
 	}
 
-	p.pluginClient = raw.(*pluginClient)
+	pluginClient := raw.(*pluginClient)
+	pluginClient.name = p.name
+	pluginClient.sandbox = p.sandbox
+	p.runner = pluginClient
 
-	return p.pluginClient.Initialize(ctx, pluginConfig, p.tag, restConfig)
+	return pluginClient.Initialize(ctx, pluginConfig, p.tag, restConfig)
 }
 
 func (p *pluginExecutor) Run(ctx context.Context, req pipeline.CapsuleRequest, opts pipeline.Options) error {
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, p.sandbox.timeout)
 	defer cancel()
-	return p.pluginClient.Run(ctx, req, opts)
+
+	start := time.Now()
+	err := p.timeoutError(ctx, p.runner.Run(ctx, req, opts))
+	pluginDuration.WithLabelValues(p.step, p.name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		pluginErrors.WithLabelValues(p.step, p.name).Inc()
+	}
+	return err
 }
 
 func (p *pluginExecutor) WatchObjectStatus(
@@ -176,13 +224,22 @@ func (p *pluginExecutor) WatchObjectStatus(
 	capsule *v1alpha2.Capsule,
 	callback pipeline.ObjectStatusCallback,
 ) error {
-	return p.pluginClient.WatchObjectStatus(ctx, capsule, callback, p.id)
+	return p.runner.WatchObjectStatus(ctx, capsule, callback, p.id)
 }
 
 func (p *pluginExecutor) ComputeConfig(ctx context.Context, req pipeline.CapsuleRequest) (string, error) {
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, p.sandbox.timeout)
 	defer cancel()
-	return p.pluginClient.ComputeConfig(ctx, req)
+	config, err := p.runner.ComputeConfig(ctx, req)
+	return config, p.timeoutError(ctx, err)
+}
+
+// timeoutError replaces the error of a call exceeding the timeout of the plugin.
+func (p *pluginExecutor) timeoutError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return errors.DeadlineExceededErrorf("plugin did not finish within %v", p.sandbox.timeout)
+	}
+	return err
 }
 
 type rigOperatorPlugin struct {
@@ -213,8 +270,10 @@ func (p *rigOperatorPlugin) GRPCClient(
 }
 
 type pluginClient struct {
-	broker *plugin.GRPCBroker
-	client apiplugin.PluginServiceClient
+	broker  *plugin.GRPCBroker
+	client  apiplugin.PluginServiceClient
+	name    string
+	sandbox sandbox
 }
 
 func (m *pluginClient) Initialize(ctx context.Context, pluginConfig, tag string, restConfig *rest.Config) error {
@@ -238,7 +297,7 @@ func (m *pluginClient) Initialize(ctx context.Context, pluginConfig, tag string,
 }
 
 func (m *pluginClient) Run(ctx context.Context, req pipeline.CapsuleRequest, opts pipeline.Options) error {
-	s, brokerID, err := m.setupGRPCServer(req)
+	s, reqServer, brokerID, err := m.setupGRPCServer(req)
 	if err != nil {
 		return err
 	}
@@ -261,13 +320,17 @@ func (m *pluginClient) Run(ctx context.Context, req pipeline.CapsuleRequest, opt
 		RunServer:         brokerID,
 		CapsuleObject:     capsuleBytes,
 		AdditionalObjects: additionalObjects,
+		Now:               timestamppb.New(req.Now()),
 	})
+	if err := reqServer.violations.err(); err != nil {
+		return err
+	}
 
 	return err
 }
 
-func (m *pluginClient) setupGRPCServer(req pipeline.CapsuleRequest) (*grpc.Server, uint32, error) {
-	reqServer := &requestServer{req: req}
+func (m *pluginClient) setupGRPCServer(req pipeline.CapsuleRequest) (*grpc.Server, *requestServer, uint32, error) {
+	reqServer := newRequestServer(req, m.name, m.sandbox)
 	c := make(chan *grpc.Server)
 	serverFunc := func(opts []grpc.ServerOption) *grpc.Server {
 		s := grpc.NewServer(opts...)
@@ -278,7 +341,7 @@ func (m *pluginClient) setupGRPCServer(req pipeline.CapsuleRequest) (*grpc.Serve
 	brokerID := m.broker.NextId()
 	go m.broker.AcceptAndServe(brokerID, serverFunc)
 	s := <-c
-	return s, brokerID, nil
+	return s, reqServer, brokerID, nil
 }
 
 func (m *pluginClient) WatchObjectStatus(
@@ -306,7 +369,7 @@ func (m *pluginClient) WatchObjectStatus(
 }
 
 func (m *pluginClient) ComputeConfig(ctx context.Context, req pipeline.CapsuleRequest) (string, error) {
-	s, brokerID, err := m.setupGRPCServer(req)
+	s, reqServer, brokerID, err := m.setupGRPCServer(req)
 	if err != nil {
 		return "", err
 	}
@@ -320,7 +383,11 @@ func (m *pluginClient) ComputeConfig(ctx context.Context, req pipeline.CapsuleRe
 	resp, err := m.client.ComputeConfig(ctx, &apiplugin.ComputeConfigRequest{
 		RunServer:     brokerID,
 		CapsuleObject: capsuleBytes,
+		Now:           timestamppb.New(req.Now()),
 	})
+	if err := reqServer.violations.err(); err != nil {
+		return "", err
+	}
 	if err != nil {
 		return "", err
 	}
@@ -331,7 +398,32 @@ func (m *pluginClient) ComputeConfig(ctx context.Context, req pipeline.CapsuleRe
 type requestServer struct {
 	apiplugin.UnimplementedRequestServiceServer
 
-	req pipeline.CapsuleRequest
+	req        pipeline.CapsuleRequest
+	plugin     string
+	sandbox    sandbox
+	violations *violations
+}
+
+func newRequestServer(req pipeline.CapsuleRequest, plugin string, sandbox sandbox) *requestServer {
+	return &requestServer{
+		req:        req,
+		plugin:     plugin,
+		sandbox:    sandbox,
+		violations: &violations{},
+	}
+}
+
+// checkAllowed returns an error if the sandbox of the plugin doesn't allow it to
+// set or delete objects of the given type.
+func (s requestServer) checkAllowed(action string, gvk schema.GroupVersionKind) error {
+	if s.sandbox.allows(gvk) {
+		return nil
+	}
+
+	err := errors.PermissionDeniedErrorf("plugin is not allowed to %s objects of kind '%s'", action, gvk.GroupKind())
+	s.violations.add(err)
+	sandboxViolations.WithLabelValues(s.plugin).Inc()
+	return err
 }
 
 func toGVK(gvk *apiplugin.GVK) schema.GroupVersionKind {
@@ -370,19 +462,54 @@ func (s requestServer) GetObject(
 	}, nil
 }
 
+// decodeObject decodes an object of the given type. The object is decoded as
+// the kind given in its bytes, so objects of another kind than the given type
+// are rejected, as they would bypass the sandbox of the plugin.
 func (s requestServer) decodeObject(gvk *apiplugin.GVK, bytes []byte) (client.Object, error) {
+	var typeMeta metav1.TypeMeta
+	if err := obj.Decode(bytes, &typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.Kind != "" {
+		if err := s.checkKind(toGVK(gvk), typeMeta.GroupVersionKind()); err != nil {
+			return nil, err
+		}
+	}
+
 	co := obj.New(toGVK(gvk), s.req.Scheme())
 	if err := obj.DecodeInto(bytes, co, s.req.Scheme()); err != nil {
 		return nil, err
 	}
 
+	if err := s.checkKind(toGVK(gvk), co.GetObjectKind().GroupVersionKind()); err != nil {
+		return nil, err
+	}
+
 	return co, nil
 }
 
+// checkKind returns an error if an object given as one type is of another kind.
+func (s requestServer) checkKind(expected, gvk schema.GroupVersionKind) error {
+	if gvk == expected {
+		return nil
+	}
+
+	err := errors.PermissionDeniedErrorf(
+		"plugin is not allowed to set an object of kind '%s' as kind '%s'", gvk.GroupKind(), expected.GroupKind(),
+	)
+	s.violations.add(err)
+	sandboxViolations.WithLabelValues(s.plugin).Inc()
+	return err
+}
+
 func (s requestServer) SetObject(
 	_ context.Context,
 	req *apiplugin.SetObjectRequest,
 ) (*apiplugin.SetObjectResponse, error) {
+	if err := s.checkAllowed("set", toGVK(req.GetGvk())); err != nil {
+		return nil, err
+	}
+
 	obj, err := s.decodeObject(req.GetGvk(), req.GetObject())
 	if err != nil {
 		return nil, err
@@ -399,6 +526,10 @@ func (s requestServer) DeleteObject(
 	_ context.Context,
 	req *apiplugin.DeleteObjectRequest,
 ) (*apiplugin.DeleteObjectResponse, error) {
+	if err := s.checkAllowed("delete", toGVK(req.GetGvk())); err != nil {
+		return nil, err
+	}
+
 	if err := s.req.Delete(toGVK(req.GetGvk()), req.GetName()); err != nil {
 		return nil, err
 	}
@@ -22,6 +22,7 @@ func ParseCapsuleTemplatedConfig[T any](data []byte, req pipeline.CapsuleRequest
 
 // Using this, we parse the config at every execution of the plugin.
 // If we get performance issues due to that we can try and optimize that.
+// If the config is an ExtensibleConfig, the value of its Capsule extension is merged over it.
 func ParseTemplatedConfig[T any](data []byte, req pipeline.CapsuleRequest, steps ...ParseStep[T]) (T, error) {
 	if len(data) == 0 {
 		data = []byte("{}")
@@ -60,7 +61,7 @@ func ParseTemplatedConfig[T any](data []byte, req pipeline.CapsuleRequest, steps
 		}
 	}
 
-	return config, nil
+	return MergeCapsuleExtension(config, req)
 }
 
 func CapsuleStep[T any](_ T, req pipeline.CapsuleRequest) (map[string]any, error) {
@@ -20,6 +20,7 @@ type Manager struct {
 	restConfig        *rest.Config
 	plugins           map[string]Info
 	builtinBinaryPath string
+	sandboxes         map[string]v1alpha1.PluginSandbox
 }
 
 type Info struct {
@@ -46,6 +47,13 @@ func SetBuiltinBinaryPathOption(path string) ManagerOption {
 	}
 }
 
+// SetPluginSandboxesOption limits what the plugins can do, keyed by the name of the plugin.
+func SetPluginSandboxesOption(sandboxes map[string]v1alpha1.PluginSandbox) ManagerOption {
+	return func(m *Manager) {
+		m.sandboxes = sandboxes
+	}
+}
+
 func thirdpartyPluginDir() string {
 	if dir, ok := os.LookupEnv("RIG_THIRDPARTY_PLUGIN_DIR"); ok {
 		return dir
@@ -81,6 +89,7 @@ var allPlugins = []string{
 	"rigdev.cron_jobs",
 	"rigdev.vpa",
 	"rigdev.service_monitor",
+	"rigdev.rollout",
 
 	"rigdev.annotations",
 	"rigdev.datadog",
@@ -137,6 +146,15 @@ func NewManager(restCfg *rest.Config, opts ...ManagerOption) (*Manager, error) {
 		}
 	}
 
+	for name, sandbox := range manager.sandboxes {
+		if _, ok := manager.plugins[name]; !ok {
+			return nil, fmt.Errorf("sandbox of plugin '%s' was unknown", name)
+		}
+		if err := validateSandbox(sandbox); err != nil {
+			return nil, fmt.Errorf("sandbox of plugin '%s' was invalid: %w", name, err)
+		}
+	}
+
 	return manager, nil
 }
 
@@ -156,14 +174,16 @@ func (m *Manager) loadThirdPartyPlugins(pluginPath string) error {
 	}
 
 	for _, e := range entries {
-		if _, ok := m.plugins[e.Name()]; ok {
-			return fmt.Errorf("multiple plugins with name '%s'", e.Name())
+		// WASM plugins are named by their file, without the extension.
+		name := strings.TrimSuffix(e.Name(), wasmExtension)
+		if _, ok := m.plugins[name]; ok {
+			return fmt.Errorf("multiple plugins with name '%s'", name)
 		}
-		if err := validatePluginName(e.Name()); err != nil {
+		if err := validatePluginName(name); err != nil {
 			return err
 		}
-		m.plugins[e.Name()] = Info{
-			Name:       e.Name(),
+		m.plugins[name] = Info{
+			Name:       name,
 			IsBuiltin:  false,
 			BinaryPath: path.Join(pluginPath, e.Name()),
 		}
@@ -248,8 +268,9 @@ func (m *Manager) NewStep(
 		}
 		p, err := newPluginExecutor(
 			execCtx,
-			plugin.GetPlugin(), step.Tag, plugin.Tag, plugin.Config, info.BinaryPath,
+			plugin.GetPlugin(), name, step.Tag, plugin.Tag, plugin.Config, info.BinaryPath,
 			info.Args,
+			newSandbox(m.sandboxes[plugin.GetPlugin()]),
 			logger,
 			m.restConfig,
 		)
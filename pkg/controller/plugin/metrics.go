@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	stepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "rig",
+		Subsystem: "pipeline",
+		Name:      "step_duration_seconds",
+		Help:      "Duration of running a step of the pipeline for a capsule.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"step"})
+
+	stepErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rig",
+		Subsystem: "pipeline",
+		Name:      "step_errors_total",
+		Help:      "Number of times a step of the pipeline failed.",
+	}, []string{"step"})
+
+	pluginDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "rig",
+		Subsystem: "pipeline",
+		Name:      "plugin_duration_seconds",
+		Help:      "Duration of running a plugin of a step for a capsule.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"step", "plugin"})
+
+	pluginErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rig",
+		Subsystem: "pipeline",
+		Name:      "plugin_errors_total",
+		Help:      "Number of times a plugin of a step failed.",
+	}, []string{"step", "plugin"})
+
+	sandboxViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rig",
+		Subsystem: "pipeline",
+		Name:      "plugin_sandbox_violations_total",
+		Help:      "Number of requests of a plugin rejected by its sandbox.",
+	}, []string{"plugin"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(stepDuration, stepErrors, pluginDuration, pluginErrors, sandboxViolations)
+}
@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rigdev/rig/pkg/api/config/v1alpha1"
+)
+
+// OrderSteps returns the indices of the steps in the order they must be executed.
+// Steps are executed in the order they are given, except where required otherwise
+// by their After and Before constraints, which refer to the tags of other steps.
+// Returns an error if a constraint refers to an unknown tag or if the constraints
+// are cyclic.
+func OrderSteps(steps []v1alpha1.Step) ([]int, error) {
+	tagged := map[string][]int{}
+	for idx, step := range steps {
+		if step.Tag != "" {
+			tagged[step.Tag] = append(tagged[step.Tag], idx)
+		}
+	}
+
+	// edges[i] are the steps which must run after step i.
+	edges := make([][]int, len(steps))
+	inDegree := make([]int, len(steps))
+	addEdge := func(from, to int) {
+		if from == to || slices.Contains(edges[from], to) {
+			return
+		}
+		edges[from] = append(edges[from], to)
+		inDegree[to]++
+	}
+
+	for idx, step := range steps {
+		for _, tag := range step.After {
+			others, ok := tagged[tag]
+			if !ok {
+				return nil, fmt.Errorf("step #%d must run after unknown tag '%s'", idx, tag)
+			}
+			for _, other := range others {
+				addEdge(other, idx)
+			}
+		}
+		for _, tag := range step.Before {
+			others, ok := tagged[tag]
+			if !ok {
+				return nil, fmt.Errorf("step #%d must run before unknown tag '%s'", idx, tag)
+			}
+			for _, other := range others {
+				addEdge(idx, other)
+			}
+		}
+	}
+
+	// Kahn's algorithm, always picking the first ready step to keep the order stable.
+	order := make([]int, 0, len(steps))
+	done := make([]bool, len(steps))
+	for len(order) < len(steps) {
+		next := -1
+		for idx := range steps {
+			if !done[idx] && inDegree[idx] == 0 {
+				next = idx
+				break
+			}
+		}
+
+		if next < 0 {
+			var cycle []string
+			for idx := range steps {
+				if !done[idx] {
+					cycle = append(cycle, fmt.Sprintf("#%d", idx))
+				}
+			}
+			return nil, fmt.Errorf("the ordering of steps %s is cyclic", strings.Join(cycle, ", "))
+		}
+
+		done[next] = true
+		order = append(order, next)
+		for _, to := range edges[next] {
+			inDegree[to]--
+		}
+	}
+
+	return order, nil
+}
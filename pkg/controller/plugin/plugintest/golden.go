@@ -0,0 +1,181 @@
+package plugintest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/homeport/dyff/pkg/dyff"
+	"github.com/rigdev/rig/pkg/obj"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"golang.org/x/exp/maps"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// UpdateGoldenEnv is the environment variable which, when set to a non-empty value,
+// makes AssertGolden write the golden files instead of comparing against them.
+const UpdateGoldenEnv = "RIG_UPDATE_GOLDEN"
+
+const documentSeparator = "---\n"
+
+// Encode returns the objects of the result as a YAML document per object.
+func (r *Result) Encode() ([]byte, error) {
+	return EncodeObjects(r.Objects, r.scheme)
+}
+
+// Diff compares the objects of the result with the expected objects. Returns a
+// human-readable report of the differences, which is empty if there are none.
+func (r *Result) Diff(expected []client.Object) (string, error) {
+	return DiffObjects(expected, r.Objects, r.scheme)
+}
+
+// AssertGolden compares the objects of the result with the objects of the golden
+// file at path, failing the test if they differ. If UpdateGoldenEnv is set, the
+// golden file is written with the objects of the result instead.
+func (r *Result) AssertGolden(t testing.TB, path string) {
+	t.Helper()
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		bs, err := r.Encode()
+		if err != nil {
+			t.Fatalf("could not encode objects: %v", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("could not create golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, bs, 0o644); err != nil {
+			t.Fatalf("could not write golden file: %v", err)
+		}
+		return
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read golden file, run with %s=true to create it: %v", UpdateGoldenEnv, err)
+	}
+
+	expected, err := DecodeObjects(bs, r.scheme)
+	if err != nil {
+		t.Fatalf("could not decode golden file '%s': %v", path, err)
+	}
+
+	report, err := r.Diff(expected)
+	if err != nil {
+		t.Fatalf("could not compare with golden file '%s': %v", path, err)
+	}
+	if report != "" {
+		t.Errorf("objects differ from golden file '%s', run with %s=true to update it:\n%s",
+			path, UpdateGoldenEnv, report)
+	}
+}
+
+// EncodeObjects encodes the objects as a YAML document per object.
+func EncodeObjects(objects []client.Object, scheme *runtime.Scheme) ([]byte, error) {
+	var buffer bytes.Buffer
+	for i, co := range objects {
+		bs, err := obj.Encode(co, scheme)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buffer.WriteString(documentSeparator)
+		}
+		buffer.Write(bs)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// DecodeObjects decodes objects from YAML documents, as encoded by EncodeObjects.
+func DecodeObjects(bs []byte, scheme *runtime.Scheme) ([]client.Object, error) {
+	var objects []client.Object
+	for _, doc := range strings.Split(string(bs), documentSeparator) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+
+		co, err := obj.DecodeAny([]byte(doc), scheme)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, co)
+	}
+
+	return objects, nil
+}
+
+// DiffObjects compares the objects by their type, namespace and name, using an
+// obj.Comparison for each pair. Returns a human-readable report of the differences,
+// which is empty if there are none.
+func DiffObjects(from, to []client.Object, scheme *runtime.Scheme) (string, error) {
+	fromObjects, err := objectsByKey(from, scheme)
+	if err != nil {
+		return "", err
+	}
+	toObjects, err := objectsByKey(to, scheme)
+	if err != nil {
+		return "", err
+	}
+
+	keys := maps.Keys(fromObjects)
+	for key := range toObjects {
+		if _, ok := fromObjects[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	var out bytes.Buffer
+	for _, key := range keys {
+		fromObject, inFrom := fromObjects[key]
+		toObject, inTo := toObjects[key]
+		switch {
+		case !inTo:
+			fmt.Fprintf(&out, "%s: missing\n", key)
+			continue
+		case !inFrom:
+			fmt.Fprintf(&out, "%s: unexpected\n", key)
+			continue
+		}
+
+		diff, err := obj.NewComparison(fromObject, toObject, scheme).ComputeDiff()
+		if err != nil {
+			return "", err
+		}
+		if len(diff.Report.Diffs) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&out, "%s:\n", key)
+		hr := &dyff.HumanReport{
+			Report:     *diff.Report,
+			OmitHeader: true,
+		}
+		if err := hr.WriteReport(&out); err != nil {
+			return "", err
+		}
+	}
+
+	return out.String(), nil
+}
+
+func objectsByKey(objects []client.Object, scheme *runtime.Scheme) (map[string]client.Object, error) {
+	res := map[string]client.Object{}
+	for _, co := range objects {
+		co = co.DeepCopyObject().(client.Object)
+		if co.GetObjectKind().GroupVersionKind().Empty() {
+			gvks, _, err := scheme.ObjectKinds(co)
+			if err != nil {
+				return nil, err
+			}
+			co.GetObjectKind().SetGroupVersionKind(gvks[0])
+		}
+		res[pipeline.ObjectKeyFromObject(co).String()] = co
+	}
+	return res, nil
+}
@@ -0,0 +1,305 @@
+// Package plugintest runs rig plugins in-process, so plugins can be tested without
+// a rig-operator or a Kubernetes cluster.
+//
+// A Harness is created from a Plugin, the Capsule to run it for and the configuration
+// of the plugin:
+//
+//	h, err := plugintest.New(&Plugin{}, capsuleYAML, `label: team`,
+//		plugintest.WithNewObjects(deployment),
+//	)
+//	res, err := h.Run(ctx)
+//	res.AssertGolden(t, "testdata/team.golden.yaml")
+package plugintest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-hclog"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/controller/plugin"
+	"github.com/rigdev/rig/pkg/obj"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/roclient"
+	"github.com/rigdev/rig/pkg/scheme"
+	"github.com/rigdev/rig/pkg/uuid"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Harness runs a Plugin for a single Capsule.
+type Harness struct {
+	plugin   plugin.Plugin
+	capsule  *v1alpha2.Capsule
+	scheme   *runtime.Scheme
+	vm       scheme.VersionMapper
+	logger   hclog.Logger
+	tag      string
+	existing []client.Object
+	objects  []client.Object
+	opts     pipeline.Options
+}
+
+// Option configures a Harness.
+type Option func(h *Harness)
+
+// WithExistingObjects adds objects owned by the Capsule, which are currently present in the cluster.
+func WithExistingObjects(objects ...client.Object) Option {
+	return func(h *Harness) {
+		h.existing = append(h.existing, objects...)
+	}
+}
+
+// WithNewObjects adds objects about to be applied, as set by the steps running before the plugin.
+func WithNewObjects(objects ...client.Object) Option {
+	return func(h *Harness) {
+		h.objects = append(h.objects, objects...)
+	}
+}
+
+// WithAdditionalObjects adds objects given to the plugin through the pipeline options,
+// as done by dry-runs.
+func WithAdditionalObjects(objects ...client.Object) Option {
+	return func(h *Harness) {
+		h.opts.AdditionalObjects = append(h.opts.AdditionalObjects, objects...)
+	}
+}
+
+// WithTag sets the tag the plugin is initialized with.
+func WithTag(tag string) Option {
+	return func(h *Harness) {
+		h.tag = tag
+	}
+}
+
+// WithLogger sets the logger given to the plugin. Defaults to discarding all logs.
+func WithLogger(logger hclog.Logger) Option {
+	return func(h *Harness) {
+		h.logger = logger
+	}
+}
+
+// New creates a Harness running the plugin for the Capsule, given as YAML. The plugin
+// is initialized with the given configuration.
+func New(p plugin.Plugin, capsuleYAML string, config string, opts ...Option) (*Harness, error) {
+	s := scheme.New()
+	capsule, err := obj.DecodeIntoT([]byte(capsuleYAML), &v1alpha2.Capsule{}, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid capsule: %w", err)
+	}
+	if capsule.GetNamespace() == "" {
+		capsule.SetNamespace("default")
+	}
+	if capsule.Annotations == nil {
+		capsule.Annotations = map[string]string{}
+	}
+	if capsule.Labels == nil {
+		capsule.Labels = map[string]string{}
+	}
+
+	h := &Harness{
+		plugin:  p,
+		capsule: capsule,
+		scheme:  s,
+		vm:      scheme.NewVersionMapperFromScheme(s),
+		logger:  hclog.NewNullLogger(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	for i, co := range h.existing {
+		if h.existing[i], err = h.ownedObject(co); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.Initialize(plugin.NewInitializeRequest([]byte(config), h.tag, s)); err != nil {
+		return nil, fmt.Errorf("could not initialize plugin: %w", err)
+	}
+
+	return h, nil
+}
+
+// ownedObject returns a copy of the object, marked as owned by the Capsule.
+func (h *Harness) ownedObject(co client.Object) (client.Object, error) {
+	co = co.DeepCopyObject().(client.Object)
+	gvks, _, err := h.scheme.ObjectKinds(co)
+	if err != nil {
+		return nil, err
+	}
+	gvk := gvks[0]
+	co.GetObjectKind().SetGroupVersionKind(gvk)
+
+	if co.GetName() == "" {
+		co.SetName(h.capsule.GetName())
+	}
+	co.SetNamespace(h.capsule.GetNamespace())
+	labels := co.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[pipeline.LabelOwnedByCapsule] = h.capsule.GetName()
+	co.SetLabels(labels)
+
+	if h.capsule.Status == nil {
+		h.capsule.Status = &v1alpha2.CapsuleStatus{}
+	}
+	ref := &corev1.TypedLocalObjectReference{
+		Kind: gvk.Kind,
+		Name: co.GetName(),
+	}
+	if gvk.Group != "" {
+		ref.APIGroup = &gvk.Group
+	}
+	h.capsule.Status.OwnedResources = append(h.capsule.Status.OwnedResources, v1alpha2.OwnedResource{
+		Ref:   ref,
+		State: string(pipeline.ResourceStateUnchanged),
+	})
+
+	return co, nil
+}
+
+// Scheme returns the scheme used for the objects of the plugin.
+func (h *Harness) Scheme() *runtime.Scheme {
+	return h.scheme
+}
+
+// Capsule returns a copy of the Capsule the plugin is run for.
+func (h *Harness) Capsule() *v1alpha2.Capsule {
+	return h.capsule.DeepCopy()
+}
+
+// Result is the outcome of running a plugin.
+type Result struct {
+	// Objects are the objects about to be applied after running the plugin, sorted by their key.
+	Objects []client.Object
+	// Deleted are the existing objects which are deleted after running the plugin.
+	Deleted []client.Object
+
+	scheme *runtime.Scheme
+}
+
+// Run runs the plugin and returns the objects about to be applied afterwards.
+func (h *Harness) Run(ctx context.Context) (*Result, error) {
+	var res *Result
+	if err := h.execute(ctx, func(ctx context.Context, req pipeline.CapsuleRequest) error {
+		if err := h.plugin.Run(ctx, req, h.logger); err != nil {
+			return err
+		}
+
+		base, ok := req.(pipeline.ExecutableRequest[pipeline.CapsuleRequest])
+		if !ok {
+			return fmt.Errorf("capsule request of type %T is not executable", req)
+		}
+
+		res = &Result{
+			Objects: base.GetBase().ListAllNew(),
+			scheme:  h.scheme,
+		}
+		for _, co := range h.existing {
+			if _, err := req.GetNew(co.GetObjectKind().GroupVersionKind(), co.GetName()); err == nil {
+				continue
+			}
+			res.Deleted = append(res.Deleted, co.DeepCopyObject().(client.Object))
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ComputeConfig returns the configuration the plugin computes for the Capsule.
+func (h *Harness) ComputeConfig(ctx context.Context) (string, error) {
+	var config string
+	if err := h.execute(ctx, func(ctx context.Context, req pipeline.CapsuleRequest) error {
+		var err error
+		config, err = h.plugin.ComputeConfig(ctx, req, h.logger)
+		return err
+	}); err != nil {
+		return "", err
+	}
+
+	return config, nil
+}
+
+// execute runs fn as a step of a dry-run pipeline, after setting the new objects
+// of the harness. The existing objects are read through a roclient.Reader.
+func (h *Harness) execute(
+	ctx context.Context,
+	fn func(ctx context.Context, req pipeline.CapsuleRequest) error,
+) error {
+	reader := roclient.NewReader(h.scheme)
+	for _, co := range h.existing {
+		if err := reader.AddObject(co.DeepCopyObject().(client.Object)); err != nil {
+			return err
+		}
+	}
+
+	p := pipeline.NewCapsulePipeline(nil, h.scheme, h.vm, logr.Discard())
+	req := pipeline.NewCapsuleRequest(p, h.capsule.DeepCopy(), readerClient{reader: reader}, pipeline.WithDryRun())
+	ereq, ok := req.(pipeline.ExecutableRequest[pipeline.CapsuleRequest])
+	if !ok {
+		return fmt.Errorf("capsule request of type %T is not executable", req)
+	}
+
+	steps := []pipeline.Step[pipeline.CapsuleRequest]{
+		&step{name: "plugintest-objects", fn: func(_ context.Context, req pipeline.CapsuleRequest) error {
+			for _, co := range h.objects {
+				if err := req.Set(co.DeepCopyObject().(client.Object)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		&step{name: "plugintest-plugin", fn: fn},
+	}
+
+	_, err := pipeline.ExecuteRequest(ctx, ereq, steps, false, h.opts)
+	return err
+}
+
+// readerClient is a client.Client serving reads from a client.Reader. Writing is not
+// supported, which is never done by dry-runs.
+type readerClient struct {
+	client.Client
+	reader client.Reader
+}
+
+func (c readerClient) Get(ctx context.Context, key client.ObjectKey, co client.Object, opts ...client.GetOption) error {
+	return c.reader.Get(ctx, key, co, opts...)
+}
+
+func (c readerClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.reader.List(ctx, list, opts...)
+}
+
+// step runs a function as a step of the pipeline.
+type step struct {
+	name string
+	fn   func(ctx context.Context, req pipeline.CapsuleRequest) error
+}
+
+func (s *step) Name() string {
+	return s.name
+}
+
+func (s *step) Apply(ctx context.Context, req pipeline.CapsuleRequest, _ pipeline.Options) error {
+	return s.fn(ctx, req)
+}
+
+func (s *step) WatchObjectStatus(context.Context, *v1alpha2.Capsule, pipeline.ObjectStatusCallback) error {
+	return nil
+}
+
+func (s *step) ComputeConfig(context.Context, pipeline.CapsuleRequest) pipeline.StepConfigResult {
+	return pipeline.StepConfigResult{Name: s.name}
+}
+
+func (s *step) PluginIDs() []uuid.UUID {
+	return nil
+}
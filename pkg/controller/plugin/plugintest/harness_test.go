@@ -0,0 +1,135 @@
+package plugintest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	"github.com/rigdev/rig/pkg/controller/plugin"
+	"github.com/rigdev/rig/plugins/builtin/annotations"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const capsuleYAML = `
+apiVersion: rig.dev/v1alpha2
+kind: Capsule
+metadata:
+  name: test
+  namespace: prod
+spec:
+  image: nginx
+`
+
+func Test_Run(t *testing.T) {
+	h, err := New(&annotations.Plugin{}, capsuleYAML, `
+kind: Deployment
+group: apps
+annotations:
+  image: "{{ .capsule.spec.image }}"`,
+		WithNewObjects(&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "prod"},
+		}),
+		WithExistingObjects(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		}),
+	)
+	require.NoError(t, err)
+
+	res, err := h.Run(context.Background())
+	require.NoError(t, err)
+	res.AssertGolden(t, "testdata/annotations.golden.yaml")
+
+	require.Len(t, res.Deleted, 1)
+	require.Equal(t, "ConfigMap", res.Deleted[0].GetObjectKind().GroupVersionKind().Kind)
+
+	report, err := res.Diff(nil)
+	require.NoError(t, err)
+	require.Equal(t, "apps/v1, Kind=Deployment/prod/test: unexpected\n", report)
+
+	expected, err := DecodeObjects([]byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  namespace: prod
+  annotations:
+    image: redis
+`), h.Scheme())
+	require.NoError(t, err)
+	report, err = res.Diff(expected)
+	require.NoError(t, err)
+	require.Contains(t, report, "redis")
+	require.Contains(t, report, "nginx")
+
+	config, err := h.ComputeConfig(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, config, "image: nginx")
+}
+
+func Test_RunError(t *testing.T) {
+	h, err := New(&annotations.Plugin{}, capsuleYAML, `kind: Deployment
+group: apps`)
+	require.NoError(t, err)
+
+	_, err = h.Run(context.Background())
+	require.ErrorContains(t, err, "step plugintest-plugin failed")
+
+	_, err = New(&annotations.Plugin{}, "spec: [", "")
+	require.ErrorContains(t, err, "invalid capsule")
+}
+
+type configMapPlugin struct {
+	annotations.Plugin
+}
+
+func (p *configMapPlugin) WatchObjectStatus(ctx context.Context, watcher plugin.CapsuleWatcher) error {
+	return watcher.WatchPrimary(ctx, &corev1.ConfigMap{}, func(
+		co client.Object,
+		_ []*corev1.Event,
+		_ plugin.ObjectWatcher,
+	) *apipipeline.ObjectStatusInfo {
+		return &apipipeline.ObjectStatusInfo{
+			Properties: map[string]string{"data": co.(*corev1.ConfigMap).Data["key"]},
+		}
+	})
+}
+
+func Test_WatchObjectStatus(t *testing.T) {
+	h, err := New(&configMapPlugin{}, capsuleYAML, "",
+		WithExistingObjects(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Data:       map[string]string{"key": "value"},
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	w, err := h.WatchObjectStatus(ctx)
+	require.NoError(t, err)
+	defer w.Stop()
+
+	require.Len(t, w.Initial, 1)
+	require.Equal(t, "test", w.Initial[0].GetObjectRef().GetName())
+	require.Equal(t, "value", w.Initial[0].GetInfo().GetProperties()["data"])
+
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, w.Client.Get(ctx, client.ObjectKey{Namespace: "prod", Name: "test"}, cm))
+	cm.Data["key"] = "updated"
+	require.NoError(t, w.Client.Update(ctx, cm))
+
+	change, err := w.Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "updated", change.GetUpdated().GetInfo().GetProperties()["data"])
+
+	h, err = New(&annotations.Plugin{}, capsuleYAML, "")
+	require.NoError(t, err)
+	_, err = h.WatchObjectStatus(ctx)
+	require.ErrorContains(t, err, "watch object status not available in plugin")
+}
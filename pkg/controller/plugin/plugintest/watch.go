@@ -0,0 +1,101 @@
+package plugintest
+
+import (
+	"context"
+	"fmt"
+
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	apiplugin "github.com/rigdev/rig-go-api/operator/api/v1/plugin"
+	"github.com/rigdev/rig/pkg/controller/plugin"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Watch is a running WatchObjectStatus of a plugin, backed by informers on a fake client.
+type Watch struct {
+	// Client is the fake client the plugin watches. Objects created, updated or deleted
+	// through it result in status changes.
+	Client client.WithWatch
+	// Initial are the statuses of the objects present when the watch started.
+	Initial []*apipipeline.ObjectStatus
+
+	changes chan *apiplugin.ObjectStatusChange
+	errs    chan error
+	cancel  context.CancelFunc
+}
+
+// WatchObjectStatus starts watching the status of the objects of the Capsule with the
+// plugin, using a fake client containing the existing objects of the harness.
+// Returns once the statuses of the existing objects are known. The watch runs until
+// the context is cancelled or Stop is called.
+func (h *Harness) WatchObjectStatus(ctx context.Context) (*Watch, error) {
+	cc := fake.NewClientBuilder().
+		WithScheme(h.scheme).
+		WithObjects(h.existing...).
+		WithIndex(&corev1.Event{}, "involvedObject.apiVersion", func(co client.Object) []string {
+			return []string{co.(*corev1.Event).InvolvedObject.APIVersion}
+		}).
+		WithIndex(&corev1.Event{}, "involvedObject.kind", func(co client.Object) []string {
+			return []string{co.(*corev1.Event).InvolvedObject.Kind}
+		}).
+		Build()
+
+	ctx, cancel := context.WithCancel(ctx)
+	w := &Watch{
+		Client:  cc,
+		changes: make(chan *apiplugin.ObjectStatusChange, 32),
+		errs:    make(chan error, 1),
+		cancel:  cancel,
+	}
+
+	cw := plugin.NewWatcher(h.logger, cc).
+		NewCapsuleWatcher(ctx, h.capsule.GetNamespace(), h.capsule.GetName(), w.changes)
+	go func() {
+		w.errs <- h.plugin.WatchObjectStatus(ctx, cw)
+	}()
+
+	change, err := w.next(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	all := change.GetAllObjects()
+	if all == nil {
+		cancel()
+		return nil, fmt.Errorf("expected status of all objects, got %v", change)
+	}
+	w.Initial = all.GetObjects()
+
+	return w, nil
+}
+
+// Next returns the next change of the status of an object.
+func (w *Watch) Next(ctx context.Context) (*apiplugin.ObjectStatusChange, error) {
+	return w.next(ctx)
+}
+
+func (w *Watch) next(ctx context.Context) (*apiplugin.ObjectStatusChange, error) {
+	for {
+		select {
+		case change := <-w.changes:
+			if change.GetCheckpoint() != nil {
+				continue
+			}
+			return change, nil
+		case err := <-w.errs:
+			if err == nil {
+				err = fmt.Errorf("plugin stopped watching object status")
+			}
+			return nil, err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Stop stops the watch.
+func (w *Watch) Stop() {
+	w.cancel()
+}
@@ -0,0 +1,157 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/rigdev/rig/pkg/api/config/v1alpha1"
+	"github.com/rigdev/rig/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+const defaultPluginTimeout = 10 * time.Second
+
+// sandbox limits what a plugin can do, as configured by its PluginSandbox.
+type sandbox struct {
+	timeout        time.Duration
+	allowedObjects []v1alpha1.ObjectType
+	readOnly       bool
+}
+
+func newSandbox(cfg v1alpha1.PluginSandbox) sandbox {
+	s := sandbox{
+		timeout:        defaultPluginTimeout,
+		allowedObjects: cfg.AllowedObjects,
+		readOnly:       cfg.ClientAccess == v1alpha1.PluginClientAccessReadOnly,
+	}
+	if cfg.TimeoutSeconds > 0 {
+		s.timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	return s
+}
+
+func validateSandbox(cfg v1alpha1.PluginSandbox) error {
+	switch cfg.ClientAccess {
+	case "", v1alpha1.PluginClientAccessReadWrite, v1alpha1.PluginClientAccessReadOnly:
+	default:
+		return fmt.Errorf("unknown client access '%s'", cfg.ClientAccess)
+	}
+
+	for _, t := range cfg.AllowedObjects {
+		if t.Kind == "" {
+			return fmt.Errorf("allowed object of group '%s' has no kind", t.Group)
+		}
+	}
+
+	return nil
+}
+
+// allows returns true if the plugin can set and delete objects of the given type.
+func (s sandbox) allows(gvk schema.GroupVersionKind) bool {
+	if len(s.allowedObjects) == 0 {
+		return true
+	}
+
+	for _, t := range s.allowedObjects {
+		if t.Group == gvk.Group && (t.Kind == "*" || t.Kind == gvk.Kind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// violations collects the requests of a plugin rejected by its sandbox, so they
+// are reported even if the plugin ignores the errors.
+type violations struct {
+	lock sync.Mutex
+	errs []error
+}
+
+func (v *violations) add(err error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.errs = append(v.errs, err)
+}
+
+func (v *violations) err() error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return errors.PermissionDeniedErrorf("plugin violated its sandbox: %v", v.errs[0])
+}
+
+// startReadOnlyProxy serves a proxy to the Kubernetes API on localhost, which only
+// forwards requests reading objects. Returns the config for connecting to the proxy,
+// which doesn't contain the credentials of the operator.
+func startReadOnlyProxy(
+	ctx context.Context,
+	restConfig *rest.Config,
+	pluginName string,
+	logger logr.Logger,
+) (*rest.Config, error) {
+	target, _, err := rest.DefaultServerUrlFor(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := rest.TransportFor(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = transport
+	// Flush immediately, as watches are streamed.
+	proxy.FlushInterval = -1
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				proxy.ServeHTTP(w, r)
+				return
+			}
+
+			logger.Info("rejected request of plugin with read-only access", "method", r.Method, "path", r.URL.Path)
+			sandboxViolations.WithLabelValues(pluginName).Inc()
+
+			status := kerrors.NewForbidden(schema.GroupResource{}, "",
+				fmt.Errorf("plugin '%s' has read-only access", pluginName)).Status()
+			status.APIVersion, status.Kind = "v1", "Status"
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(status)
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error(err, "read-only proxy of plugin failed")
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	return &rest.Config{
+		Host: "http://" + listener.Addr().String(),
+	}, nil
+}
@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	apiplugin "github.com/rigdev/rig-go-api/operator/api/v1/plugin"
+	"github.com/rigdev/rig/pkg/api/config/v1alpha1"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/obj"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/scheme"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+func Test_sandboxAllows(t *testing.T) {
+	s := newSandbox(v1alpha1.PluginSandbox{
+		AllowedObjects: []v1alpha1.ObjectType{
+			{Kind: "ConfigMap"},
+			{Group: "apps", Kind: "*"},
+		},
+	})
+
+	assert.True(t, s.allows(corev1.SchemeGroupVersion.WithKind("ConfigMap")))
+	assert.True(t, s.allows(appsv1.SchemeGroupVersion.WithKind("Deployment")))
+	assert.True(t, s.allows(appsv1.SchemeGroupVersion.WithKind("StatefulSet")))
+	assert.False(t, s.allows(corev1.SchemeGroupVersion.WithKind("Secret")))
+	assert.False(t, s.allows(v1alpha2.GroupVersion.WithKind("Capsule")))
+
+	assert.True(t, newSandbox(v1alpha1.PluginSandbox{}).allows(corev1.SchemeGroupVersion.WithKind("Secret")))
+	assert.Equal(t, defaultPluginTimeout, newSandbox(v1alpha1.PluginSandbox{}).timeout)
+}
+
+func Test_validateSandbox(t *testing.T) {
+	require.NoError(t, validateSandbox(v1alpha1.PluginSandbox{
+		ClientAccess:   v1alpha1.PluginClientAccessReadOnly,
+		AllowedObjects: []v1alpha1.ObjectType{{Group: "apps", Kind: "Deployment"}},
+	}))
+	require.Error(t, validateSandbox(v1alpha1.PluginSandbox{ClientAccess: "admin"}))
+	require.Error(t, validateSandbox(v1alpha1.PluginSandbox{AllowedObjects: []v1alpha1.ObjectType{{Group: "apps"}}}))
+}
+
+func Test_requestServerSandbox(t *testing.T) {
+	vm := scheme.NewVersionMapperFromScheme(scheme.New())
+	p := pipeline.NewCapsulePipeline(nil, scheme.New(), vm, logr.Discard())
+	req := pipeline.NewCapsuleRequest(p, &v1alpha2.Capsule{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+	}, nil)
+
+	s := newRequestServer(req, "myorg.plugin", newSandbox(v1alpha1.PluginSandbox{
+		AllowedObjects: []v1alpha1.ObjectType{{Kind: "ConfigMap"}, {Group: "example.com", Kind: "Widget"}},
+	}))
+
+	cm := &corev1.ConfigMap{}
+	cm.SetName("test")
+	bs, err := obj.Encode(cm, req.Scheme())
+	require.NoError(t, err)
+	_, err = s.SetObject(context.Background(), &apiplugin.SetObjectRequest{
+		Gvk:    &apiplugin.GVK{Version: "v1", Kind: "ConfigMap"},
+		Object: bs,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.violations.err())
+
+	// Objects are decoded as the kind given in their bytes, which must match the
+	// kind checked by the sandbox.
+	for _, bs := range []string{
+		"apiVersion: v1\nkind: Secret\nmetadata:\n  name: test\n",
+		"apiVersion: example.com/v1\nkind: Secret\nmetadata:\n  name: test\n",
+	} {
+		_, err = s.SetObject(context.Background(), &apiplugin.SetObjectRequest{
+			Gvk:    &apiplugin.GVK{Version: "v1", Kind: "ConfigMap"},
+			Object: []byte(bs),
+		})
+		require.True(t, errors.IsPermissionDenied(err))
+	}
+	_, err = s.SetObject(context.Background(), &apiplugin.SetObjectRequest{
+		Gvk:    &apiplugin.GVK{Group: "example.com", Version: "v1", Kind: "Widget"},
+		Object: []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: test\n"),
+	})
+	require.True(t, errors.IsPermissionDenied(err))
+	require.ErrorContains(t, err, "plugin is not allowed to set an object of kind 'Secret' as kind 'Widget.example.com'")
+
+	s.violations = &violations{}
+	_, err = s.DeleteObject(context.Background(), &apiplugin.DeleteObjectRequest{
+		Gvk:  &apiplugin.GVK{Version: "v1", Kind: "Secret"},
+		Name: "test",
+	})
+	require.True(t, errors.IsPermissionDenied(err))
+
+	err = s.violations.err()
+	require.True(t, errors.IsPermissionDenied(err))
+	require.ErrorContains(t, err, "plugin is not allowed to delete objects of kind 'Secret'")
+}
+
+func Test_startReadOnlyProxy(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer operator-token", r.Header.Get("Authorization"))
+		_, _ = io.WriteString(w, r.Method+" "+r.URL.Path)
+	}))
+	defer api.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg, err := startReadOnlyProxy(ctx, &rest.Config{
+		Host:        api.URL,
+		BearerToken: "operator-token",
+	}, "myorg.plugin", logr.Discard())
+	require.NoError(t, err)
+	require.Empty(t, cfg.BearerToken)
+
+	res, err := http.Get(cfg.Host + "/api/v1/namespaces/default/configmaps")
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "GET /api/v1/namespaces/default/configmaps", string(body))
+
+	res, err = http.Post(cfg.Host+"/api/v1/namespaces/default/configmaps", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+	require.Contains(t, string(body), "plugin 'myorg.plugin' has read-only access")
+}
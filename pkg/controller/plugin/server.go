@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
@@ -14,6 +15,7 @@ import (
 	"github.com/rigdev/rig/pkg/pipeline"
 	"github.com/rigdev/rig/pkg/roclient"
 	"github.com/rigdev/rig/pkg/scheme"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
@@ -117,6 +119,7 @@ func (m *GRPCServer) RunCapsule(
 		cc:      m.cc,
 		vm:      m.vm,
 		cr:      roclient.NewLayeredReader(reader, m.cc),
+		now:     requestTime(req.GetNow()),
 	}, m.logger); err != nil {
 		return nil, err
 	}
@@ -190,6 +193,7 @@ func (m *GRPCServer) ComputeConfig(
 		cc:      m.cc,
 		vm:      m.vm,
 		cr:      roclient.NewLayeredReader(reader, m.cc),
+		now:     requestTime(req.GetNow()),
 	}, m.logger)
 	if err != nil {
 		return nil, err
@@ -209,6 +213,16 @@ type capsuleRequestClient struct {
 	cc      client.Client
 	cr      client.Reader
 	vm      scheme.VersionMapper
+	now     time.Time
+}
+
+// requestTime returns the time a request is executed at. Operators which
+// don't send the time execute requests at the current time.
+func requestTime(now *timestamppb.Timestamp) time.Time {
+	if now == nil {
+		return time.Now()
+	}
+	return now.AsTime()
 }
 
 func (c *capsuleRequestClient) getGVK(obj client.Object) (schema.GroupVersionKind, error) {
@@ -233,6 +247,10 @@ func (c *capsuleRequestClient) Capsule() *v1alpha2.Capsule {
 	return c.capsule
 }
 
+func (c *capsuleRequestClient) Now() time.Time {
+	return c.now
+}
+
 func fromGVK(gvk schema.GroupVersionKind) *apiplugin.GVK {
 	return &apiplugin.GVK{
 		Group:   gvk.Group,
@@ -435,6 +453,16 @@ type InitializeRequest struct {
 	scheme *runtime.Scheme
 }
 
+// NewInitializeRequest creates an InitializeRequest for initializing a plugin outside
+// of the rig-operator, e.g. when testing it.
+func NewInitializeRequest(config []byte, tag string, scheme *runtime.Scheme) InitializeRequest {
+	return InitializeRequest{
+		Config: config,
+		Tag:    tag,
+		scheme: scheme,
+	}
+}
+
 func (r InitializeRequest) Scheme() *runtime.Scheme {
 	return r.scheme
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/gobwas/glob"
@@ -39,9 +40,20 @@ func makeGlobs(strings []string) ([]glob.Glob, error) {
 
 func (s *Step) Apply(ctx context.Context, req pipeline.CapsuleRequest, opts pipeline.Options) error {
 	c := req.Capsule()
-	if !s.matcher.Match(c.Namespace, c.Name, c.Annotations) {
+	if ok, err := s.matcher.MatchCapsule(c); err != nil {
+		// Skipping the step could silently remove the objects it owns, so the
+		// capsule fails until the condition can be evaluated.
+		stepErrors.WithLabelValues(s.name).Inc()
+		return fmt.Errorf("condition of step %s could not be evaluated: %w", s.name, err)
+	} else if !ok {
 		return nil
 	}
+
+	start := time.Now()
+	defer func() {
+		stepDuration.WithLabelValues(s.name).Observe(time.Since(start).Seconds())
+	}()
+
 	for i, p := range s.plugins {
 		logger := s.logger.WithValues(
 			"step", s.name, "plugin_idx", i+1, "plugin", s.step.Plugins[i].GetPlugin(),
@@ -50,6 +62,7 @@ func (s *Step) Apply(ctx context.Context, req pipeline.CapsuleRequest, opts pipe
 		logger.Info("running plugin")
 		if err := p.Run(ctx, req, opts); err != nil {
 			logger.Error(err, "plugin failed")
+			stepErrors.WithLabelValues(s.name).Inc()
 			return fmt.Errorf("plugin #%v (%s) failed: %w", i+1, p.name, err)
 		}
 	}
@@ -62,7 +75,13 @@ func (s *Step) WatchObjectStatus(
 	callback pipeline.ObjectStatusCallback,
 ) error {
 	// TODO: We need annotations here.
-	if !s.matcher.Match(capsule.GetNamespace(), capsule.GetName(), capsule.GetAnnotations()) {
+	ok, err := s.matcher.MatchCapsule(capsule)
+	if err != nil {
+		// The step fails when the condition can't be evaluated, which keeps the
+		// objects it created previously, so their status is still watched.
+		s.logger.Info("step condition could not be evaluated", "step", s.name, "reason", err.Error(),
+			"capsule_id", capsule.GetName(), "namespace", capsule.GetNamespace())
+	} else if !ok {
 		for _, p := range s.plugins {
 			callback.UpdateStatus(capsule.GetNamespace(), capsule.GetName(), p.id, &plugin.ObjectStatusChange{
 				Change: &plugin.ObjectStatusChange_Checkpoint_{},
@@ -148,6 +167,7 @@ type Matcher struct {
 	capsules          []glob.Glob
 	selector          labels.Selector
 	enableForPlatform bool
+	condition         *condition
 }
 
 func NewMatcher(match v1alpha1.CapsuleMatch) (Matcher, error) {
@@ -166,14 +186,37 @@ func NewMatcher(match v1alpha1.CapsuleMatch) (Matcher, error) {
 	if err != nil {
 		return Matcher{}, err
 	}
+
+	var cond *condition
+	if match.Condition != "" {
+		if cond, err = newCondition(match.Condition); err != nil {
+			return Matcher{}, err
+		}
+	}
+
 	return Matcher{
 		namespaces:        nsGlobs,
 		capsules:          cGlobs,
 		selector:          s,
 		enableForPlatform: match.EnableForPlatform,
+		condition:         cond,
 	}, nil
 }
 
+// MatchCapsule returns true if the capsule matches both the filters and the
+// condition of the matcher.
+func (m Matcher) MatchCapsule(capsule *v1alpha2.Capsule) (bool, error) {
+	if !m.Match(capsule.GetNamespace(), capsule.GetName(), capsule.GetAnnotations()) {
+		return false, nil
+	}
+	if m.condition == nil {
+		return true, nil
+	}
+	return m.condition.Eval(capsule)
+}
+
+// Match returns true if the capsule matches the namespaces, names and annotations
+// of the matcher. The condition of the matcher is only evaluated by MatchCapsule.
 func (m Matcher) Match(namespace, capsule string, capsuleAnnotations map[string]string) bool {
 	if !m.selector.Matches(labels.Set(capsuleAnnotations)) {
 		return false
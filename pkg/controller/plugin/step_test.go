@@ -1,10 +1,15 @@
 package plugin
 
 import (
+	"context"
 	"testing"
 
 	"github.com/rigdev/rig/pkg/api/config/v1alpha1"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/pipeline"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type inp struct {
@@ -109,3 +114,131 @@ func Test_Matcher(t *testing.T) {
 		})
 	}
 }
+
+func Test_MatcherCondition(t *testing.T) {
+	matcher, err := NewMatcher(v1alpha1.CapsuleMatch{
+		Namespaces: []string{"prod"},
+		Condition:  `capsule.spec.image.startsWith("ghcr.io/") && capsule.metadata.labels["tier"] == "web"`,
+	})
+	require.NoError(t, err)
+
+	newCapsule := func(ns, image string, labels map[string]string) *v1alpha2.Capsule {
+		return &v1alpha2.Capsule{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "cap", Labels: labels},
+			Spec:       v1alpha2.CapsuleSpec{Image: image},
+		}
+	}
+
+	ok, err := matcher.MatchCapsule(newCapsule("prod", "ghcr.io/rigdev/rig", map[string]string{"tier": "web"}))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = matcher.MatchCapsule(newCapsule("prod", "nginx", map[string]string{"tier": "web"}))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = matcher.MatchCapsule(newCapsule("dev", "ghcr.io/rigdev/rig", map[string]string{"tier": "web"}))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = matcher.MatchCapsule(newCapsule("prod", "ghcr.io/rigdev/rig", nil))
+	assert.Error(t, err)
+	assert.False(t, ok)
+
+	_, err = NewMatcher(v1alpha1.CapsuleMatch{Condition: `capsule.spec.image.startsWith(`})
+	assert.Error(t, err)
+
+	_, err = NewMatcher(v1alpha1.CapsuleMatch{Condition: `capsule.metadata.labels["tier"]`})
+	assert.NoError(t, err)
+
+	_, err = NewMatcher(v1alpha1.CapsuleMatch{Condition: `"foo"`})
+	assert.Error(t, err)
+}
+
+type capsuleRequest struct {
+	pipeline.CapsuleRequest
+	capsule *v1alpha2.Capsule
+}
+
+func (r capsuleRequest) Capsule() *v1alpha2.Capsule {
+	return r.capsule
+}
+
+func Test_StepApplyConditionError(t *testing.T) {
+	matcher, err := NewMatcher(v1alpha1.CapsuleMatch{
+		Condition: `capsule.metadata.labels["tier"] == "web"`,
+	})
+	require.NoError(t, err)
+
+	s := &Step{matcher: matcher, name: "web"}
+	req := capsuleRequest{capsule: &v1alpha2.Capsule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "cap"},
+	}}
+
+	err = s.Apply(context.Background(), req, pipeline.Options{})
+	assert.ErrorContains(t, err, "condition of step web could not be evaluated")
+
+	req.capsule.Labels = map[string]string{"tier": "db"}
+	assert.NoError(t, s.Apply(context.Background(), req, pipeline.Options{}))
+}
+
+func Test_OrderSteps(t *testing.T) {
+	tests := []struct {
+		name     string
+		steps    []v1alpha1.Step
+		expected []int
+		err      string
+	}{
+		{
+			name:     "no constraints",
+			steps:    []v1alpha1.Step{{}, {}, {}},
+			expected: []int{0, 1, 2},
+		},
+		{
+			name: "after",
+			steps: []v1alpha1.Step{
+				{Tag: "a", After: []string{"c"}},
+				{Tag: "b"},
+				{Tag: "c"},
+			},
+			expected: []int{1, 2, 0},
+		},
+		{
+			name: "before",
+			steps: []v1alpha1.Step{
+				{Tag: "a"},
+				{Tag: "b"},
+				{Tag: "c", Before: []string{"b"}},
+			},
+			expected: []int{0, 2, 1},
+		},
+		{
+			name: "unknown tag",
+			steps: []v1alpha1.Step{
+				{Tag: "a", After: []string{"b"}},
+			},
+			err: "step #0 must run after unknown tag 'b'",
+		},
+		{
+			name: "cycle",
+			steps: []v1alpha1.Step{
+				{Tag: "a", After: []string{"b"}},
+				{Tag: "b", After: []string{"a"}},
+				{},
+			},
+			err: "the ordering of steps #0, #1 is cyclic",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := OrderSteps(tt.steps)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, order)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+//go:build wasip1
+
+// A WASM plugin used by the tests of the plugin runtime. It sets a label, given
+// by its configuration, on the ServiceAccount of the capsule.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/rigdev/rig/pkg/controller/plugin/wasm"
+)
+
+type config struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+type object struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+}
+
+type labelPlugin struct {
+	config config
+}
+
+func (p *labelPlugin) Initialize(req wasm.InitializeRequest) error {
+	return json.Unmarshal([]byte(req.Config), &p.config)
+}
+
+func (p *labelPlugin) Run(req *wasm.Request) error {
+	var capsule object
+	if err := json.Unmarshal(req.Capsule(), &capsule); err != nil {
+		return err
+	}
+
+	gvk := wasm.GVK{Version: "v1", Kind: "ServiceAccount"}
+	bs, err := req.GetNew(gvk, capsule.Metadata.Name)
+	if err != nil {
+		return err
+	}
+
+	var sa map[string]any
+	if err := json.Unmarshal(bs, &sa); err != nil {
+		return err
+	}
+	metadata := sa["metadata"].(map[string]any)
+	labels, _ := metadata["labels"].(map[string]any)
+	if labels == nil {
+		labels = map[string]any{}
+	}
+	labels[p.config.Label] = p.config.Value
+	metadata["labels"] = labels
+
+	if bs, err = json.Marshal(sa); err != nil {
+		return err
+	}
+
+	wasm.Log("setting label " + p.config.Label)
+	return req.Set(gvk, bs)
+}
+
+func (p *labelPlugin) ComputeConfig(*wasm.Request) (string, error) {
+	if p.config.Label == "" {
+		return "", errors.New("label is not configured")
+	}
+	bs, err := json.Marshal(p.config)
+	return string(bs), err
+}
+
+func init() {
+	wasm.Register("rigdev.wasm_label", &labelPlugin{})
+}
+
+func main() {}
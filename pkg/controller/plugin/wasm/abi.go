@@ -0,0 +1,121 @@
+// Package wasm contains the ABI between the rig-operator and plugins compiled to
+// WebAssembly, along with helpers for writing such plugins in Go.
+//
+// A WASM plugin is a reactor module which exports its memory and the functions
+//
+//	rig_alloc(size u32) u32
+//	rig_initialize(ptr, len u32) u64
+//	rig_run(ptr, len u32) u64
+//	rig_compute_config(ptr, len u32) u64
+//
+// The operator writes the input of a call into memory allocated with rig_alloc,
+// after which ownership of the memory is passed to the plugin. rig_initialize
+// takes an InitializeRequest and rig_run and rig_compute_config take a
+// CapsuleRequest. The functions return the location of a Response, packed as
+// ptr<<32 | len, which must stay valid until the next call into the plugin.
+//
+// While running, the plugin can access the objects of the request through the
+// functions imported from the "rig" module
+//
+//	get_object(ptr, len u32) u32
+//	list_objects(ptr, len u32) u32
+//	set_object(ptr, len u32) u32
+//	delete_object(ptr, len u32) u32
+//	read_response(ptr u32)
+//	log(ptr, len u32)
+//
+// The object functions take an ObjectRequest and return the length of the
+// ObjectResponse, which the plugin must copy into its own memory using
+// read_response before calling any other function of the operator.
+//
+// All requests and responses are encoded as JSON, as are the objects they contain.
+package wasm
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// HostModule is the name of the module of the functions imported by a plugin.
+	HostModule = "rig"
+
+	FuncAlloc         = "rig_alloc"
+	FuncInitialize    = "rig_initialize"
+	FuncRun           = "rig_run"
+	FuncComputeConfig = "rig_compute_config"
+
+	FuncGetObject    = "get_object"
+	FuncListObjects  = "list_objects"
+	FuncSetObject    = "set_object"
+	FuncDeleteObject = "delete_object"
+	FuncReadResponse = "read_response"
+	FuncLog          = "log"
+)
+
+// InitializeRequest is the input of rig_initialize.
+type InitializeRequest struct {
+	// Plugin is the name of the plugin, as a single module can implement multiple plugins.
+	Plugin string `json:"plugin"`
+	// Config is the configuration of the plugin, as given in the step of the pipeline.
+	Config string `json:"config,omitempty"`
+	// Tag is the tag of the plugin, as given in the step of the pipeline.
+	Tag string `json:"tag,omitempty"`
+}
+
+// CapsuleRequest is the input of rig_run and rig_compute_config.
+type CapsuleRequest struct {
+	// Capsule is the Capsule being reconciled.
+	Capsule json.RawMessage `json:"capsule"`
+	// AdditionalObjects are objects given by the operator to be read by the plugin.
+	AdditionalObjects []json.RawMessage `json:"additionalObjects,omitempty"`
+	// Now is the time the request is executed at.
+	Now time.Time `json:"now"`
+}
+
+// Response is the output of the functions exported by a plugin.
+type Response struct {
+	// Error is set if the call failed.
+	Error string `json:"error,omitempty"`
+	// Config is the output of rig_compute_config.
+	Config string `json:"config,omitempty"`
+}
+
+// GVK identifies the type of an object.
+type GVK struct {
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version,omitempty"`
+	Kind    string `json:"kind"`
+}
+
+// ObjectRequest is the input of the object functions imported by a plugin.
+type ObjectRequest struct {
+	GVK GVK `json:"gvk"`
+	// Name of the object to get or delete.
+	Name string `json:"name,omitempty"`
+	// Current is set to get or list the objects currently in the cluster,
+	// instead of the objects about to be applied.
+	Current bool `json:"current,omitempty"`
+	// Object is the object to set.
+	Object json.RawMessage `json:"object,omitempty"`
+}
+
+// ObjectResponse is the output of the object functions imported by a plugin.
+type ObjectResponse struct {
+	// Error is set if the call failed.
+	Error string `json:"error,omitempty"`
+	// Object is the output of get_object.
+	Object json.RawMessage `json:"object,omitempty"`
+	// Objects is the output of list_objects.
+	Objects []json.RawMessage `json:"objects,omitempty"`
+}
+
+// Pack packs the location of a buffer into a single value.
+func Pack(ptr, size uint32) uint64 {
+	return uint64(ptr)<<32 | uint64(size)
+}
+
+// Unpack returns the location of a buffer packed with Pack.
+func Unpack(v uint64) (uint32, uint32) {
+	return uint32(v >> 32), uint32(v)
+}
@@ -0,0 +1,251 @@
+//go:build wasip1
+
+package wasm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+// Plugin is the interface a rig plugin compiled to WebAssembly must implement.
+// Plugins are registered with Register from an init function, and compiled with
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o myorg.plugin.wasm
+type Plugin interface {
+	// Initialize is executed once when the rig-operator starts up, with the
+	// configuration of the plugin.
+	Initialize(req InitializeRequest) error
+	// Run is executed once per reconciliation, with read access to the Capsule
+	// being reconciled and read/write access to all other derived resources.
+	Run(req *Request) error
+	// ComputeConfig returns the configuration the plugin would use for the
+	// Capsule of the request.
+	ComputeConfig(req *Request) (string, error)
+}
+
+var (
+	registered  = map[string]Plugin{}
+	plugin      Plugin
+	allocations = map[uint32][]byte{}
+	output      []byte
+)
+
+// Register registers the implementation of the plugin with the given name.
+// A module can implement multiple plugins, which the operator selects by name
+// when initializing the module.
+func Register(name string, p Plugin) {
+	registered[name] = p
+}
+
+// Request gives access to the Capsule being reconciled and to the objects
+// derived from it.
+type Request struct {
+	capsule           json.RawMessage
+	additionalObjects []json.RawMessage
+	now               time.Time
+}
+
+// Capsule returns the Capsule being reconciled.
+func (r *Request) Capsule() json.RawMessage {
+	return r.capsule
+}
+
+// Now returns the time the request is executed at. Plugins which depend on
+// time should use it instead of the current time.
+func (r *Request) Now() time.Time {
+	return r.now
+}
+
+// AdditionalObjects returns the objects given by the operator to be read by the plugin.
+func (r *Request) AdditionalObjects() []json.RawMessage {
+	return r.additionalObjects
+}
+
+// GetExisting returns the object currently in the cluster.
+func (r *Request) GetExisting(gvk GVK, name string) (json.RawMessage, error) {
+	res, err := call(getObject, ObjectRequest{GVK: gvk, Name: name, Current: true})
+	return res.Object, err
+}
+
+// GetNew returns the object about to be applied to the cluster.
+func (r *Request) GetNew(gvk GVK, name string) (json.RawMessage, error) {
+	res, err := call(getObject, ObjectRequest{GVK: gvk, Name: name})
+	return res.Object, err
+}
+
+// ListExisting returns the objects of the given type currently in the cluster.
+func (r *Request) ListExisting(gvk GVK) ([]json.RawMessage, error) {
+	res, err := call(listObjects, ObjectRequest{GVK: gvk, Current: true})
+	return res.Objects, err
+}
+
+// ListNew returns the objects of the given type about to be applied to the cluster.
+func (r *Request) ListNew(gvk GVK) ([]json.RawMessage, error) {
+	res, err := call(listObjects, ObjectRequest{GVK: gvk})
+	return res.Objects, err
+}
+
+// Set creates or replaces an object to be applied to the cluster.
+func (r *Request) Set(gvk GVK, object json.RawMessage) error {
+	_, err := call(setObject, ObjectRequest{GVK: gvk, Object: object})
+	return err
+}
+
+// Delete removes an object, so it is deleted from the cluster.
+func (r *Request) Delete(gvk GVK, name string) error {
+	_, err := call(deleteObject, ObjectRequest{GVK: gvk, Name: name})
+	return err
+}
+
+// Log writes a message to the log of the operator.
+func Log(msg string) {
+	bs := []byte(msg)
+	hostLog(pointer(bs), uint32(len(bs)))
+	runtime.KeepAlive(bs)
+}
+
+//go:wasmimport rig get_object
+func getObject(ptr, size uint32) uint32
+
+//go:wasmimport rig list_objects
+func listObjects(ptr, size uint32) uint32
+
+//go:wasmimport rig set_object
+func setObject(ptr, size uint32) uint32
+
+//go:wasmimport rig delete_object
+func deleteObject(ptr, size uint32) uint32
+
+//go:wasmimport rig read_response
+func readResponse(ptr uint32)
+
+//go:wasmimport rig log
+func hostLog(ptr, size uint32)
+
+func call(fn func(ptr, size uint32) uint32, req ObjectRequest) (ObjectResponse, error) {
+	bs, err := json.Marshal(req)
+	if err != nil {
+		return ObjectResponse{}, err
+	}
+
+	size := fn(pointer(bs), uint32(len(bs)))
+	runtime.KeepAlive(bs)
+
+	out := make([]byte, size)
+	readResponse(pointer(out))
+
+	var res ObjectResponse
+	if err := json.Unmarshal(out, &res); err != nil {
+		return ObjectResponse{}, err
+	}
+	if res.Error != "" {
+		return res, errors.New(res.Error)
+	}
+
+	return res, nil
+}
+
+func pointer(bs []byte) uint32 {
+	if len(bs) == 0 {
+		return 0
+	}
+	return uint32(uintptr(unsafe.Pointer(unsafe.SliceData(bs))))
+}
+
+//go:wasmexport rig_alloc
+func alloc(size uint32) uint32 {
+	bs := make([]byte, size)
+	ptr := pointer(bs)
+	allocations[ptr] = bs
+	return ptr
+}
+
+// input takes ownership of memory allocated by the operator.
+func input(ptr, size uint32) []byte {
+	bs := allocations[ptr]
+	delete(allocations, ptr)
+	return bs[:size]
+}
+
+func respond(res Response) uint64 {
+	bs, err := json.Marshal(res)
+	if err != nil {
+		bs, _ = json.Marshal(Response{Error: err.Error()})
+	}
+	output = bs
+	return Pack(pointer(output), uint32(len(output)))
+}
+
+func respondError(err error) uint64 {
+	return respond(Response{Error: err.Error()})
+}
+
+//go:wasmexport rig_initialize
+func initialize(ptr, size uint32) uint64 {
+	var req InitializeRequest
+	if err := json.Unmarshal(input(ptr, size), &req); err != nil {
+		return respondError(err)
+	}
+
+	p, ok := registered[req.Plugin]
+	if !ok {
+		return respondError(fmt.Errorf("plugin '%s' is not registered", req.Plugin))
+	}
+	plugin = p
+
+	if err := plugin.Initialize(req); err != nil {
+		return respondError(err)
+	}
+
+	return respond(Response{})
+}
+
+func request(ptr, size uint32) (*Request, error) {
+	if plugin == nil {
+		return nil, errors.New("plugin is not initialized")
+	}
+
+	var req CapsuleRequest
+	if err := json.Unmarshal(input(ptr, size), &req); err != nil {
+		return nil, err
+	}
+
+	return &Request{
+		capsule:           req.Capsule,
+		additionalObjects: req.AdditionalObjects,
+		now:               req.Now,
+	}, nil
+}
+
+//go:wasmexport rig_run
+func run(ptr, size uint32) uint64 {
+	req, err := request(ptr, size)
+	if err != nil {
+		return respondError(err)
+	}
+
+	if err := plugin.Run(req); err != nil {
+		return respondError(err)
+	}
+
+	return respond(Response{})
+}
+
+//go:wasmexport rig_compute_config
+func computeConfig(ptr, size uint32) uint64 {
+	req, err := request(ptr, size)
+	if err != nil {
+		return respondError(err)
+	}
+
+	config, err := plugin.ComputeConfig(req)
+	if err != nil {
+		return respondError(err)
+	}
+
+	return respond(Response{Config: config})
+}
@@ -0,0 +1,373 @@
+package plugin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/go-logr/logr"
+	apiplugin "github.com/rigdev/rig-go-api/operator/api/v1/plugin"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/controller/plugin/wasm"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/obj"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const wasmExtension = ".wasm"
+
+// wasmCompilationCache is shared by all WASM plugins, so modules implementing
+// multiple plugins are only compiled once.
+var wasmCompilationCache = wazero.NewCompilationCache()
+
+// wasmPlugin runs a plugin compiled to WebAssembly within the operator, using the
+// ABI described in the wasm package. An instance of a module is single-threaded,
+// so calls into the plugin are serialized.
+type wasmPlugin struct {
+	name        string
+	sandbox     sandbox
+	logger      logr.Logger
+	runtime     wazero.Runtime
+	module      wazero.CompiledModule
+	initRequest wasm.InitializeRequest
+
+	lock     sync.Mutex
+	instance api.Module
+	// req is the request of the running call and response is the output of the
+	// last host function called, until it's read by the plugin.
+	req      *requestServer
+	response []byte
+}
+
+func newWASMPlugin(
+	ctx context.Context,
+	execCtx ExecutionContext,
+	name, path, pluginConfig, tag string,
+	sandbox sandbox,
+	logger logr.Logger,
+) (*wasmPlugin, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calls exceeding their deadline close the instance, which is then recreated
+	// on the next call.
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCompilationCache(wasmCompilationCache).
+		WithCloseOnContextDone(true))
+	go func() {
+		<-execCtx.Context().Done()
+		_ = r.Close(context.Background())
+	}()
+
+	p := &wasmPlugin{
+		name:    name,
+		sandbox: sandbox,
+		logger:  logger,
+		runtime: r,
+		initRequest: wasm.InitializeRequest{
+			Plugin: name,
+			Config: pluginConfig,
+			Tag:    tag,
+		},
+	}
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.NewHostModuleBuilder(wasm.HostModule).
+		NewFunctionBuilder().WithFunc(p.getObject).Export(wasm.FuncGetObject).
+		NewFunctionBuilder().WithFunc(p.listObjects).Export(wasm.FuncListObjects).
+		NewFunctionBuilder().WithFunc(p.setObject).Export(wasm.FuncSetObject).
+		NewFunctionBuilder().WithFunc(p.deleteObject).Export(wasm.FuncDeleteObject).
+		NewFunctionBuilder().WithFunc(p.readResponse).Export(wasm.FuncReadResponse).
+		NewFunctionBuilder().WithFunc(p.log).Export(wasm.FuncLog).
+		Instantiate(ctx); err != nil {
+		return nil, err
+	}
+
+	if p.module, err = r.CompileModule(ctx, bs); err != nil {
+		return nil, fmt.Errorf("could not compile WASM plugin '%s': %w", path, err)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err := p.instantiate(ctx); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *wasmPlugin) instantiate(ctx context.Context) error {
+	if p.instance != nil && !p.instance.IsClosed() {
+		return nil
+	}
+
+	instance, err := p.runtime.InstantiateModule(ctx, p.module, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize").
+		WithStdout(os.Stderr).
+		WithStderr(os.Stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader))
+	if err != nil {
+		return err
+	}
+	p.instance = instance
+
+	_, err = p.call(ctx, wasm.FuncInitialize, p.initRequest)
+	return err
+}
+
+func (p *wasmPlugin) call(ctx context.Context, name string, req any) (wasm.Response, error) {
+	fn := p.instance.ExportedFunction(name)
+	if fn == nil {
+		return wasm.Response{}, fmt.Errorf("WASM plugin '%s' does not export '%s'", p.name, name)
+	}
+	alloc := p.instance.ExportedFunction(wasm.FuncAlloc)
+	if alloc == nil {
+		return wasm.Response{}, fmt.Errorf("WASM plugin '%s' does not export '%s'", p.name, wasm.FuncAlloc)
+	}
+
+	bs, err := json.Marshal(req)
+	if err != nil {
+		return wasm.Response{}, err
+	}
+
+	res, err := alloc.Call(ctx, uint64(len(bs)))
+	if err != nil {
+		return wasm.Response{}, err
+	}
+	ptr := uint32(res[0])
+	if !p.instance.Memory().Write(ptr, bs) {
+		return wasm.Response{}, fmt.Errorf("WASM plugin '%s' allocated memory out of range", p.name)
+	}
+
+	if res, err = fn.Call(ctx, uint64(ptr), uint64(len(bs))); err != nil {
+		return wasm.Response{}, err
+	}
+
+	out, ok := p.instance.Memory().Read(wasm.Unpack(res[0]))
+	if !ok {
+		return wasm.Response{}, fmt.Errorf("WASM plugin '%s' returned memory out of range", p.name)
+	}
+
+	var response wasm.Response
+	if err := json.Unmarshal(out, &response); err != nil {
+		return wasm.Response{}, err
+	}
+	if response.Error != "" {
+		return response, errors.New(response.Error)
+	}
+
+	return response, nil
+}
+
+func (p *wasmPlugin) callWithRequest(
+	ctx context.Context,
+	name string,
+	req pipeline.CapsuleRequest,
+	additionalObjects []client.Object,
+) (wasm.Response, error) {
+	capsuleRequest := wasm.CapsuleRequest{
+		Now: req.Now(),
+	}
+	var err error
+	if capsuleRequest.Capsule, err = encodeJSON(req.Capsule(), req.Scheme()); err != nil {
+		return wasm.Response{}, err
+	}
+	for _, ao := range additionalObjects {
+		bs, err := encodeJSON(ao, req.Scheme())
+		if err != nil {
+			return wasm.Response{}, err
+		}
+		capsuleRequest.AdditionalObjects = append(capsuleRequest.AdditionalObjects, bs)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if err := p.instantiate(ctx); err != nil {
+		return wasm.Response{}, err
+	}
+
+	p.req = newRequestServer(req, p.name, p.sandbox)
+	defer func() {
+		p.req = nil
+		p.response = nil
+	}()
+
+	res, err := p.call(ctx, name, capsuleRequest)
+	if err := p.req.violations.err(); err != nil {
+		return wasm.Response{}, err
+	}
+
+	return res, err
+}
+
+func (p *wasmPlugin) Run(ctx context.Context, req pipeline.CapsuleRequest, opts pipeline.Options) error {
+	_, err := p.callWithRequest(ctx, wasm.FuncRun, req, opts.AdditionalObjects)
+	return err
+}
+
+func (p *wasmPlugin) WatchObjectStatus(
+	context.Context,
+	*v1alpha2.Capsule,
+	pipeline.ObjectStatusCallback,
+	uuid.UUID,
+) error {
+	return errors.UnimplementedErrorf("watch object status not available in WASM plugins")
+}
+
+func (p *wasmPlugin) ComputeConfig(ctx context.Context, req pipeline.CapsuleRequest) (string, error) {
+	res, err := p.callWithRequest(ctx, wasm.FuncComputeConfig, req, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return res.Config, nil
+}
+
+func encodeJSON(o runtime.Object, scheme *runtime.Scheme) ([]byte, error) {
+	bs, err := obj.Encode(o, scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.YAMLToJSON(bs)
+}
+
+func toAPIGVK(gvk wasm.GVK) *apiplugin.GVK {
+	return &apiplugin.GVK{
+		Group:   gvk.Group,
+		Version: gvk.Version,
+		Kind:    gvk.Kind,
+	}
+}
+
+// handleObjectRequest decodes the request of a host function called by the plugin
+// and stores the response, to be read using read_response. Returns the size of the
+// response.
+func (p *wasmPlugin) handleObjectRequest(
+	m api.Module,
+	ptr, size uint32,
+	handler func(req wasm.ObjectRequest) (wasm.ObjectResponse, error),
+) uint32 {
+	res, err := func() (wasm.ObjectResponse, error) {
+		if p.req == nil {
+			return wasm.ObjectResponse{}, errors.FailedPreconditionErrorf("no request is running")
+		}
+
+		bs, ok := m.Memory().Read(ptr, size)
+		if !ok {
+			return wasm.ObjectResponse{}, errors.InvalidArgumentErrorf("request out of range")
+		}
+
+		var req wasm.ObjectRequest
+		if err := json.Unmarshal(bs, &req); err != nil {
+			return wasm.ObjectResponse{}, errors.InvalidArgumentErrorf("invalid request: %v", err)
+		}
+
+		return handler(req)
+	}()
+	if err != nil {
+		res = wasm.ObjectResponse{Error: err.Error()}
+	}
+
+	p.response, err = json.Marshal(res)
+	if err != nil {
+		p.response, _ = json.Marshal(wasm.ObjectResponse{Error: err.Error()})
+	}
+
+	return uint32(len(p.response))
+}
+
+func (p *wasmPlugin) getObject(ctx context.Context, m api.Module, ptr, size uint32) uint32 {
+	return p.handleObjectRequest(m, ptr, size, func(req wasm.ObjectRequest) (wasm.ObjectResponse, error) {
+		res, err := p.req.GetObject(ctx, &apiplugin.GetObjectRequest{
+			Gvk:     toAPIGVK(req.GVK),
+			Name:    req.Name,
+			Current: req.Current,
+		})
+		if err != nil {
+			return wasm.ObjectResponse{}, err
+		}
+
+		object, err := yaml.YAMLToJSON(res.GetObject())
+		if err != nil {
+			return wasm.ObjectResponse{}, err
+		}
+
+		return wasm.ObjectResponse{Object: object}, nil
+	})
+}
+
+func (p *wasmPlugin) listObjects(ctx context.Context, m api.Module, ptr, size uint32) uint32 {
+	return p.handleObjectRequest(m, ptr, size, func(req wasm.ObjectRequest) (wasm.ObjectResponse, error) {
+		res, err := p.req.ListObjects(ctx, &apiplugin.ListObjectsRequest{
+			Gvk:     toAPIGVK(req.GVK),
+			Current: req.Current,
+		})
+		if err != nil {
+			return wasm.ObjectResponse{}, err
+		}
+
+		var objects []json.RawMessage
+		for _, o := range res.GetObjects() {
+			object, err := yaml.YAMLToJSON(o)
+			if err != nil {
+				return wasm.ObjectResponse{}, err
+			}
+			objects = append(objects, object)
+		}
+
+		return wasm.ObjectResponse{Objects: objects}, nil
+	})
+}
+
+func (p *wasmPlugin) setObject(ctx context.Context, m api.Module, ptr, size uint32) uint32 {
+	return p.handleObjectRequest(m, ptr, size, func(req wasm.ObjectRequest) (wasm.ObjectResponse, error) {
+		_, err := p.req.SetObject(ctx, &apiplugin.SetObjectRequest{
+			Gvk:    toAPIGVK(req.GVK),
+			Object: req.Object,
+		})
+		return wasm.ObjectResponse{}, err
+	})
+}
+
+func (p *wasmPlugin) deleteObject(ctx context.Context, m api.Module, ptr, size uint32) uint32 {
+	return p.handleObjectRequest(m, ptr, size, func(req wasm.ObjectRequest) (wasm.ObjectResponse, error) {
+		_, err := p.req.DeleteObject(ctx, &apiplugin.DeleteObjectRequest{
+			Gvk:  toAPIGVK(req.GVK),
+			Name: req.Name,
+		})
+		return wasm.ObjectResponse{}, err
+	})
+}
+
+func (p *wasmPlugin) readResponse(_ context.Context, m api.Module, ptr uint32) {
+	if !m.Memory().Write(ptr, p.response) {
+		p.logger.Info("WASM plugin read response out of range")
+	}
+	p.response = nil
+}
+
+func (p *wasmPlugin) log(_ context.Context, m api.Module, ptr, size uint32) {
+	if msg, ok := m.Memory().Read(ptr, size); ok {
+		p.logger.WithName("plugin").Info(string(msg))
+	}
+}
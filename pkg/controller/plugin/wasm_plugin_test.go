@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/rigdev/rig/pkg/api/config/v1alpha1"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/scheme"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// buildWASMPlugin compiles the plugin in testdata/wasm, which requires a Go
+// toolchain supporting go:wasmexport.
+func buildWASMPlugin(t *testing.T) string {
+	if testing.Short() {
+		t.Skip("skipping compilation of WASM plugin in short mode")
+	}
+
+	path := filepath.Join(t.TempDir(), "rigdev.wasm_label.wasm")
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", path, "./testdata/wasm")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("could not compile WASM plugin: %v\n%s", err, out)
+	}
+
+	return path
+}
+
+func Test_WASMPlugin(t *testing.T) {
+	path := buildWASMPlugin(t)
+
+	execCtx := NewExecutionContext(context.Background())
+	defer execCtx.Stop()
+
+	p, err := newPluginExecutor(
+		execCtx,
+		"rigdev.wasm_label", "step", "", "", `{"label": "team", "value": "core"}`, path,
+		nil,
+		newSandbox(v1alpha1.PluginSandbox{}),
+		logr.Discard(),
+		nil,
+	)
+	require.NoError(t, err)
+
+	vm := scheme.NewVersionMapperFromScheme(scheme.New())
+	cp := pipeline.NewCapsulePipeline(nil, scheme.New(), vm, logr.Discard())
+	req := pipeline.NewCapsuleRequest(cp, &v1alpha2.Capsule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+	}, nil)
+
+	sa := &corev1.ServiceAccount{}
+	sa.SetName("test")
+	require.NoError(t, req.Set(sa))
+
+	require.NoError(t, p.Run(context.Background(), req, pipeline.Options{}))
+	require.NoError(t, req.GetNewInto(sa))
+	require.Equal(t, map[string]string{"team": "core"}, sa.GetLabels())
+
+	config, err := p.ComputeConfig(context.Background(), req)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"label": "team", "value": "core"}`, config)
+
+	require.NoError(t, req.Delete(corev1.SchemeGroupVersion.WithKind("ServiceAccount"), "test"))
+	require.ErrorContains(t, p.Run(context.Background(), req, pipeline.Options{}), "has no new version")
+
+	sandboxed, err := newPluginExecutor(
+		execCtx,
+		"rigdev.wasm_label", "step", "", "", `{"label": "team", "value": "core"}`, path,
+		nil,
+		newSandbox(v1alpha1.PluginSandbox{
+			AllowedObjects: []v1alpha1.ObjectType{{Kind: "ConfigMap"}},
+		}),
+		logr.Discard(),
+		nil,
+	)
+	require.NoError(t, err)
+	require.NoError(t, req.Set(sa))
+	err = sandboxed.Run(context.Background(), req, pipeline.Options{})
+	require.ErrorContains(t, err, "plugin is not allowed to set objects of kind 'ServiceAccount'")
+
+	_, err = newPluginExecutor(
+		execCtx,
+		"rigdev.unknown", "step", "", "", "", path,
+		nil,
+		newSandbox(v1alpha1.PluginSandbox{}),
+		logr.Discard(),
+		nil,
+	)
+	require.ErrorContains(t, err, "plugin 'rigdev.unknown' is not registered")
+}
@@ -16,7 +16,9 @@ import (
 	"github.com/rigdev/rig/pkg/pipeline"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -38,6 +40,10 @@ type CapsuleWatcher interface {
 	// All objects which type matches `objType` and that belongs to the capsule
 	// will be processed.
 	WatchPrimary(ctx context.Context, objType client.Object, cb WatchCallback) error
+	// HasKind reports whether the cluster serves objects of the type of
+	// `objType`, e.g. if the CRD of the type is installed. A watch of a type
+	// which isn't served never syncs, and holds back the status of all capsules.
+	HasKind(objType client.Object) (bool, error)
 }
 
 type capsuleWatcher struct {
@@ -118,6 +124,22 @@ func (w *capsuleWatcher) WatchPrimary(ctx context.Context, objType client.Object
 	return w.w.watchPrimary(ctx, w.namespace, w.capsule, objType, w, cb)
 }
 
+func (w *capsuleWatcher) HasKind(objType client.Object) (bool, error) {
+	gvks, _, err := w.w.cc.Scheme().ObjectKinds(objType)
+	if err != nil {
+		return false, err
+	}
+
+	gvk := gvks[0]
+	if _, err := w.w.cc.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); meta.IsNoMatchError(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 type WatchCallback func(
 	obj client.Object,
 	events []*corev1.Event,
@@ -498,12 +520,12 @@ func (ow *objectWatcher) run(ctx context.Context) {
 }
 
 func (ow *objectWatcher) List(options metav1.ListOptions) (runtime.Object, error) {
-	list, err := ow.cc.Scheme().New(ow.gvkList)
+	list, err := ow.newList()
 	if err != nil {
 		return nil, err
 	}
 
-	if err := ow.cc.List(ow.ctx, list.(client.ObjectList), &client.ListOptions{
+	if err := ow.cc.List(ow.ctx, list, &client.ListOptions{
 		Namespace: ow.namespace,
 		Raw:       &options,
 	}); err != nil {
@@ -520,12 +542,12 @@ func (ow *objectWatcher) List(options metav1.ListOptions) (runtime.Object, error
 }
 
 func (ow *objectWatcher) Watch(options metav1.ListOptions) (watch.Interface, error) {
-	list, err := ow.cc.Scheme().New(ow.gvkList)
+	list, err := ow.newList()
 	if err != nil {
 		return nil, err
 	}
 
-	wi, err := ow.cc.Watch(ow.ctx, list.(client.ObjectList), &client.ListOptions{
+	wi, err := ow.cc.Watch(ow.ctx, list, &client.ListOptions{
 		Namespace: ow.namespace,
 		Raw:       &options,
 	})
@@ -535,6 +557,22 @@ func (ow *objectWatcher) Watch(options metav1.ListOptions) (watch.Interface, err
 	return wi, err
 }
 
+// newList returns an empty list of the watched type. Types which are not
+// registered in the scheme, such as CRDs of third-party operators, are listed
+// as unstructured objects.
+func (ow *objectWatcher) newList() (client.ObjectList, error) {
+	list, err := ow.cc.Scheme().New(ow.gvkList)
+	if runtime.IsNotRegisteredError(err) {
+		ul := &unstructured.UnstructuredList{}
+		ul.SetGroupVersionKind(ow.gvkList)
+		return ul, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return list.(client.ObjectList), nil
+}
+
 func (ow *objectWatcher) OnAdd(obj any, _ bool) {
 	if e, ok := obj.(*corev1.Event); ok {
 		key := cache.NewObjectName(e.InvolvedObject.Namespace, e.InvolvedObject.Name)
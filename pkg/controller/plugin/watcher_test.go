@@ -0,0 +1,36 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/rigdev/rig/pkg/scheme"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_HasKind(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	cc := fake.NewClientBuilder().WithScheme(scheme.New()).WithRESTMapper(mapper).Build()
+	cw := NewWatcher(hclog.NewNullLogger(), cc).NewCapsuleWatcher(context.Background(), "prod", "test", nil)
+
+	ok, err := cw.HasKind(&corev1.ConfigMap{})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	es := &unstructured.Unstructured{}
+	es.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "external-secrets.io",
+		Version: "v1beta1",
+		Kind:    "ExternalSecret",
+	})
+	ok, err = cw.HasKind(es)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
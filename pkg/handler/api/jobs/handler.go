@@ -0,0 +1,113 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-api/operator/api/v1/jobs"
+	"github.com/rigdev/rig-go-api/operator/api/v1/jobs/jobsconnect"
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	svcjobs "github.com/rigdev/rig/pkg/service/jobs"
+	"github.com/rigdev/rig/plugins/capsulesteps/cron_jobs"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+func NewHandler(
+	jobs svcjobs.Service,
+) jobsconnect.ServiceHandler {
+	return &handler{
+		jobs: jobs,
+	}
+}
+
+type handler struct {
+	jobs svcjobs.Service
+}
+
+func (h *handler) RunJob(
+	ctx context.Context,
+	req *connect.Request[jobs.RunJobRequest],
+	stream *connect.ServerStream[jobs.RunJobResponse],
+) error {
+	overrides := cron_jobs.JobOverrides{
+		Env:             req.Msg.GetEnv(),
+		QueryParameters: req.Msg.GetQueryParameters(),
+		MaxRetries:      ptr.Convert[uint32, uint](req.Msg.MaxRetries),
+		TimeoutSeconds:  ptr.Convert[uint32, uint](req.Msg.TimeoutSeconds),
+	}
+	if len(req.Msg.GetArgs()) > 0 {
+		overrides.Args = req.Msg.GetArgs()
+	}
+
+	job, err := h.jobs.RunJob(ctx, req.Msg.GetNamespace(), req.Msg.GetCapsule(), req.Msg.GetJobName(), overrides)
+	if err != nil {
+		return err
+	}
+
+	if err := stream.Send(&jobs.RunJobResponse{
+		Response: &jobs.RunJobResponse_Execution{
+			Execution: toExecution(req.Msg.GetCapsule(), req.Msg.GetJobName(), job, 0),
+		},
+	}); err != nil {
+		return err
+	}
+
+	if !req.Msg.GetFollow() {
+		return nil
+	}
+
+	job, exitCode, err := h.jobs.FollowJob(ctx, job, func(pod string, timestamp time.Time, line []byte) error {
+		return stream.Send(&jobs.RunJobResponse{
+			Response: &jobs.RunJobResponse_Log{
+				Log: &capsule.Log{
+					Timestamp:  timestamppb.New(timestamp),
+					InstanceId: pod,
+					Message: &capsule.LogMessage{
+						Message: &capsule.LogMessage_Stdout{Stdout: line},
+					},
+				},
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return stream.Send(&jobs.RunJobResponse{
+		Response: &jobs.RunJobResponse_Execution{
+			Execution: toExecution(req.Msg.GetCapsule(), req.Msg.GetJobName(), job, exitCode),
+		},
+	})
+}
+
+func toExecution(capsuleName, jobName string, job *batchv1.Job, exitCode int32) *capsule.JobExecution {
+	e := &capsule.JobExecution{
+		JobName:     jobName,
+		CapsuleId:   capsuleName,
+		ExecutionId: job.GetName(),
+		CreatedAt:   timestamppb.New(job.GetCreationTimestamp().Time),
+		Retries:     job.Status.Failed,
+		ExitCode:    exitCode,
+	}
+
+	state, finishedAt := cron_jobs.JobExecutionState(job)
+	switch state {
+	case apipipeline.JobExecutionState_JOB_STATE_COMPLETED:
+		e.State = capsule.JobState_JOB_STATE_COMPLETED
+	case apipipeline.JobExecutionState_JOB_STATE_FAILED:
+		e.State = capsule.JobState_JOB_STATE_FAILED
+	case apipipeline.JobExecutionState_JOB_STATE_TERMINATED:
+		e.State = capsule.JobState_JOB_STATE_TERMINATED
+	default:
+		e.State = capsule.JobState_JOB_STATE_ONGOING
+	}
+	if !finishedAt.IsZero() {
+		e.FinishedAt = timestamppb.New(finishedAt)
+	}
+
+	return e
+}
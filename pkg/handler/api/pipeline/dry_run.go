@@ -9,6 +9,7 @@ import (
 	"github.com/rigdev/rig/pkg/api/v1alpha2"
 	"github.com/rigdev/rig/pkg/obj"
 	"github.com/rigdev/rig/pkg/pipeline"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 func (h *handler) decodeOperatorConfig(config string) (*v1alpha1.OperatorConfig, error) {
@@ -112,6 +113,13 @@ func (h *handler) DryRun(
 		})
 	}
 
+	for _, s := range result.Steps {
+		res.Steps = append(res.Steps, &apipipeline.StepTiming{
+			Name:     s.Name,
+			Duration: durationpb.New(s.Duration),
+		})
+	}
+
 	return connect.NewResponse(res), nil
 }
 
@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/service/jobs"
+	"github.com/rigdev/rig/plugins/capsulesteps/cron_jobs"
+)
+
+// Pattern is the pattern of the webhook endpoint running a job, to be
+// registered on a http.ServeMux.
+const Pattern = "POST /jobs/{namespace}/{capsule}/{job}"
+
+// maxBodySize is the maximum size of the body of a request to the webhook
+// endpoint, which is reachable by unauthenticated clients.
+const maxBodySize = 64 << 10
+
+// RunJobResponse is the response of the webhook endpoint, with the Job running
+// the job.
+type RunJobResponse struct {
+	Namespace string `json:"namespace"`
+	Job       string `json:"job"`
+}
+
+// NewHandler returns the webhook endpoint running a cron job of a capsule
+// outside of its schedule. Requests are authenticated by the token of the
+// webhook of the job, given as a bearer token. The body of the request is an
+// optional JSON encoded cron_jobs.JobOverrides.
+func NewHandler(jobs jobs.Service) http.Handler {
+	return &handler{
+		jobs: jobs,
+	}
+}
+
+type handler struct {
+	jobs jobs.Service
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, errors.UnauthenticatedErrorf("missing bearer token"))
+		return
+	}
+
+	var overrides cron_jobs.JobOverrides
+	if r.ContentLength != 0 {
+		body := http.MaxBytesReader(w, r.Body, maxBodySize)
+		if err := json.NewDecoder(body).Decode(&overrides); err != nil {
+			writeError(w, errors.InvalidArgumentErrorf("invalid body: %v", err))
+			return
+		}
+	}
+
+	job, err := h.jobs.RunJobWebhook(
+		r.Context(),
+		r.PathValue("namespace"),
+		r.PathValue("capsule"),
+		r.PathValue("job"),
+		token,
+		overrides,
+	)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(RunJobResponse{
+		Namespace: job.GetNamespace(),
+		Job:       job.GetName(),
+	})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	http.Error(w, errors.MessageOf(err), errors.ToHTTP(err))
+}
@@ -10,9 +10,11 @@ import (
 	"github.com/rigdev/rig/pkg/api/v1alpha2"
 	"github.com/rigdev/rig/pkg/controller"
 	"github.com/rigdev/rig/pkg/service/capabilities"
+	"github.com/rigdev/rig/pkg/service/config"
 	"github.com/rigdev/rig/pkg/service/objectstatus"
 	"github.com/rigdev/rig/pkg/service/pipeline"
 	"go.uber.org/fx"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -31,6 +33,7 @@ func getEnvWithDefault(env, def string) string {
 
 func New(
 	cfg *cfg_v1alpha1.OperatorConfig,
+	configSource *config.OperatorConfigSource,
 	scheme *runtime.Scheme,
 	capabilitiesService capabilities.Service,
 	pipeline pipeline.Service,
@@ -53,6 +56,42 @@ func New(
 		return nil, err
 	}
 
+	var driftScanner *controller.DriftScanner
+	if cfg.DriftDetection.Enabled {
+		driftScanner = controller.NewDriftScanner(mgr.GetClient(), scheme, cfg, pipeline, objectstatus, logger)
+		if err := mgr.Add(driftScanner); err != nil {
+			return nil, err
+		}
+	}
+
+	var configReloader *controller.ConfigReloader
+	if cfg.ConfigReload.Enabled {
+		var eventObject runtime.Object
+		if podName := os.Getenv("POD_NAME"); podName != "" {
+			eventObject = &corev1.ObjectReference{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Namespace:  getEnvWithDefault("POD_NAMESPACE", "rig-system"),
+				Name:       podName,
+			}
+		}
+		configReloader = controller.NewConfigReloader(
+			mgr.GetClient(), configSource, cfg, pipeline, objectstatus,
+			mgr.GetEventRecorderFor("rig-operator"), eventObject, mgr.Elected(), logger,
+		)
+		if err := mgr.Add(configReloader); err != nil {
+			return nil, err
+		}
+	}
+
+	var executionHistory *controller.ExecutionHistory
+	if cfg.ExecutionHistory.Enabled {
+		executionHistory = controller.NewExecutionHistory(mgr.GetClient(), scheme, cfg, pipeline, logger)
+		if err := mgr.Add(executionHistory); err != nil {
+			return nil, err
+		}
+	}
+
 	cr := &controller.CapsuleReconciler{
 		Client:              mgr.GetClient(),
 		Scheme:              scheme,
@@ -61,6 +100,9 @@ func New(
 		PipelineService:     pipeline,
 		ObjectStatusService: objectstatus,
 		Lifecycle:           lc,
+		DriftScanner:        driftScanner,
+		ConfigReloader:      configReloader,
+		ExecutionHistory:    executionHistory,
 	}
 
 	if err := cr.SetupWithManager(mgr, ""); err != nil {
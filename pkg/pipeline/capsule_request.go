@@ -2,6 +2,8 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
 	"github.com/rigdev/rig/pkg/api/v1alpha2"
@@ -11,6 +13,8 @@ import (
 	"golang.org/x/exp/maps"
 	v1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -33,6 +37,42 @@ const (
 	AnnotationChecksumAutoEnv   = "rig.dev/config-checksum-auto-env"
 	AnnotationChecksumEnv       = "rig.dev/config-checksum-env"
 	AnnotationChecksumSharedEnv = "rig.dev/config-checksum-shared-env"
+
+	// AnnotationReconcileAfter can be set on an object by a step, with an RFC3339 timestamp,
+	// to have the capsule reconciled again at that time.
+	AnnotationReconcileAfter = "rig.dev/reconcile-after"
+
+	// AnnotationLastRequest is set on a capsule which scales to zero by the activator, with
+	// an RFC3339 timestamp of the last request it forwarded to the capsule.
+	AnnotationLastRequest = "rig.dev/last-request"
+	// AnnotationAwakeSince is set on the workload of a capsule which scales to zero, with an
+	// RFC3339 timestamp of when it was last woken up. It is removed while the capsule is asleep.
+	AnnotationAwakeSince = "rig.dev/awake-since"
+	// AnnotationRoutedThroughActivator is set on the workload of a capsule which scales to
+	// zero, to whether its routes must be sent through the activator. This is the case while
+	// the capsule is asleep or on standby, after having been idle for its idle timeout.
+	AnnotationRoutedThroughActivator = "rig.dev/routed-through-activator"
+
+	LabelRolloutTrack             = "rig.dev/rollout-track"
+	AnnotationRolloutTemplateHash = "rig.dev/rollout-template-hash"
+	AnnotationRolloutStep         = "rig.dev/rollout-step"
+	AnnotationRolloutSteps        = "rig.dev/rollout-steps"
+	AnnotationRolloutWeight       = "rig.dev/rollout-weight"
+	AnnotationRolloutReadySince   = "rig.dev/rollout-ready-since"
+
+	RolloutTrackStable = "stable"
+	RolloutTrackCanary = "canary"
+
+	// LabelRolloutHook is set on the Jobs of the rollout hooks of a capsule, with the name
+	// of the hook, i.e. `pre-rollout` or `post-rollout`.
+	LabelRolloutHook = "rig.dev/rollout-hook"
+	// AnnotationRolloutHookTemplateHash is set on the Deployment of a capsule with rollout
+	// hooks, with the hash of the pod template whose pre-rollout hook has succeeded.
+	AnnotationRolloutHookTemplateHash = "rig.dev/rollout-hook-template-hash"
+
+	// AnnotationJobTrigger is set on the Jobs of a capsule which are run outside of the
+	// schedule of their cron job, with what triggered the run, i.e. `manual` or `webhook`.
+	AnnotationJobTrigger = "rig.dev/job-trigger"
 )
 
 // CapsuleRequest contains a single reconcile request for a given capsule.
@@ -48,6 +88,9 @@ type CapsuleRequest interface {
 	Capsule() *v1alpha2.Capsule
 	// MarkUsedObject marks the object as used by the Capsule which will be present in the Capsule's Status
 	MarkUsedObject(res v1alpha2.UsedResource) error
+	// Now returns the time the request is executed at. Steps which depend on time, fx. schedules, should use
+	// it instead of the current time, as dry-runs can be executed at the time of a previous reconciliation.
+	Now() time.Time
 }
 
 type capsuleRequest struct {
@@ -55,6 +98,7 @@ type capsuleRequest struct {
 	capsule           *v1alpha2.Capsule
 	usedResources     []v1alpha2.UsedResource
 	additionalObjects []client.Object
+	now               time.Time
 }
 
 type CapsuleRequestOption interface {
@@ -101,6 +145,17 @@ func WithForce() CapsuleRequestOption {
 	return withForce{}
 }
 
+type withNow struct {
+	now time.Time
+}
+
+func (w withNow) apply(r *capsuleRequest) { r.now = w.now }
+
+// WithNow executes the request at the given time instead of the current time.
+func WithNow(now time.Time) CapsuleRequestOption {
+	return withNow{now}
+}
+
 func NewCapsuleRequest(
 	p *CapsulePipeline,
 	capsule *v1alpha2.Capsule,
@@ -120,6 +175,7 @@ func newCapsuleRequest(
 	r := &capsuleRequest{
 		RequestBase: NewRequestBase(client, client, vm, p.config, p.scheme, p.logger, nil, capsule),
 		capsule:     capsule,
+		now:         time.Now(),
 	}
 	// TODO This is an ugly hack. Find a better solution
 	// Good rule of thumb: If the Rust compiler would throw a fit, do it differently.
@@ -149,6 +205,10 @@ func (r *capsuleRequest) Capsule() *v1alpha2.Capsule {
 	return r.capsule.DeepCopy()
 }
 
+func (r *capsuleRequest) Now() time.Time {
+	return r.now
+}
+
 func (r *capsuleRequest) GetKey(gvk schema.GroupVersionKind, name string) (ObjectKey, error) {
 	gvk2, err := r.vm.FromGroupKind(gvk.GroupKind())
 	if err != nil {
@@ -240,6 +300,7 @@ func (r *capsuleRequest) UpdateStatusWithChanges(
 
 	status := &v1alpha2.CapsuleStatus{
 		ObservedGeneration: generation,
+		AppliedAt:          &metav1.Time{Time: r.now},
 	}
 
 	for _, key := range sortedKeys(maps.Keys(changes)) {
@@ -261,12 +322,22 @@ func (r *capsuleRequest) UpdateStatusWithChanges(
 		}
 		if change.err != nil {
 			or.Message = change.err.Error()
+		} else if len(change.conflicts) > 0 {
+			or.Message = fmt.Sprintf("%d fields conflict with other field managers", len(change.conflicts))
 		}
+		or.Conflicts = change.conflicts
 		status.OwnedResources = append(status.OwnedResources, or)
 	}
 
 	status.UsedResources = r.usedResources
 
+	if r.capsule.Status != nil {
+		status.Conditions = r.capsule.Status.Conditions
+		if meta.FindStatusCondition(status.Conditions, v1alpha2.CapsuleConditionDrifted) != nil {
+			status.SetDrift(remainingDrift(r.capsule.Status.Drift, changes), generation)
+		}
+	}
+
 	capsuleCopy.Status = status
 
 	if err := r.client.Status().Update(ctx, capsuleCopy); err != nil {
@@ -294,6 +365,9 @@ func (r *capsuleRequest) UpdateStatusWithError(ctx context.Context, err error) e
 	if capsuleCopy.Status != nil {
 		status.OwnedResources = capsuleCopy.Status.OwnedResources
 		status.UsedResources = capsuleCopy.Status.UsedResources
+		status.Conditions = capsuleCopy.Status.Conditions
+		status.Drift = capsuleCopy.Status.Drift
+		status.AppliedAt = capsuleCopy.Status.AppliedAt
 	}
 
 	capsuleCopy.Status = status
@@ -310,6 +384,26 @@ func (r *capsuleRequest) UpdateStatusWithError(ctx context.Context, err error) e
 	return nil
 }
 
+// remainingDrift returns the drifted objects which were not reverted by
+// applying the changes.
+func remainingDrift(drift []v1alpha2.ObjectDrift, changes map[ObjectKey]*Change) []v1alpha2.ObjectDrift {
+	var res []v1alpha2.ObjectDrift
+	for _, d := range drift {
+		reverted := false
+		for key, change := range changes {
+			if d.Ref.APIGroup != nil && key.Group == *d.Ref.APIGroup &&
+				key.Kind == d.Ref.Kind && key.Name == d.Ref.Name {
+				reverted = change.applied
+				break
+			}
+		}
+		if !reverted {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
 func (*capsuleRequest) OwnedLabel() string {
 	return LabelOwnedByCapsule
 }
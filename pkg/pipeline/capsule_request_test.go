@@ -3,13 +3,16 @@ package pipeline
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	mockclient "github.com/rigdev/rig/gen/mocks/sigs.k8s.io/controller-runtime/pkg/client"
 	"github.com/rigdev/rig/pkg/api/config/v1alpha1"
 	"github.com/rigdev/rig/pkg/api/v1alpha2"
 	"github.com/rigdev/rig/pkg/scheme"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -136,3 +139,127 @@ func Test_ListNew(t *testing.T) {
 		{ObjectMeta: metav1.ObjectMeta{Name: "service2"}, TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"}},
 	}, services)
 }
+
+func Test_WithNow(t *testing.T) {
+	before := time.Now()
+	_, _, c := preparePipelineTest(t, pipelineTestOpts{})
+	require.False(t, c.Now().Before(before))
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	_, _, c = preparePipelineTest(t, pipelineTestOpts{
+		options: []CapsuleRequestOption{WithNow(now)},
+	})
+	require.Equal(t, now, c.Now())
+}
+
+func Test_StableSelector(t *testing.T) {
+	notCanary := metav1.LabelSelectorRequirement{
+		Key:      LabelRolloutTrack,
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   []string{RolloutTrackCanary},
+	}
+
+	tests := []struct {
+		name     string
+		selector *metav1.LabelSelector
+		expected *metav1.LabelSelector
+	}{
+		{
+			name:     "no selector",
+			expected: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{notCanary}},
+		},
+		{
+			name: "labels are kept",
+			selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{LabelCapsule: "name"},
+			},
+			expected: &metav1.LabelSelector{
+				MatchLabels:      map[string]string{LabelCapsule: "name"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{notCanary},
+			},
+		},
+		{
+			name: "already excludes canary",
+			selector: &metav1.LabelSelector{
+				MatchLabels:      map[string]string{LabelCapsule: "name"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{notCanary},
+			},
+			expected: &metav1.LabelSelector{
+				MatchLabels:      map[string]string{LabelCapsule: "name"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{notCanary},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, StableSelector(tt.selector))
+		})
+	}
+}
+
+func Test_needsReplace(t *testing.T) {
+	key := ObjectKey{
+		ObjectKey:        client.ObjectKey{Name: "name", Namespace: "namespace"},
+		GroupVersionKind: AppsDeploymentGVK,
+	}
+	deployment := func(selector *metav1.LabelSelector) *appsv1.Deployment {
+		return &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Selector: selector}}
+	}
+	labels := &metav1.LabelSelector{MatchLabels: map[string]string{LabelCapsule: "name"}}
+	notCanary := &metav1.LabelSelector{
+		MatchLabels: map[string]string{LabelCapsule: "name"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{{
+			Key:      LabelRolloutTrack,
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   []string{RolloutTrackCanary},
+		}},
+	}
+
+	require.False(t, needsReplace(key, &Object{Current: deployment(labels), New: deployment(labels)}))
+	require.True(t, needsReplace(key, &Object{Current: deployment(labels), New: deployment(notCanary)}))
+	require.False(t, needsReplace(key, &Object{Current: deployment(labels), New: deployment(&metav1.LabelSelector{
+		MatchLabels: map[string]string{LabelCapsule: "other"},
+	})}))
+	require.False(t, needsReplace(ObjectKey{GroupVersionKind: corev1.SchemeGroupVersion.WithKind("Service")}, &Object{
+		Current: &corev1.Service{}, New: &corev1.Service{},
+	}))
+}
+
+func Test_applyChangeReplace(t *testing.T) {
+	ctx, cc, c := preparePipelineTest(t, pipelineTestOpts{})
+
+	current := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "name", ResourceVersion: "1"}}
+	newObj := current.DeepCopy()
+	key, err := c.GetKey(AppsDeploymentGVK, current.GetName())
+	require.NoError(t, err)
+	c.newObjects[key] = &Object{Current: current, New: newObj}
+
+	cc.EXPECT().Delete(ctx, current, client.PropagationPolicy(metav1.DeletePropagationOrphan)).Return(nil)
+	cc.EXPECT().Get(mock.Anything, key.ObjectKey, mock.AnythingOfType("*v1.Deployment")).
+		Return(kerrors.NewNotFound(schema.ParseGroupResource("deployments.apps"), current.GetName())).Once()
+	cc.EXPECT().Create(ctx, newObj).Return(nil)
+
+	require.NoError(t, c.applyChange(ctx, key, &Change{state: ResourceStateUpdated, replace: true}))
+	require.Empty(t, newObj.GetResourceVersion())
+}
+
+func Test_applyChangeReplaceTerminating(t *testing.T) {
+	ctx, cc, c := preparePipelineTest(t, pipelineTestOpts{})
+
+	current := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "name", ResourceVersion: "1"}}
+	key, err := c.GetKey(AppsDeploymentGVK, current.GetName())
+	require.NoError(t, err)
+	c.newObjects[key] = &Object{Current: current, New: current.DeepCopy()}
+
+	// The new version is created when the request is run again, once the current one is gone.
+	cc.EXPECT().Delete(ctx, current, client.PropagationPolicy(metav1.DeletePropagationOrphan)).Return(nil)
+	cc.EXPECT().Get(ctx, key.ObjectKey, mock.AnythingOfType("*v1.Deployment")).Return(nil).Once()
+
+	change := &Change{state: ResourceStateUpdated, replace: true}
+	require.NoError(t, c.applyChange(ctx, key, change))
+	require.True(t, change.requeue)
+
+	res := &Result{Requeue: true}
+	require.Equal(t, replaceRequeueAfter, ReconcileAfter(res, time.Now()))
+}
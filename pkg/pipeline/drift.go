@@ -0,0 +1,234 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/obj"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ComputeDrift returns the objects of a dry-run result which differ from the
+// existing objects they were computed from. A capsule which is reconciled at
+// its current generation is expected to have no changes in a dry-run, so any
+// change is the result of the objects being modified outside of the operator.
+func ComputeDrift(res *Result, scheme *runtime.Scheme) ([]v1alpha2.ObjectDrift, error) {
+	existing := map[schema.GroupKind]map[string]client.Object{}
+	for _, co := range res.InputObjects {
+		gk := co.GetObjectKind().GroupVersionKind().GroupKind()
+		if existing[gk] == nil {
+			existing[gk] = map[string]client.Object{}
+		}
+		existing[gk][co.GetName()] = co
+	}
+
+	outputs := map[ObjectKey]OutputObject{}
+	var keys []ObjectKey
+	for _, oo := range res.OutputObjects {
+		outputs[oo.ObjectKey] = oo
+		keys = append(keys, oo.ObjectKey)
+	}
+
+	var drift []v1alpha2.ObjectDrift
+	for _, key := range sortedKeys(keys) {
+		oo := outputs[key]
+		d := v1alpha2.ObjectDrift{
+			Ref: &v1.TypedLocalObjectReference{
+				APIGroup: &key.Group,
+				Kind:     key.Kind,
+				Name:     key.Name,
+			},
+		}
+
+		switch oo.State {
+		case ResourceStateCreated:
+			d.State = v1alpha2.ObjectDriftStateMissing
+		case ResourceStateDeleted:
+			d.State = v1alpha2.ObjectDriftStateUnexpected
+		case ResourceStateUpdated:
+			current := existing[key.GroupKind()][key.Name]
+			if current == nil || oo.Object == nil {
+				continue
+			}
+
+			fields, err := driftedFields(current, oo.Object, scheme)
+			if err != nil {
+				return nil, err
+			}
+			if len(fields) == 0 {
+				continue
+			}
+
+			d.State = v1alpha2.ObjectDriftStateChanged
+			d.Fields = fields
+		default:
+			continue
+		}
+
+		drift = append(drift, d)
+	}
+
+	return drift, nil
+}
+
+func driftedFields(current, desired client.Object, scheme *runtime.Scheme) ([]v1alpha2.FieldDrift, error) {
+	comp := obj.NewComparison(desired, current, scheme)
+	comp.AddFilter(obj.RemoveAnnotationsFilter(
+		"deployment.kubernetes.io/revision",
+	))
+	comp.AddRemoveDiffs("status", "status.**")
+
+	diff, err := comp.ComputeDiff()
+	if err != nil {
+		return nil, err
+	}
+
+	managers, err := managedFieldPaths(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []v1alpha2.FieldDrift
+	for _, d := range diff.Report.Diffs {
+		if d.Path == nil {
+			continue
+		}
+
+		field := d.Path.ToDotStyle()
+		fields = append(fields, v1alpha2.FieldDrift{
+			Field:   field,
+			Manager: lastFieldManager(managers, field),
+		})
+	}
+
+	return fields, nil
+}
+
+type managedFields struct {
+	entry  metav1.ManagedFieldsEntry
+	paths  map[string]struct{}
+	leaves map[string]struct{}
+}
+
+// managedFieldPaths returns the fields managed by each field manager of the
+// object, as paths in the dot style used by the object comparison.
+func managedFieldPaths(co client.Object) ([]managedFields, error) {
+	var res []managedFields
+	for _, entry := range co.GetManagedFields() {
+		if entry.Subresource != "" || entry.FieldsV1 == nil {
+			continue
+		}
+
+		var fields map[string]any
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			return nil, err
+		}
+
+		mf := managedFields{
+			entry:  entry,
+			paths:  map[string]struct{}{},
+			leaves: map[string]struct{}{},
+		}
+		mf.add("", fields)
+		res = append(res, mf)
+	}
+
+	return res, nil
+}
+
+func (mf managedFields) add(prefix string, fields map[string]any) {
+	for key, value := range fields {
+		if key == "." {
+			continue
+		}
+
+		path := fieldPathElement(key)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		mf.paths[path] = struct{}{}
+
+		children, _ := value.(map[string]any)
+		if len(children) == 0 {
+			mf.leaves[path] = struct{}{}
+			continue
+		}
+		mf.add(path, children)
+	}
+}
+
+// fieldPathElement converts an element of a FieldsV1 set to a path element.
+// Keyed list elements are identified by their name, as in the comparison.
+func fieldPathElement(key string) string {
+	prefix, element, ok := strings.Cut(key, ":")
+	if !ok {
+		return key
+	}
+
+	switch prefix {
+	case "k":
+		var keys map[string]any
+		if err := json.Unmarshal([]byte(element), &keys); err != nil {
+			return element
+		}
+		if name, ok := keys["name"].(string); ok {
+			return name
+		}
+		return element
+	case "v":
+		var value any
+		if err := json.Unmarshal([]byte(element), &value); err != nil {
+			return element
+		}
+		if s, ok := value.(string); ok {
+			return s
+		}
+		return element
+	default:
+		return element
+	}
+}
+
+// lastFieldManager returns the field manager, other than the operator, which
+// most recently changed the field. Returns an empty string if not known.
+func lastFieldManager(managers []managedFields, field string) string {
+	var manager string
+	var updatedAt *metav1.Time
+	for _, mf := range managers {
+		if isOperatorFieldManager(mf.entry.Manager) || !mf.manages(field) {
+			continue
+		}
+
+		if updatedAt == nil || (mf.entry.Time != nil && !mf.entry.Time.Before(updatedAt)) {
+			manager = mf.entry.Manager
+			updatedAt = mf.entry.Time
+		}
+	}
+
+	return manager
+}
+
+func (mf managedFields) manages(field string) bool {
+	if _, ok := mf.paths[field]; ok {
+		return true
+	}
+
+	for path := range mf.paths {
+		if strings.HasPrefix(path, field+".") {
+			return true
+		}
+	}
+
+	for leaf := range mf.leaves {
+		if strings.HasPrefix(field, leaf+".") {
+			return true
+		}
+	}
+
+	return false
+}
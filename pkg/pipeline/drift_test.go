@@ -0,0 +1,136 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/rigdev/rig/pkg/scheme"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestComputeDrift(t *testing.T) {
+	now := time.Now()
+
+	desired := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.New(int32(2)),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Image: "nginx:1"}},
+				},
+			},
+		},
+	}
+
+	current := desired.DeepCopy()
+	current.Spec.Replicas = ptr.New(int32(5))
+	current.Spec.Template.Spec.Containers[0].Image = "nginx:2"
+	current.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:   FieldManager,
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			Time:      &metav1.Time{Time: now.Add(-time.Hour)},
+			FieldsV1: &metav1.FieldsV1{
+				Raw: []byte(`{"f:spec":{"f:replicas":{},"f:template":{"f:spec":{"f:containers":{` +
+					`"k:{\"name\":\"app\"}":{".":{},"f:image":{},"f:name":{}}}}}}}`),
+			},
+		},
+		{
+			Manager:   "kubectl-scale",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			Time:      &metav1.Time{Time: now.Add(-time.Minute)},
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+		},
+		{
+			Manager:     "kubectl-edit",
+			Operation:   metav1.ManagedFieldsOperationUpdate,
+			Subresource: "status",
+			Time:        &metav1.Time{Time: now},
+			FieldsV1:    &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+		},
+	})
+
+	deploymentKey := ObjectKey{
+		GroupVersionKind: AppsDeploymentGVK,
+		ObjectKey:        types.NamespacedName{Namespace: "default", Name: "test"},
+	}
+	serviceKey := ObjectKey{
+		GroupVersionKind: corev1.SchemeGroupVersion.WithKind("Service"),
+		ObjectKey:        types.NamespacedName{Namespace: "default", Name: "test"},
+	}
+	serviceAccountKey := ObjectKey{
+		GroupVersionKind: corev1.SchemeGroupVersion.WithKind("ServiceAccount"),
+		ObjectKey:        types.NamespacedName{Namespace: "default", Name: "test"},
+	}
+
+	drift, err := ComputeDrift(&Result{
+		InputObjects: []client.Object{current},
+		OutputObjects: []OutputObject{
+			{ObjectKey: deploymentKey, Object: desired, State: ResourceStateUpdated},
+			{ObjectKey: serviceKey, Object: &corev1.Service{}, State: ResourceStateCreated},
+			{ObjectKey: serviceAccountKey, Object: &corev1.ServiceAccount{}, State: ResourceStateUnchanged},
+		},
+	}, scheme.New())
+	require.NoError(t, err)
+
+	require.Equal(t, []v1alpha2.ObjectDrift{
+		{
+			Ref: &corev1.TypedLocalObjectReference{
+				APIGroup: ptr.New(""),
+				Kind:     "Service",
+				Name:     "test",
+			},
+			State: v1alpha2.ObjectDriftStateMissing,
+		},
+		{
+			Ref: &corev1.TypedLocalObjectReference{
+				APIGroup: ptr.New("apps"),
+				Kind:     "Deployment",
+				Name:     "test",
+			},
+			State: v1alpha2.ObjectDriftStateChanged,
+			Fields: []v1alpha2.FieldDrift{
+				{Field: "spec.replicas", Manager: "kubectl-scale"},
+				{Field: "spec.template.spec.containers.app.image"},
+			},
+		},
+	}, drift)
+}
+
+func Test_remainingDrift(t *testing.T) {
+	drift := []v1alpha2.ObjectDrift{
+		{
+			Ref:   &corev1.TypedLocalObjectReference{APIGroup: ptr.New("apps"), Kind: "Deployment", Name: "test"},
+			State: v1alpha2.ObjectDriftStateChanged,
+		},
+		{
+			Ref:   &corev1.TypedLocalObjectReference{APIGroup: ptr.New(""), Kind: "Service", Name: "test"},
+			State: v1alpha2.ObjectDriftStateMissing,
+		},
+	}
+
+	changes := map[ObjectKey]*Change{
+		{
+			GroupVersionKind: AppsDeploymentGVK,
+			ObjectKey:        types.NamespacedName{Namespace: "default", Name: "test"},
+		}: {state: ResourceStateUpdated, applied: true},
+		{
+			GroupVersionKind: corev1.SchemeGroupVersion.WithKind("Service"),
+			ObjectKey:        types.NamespacedName{Namespace: "default", Name: "test"},
+		}: {state: ResourceStateCreated},
+	}
+
+	require.Equal(t, drift[1:], remainingDrift(drift, changes))
+}
@@ -3,9 +3,13 @@ package pipeline
 import (
 	"path"
 	"strings"
+	"time"
 
 	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var _defaultPodAnnotations = []string{RigDevRolloutLabel}
@@ -102,3 +106,87 @@ func EnvSources(refs []v1alpha2.EnvReference) []v1.EnvFromSource {
 	}
 	return res
 }
+
+// IsStateful returns true if the capsule is run as a StatefulSet instead of a
+// Deployment, which is the case when it has persistent volumes.
+func IsStateful(capsule *v1alpha2.Capsule) bool {
+	return capsule.Spec.Storage != nil && len(capsule.Spec.Storage.Volumes) > 0
+}
+
+// StableSelector returns the selector of the stable Deployment of a capsule with
+// rollouts, which excludes the pods of the canary. The pods of the stable version
+// are not required to be labelled with the stable track, so no pods are restarted
+// when changing to this selector.
+func StableSelector(selector *metav1.LabelSelector) *metav1.LabelSelector {
+	res := &metav1.LabelSelector{}
+	if selector != nil {
+		res = selector.DeepCopy()
+	}
+
+	for _, e := range res.MatchExpressions {
+		if e.Key == LabelRolloutTrack {
+			return res
+		}
+	}
+	res.MatchExpressions = append(res.MatchExpressions, metav1.LabelSelectorRequirement{
+		Key:      LabelRolloutTrack,
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   []string{RolloutTrackCanary},
+	})
+	return res
+}
+
+// GetNewWorkload returns the new object running the instances of the capsule,
+// together with its pod template. The object is a StatefulSet if the capsule
+// is stateful and a Deployment otherwise. Changes to the pod template are
+// recorded by passing the returned object to req.Set.
+func GetNewWorkload(req CapsuleRequest) (client.Object, *v1.PodTemplateSpec, error) {
+	if IsStateful(req.Capsule()) {
+		sts := &appsv1.StatefulSet{}
+		if err := req.GetNewInto(sts); err != nil {
+			return nil, nil, err
+		}
+		return sts, &sts.Spec.Template, nil
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := req.GetNewInto(deployment); err != nil {
+		return nil, nil, err
+	}
+	return deployment, &deployment.Spec.Template, nil
+}
+
+// ReconcileAfter returns how long to wait before the capsule should be
+// reconciled again, as requested by steps through AnnotationReconcileAfter on
+// the output objects, or while objects are being replaced. Zero is returned if
+// no reconcile is requested.
+func ReconcileAfter(res *Result, now time.Time) time.Duration {
+	var after time.Duration
+	if res.Requeue {
+		after = replaceRequeueAfter
+	}
+	for _, o := range res.OutputObjects {
+		if o.Object == nil || o.State == ResourceStateDeleted {
+			continue
+		}
+
+		v, ok := o.Object.GetAnnotations()[AnnotationReconcileAfter]
+		if !ok {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			continue
+		}
+
+		d := t.Sub(now)
+		if d <= 0 {
+			d = time.Second
+		}
+		if after == 0 || d < after {
+			after = d
+		}
+	}
+	return after
+}
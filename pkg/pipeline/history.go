@@ -0,0 +1,198 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LabelExecutionHistory is set on the ConfigMap holding the execution history of
+	// a capsule, with the name of the capsule as value.
+	LabelExecutionHistory = "rig.dev/execution-history"
+
+	// maxHistorySize is the maximum size of the records in an execution history,
+	// keeping the ConfigMap below the 1MiB limit of Kubernetes objects.
+	maxHistorySize = 900 * 1024
+	// maxPluginConfigSize is the maximum size of the config of a plugin in a record.
+	maxPluginConfigSize = 16 * 1024
+)
+
+// ExecutionRecord is a record of an execution of the pipeline of a capsule.
+type ExecutionRecord struct {
+	// ID of the record, unique within the execution history of the capsule.
+	ID string `json:"id"`
+	// Generation of the capsule which was executed.
+	Generation int64 `json:"generation"`
+	// StartedAt is the time the execution started.
+	StartedAt metav1.Time `json:"startedAt"`
+	// Duration of the execution.
+	Duration metav1.Duration `json:"duration"`
+	// Steps which were run, in order. Empty if the execution failed.
+	Steps []StepRecord `json:"steps,omitempty"`
+	// Objects changed by the execution.
+	Objects []ObjectRecord `json:"objects,omitempty"`
+	// Error of the execution, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// StepRecord is a record of a step run by an execution.
+type StepRecord struct {
+	Name     string          `json:"name"`
+	Duration metav1.Duration `json:"duration"`
+	// Plugins of the step, with the configs computed for the capsule.
+	Plugins []PluginRecord `json:"plugins,omitempty"`
+}
+
+// PluginRecord is a record of the config of a plugin, as computed for the capsule.
+type PluginRecord struct {
+	Name   string `json:"name"`
+	Config string `json:"config,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ObjectRecord is a record of an object changed by an execution.
+type ObjectRecord struct {
+	Group     string        `json:"group,omitempty"`
+	Version   string        `json:"version,omitempty"`
+	Kind      string        `json:"kind"`
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace,omitempty"`
+	State     ResourceState `json:"state"`
+}
+
+// NewExecutionRecord creates a record of an execution of the capsule, started at start,
+// with the result or error of the execution.
+func NewExecutionRecord(capsule *v1alpha2.Capsule, start time.Time, result *Result, err error) ExecutionRecord {
+	record := ExecutionRecord{
+		ID:         strconv.FormatInt(start.UnixMilli(), 10),
+		Generation: capsule.GetGeneration(),
+		StartedAt:  metav1.NewTime(start),
+		Duration:   metav1.Duration{Duration: time.Since(start)},
+	}
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+
+	for _, s := range result.Steps {
+		record.Steps = append(record.Steps, StepRecord{
+			Name:     s.Name,
+			Duration: metav1.Duration{Duration: s.Duration},
+		})
+	}
+
+	for _, o := range result.OutputObjects {
+		if o.State == ResourceStateUnchanged {
+			continue
+		}
+		record.Objects = append(record.Objects, ObjectRecord{
+			Group:     o.ObjectKey.Group,
+			Version:   o.ObjectKey.Version,
+			Kind:      o.ObjectKey.Kind,
+			Name:      o.ObjectKey.Name,
+			Namespace: o.ObjectKey.Namespace,
+			State:     o.State,
+		})
+	}
+	slices.SortFunc(record.Objects, func(o1, o2 ObjectRecord) int {
+		return strings.Compare(o1.Kind+"/"+o1.Name, o2.Kind+"/"+o2.Name)
+	})
+
+	return record
+}
+
+// SetPluginConfigs sets the configs of the plugins of the steps of the record, as
+// computed by ComputeConfig of the same pipeline. Large configs are truncated.
+func (r *ExecutionRecord) SetPluginConfigs(configs PluginConfigResult) {
+	for i := range r.Steps {
+		if i >= len(configs.Steps) || configs.Steps[i].Name != r.Steps[i].Name {
+			continue
+		}
+
+		r.Steps[i].Plugins = nil
+		for _, p := range configs.Steps[i].Plugins {
+			config := p.Config
+			if len(config) > maxPluginConfigSize {
+				config = config[:maxPluginConfigSize] + "\n# truncated"
+			}
+			r.Steps[i].Plugins = append(r.Steps[i].Plugins, PluginRecord{
+				Name:   p.Name,
+				Config: config,
+				Error:  p.Err,
+			})
+		}
+	}
+}
+
+// ExecutionHistoryName returns the name of the ConfigMap holding the execution
+// history of the capsule. The name is prefixed with rig.dev, to not collide
+// with the ConfigMaps of the capsule.
+func ExecutionHistoryName(capsule string) string {
+	return fmt.Sprintf("rig.dev.execution-history.%s", capsule)
+}
+
+// DecodeExecutionHistory returns the records of an execution history ConfigMap,
+// newest first.
+func DecodeExecutionHistory(cm *corev1.ConfigMap) ([]ExecutionRecord, error) {
+	var records []ExecutionRecord
+	for key, data := range cm.Data {
+		var record ExecutionRecord
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			return nil, fmt.Errorf("invalid execution record '%s': %w", key, err)
+		}
+		records = append(records, record)
+	}
+
+	slices.SortFunc(records, func(r1, r2 ExecutionRecord) int {
+		return r2.StartedAt.Compare(r1.StartedAt.Time)
+	})
+	return records, nil
+}
+
+// AppendExecutionRecord adds the record to an execution history ConfigMap. The oldest
+// records are removed to keep at most maxRecords records which are no older than maxAge,
+// if not zero. The newest record is always kept.
+func AppendExecutionRecord(
+	cm *corev1.ConfigMap,
+	record ExecutionRecord,
+	maxRecords int,
+	maxAge time.Duration,
+) error {
+	records, err := DecodeExecutionHistory(cm)
+	if err != nil {
+		// Start over, rather than getting stuck on an invalid history.
+		records = nil
+	}
+	records = append([]ExecutionRecord{record}, records...)
+
+	cm.Data = map[string]string{}
+	size := 0
+	for i, r := range records {
+		if i > 0 && i >= maxRecords {
+			break
+		}
+		if i > 0 && maxAge > 0 && time.Since(r.StartedAt.Time) > maxAge {
+			break
+		}
+
+		bs, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if i > 0 && size+len(bs) > maxHistorySize {
+			break
+		}
+		size += len(bs)
+		cm.Data[r.ID+".json"] = string(bs)
+	}
+
+	return nil
+}
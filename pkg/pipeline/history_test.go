@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestNewExecutionRecord(t *testing.T) {
+	capsule := &v1alpha2.Capsule{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Generation: 3}}
+	start := time.Now()
+
+	key := func(kind, name string) ObjectKey {
+		return ObjectKey{
+			ObjectKey:        types.NamespacedName{Name: name, Namespace: "default"},
+			GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: kind},
+		}
+	}
+	result := &Result{
+		OutputObjects: []OutputObject{
+			{ObjectKey: key("Service", "test"), State: ResourceStateCreated},
+			{ObjectKey: key("ConfigMap", "test"), State: ResourceStateUnchanged},
+			{ObjectKey: key("Deployment", "test"), State: ResourceStateUpdated},
+		},
+		Steps: []StepTiming{{Name: "deployment", Duration: time.Second}, {Name: "annotations"}},
+	}
+
+	record := NewExecutionRecord(capsule, start, result, nil)
+	require.Equal(t, int64(3), record.Generation)
+	require.Empty(t, record.Error)
+	require.Equal(t, []ObjectRecord{
+		{Version: "v1", Kind: "Deployment", Name: "test", Namespace: "default", State: ResourceStateUpdated},
+		{Version: "v1", Kind: "Service", Name: "test", Namespace: "default", State: ResourceStateCreated},
+	}, record.Objects)
+	require.Len(t, record.Steps, 2)
+
+	record.SetPluginConfigs(PluginConfigResult{Steps: []StepConfigResult{
+		{Name: "deployment", Plugins: []PluginConfig{{Name: "rigdev.deployment", Config: "a: b"}}},
+		{Name: "other", Plugins: []PluginConfig{{Name: "rigdev.annotations"}}},
+	}})
+	require.Equal(t, []PluginRecord{{Name: "rigdev.deployment", Config: "a: b"}}, record.Steps[0].Plugins)
+	require.Empty(t, record.Steps[1].Plugins)
+
+	failed := NewExecutionRecord(capsule, start, nil, errors.New("failed"))
+	require.Equal(t, "failed", failed.Error)
+	require.Empty(t, failed.Steps)
+}
+
+func TestAppendExecutionRecord(t *testing.T) {
+	now := time.Now()
+	newRecord := func(age time.Duration) ExecutionRecord {
+		start := now.Add(-age)
+		return NewExecutionRecord(&v1alpha2.Capsule{}, start, &Result{}, nil)
+	}
+
+	cm := &corev1.ConfigMap{}
+	for i := 5; i >= 0; i-- {
+		require.NoError(t, AppendExecutionRecord(cm, newRecord(time.Duration(i)*time.Minute), 3, 0))
+	}
+	records, err := DecodeExecutionHistory(cm)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, newRecord(0).ID, records[0].ID)
+	require.Equal(t, newRecord(2*time.Minute).ID, records[2].ID)
+
+	require.NoError(t, AppendExecutionRecord(cm, newRecord(-time.Minute), 10, 90*time.Second))
+	records, err = DecodeExecutionHistory(cm)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, newRecord(time.Minute).ID, records[2].ID)
+
+	// The newest record is kept, even if it is too old.
+	cm = &corev1.ConfigMap{}
+	require.NoError(t, AppendExecutionRecord(cm, newRecord(time.Hour), 0, time.Minute))
+	require.Len(t, cm.Data, 1)
+
+	// Invalid histories are replaced.
+	cm = &corev1.ConfigMap{Data: map[string]string{"invalid.json": "{"}}
+	require.NoError(t, AppendExecutionRecord(cm, newRecord(0), 10, 0))
+	require.Len(t, cm.Data, 1)
+	require.NotContains(t, cm.Data, "invalid.json")
+}
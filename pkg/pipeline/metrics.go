@@ -0,0 +1,57 @@
+package pipeline
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	executionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "rig",
+		Subsystem: "pipeline",
+		Name:      "execution_duration_seconds",
+		Help:      "Duration of pipeline executions, including committing the changes.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"dry_run", "result"})
+
+	executionRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rig",
+		Subsystem: "pipeline",
+		Name:      "execution_retries_total",
+		Help:      "Number of times the steps of a pipeline were re-run, as the commit was aborted.",
+	}, []string{"dry_run"})
+
+	objectsChanged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rig",
+		Subsystem: "pipeline",
+		Name:      "objects_changed_total",
+		Help:      "Number of objects changed by pipeline executions.",
+	}, []string{"group", "kind", "state"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(executionDuration, executionRetries, objectsChanged)
+}
+
+func observeExecution(dryRun bool, seconds float64, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	executionDuration.WithLabelValues(strconv.FormatBool(dryRun), result).Observe(seconds)
+}
+
+func countChangedObjects(changes map[ObjectKey]*Change) {
+	for key, change := range changes {
+		if !change.applied {
+			continue
+		}
+
+		switch change.state {
+		case ResourceStateCreated, ResourceStateUpdated, ResourceStateDeleted:
+			objectsChanged.WithLabelValues(key.Group, key.Kind, string(change.state)).Inc()
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_countChangedObjects(t *testing.T) {
+	objectsChanged.Reset()
+
+	countChangedObjects(map[ObjectKey]*Change{
+		{
+			GroupVersionKind: AppsDeploymentGVK,
+			ObjectKey:        types.NamespacedName{Namespace: "default", Name: "test"},
+		}: {state: ResourceStateUpdated, applied: true},
+		{
+			GroupVersionKind: corev1.SchemeGroupVersion.WithKind("Service"),
+			ObjectKey:        types.NamespacedName{Namespace: "default", Name: "test"},
+		}: {state: ResourceStateCreated},
+		{
+			GroupVersionKind: corev1.SchemeGroupVersion.WithKind("ServiceAccount"),
+			ObjectKey:        types.NamespacedName{Namespace: "default", Name: "test"},
+		}: {state: ResourceStateUnchanged, applied: true},
+	})
+
+	require.Equal(t, 1.0, testutil.ToFloat64(objectsChanged.WithLabelValues("apps", "Deployment", "updated")))
+	require.Equal(t, 0.0, testutil.ToFloat64(objectsChanged.WithLabelValues("", "Service", "created")))
+	require.Equal(t, 0.0, testutil.ToFloat64(objectsChanged.WithLabelValues("", "ServiceAccount", "unchanged")))
+}
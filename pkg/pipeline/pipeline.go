@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	configv1alpha1 "github.com/rigdev/rig/pkg/api/config/v1alpha1"
@@ -21,6 +23,10 @@ type Object struct {
 	Current      client.Object
 	New          client.Object
 	Materialized client.Object
+
+	// versions and patches are used with server-side apply.
+	versions []managedVersion
+	patches  []fieldManagerPatch
 }
 
 type ObjectKey struct {
@@ -147,6 +153,16 @@ type OutputObject struct {
 type Result struct {
 	InputObjects  []client.Object
 	OutputObjects []OutputObject
+	// Steps holds the time spent in each step of the pipeline.
+	Steps []StepTiming
+	// Requeue is set if objects are being replaced, and the request must be run
+	// again to create their new versions.
+	Requeue bool
+}
+
+type StepTiming struct {
+	Name     string
+	Duration time.Duration
 }
 
 type PluginConfigResult struct {
@@ -178,7 +194,9 @@ func ExecuteRequest[T Request](
 	commit bool,
 	opts Options,
 ) (*Result, error) {
+	start := time.Now()
 	result, err := executeRequestInner(ctx, req, steps, commit, opts)
+	observeExecution(req.GetBase().dryRun, time.Since(start).Seconds(), err)
 	if errors.IsFailedPrecondition(err) {
 		return nil, err
 	} else if err != nil {
@@ -209,10 +227,18 @@ func executeRequestInner[T Request](
 		req.GetBase().logger.Info("run steps", "existing_objects", maps.Keys(req.GetBase().existingObjects))
 
 		for _, s := range steps {
-			if err := s.Apply(ctx, req.GetRequest(), opts); err != nil {
+			req.GetBase().fieldManager = StepFieldManager(s.Name())
+			start := time.Now()
+			err := s.Apply(ctx, req.GetRequest(), opts)
+			result.Steps = append(result.Steps, StepTiming{
+				Name:     s.Name(),
+				Duration: time.Since(start),
+			})
+			if err != nil {
 				return nil, fmt.Errorf("step %s failed: %w", s.Name(), err)
 			}
 		}
+		req.GetBase().fieldManager = FieldManager
 
 		if !commit {
 			return result, nil
@@ -221,13 +247,19 @@ func executeRequestInner[T Request](
 		changes, err := req.GetBase().Commit(ctx)
 		if errors.IsAborted(err) {
 			req.GetBase().logger.Info("retry running steps", "reason", errors.MessageOf(err))
+			executionRetries.WithLabelValues(strconv.FormatBool(req.GetBase().dryRun)).Inc()
 			continue
 		} else if err != nil {
 			req.GetBase().logger.Error(err, "error committing changes")
 			return nil, err
 		}
 
+		countChangedObjects(changes)
+
 		for key, c := range changes {
+			if c.requeue {
+				result.Requeue = true
+			}
 			obj := req.GetBase().newObjects[key].Materialized
 			if obj == nil {
 				obj = req.GetBase().newObjects[key].New
@@ -3,15 +3,18 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	configv1alpha1 "github.com/rigdev/rig/pkg/api/config/v1alpha1"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
 	"github.com/rigdev/rig/pkg/errors"
 	"github.com/rigdev/rig/pkg/scheme"
 	"golang.org/x/exp/maps"
 	v1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -109,6 +112,7 @@ type RequestState struct {
 	lastErrors         []string
 	dryRun             bool
 	force              bool
+	fieldManager       string
 }
 
 type RequestStrategies interface {
@@ -253,6 +257,11 @@ func (r *RequestBase) Set(obj client.Object) error {
 	if !ok {
 		o = &Object{}
 	}
+	if r.serverSideApply() {
+		if err := r.addVersion(o, obj); err != nil {
+			return err
+		}
+	}
 	o.New = obj
 	r.newObjects[key] = o
 	return nil
@@ -267,6 +276,7 @@ func (r *RequestBase) Delete(gvk schema.GroupVersionKind, name string) error {
 	o, ok := r.newObjects[key]
 	if ok {
 		o.New = nil
+		o.versions = nil
 	}
 
 	return nil
@@ -315,6 +325,22 @@ func (r *RequestBase) ListNew(gvk schema.GroupVersionKind) ([]client.Object, err
 	return res, nil
 }
 
+// ListAllNew returns a list with a copy of all the objects about to be applied, sorted by their key.
+func (r *RequestBase) ListAllNew() []client.Object {
+	var res []client.Object
+	for _, key := range sortedKeys(maps.Keys(r.newObjects)) {
+		no := r.newObjects[key]
+		if no.New == nil {
+			continue
+		}
+		o := no.New.DeepCopyObject().(client.Object)
+		o.GetObjectKind().SetGroupVersionKind(key.GroupVersionKind)
+		res = append(res, o)
+	}
+
+	return res
+}
+
 func (r *RequestBase) Commit(ctx context.Context) (map[ObjectKey]*Change, error) {
 	allKeys := sortedKeys(maps.Keys(r.newObjects))
 
@@ -398,6 +424,25 @@ func (r *RequestBase) Commit(ctx context.Context) (map[ObjectKey]*Change, error)
 			continue
 		}
 
+		if needsReplace(key, cObj) {
+			// Selectors of Deployments are immutable, so the Deployment is replaced. Its pods are
+			// orphaned and adopted by the new Deployment, which is created right after.
+			cObj.Materialized = normalizeObject(key, cObj.New.DeepCopyObject().(client.Object))
+			r.logger.Info("replace object", "object", key)
+			changes[key] = &Change{state: ResourceStateUpdated, replace: true}
+			continue
+		}
+
+		if r.serverSideApply() {
+			change, err := r.dryRunServerSideApply(ctx, key, cObj)
+			if err != nil {
+				return nil, fmt.Errorf("could not render apply to %s: %w", key, err)
+			}
+			r.logger.Info("apply object", "object", key, "state", change.state, "conflicts", len(change.conflicts))
+			changes[key] = change
+			continue
+		}
+
 		materializedObj := cObj.New.DeepCopyObject().(client.Object)
 		materializedObj.GetObjectKind().SetGroupVersionKind(cObj.Current.GetObjectKind().GroupVersionKind())
 
@@ -482,7 +527,7 @@ func (r *RequestBase) Commit(ctx context.Context) (map[ObjectKey]*Change, error)
 	var errs []error
 	for _, key := range sortedKeys(maps.Keys(changes)) {
 		change := changes[key]
-		if err := r.applyChange(ctx, key, change.state); err != nil {
+		if err := r.applyChange(ctx, key, change); err != nil {
 			change.err = err
 			errs = append(errs, err)
 		} else {
@@ -501,9 +546,13 @@ func (r *RequestBase) Commit(ctx context.Context) (map[ObjectKey]*Change, error)
 	return changes, nil
 }
 
-func (r *RequestBase) applyChange(ctx context.Context, key ObjectKey, state ResourceState) error {
-	switch state {
+func (r *RequestBase) applyChange(ctx context.Context, key ObjectKey, change *Change) error {
+	switch change.state {
 	case ResourceStateUpdated:
+		if change.replace {
+			return r.replaceObject(ctx, key, change)
+		}
+
 		r.logger.Info("update object", "object", key)
 		object := r.newObjects[key]
 
@@ -543,6 +592,10 @@ func (r *RequestBase) applyChange(ctx context.Context, key ObjectKey, state Reso
 			}
 		}
 
+		if r.serverSideApply() {
+			return r.applyServerSide(ctx, key, object, false)
+		}
+
 		object.New.SetResourceVersion(object.Current.GetResourceVersion())
 		if err := r.client.Update(ctx, object.New); err != nil {
 			return fmt.Errorf("could not update %s: %w", key.GroupVersionKind, err)
@@ -551,6 +604,10 @@ func (r *RequestBase) applyChange(ctx context.Context, key ObjectKey, state Reso
 	case ResourceStateCreated:
 		r.logger.Info("create object", "object", key)
 		obj := r.newObjects[key]
+		if r.serverSideApply() {
+			return r.applyServerSide(ctx, key, obj, true)
+		}
+
 		if err := r.client.Create(ctx, obj.New); err != nil {
 			return fmt.Errorf("could not create %s: %w", key.GroupVersionKind, err)
 		}
@@ -558,7 +615,10 @@ func (r *RequestBase) applyChange(ctx context.Context, key ObjectKey, state Reso
 	case ResourceStateDeleted:
 		r.logger.Info("delete object", "object", key)
 		obj := r.newObjects[key]
-		if err := r.client.Delete(ctx, obj.Current); err != nil {
+		// Jobs orphan their pods by default, so dependents are deleted explicitly.
+		if err := r.client.Delete(
+			ctx, obj.Current, client.PropagationPolicy(metav1.DeletePropagationBackground),
+		); err != nil {
 			return fmt.Errorf("could not update %s: %w", key.GroupVersionKind, err)
 		}
 	}
@@ -566,6 +626,67 @@ func (r *RequestBase) applyChange(ctx context.Context, key ObjectKey, state Reso
 	return nil
 }
 
+// replaceRequeueAfter is how long to wait before running the request again, while the
+// current version of a replaced object is terminating.
+const replaceRequeueAfter = time.Second
+
+// replaceObject deletes the current version of the object, orphaning its dependents, and
+// creates the new version if the current one is gone. The current version is kept until
+// the garbage collector has orphaned its dependents, in which case the change is marked
+// for requeue, and the object is replaced again when the request is run next.
+func (r *RequestBase) replaceObject(ctx context.Context, key ObjectKey, change *Change) error {
+	r.logger.Info("replace object", "object", key)
+	object := r.newObjects[key]
+	if err := r.client.Delete(
+		ctx, object.Current, client.PropagationPolicy(metav1.DeletePropagationOrphan),
+	); err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("could not replace %s: %w", key.GroupVersionKind, err)
+	}
+
+	if err := r.client.Get(ctx, key.ObjectKey, object.Current.DeepCopyObject().(client.Object)); err == nil {
+		r.logger.Info("replaced object is terminating, requeueing", "object", key)
+		change.requeue = true
+		return nil
+	} else if !kerrors.IsNotFound(err) {
+		return fmt.Errorf("could not replace %s: %w", key.GroupVersionKind, err)
+	}
+
+	object.New.SetResourceVersion("")
+	if r.serverSideApply() {
+		return r.applyServerSide(ctx, key, object, true)
+	}
+
+	if err := r.client.Create(ctx, object.New); err != nil {
+		return fmt.Errorf("could not create %s: %w", key.GroupVersionKind, err)
+	}
+
+	return nil
+}
+
+// needsReplace returns true if the selector of a Deployment is changed to exclude the
+// pods of a canary, see StableSelector. Selectors are immutable, so the change can only
+// be applied by deleting and recreating the Deployment. Other changes to the selector
+// are applied as updates, which the API server rejects.
+func needsReplace(key ObjectKey, object *Object) bool {
+	if key.GroupVersionKind != AppsDeploymentGVK {
+		return false
+	}
+
+	current, ok := object.Current.(*v1.Deployment)
+	if !ok {
+		return false
+	}
+	newObj, ok := object.New.(*v1.Deployment)
+	if !ok {
+		return false
+	}
+
+	if equality.Semantic.DeepEqual(current.Spec.Selector, newObj.Spec.Selector) {
+		return false
+	}
+	return equality.Semantic.DeepEqual(StableSelector(current.Spec.Selector), newObj.Spec.Selector)
+}
+
 func normalizeObject(key ObjectKey, obj client.Object) client.Object {
 	obj.SetManagedFields(nil)
 	obj.GetObjectKind().SetGroupVersionKind(key.GroupVersionKind)
@@ -573,9 +694,15 @@ func normalizeObject(key ObjectKey, obj client.Object) client.Object {
 }
 
 type Change struct {
-	state   ResourceState
-	applied bool
-	err     error
+	state     ResourceState
+	applied   bool
+	err       error
+	conflicts []v1alpha2.FieldConflict
+	// replace is set if the object is deleted and recreated to apply the change.
+	replace bool
+	// requeue is set if the object is replaced, but the current version is still
+	// terminating.
+	requeue bool
 }
 
 type ResourceState string
@@ -593,6 +720,7 @@ const (
 func (r *RequestBase) PrepareRequest() *Result {
 	result := &Result{}
 	r.newObjects = map[ObjectKey]*Object{}
+	r.fieldManager = FieldManager
 	for _, k := range sortedKeys(maps.Keys(r.existingObjects)) {
 		o := r.existingObjects[k]
 		r.newObjects[k] = &Object{
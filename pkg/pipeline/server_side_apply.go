@@ -0,0 +1,588 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/obj"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FieldManager is the field manager of the operator. With server-side apply,
+// each step applies its fields under the field manager `rig-operator/<step>`,
+// while the fields set by the operator itself, such as owner references, are
+// applied under FieldManager.
+const FieldManager = "rig-operator"
+
+// StepFieldManager returns the field manager of the step with the given name.
+func StepFieldManager(step string) string {
+	return FieldManager + "/" + step
+}
+
+// isOperatorFieldManager returns true if the field manager is the operator or one of its steps.
+func isOperatorFieldManager(manager string) bool {
+	return manager == FieldManager || strings.HasPrefix(manager, FieldManager+"/")
+}
+
+var conflictManagerRegexp = regexp.MustCompile(`conflict with "([^"]*)"`)
+
+// managedVersion is a version of an object, as set by a field manager.
+type managedVersion struct {
+	manager string
+	object  map[string]any
+}
+
+// fieldManagerPatch is the part of an object applied by a field manager.
+type fieldManagerPatch struct {
+	manager string
+	object  *unstructured.Unstructured
+	force   bool
+}
+
+func (r *RequestBase) serverSideApply() bool {
+	return r.config != nil && r.config.Pipeline.ServerSideApply
+}
+
+// addVersion records the object as set by the current field manager.
+func (r *RequestBase) addVersion(o *Object, co client.Object) error {
+	content, err := toUnstructured(co)
+	if err != nil {
+		return err
+	}
+
+	v := managedVersion{manager: r.fieldManager, object: content}
+	if n := len(o.versions); n > 0 && o.versions[n-1].manager == v.manager {
+		o.versions[n-1] = v
+	} else {
+		o.versions = append(o.versions, v)
+	}
+	return nil
+}
+
+// fieldManagerPatches splits the new version of an object into the parts
+// applied by each field manager. Each field is applied by the field manager
+// which last changed it, where fields set by Commit are applied by FieldManager.
+// Lists are not split, but applied as a whole by a single field manager.
+func fieldManagerPatches(o *Object) ([]fieldManagerPatch, error) {
+	final, err := toUnstructured(o.New)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := append(slices.Clone(o.versions), managedVersion{manager: FieldManager, object: final})
+
+	var managers []string
+	partials := map[string]map[string]any{}
+	var walk func(path []string, value any)
+	walk = func(path []string, value any) {
+		if m, ok := value.(map[string]any); ok && len(m) > 0 {
+			for k, v := range m {
+				walk(append(slices.Clone(path), k), v)
+			}
+			return
+		}
+
+		if isIdentityField(path) {
+			return
+		}
+
+		manager := FieldManager
+		for i := len(versions) - 1; i >= 0; i-- {
+			cur, curOK, _ := unstructured.NestedFieldNoCopy(versions[i].object, path...)
+			var prev any
+			prevOK := false
+			if i > 0 {
+				prev, prevOK, _ = unstructured.NestedFieldNoCopy(versions[i-1].object, path...)
+			}
+			if curOK != prevOK || !reflect.DeepEqual(cur, prev) {
+				manager = versions[i].manager
+				break
+			}
+		}
+
+		partial, ok := partials[manager]
+		if !ok {
+			partial = map[string]any{}
+			partials[manager] = partial
+			managers = append(managers, manager)
+		}
+		_ = unstructured.SetNestedField(partial, value, path...)
+	}
+	walk(nil, final)
+
+	if _, ok := partials[FieldManager]; !ok {
+		partials[FieldManager] = map[string]any{}
+		managers = append(managers, FieldManager)
+	}
+
+	// Apply the fields of the steps in order, and the fields of the operator last.
+	slices.SortStableFunc(managers, func(a, b string) int {
+		return managerIndex(versions, a) - managerIndex(versions, b)
+	})
+
+	var res []fieldManagerPatch
+	for _, m := range managers {
+		res = append(res, fieldManagerPatch{
+			manager: m,
+			object:  identityObject(o.New, partials[m]),
+		})
+	}
+	return res, nil
+}
+
+// releasedFieldManagers returns the step field managers which have applied fields
+// to the current version of the object, but no longer set any fields of it.
+func releasedFieldManagers(current client.Object, patches []fieldManagerPatch) []string {
+	var res []string
+	for _, mf := range current.GetManagedFields() {
+		if mf.Operation != metav1.ManagedFieldsOperationApply ||
+			mf.Subresource != "" ||
+			!isOperatorFieldManager(mf.Manager) {
+			continue
+		}
+		if slices.ContainsFunc(patches, func(p fieldManagerPatch) bool { return p.manager == mf.Manager }) {
+			continue
+		}
+		if !slices.Contains(res, mf.Manager) {
+			res = append(res, mf.Manager)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+func managerIndex(versions []managedVersion, manager string) int {
+	if manager == FieldManager {
+		return len(versions)
+	}
+	return slices.IndexFunc(versions, func(v managedVersion) bool { return v.manager == manager })
+}
+
+func isIdentityField(path []string) bool {
+	switch strings.Join(path, ".") {
+	case "apiVersion", "kind", "metadata.name", "metadata.namespace":
+		return true
+	}
+	return false
+}
+
+// identityObject returns an object with the type and name of co and the given content.
+func identityObject(co client.Object, content map[string]any) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(co.GetObjectKind().GroupVersionKind())
+	u.SetName(co.GetName())
+	u.SetNamespace(co.GetNamespace())
+	return u
+}
+
+func toUnstructured(co client.Object) (map[string]any, error) {
+	var content map[string]any
+	if u, ok := co.(*unstructured.Unstructured); ok {
+		content = runtime.DeepCopyJSON(u.UnstructuredContent())
+	} else {
+		var err error
+		if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(co); err != nil {
+			return nil, err
+		}
+	}
+
+	// Remove fields which are set by the API server.
+	delete(content, "status")
+	for _, f := range []string{
+		"managedFields", "resourceVersion", "uid", "creationTimestamp",
+		"generation", "selfLink", "deletionTimestamp", "deletionGracePeriodSeconds",
+	} {
+		unstructured.RemoveNestedField(content, "metadata", f)
+	}
+
+	return content, nil
+}
+
+// applyPatch applies the patch of a field manager. If the patch conflicts with
+// field managers of the operator, it is retried with force. Fields conflicting
+// with other field managers are removed from the patch, after which the rest of
+// the patch is applied, and the removed fields are returned as conflicts.
+func (r *RequestBase) applyPatch(
+	ctx context.Context,
+	key ObjectKey,
+	patch *fieldManagerPatch,
+	dryRun bool,
+) (client.Object, []v1alpha2.FieldConflict, error) {
+	var skipped []v1alpha2.FieldConflict
+	for {
+		u := patch.object.DeepCopy()
+		opts := []client.PatchOption{client.FieldOwner(patch.manager)}
+		if patch.force {
+			opts = append(opts, client.ForceOwnership)
+		}
+		if dryRun {
+			opts = append(opts, client.DryRunAll)
+		}
+
+		err := r.client.Patch(ctx, u, client.Apply, opts...)
+		if err == nil {
+			co, err := r.fromUnstructured(key, u)
+			return co, skipped, err
+		}
+
+		if !kerrors.IsConflict(err) || patch.force {
+			return nil, nil, fmt.Errorf("could not apply %s as %s: %w", key, patch.manager, err)
+		}
+
+		conflicts := fieldConflicts(err)
+		if len(conflicts) == 0 {
+			return nil, nil, fmt.Errorf("could not apply %s as %s: %w", key, patch.manager, err)
+		}
+
+		var foreign []v1alpha2.FieldConflict
+		for _, c := range conflicts {
+			if !isOperatorFieldManager(c.Manager) {
+				foreign = append(foreign, c)
+			}
+		}
+		if len(foreign) == 0 {
+			// Only conflicting with the operator itself, e.g. when a field moves
+			// between steps or was previously set with an update.
+			r.logger.Info("forcing apply of fields owned by the operator", "object", key, "manager", patch.manager)
+			patch.force = true
+			continue
+		}
+
+		// The patch is copied, as it is shared with the object.
+		object := patch.object.DeepCopy()
+		for _, c := range foreign {
+			if !removeField(object.Object, c.Field) {
+				return nil, nil, fmt.Errorf(
+					"could not apply %s as %s, could not skip field %s conflicting with %s",
+					key, patch.manager, c.Field, c.Manager,
+				)
+			}
+		}
+		r.logger.Info("skipping fields conflicting with other field managers",
+			"object", key, "manager", patch.manager, "conflicts", len(foreign))
+		patch.object = object
+		skipped = append(skipped, foreign...)
+		if len(foreign) < len(conflicts) {
+			// The rest of the conflicts are with the operator itself.
+			patch.force = true
+		}
+	}
+}
+
+// removeField removes the field with the given path, as formatted in field
+// manager conflicts (e.g. `.spec.containers[name="main"].image`), from the
+// content. Keys of maps can contain dots, so the path is matched against the
+// keys of the content instead of being split. Returns false if the field is
+// not found.
+func removeField(content map[string]any, field string) bool {
+	rest, ok := strings.CutPrefix(field, ".")
+	if !ok {
+		return false
+	}
+
+	for k, v := range content {
+		if rest == k {
+			delete(content, k)
+			return true
+		}
+
+		sub, ok := strings.CutPrefix(rest, k)
+		if !ok {
+			continue
+		}
+
+		switch v := v.(type) {
+		case map[string]any:
+			if strings.HasPrefix(sub, ".") && removeField(v, sub) {
+				if len(v) == 0 {
+					delete(content, k)
+				}
+				return true
+			}
+		case []any:
+			if l, ok := removeListField(v, sub); ok {
+				if len(l) == 0 {
+					delete(content, k)
+				} else {
+					content[k] = l
+				}
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// removeListField removes the field with the given path, starting with the
+// selector of a list element, from the list.
+func removeListField(list []any, field string) ([]any, bool) {
+	selector, rest, ok := cutListSelector(field)
+	if !ok {
+		return nil, false
+	}
+
+	idx := -1
+	if i, err := strconv.Atoi(selector); err == nil {
+		idx = i
+	} else if value, ok := strings.CutPrefix(selector, "="); ok {
+		idx = slices.IndexFunc(list, func(e any) bool { return jsonEqual(e, value) })
+	} else {
+		keys := splitListSelector(selector)
+		idx = slices.IndexFunc(list, func(e any) bool {
+			m, ok := e.(map[string]any)
+			if !ok {
+				return false
+			}
+			for _, kv := range keys {
+				k, v, _ := strings.Cut(kv, "=")
+				if !jsonEqual(m[k], v) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+	if idx < 0 || idx >= len(list) {
+		return nil, false
+	}
+
+	if rest == "" {
+		return slices.Delete(slices.Clone(list), idx, idx+1), true
+	}
+
+	m, ok := list[idx].(map[string]any)
+	if !ok || !removeField(m, rest) {
+		return nil, false
+	}
+	return list, true
+}
+
+// cutListSelector returns the selector of a list element in brackets at the
+// start of the path, and the rest of the path.
+func cutListSelector(field string) (string, string, bool) {
+	if !strings.HasPrefix(field, "[") {
+		return "", "", false
+	}
+
+	inString := false
+	for i := 1; i < len(field); i++ {
+		switch {
+		case inString && field[i] == '\\':
+			i++
+		case field[i] == '"':
+			inString = !inString
+		case !inString && field[i] == ']':
+			return field[1:i], field[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// splitListSelector splits the key-value pairs of a list element selector.
+func splitListSelector(selector string) []string {
+	var res []string
+	inString := false
+	start := 0
+	for i := 0; i < len(selector); i++ {
+		switch {
+		case inString && selector[i] == '\\':
+			i++
+		case selector[i] == '"':
+			inString = !inString
+		case !inString && selector[i] == ',':
+			res = append(res, selector[start:i])
+			start = i + 1
+		}
+	}
+	return append(res, selector[start:])
+}
+
+// jsonEqual returns true if the value is equal to the JSON encoded value.
+func jsonEqual(value any, encoded string) bool {
+	var decoded any
+	if err := json.Unmarshal([]byte(encoded), &decoded); err != nil {
+		return false
+	}
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	var normalized any
+	if err := json.Unmarshal(bs, &normalized); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(decoded, normalized)
+}
+
+// fieldConflicts returns the field conflicts of an apply conflict error.
+func fieldConflicts(err error) []v1alpha2.FieldConflict {
+	var res []v1alpha2.FieldConflict
+	status, ok := err.(kerrors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return nil
+	}
+
+	for _, c := range status.Status().Details.Causes {
+		if c.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		fc := v1alpha2.FieldConflict{
+			Field:   c.Field,
+			Message: c.Message,
+		}
+		if m := conflictManagerRegexp.FindStringSubmatch(c.Message); m != nil {
+			fc.Manager = m[1]
+		}
+		res = append(res, fc)
+	}
+	return res
+}
+
+// fromUnstructured converts an applied object to its type in the scheme.
+func (r *RequestBase) fromUnstructured(key ObjectKey, u *unstructured.Unstructured) (client.Object, error) {
+	co := obj.New(key.GroupVersionKind, r.scheme)
+	if _, ok := co.(*unstructured.Unstructured); ok {
+		return u, nil
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), co); err != nil {
+		return nil, err
+	}
+	co.GetObjectKind().SetGroupVersionKind(key.GroupVersionKind)
+	return co, nil
+}
+
+// managedFieldsChanged returns true if the fields managed by the manager differ between the objects.
+func managedFieldsChanged(current, updated client.Object, manager string) bool {
+	return !reflect.DeepEqual(managedFieldsOf(current, manager), managedFieldsOf(updated, manager))
+}
+
+func managedFieldsOf(co client.Object, manager string) []byte {
+	for _, mf := range co.GetManagedFields() {
+		if mf.Manager == manager && mf.Operation == metav1.ManagedFieldsOperationApply && mf.Subresource == "" {
+			if mf.FieldsV1 == nil {
+				return nil
+			}
+			return mf.FieldsV1.Raw
+		}
+	}
+	return nil
+}
+
+// dryRunServerSideApply computes the change of an existing object by dry-running
+// the apply of each field manager.
+func (r *RequestBase) dryRunServerSideApply(ctx context.Context, key ObjectKey, o *Object) (*Change, error) {
+	patches, err := fieldManagerPatches(o)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &Change{state: ResourceStateUnchanged}
+	o.patches = nil
+	for i := range patches {
+		patch := &patches[i]
+		patch.force = r.force
+
+		res, conflicts, err := r.applyPatch(ctx, key, patch, true)
+		if err != nil {
+			return nil, err
+		}
+		change.conflicts = append(change.conflicts, conflicts...)
+
+		o.patches = append(o.patches, *patch)
+
+		equal, err := ObjectsEquals(o.Current, res, r.scheme)
+		if err != nil {
+			return nil, err
+		}
+		if !equal || managedFieldsChanged(o.Current, res, patch.manager) {
+			change.state = ResourceStateUpdated
+		}
+	}
+
+	for _, m := range releasedFieldManagers(o.Current, patches) {
+		r.logger.Info("releasing fields of field manager", "object", key, "manager", m)
+		o.patches = append(o.patches, fieldManagerPatch{
+			manager: m,
+			object:  identityObject(o.New, map[string]any{}),
+		})
+		change.state = ResourceStateUpdated
+	}
+
+	if change.state == ResourceStateUpdated {
+		// Materialize the complete new version of the object.
+		full, err := fullPatch(o.New, true)
+		if err != nil {
+			return nil, err
+		}
+		materialized, _, err := r.applyPatch(ctx, key, full, true)
+		if err != nil {
+			return nil, err
+		}
+		o.Materialized = normalizeObject(key, materialized)
+	}
+
+	return change, nil
+}
+
+// applyServerSide creates or updates the object by applying the fields of
+// each field manager.
+func (r *RequestBase) applyServerSide(ctx context.Context, key ObjectKey, o *Object, create bool) error {
+	// Patches are recomputed, as the new object may have been adjusted since the dry run.
+	patches, err := fieldManagerPatches(o)
+	if err != nil {
+		return err
+	}
+
+	if create {
+		// Create the complete object, after which its fields are handed over to the steps.
+		full, err := fullPatch(o.New, r.force)
+		if err != nil {
+			return err
+		}
+		if _, _, err := r.applyPatch(ctx, key, full, false); err != nil {
+			return err
+		}
+		o.patches = patches
+	}
+
+	for _, p := range o.patches {
+		if idx := slices.IndexFunc(patches, func(np fieldManagerPatch) bool {
+			return np.manager == p.manager
+		}); idx >= 0 {
+			p.object = patches[idx].object
+		}
+
+		if _, _, err := r.applyPatch(ctx, key, &p, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fullPatch returns a patch of the complete object, applied by FieldManager.
+func fullPatch(co client.Object, force bool) (*fieldManagerPatch, error) {
+	content, err := toUnstructured(co)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fieldManagerPatch{
+		manager: FieldManager,
+		object:  identityObject(co, content),
+		force:   force,
+	}, nil
+}
@@ -0,0 +1,293 @@
+package pipeline
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_fieldManagerPatches(t *testing.T) {
+	r := &RequestBase{}
+	o := &Object{}
+
+	d := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "test"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.New(int32(2)),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "test", Image: "nginx"}},
+				},
+			},
+		},
+	}
+	r.fieldManager = StepFieldManager("deployment")
+	require.NoError(t, r.addVersion(o, d))
+
+	d = d.DeepCopy()
+	d.Spec.Replicas = ptr.New(int32(3))
+	d.Spec.Template.Spec.ServiceAccountName = "test"
+	r.fieldManager = StepFieldManager("custom")
+	require.NoError(t, r.addVersion(o, d))
+
+	d = d.DeepCopy()
+	d.Labels[LabelOwnedByCapsule] = "test"
+	o.New = d
+
+	patches, err := fieldManagerPatches(o)
+	require.NoError(t, err)
+
+	require.Len(t, patches, 3)
+	require.Equal(t, StepFieldManager("deployment"), patches[0].manager)
+	require.Equal(t, StepFieldManager("custom"), patches[1].manager)
+	require.Equal(t, FieldManager, patches[2].manager)
+
+	deployment := patches[0].object
+	require.Equal(t, "test", deployment.GetName())
+	require.Equal(t, "default", deployment.GetNamespace())
+	require.Equal(t, map[string]string{"app": "test"}, deployment.GetLabels())
+	_, found := deployment.Object["spec"].(map[string]any)["replicas"]
+	require.False(t, found)
+	containers, _, err := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	require.NoError(t, err)
+	require.Len(t, containers, 1)
+
+	custom := patches[1].object
+	require.Equal(t, int64(3), custom.Object["spec"].(map[string]any)["replicas"])
+	podSpec, _, err := unstructured.NestedMap(custom.Object, "spec", "template", "spec")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"serviceAccountName": "test"}, podSpec)
+
+	require.Equal(t, map[string]string{LabelOwnedByCapsule: "test"}, patches[2].object.GetLabels())
+}
+
+func Test_releasedFieldManagers(t *testing.T) {
+	current := &appsv1.Deployment{}
+	current.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: StepFieldManager("deployment"), Operation: metav1.ManagedFieldsOperationApply},
+		{Manager: StepFieldManager("custom"), Operation: metav1.ManagedFieldsOperationApply},
+		{Manager: StepFieldManager("custom"), Operation: metav1.ManagedFieldsOperationApply, Subresource: "status"},
+		{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate},
+		{Manager: FieldManager, Operation: metav1.ManagedFieldsOperationUpdate},
+	})
+
+	require.Equal(t, []string{StepFieldManager("custom")}, releasedFieldManagers(current, []fieldManagerPatch{
+		{manager: StepFieldManager("deployment")},
+		{manager: FieldManager},
+	}))
+}
+
+func Test_fieldConflicts(t *testing.T) {
+	err := &kerrors.StatusError{ErrStatus: metav1.Status{
+		Status: metav1.StatusFailure,
+		Code:   http.StatusConflict,
+		Reason: metav1.StatusReasonConflict,
+		Details: &metav1.StatusDetails{
+			Causes: []metav1.StatusCause{
+				{
+					Type:    metav1.CauseTypeFieldManagerConflict,
+					Message: `conflict with "kubectl-edit" using apps/v1`,
+					Field:   ".spec.replicas",
+				},
+				{
+					Type:    metav1.CauseTypeFieldManagerConflict,
+					Message: `conflict with "rig-operator/rigdev.deployment"`,
+					Field:   ".spec.template.spec.serviceAccountName",
+				},
+			},
+		},
+	}}
+
+	require.Equal(t, []v1alpha2.FieldConflict{
+		{
+			Field:   ".spec.replicas",
+			Manager: "kubectl-edit",
+			Message: `conflict with "kubectl-edit" using apps/v1`,
+		},
+		{
+			Field:   ".spec.template.spec.serviceAccountName",
+			Manager: "rig-operator/rigdev.deployment",
+			Message: `conflict with "rig-operator/rigdev.deployment"`,
+		},
+	}, fieldConflicts(err))
+}
+
+func Test_removeField(t *testing.T) {
+	content := func() map[string]any {
+		return map[string]any{
+			"metadata": map[string]any{
+				"labels": map[string]any{
+					"app.kubernetes.io/name": "test",
+					"app":                    "test",
+				},
+			},
+			"spec": map[string]any{
+				"replicas": int64(2),
+				"template": map[string]any{
+					"spec": map[string]any{
+						"containers": []any{
+							map[string]any{
+								"name":  "main",
+								"image": "nginx",
+								"ports": []any{
+									map[string]any{"containerPort": int64(80), "protocol": "TCP"},
+								},
+							},
+							map[string]any{"name": "sidecar", "image": "envoy"},
+						},
+						"finalizers": []any{"a", "b"},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		field    string
+		removed  bool
+		expected func(m map[string]any)
+	}{
+		{
+			name:    "scalar",
+			field:   ".spec.replicas",
+			removed: true,
+			expected: func(m map[string]any) {
+				delete(m["spec"].(map[string]any), "replicas")
+			},
+		},
+		{
+			name:    "key with dots",
+			field:   ".metadata.labels.app.kubernetes.io/name",
+			removed: true,
+			expected: func(m map[string]any) {
+				delete(m["metadata"].(map[string]any)["labels"].(map[string]any), "app.kubernetes.io/name")
+			},
+		},
+		{
+			name:    "field of keyed list element",
+			field:   `.spec.template.spec.containers[name="sidecar"].image`,
+			removed: true,
+			expected: func(m map[string]any) {
+				containers := m["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["containers"]
+				delete(containers.([]any)[1].(map[string]any), "image")
+			},
+		},
+		{
+			name: "element with multiple keys",
+			field: `.spec.template.spec.containers[name="main"].ports` +
+				`[containerPort=80,protocol="TCP"]`,
+			removed: true,
+			expected: func(m map[string]any) {
+				containers := m["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["containers"]
+				delete(containers.([]any)[0].(map[string]any), "ports")
+			},
+		},
+		{
+			name:    "set element",
+			field:   `.spec.template.spec.finalizers[="a"]`,
+			removed: true,
+			expected: func(m map[string]any) {
+				m["spec"].(map[string]any)["template"].(map[string]any)["spec"].(map[string]any)["finalizers"] = []any{"b"}
+			},
+		},
+		{
+			name:     "missing field",
+			field:    `.spec.template.spec.containers[name="other"].image`,
+			expected: func(map[string]any) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := content()
+			require.Equal(t, tt.removed, removeField(m, tt.field))
+			expected := content()
+			tt.expected(expected)
+			require.Equal(t, expected, m)
+		})
+	}
+}
+
+func Test_applyPatch_skipsConflictingFields(t *testing.T) {
+	ctx, cc, c := preparePipelineTest(t, pipelineTestOpts{})
+
+	d := &appsv1.Deployment{}
+	d.SetName("test")
+	key, err := c.GetKey(appsv1.SchemeGroupVersion.WithKind("Deployment"), d.GetName())
+	require.NoError(t, err)
+
+	patch := &fieldManagerPatch{
+		manager: StepFieldManager("deployment"),
+		object: identityObject(d, map[string]any{
+			"spec": map[string]any{
+				"replicas":        int64(2),
+				"minReadySeconds": int64(5),
+				"paused":          false,
+			},
+		}),
+	}
+	shared := patch.object
+	original := shared.DeepCopy()
+
+	conflict := &kerrors.StatusError{ErrStatus: metav1.Status{
+		Status: metav1.StatusFailure,
+		Code:   http.StatusConflict,
+		Reason: metav1.StatusReasonConflict,
+		Details: &metav1.StatusDetails{
+			Causes: []metav1.StatusCause{
+				{
+					Type:    metav1.CauseTypeFieldManagerConflict,
+					Message: `conflict with "kubectl-edit" using apps/v1`,
+					Field:   ".spec.replicas",
+				},
+				{
+					Type:    metav1.CauseTypeFieldManagerConflict,
+					Message: `conflict with "rig-operator/rigdev.other"`,
+					Field:   ".spec.minReadySeconds",
+				},
+			},
+		},
+	}}
+
+	var applied []map[string]any
+	record := func(_ context.Context, o client.Object, _ client.Patch, _ ...client.PatchOption) {
+		applied = append(applied, o.(*unstructured.Unstructured).DeepCopy().Object)
+	}
+	owner := client.FieldOwner(patch.manager)
+	cc.EXPECT().Patch(ctx, mock.Anything, client.Apply, owner, client.DryRunAll).
+		Run(record).Return(conflict).Once()
+	cc.EXPECT().Patch(ctx, mock.Anything, client.Apply, owner, client.ForceOwnership, client.DryRunAll).
+		Run(record).Return(nil).Once()
+
+	_, conflicts, err := c.applyPatch(ctx, key, patch, true)
+	require.NoError(t, err)
+	require.Equal(t, []v1alpha2.FieldConflict{{
+		Field:   ".spec.replicas",
+		Manager: "kubectl-edit",
+		Message: `conflict with "kubectl-edit" using apps/v1`,
+	}}, conflicts)
+
+	require.Len(t, applied, 2)
+	require.Equal(t, map[string]any{
+		"minReadySeconds": int64(5),
+		"paused":          false,
+	}, applied[1]["spec"])
+	require.Equal(t, original, shared)
+}
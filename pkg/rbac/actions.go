@@ -74,6 +74,8 @@ const (
 	ActionCapsuleExecute = "capsule:execute"
 	// Restart instance
 	ActionCapsuleRestartInstance = "capsule:restartinstance"
+	// Run a job outside of its schedule
+	ActionCapsuleRunJob = "capsule:runjob"
 	// Abort rollout
 	ActionCapsuleAbortRollout = "capsule:abortrollout"
 	// Stop rollout
@@ -168,6 +170,7 @@ var CapsuleActionMap = map[string]string{
 	capsuleconnect.ServiceAbortRolloutProcedure:             ActionCapsuleAbortRollout,
 	capsuleconnect.ServiceStopRolloutProcedure:              ActionCapsuleStopRollout,
 	capsuleconnect.ServiceExecuteProcedure:                  ActionCapsuleExecute,
+	capsuleconnect.ServiceRunJobProcedure:                   ActionCapsuleRunJob,
 	capsuleconnect.ServiceRestartInstanceProcedure:          ActionCapsuleRestartInstance,
 	capsuleconnect.ServiceGetRevisionProcedure:              ActionCapsuleGetRevision,
 	capsuleconnect.ServiceGetRolloutOfRevisionsProcedure:    ActionCapsuleView,
@@ -278,6 +278,14 @@ func GetDeveloperPermissions(projectID, environmentID string) []*role.Permission
 				Project:     projectID,
 			},
 		},
+		{
+			Action: ActionCapsuleRunJob,
+			Scope: &role.Scope{
+				Resource:    WithWildcard(ResourceCapsule),
+				Environment: environmentID,
+				Project:     projectID,
+			},
+		},
 		{
 			Action: ActionImageAdd,
 			Scope: &role.Scope{
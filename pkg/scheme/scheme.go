@@ -12,6 +12,7 @@ import (
 	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
 	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
 	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 // New returns a new *runtime.Scheme configured with the types we use in
@@ -28,6 +29,7 @@ func New() *runtime.Scheme {
 	utilruntime.Must(v1alpha2.AddToScheme(s))
 	utilruntime.Must(apiextensionsv1.AddToScheme(s))
 	utilruntime.Must(vpav1.AddToScheme(s))
+	utilruntime.Must(gatewayv1.Install(s))
 
 	utilruntime.Must(metricsv1beta1.AddToScheme(s))
 
@@ -62,6 +62,18 @@ func (s *service) Get(ctx context.Context) (*capabilities.GetResponse, error) {
 	}
 	res.HasVerticalPodAutoscaler = ok
 
+	ok, err = s.hasCRD(ctx, "httproutes.gateway.networking.k8s.io")
+	if err != nil {
+		return nil, err
+	}
+	res.HasHttpRoutes = ok
+
+	ok, err = s.hasCRD(ctx, "grpcroutes.gateway.networking.k8s.io")
+	if err != nil {
+		return nil, err
+	}
+	res.HasGrpcRoutes = ok
+
 	return res, nil
 }
 
@@ -76,6 +88,17 @@ func (s *service) hasServiceMonitor(ctx context.Context) (bool, error) {
 	return true, nil
 }
 
+func (s *service) hasCRD(ctx context.Context, name string) (bool, error) {
+	if err := s.client.Get(ctx, client.ObjectKey{
+		Name: name,
+	}, &apiextensionsv1.CustomResourceDefinition{}); errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (s *service) hasVPA(ctx context.Context) (bool, error) {
 	if err := s.client.Get(ctx, client.ObjectKey{
 		Name: "verticalpodautoscalers.autoscaling.k8s.io",
@@ -43,6 +43,8 @@ func TestGet(t *testing.T) {
 				HasPrometheusServiceMonitor: true,
 				HasCustomMetrics:            true,
 				HasVerticalPodAutoscaler:    true,
+				HasHttpRoutes:               true,
+				HasGrpcRoutes:               true,
 			},
 			crdErr: nil,
 			apiGroups: []metav1.APIGroup{{Name: "metrics.k8s.io"}, {Name: "custom.metrics.k8s.io"},
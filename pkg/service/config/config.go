@@ -39,6 +39,32 @@ func NewOperatorConfig(fs afero.Fs, scheme *runtime.Scheme, options ...Option) (
 	return config.Default(), nil
 }
 
+// OperatorConfigSource reads the operator config from files, which can be read
+// again when they change.
+type OperatorConfigSource struct {
+	fs        afero.Fs
+	scheme    *runtime.Scheme
+	filePaths []string
+}
+
+func NewOperatorConfigSource(fs afero.Fs, scheme *runtime.Scheme, filePaths ...string) *OperatorConfigSource {
+	return &OperatorConfigSource{
+		fs:        fs,
+		scheme:    scheme,
+		filePaths: filePaths,
+	}
+}
+
+// FilePaths returns the paths of the files the config is read from.
+func (s *OperatorConfigSource) FilePaths() []string {
+	return s.filePaths
+}
+
+// Load reads the current operator config.
+func (s *OperatorConfigSource) Load() (*v1alpha1.OperatorConfig, error) {
+	return NewOperatorConfig(s.fs, s.scheme, WithFilePaths(s.filePaths...))
+}
+
 func newConfigBuilder(fs afero.Fs, scheme *runtime.Scheme, options ...Option) *configBuilder {
 	c := &configBuilder{
 		scheme:     scheme,
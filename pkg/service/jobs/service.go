@@ -0,0 +1,281 @@
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/go-logr/logr"
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/plugins/capsulesteps/cron_jobs"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WebhookTokenKey is the key of the token in the Secret of the webhook of a job.
+const WebhookTokenKey = "token"
+
+// LogFunc is called with each log line of the pods of a Job.
+type LogFunc func(pod string, timestamp time.Time, line []byte) error
+
+type Service interface {
+	// RunJob runs a cron job of a capsule outside of its schedule, and returns
+	// the created Job.
+	RunJob(
+		ctx context.Context,
+		namespace, capsule, job string,
+		overrides cron_jobs.JobOverrides,
+	) (*batchv1.Job, error)
+	// FollowJob streams the logs of the pods of the Job to onLog, in the order
+	// the pods are created, until the Job has finished. The finished Job is
+	// returned together with the exit code of the last pod.
+	FollowJob(ctx context.Context, job *batchv1.Job, onLog LogFunc) (*batchv1.Job, int32, error)
+	// RunJobWebhook runs a cron job of a capsule for a request to the webhook
+	// of the job. The token must match the one of the Secret configured for the
+	// webhook, and the overrides must be allowed by it.
+	RunJobWebhook(
+		ctx context.Context,
+		namespace, capsule, job, token string,
+		overrides cron_jobs.JobOverrides,
+	) (*batchv1.Job, error)
+}
+
+func NewService(client client.Client, clientset kubernetes.Interface, logger logr.Logger) Service {
+	return &service{
+		client:    client,
+		clientset: clientset,
+		logger:    logger,
+	}
+}
+
+type service struct {
+	client    client.Client
+	clientset kubernetes.Interface
+	logger    logr.Logger
+}
+
+func (s *service) RunJob(
+	ctx context.Context,
+	namespace, capsule, job string,
+	overrides cron_jobs.JobOverrides,
+) (*batchv1.Job, error) {
+	spec, err := s.getJob(ctx, namespace, capsule, job)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createJob(ctx, namespace, capsule, *spec, cron_jobs.TriggerManual, overrides)
+}
+
+// followPollInterval is how often a followed Job is checked for new pods, and
+// whether it has finished.
+const followPollInterval = time.Second
+
+func (s *service) FollowJob(ctx context.Context, job *batchv1.Job, onLog LogFunc) (*batchv1.Job, int32, error) {
+	followed := map[string]bool{}
+	var exitCode int32
+	for {
+		// The Job is read before its pods, so the logs of all pods are
+		// streamed before the Job is returned as finished.
+		current := &batchv1.Job{}
+		if err := errors.FromK8sClient(s.client.Get(ctx, client.ObjectKeyFromObject(job), current)); err != nil {
+			return nil, 0, err
+		}
+
+		pods := &corev1.PodList{}
+		if err := errors.FromK8sClient(s.client.List(ctx, pods,
+			client.InNamespace(job.GetNamespace()),
+			client.MatchingLabels{batchv1.JobNameLabel: job.GetName()},
+		)); err != nil {
+			return nil, 0, err
+		}
+		slices.SortFunc(pods.Items, func(a, b corev1.Pod) int {
+			return a.CreationTimestamp.Compare(b.CreationTimestamp.Time)
+		})
+
+		for _, pod := range pods.Items {
+			if followed[pod.GetName()] || pod.Status.Phase == corev1.PodPending {
+				continue
+			}
+			followed[pod.GetName()] = true
+
+			code, err := s.followPod(ctx, pod, onLog)
+			if err != nil {
+				return nil, 0, err
+			}
+			exitCode = code
+		}
+
+		if state, _ := cron_jobs.JobExecutionState(current); state != apipipeline.JobExecutionState_JOB_STATE_ONGOING {
+			return current, exitCode, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-time.After(followPollInterval):
+		}
+	}
+}
+
+// followPod streams the logs of the first container of the pod until it has
+// terminated, and returns its exit code.
+func (s *service) followPod(ctx context.Context, pod corev1.Pod, onLog LogFunc) (int32, error) {
+	if len(pod.Spec.Containers) == 0 {
+		return 0, nil
+	}
+	container := pod.Spec.Containers[0].Name
+
+	logs, err := s.clientset.CoreV1().Pods(pod.GetNamespace()).GetLogs(pod.GetName(), &corev1.PodLogOptions{
+		Container:  container,
+		Follow:     true,
+		Timestamps: true,
+	}).Stream(ctx)
+	if err != nil {
+		return 0, errors.FromK8sClient(err)
+	}
+	defer logs.Close()
+
+	r := bufio.NewReader(logs)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			ts, msg, _ := bytes.Cut(line, []byte(" "))
+			timestamp, tsErr := time.Parse(time.RFC3339Nano, string(ts))
+			if tsErr != nil {
+				timestamp, msg = time.Now(), line
+			}
+			if err := onLog(pod.GetName(), timestamp, msg); err != nil {
+				return 0, err
+			}
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+
+	// The log stream ends when the container terminates, which is then
+	// reflected in the status of the pod shortly after.
+	var exitCode int32
+	err = wait.PollUntilContextTimeout(ctx, followPollInterval, 30*time.Second, true,
+		func(ctx context.Context) (bool, error) {
+			current := &corev1.Pod{}
+			if err := s.client.Get(ctx, client.ObjectKeyFromObject(&pod), current); err != nil {
+				return false, client.IgnoreNotFound(err)
+			}
+			for _, cs := range current.Status.ContainerStatuses {
+				if cs.Name == container && cs.State.Terminated != nil {
+					exitCode = cs.State.Terminated.ExitCode
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+	if err != nil && !wait.Interrupted(err) {
+		return 0, err
+	}
+
+	return exitCode, nil
+}
+
+func (s *service) RunJobWebhook(
+	ctx context.Context,
+	namespace, capsule, job, token string,
+	overrides cron_jobs.JobOverrides,
+) (*batchv1.Job, error) {
+	// Missing capsules, and jobs without a webhook, are reported the same way,
+	// to not reveal which capsules and jobs exist to unauthenticated requests.
+	spec, err := s.getJob(ctx, namespace, capsule, job)
+	if errors.IsNotFound(err) || (err == nil && spec.Webhook == nil) {
+		return nil, errors.NotFoundErrorf("no job '%s' with a webhook in capsule '%s'", job, capsule)
+	} else if err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{}
+	if err := errors.FromK8sClient(s.client.Get(ctx, types.NamespacedName{
+		Namespace: namespace,
+		Name:      spec.Webhook.SecretName,
+	}, secret)); errors.IsNotFound(err) {
+		s.logger.Info("secret of job webhook not found", "namespace", namespace, "secret", spec.Webhook.SecretName)
+		return nil, errors.UnauthenticatedErrorf("invalid token")
+	} else if err != nil {
+		return nil, err
+	}
+
+	expected := secret.Data[WebhookTokenKey]
+	if token == "" || len(expected) == 0 || subtle.ConstantTimeCompare([]byte(token), expected) != 1 {
+		return nil, errors.UnauthenticatedErrorf("invalid token")
+	}
+
+	if err := overrides.CheckAllowed(spec.Webhook.AllowedOverrides); err != nil {
+		return nil, err
+	}
+
+	return s.createJob(ctx, namespace, capsule, *spec, cron_jobs.TriggerWebhook, overrides)
+}
+
+func (s *service) getJob(ctx context.Context, namespace, capsule, job string) (*v1alpha2.CronJob, error) {
+	c := &v1alpha2.Capsule{}
+	if err := errors.FromK8sClient(s.client.Get(ctx, types.NamespacedName{
+		Namespace: namespace,
+		Name:      capsule,
+	}, c)); errors.IsNotFound(err) {
+		return nil, errors.NotFoundErrorf("capsule '%s' does not exist in namespace '%s'", capsule, namespace)
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, j := range c.Spec.CronJobs {
+		if j.Name == job {
+			return &j, nil
+		}
+	}
+
+	return nil, errors.NotFoundErrorf("capsule '%s' has no job '%s'", capsule, job)
+}
+
+func (s *service) createJob(
+	ctx context.Context,
+	namespace, capsule string,
+	spec v1alpha2.CronJob,
+	trigger string,
+	overrides cron_jobs.JobOverrides,
+) (*batchv1.Job, error) {
+	// The Job is created from the CronJob, so it runs the version of the
+	// capsule which is currently rolled out.
+	cronJob := &batchv1.CronJob{}
+	if err := errors.FromK8sClient(s.client.Get(ctx, types.NamespacedName{
+		Namespace: namespace,
+		Name:      fmt.Sprintf("%s-%s", capsule, spec.Name),
+	}, cronJob)); errors.IsNotFound(err) {
+		return nil, errors.FailedPreconditionErrorf("job '%s' of capsule '%s' is not rolled out yet", spec.Name, capsule)
+	} else if err != nil {
+		return nil, err
+	}
+
+	j, err := cron_jobs.JobFromCronJob(cronJob, spec, trigger, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := errors.FromK8sClient(s.client.Create(ctx, j)); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("created job", "namespace", namespace, "job", j.GetName(), "trigger", trigger)
+	return j, nil
+}
@@ -0,0 +1,273 @@
+package jobs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	mockclient "github.com/rigdev/rig/gen/mocks/sigs.k8s.io/controller-runtime/pkg/client"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/errors"
+	svcjobs "github.com/rigdev/rig/pkg/service/jobs"
+	"github.com/rigdev/rig/plugins/capsulesteps/cron_jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRunJobWebhook(t *testing.T) {
+	t.Parallel()
+	capsule := &v1alpha2.Capsule{
+		Spec: v1alpha2.CapsuleSpec{
+			CronJobs: []v1alpha2.CronJob{
+				{
+					Name:     "report",
+					Schedule: "0 * * * *",
+					Command:  &v1alpha2.JobCommand{Command: "report"},
+					Webhook: &v1alpha2.JobWebhook{
+						SecretName: "report-webhook",
+						AllowedOverrides: &v1alpha2.JobAllowedOverrides{
+							Env: []string{"LEVEL"},
+						},
+					},
+				},
+				{
+					Name:     "cleanup",
+					Schedule: "0 * * * *",
+					Command:  &v1alpha2.JobCommand{Command: "cleanup"},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		Data: map[string][]byte{svcjobs.WebhookTokenKey: []byte("s3cr3t")},
+	}
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-report", Namespace: "prod"},
+		Spec: batchv1.CronJobSpec{
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "test", Command: []string{"report"}}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		capsule   string
+		job       string
+		token     string
+		overrides cron_jobs.JobOverrides
+		isErr     func(error) bool
+	}{
+		{
+			name:      "valid token and allowed overrides",
+			capsule:   "test",
+			job:       "report",
+			token:     "s3cr3t",
+			overrides: cron_jobs.JobOverrides{Env: map[string]string{"LEVEL": "debug"}},
+		},
+		{
+			name:    "invalid token",
+			capsule: "test",
+			job:     "report",
+			token:   "guess",
+			isErr:   errors.IsUnauthenticated,
+		},
+		{
+			name:    "empty token",
+			capsule: "test",
+			job:     "report",
+			isErr:   errors.IsUnauthenticated,
+		},
+		{
+			name:    "job without webhook",
+			capsule: "test",
+			job:     "cleanup",
+			token:   "s3cr3t",
+			isErr:   errors.IsNotFound,
+		},
+		{
+			name:    "missing capsule",
+			capsule: "other",
+			job:     "report",
+			token:   "s3cr3t",
+			isErr:   errors.IsNotFound,
+		},
+		{
+			name:      "override not allowed",
+			capsule:   "test",
+			job:       "report",
+			token:     "s3cr3t",
+			overrides: cron_jobs.JobOverrides{Env: map[string]string{"DRY_RUN": "true"}},
+			isErr:     errors.IsPermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockClient := mockclient.NewMockClient(t)
+			mockClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(
+				func(_ context.Context, key types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+					switch o := obj.(type) {
+					case *v1alpha2.Capsule:
+						if key.Name == "test" {
+							capsule.DeepCopyInto(o)
+							return nil
+						}
+					case *corev1.Secret:
+						if key.Name == "report-webhook" {
+							secret.DeepCopyInto(o)
+							return nil
+						}
+					case *batchv1.CronJob:
+						if key.Name == "test-report" {
+							cronJob.DeepCopyInto(o)
+							return nil
+						}
+					}
+					return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+				},
+			)
+			if tt.isErr == nil {
+				mockClient.EXPECT().Create(mock.Anything, mock.Anything).Return(nil)
+			}
+
+			s := svcjobs.NewService(mockClient, fake.NewSimpleClientset(), logr.Discard())
+			job, err := s.RunJobWebhook(context.Background(), "prod", tt.capsule, tt.job, tt.token, tt.overrides)
+			if tt.isErr != nil {
+				assert.True(t, tt.isErr(err), err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "test-report-", job.GetGenerateName())
+			assert.Equal(t, cron_jobs.TriggerWebhook, job.GetAnnotations()["rig.dev/job-trigger"])
+		})
+	}
+}
+
+func TestRunJob(t *testing.T) {
+	capsule := &v1alpha2.Capsule{
+		Spec: v1alpha2.CapsuleSpec{
+			CronJobs: []v1alpha2.CronJob{{
+				Name:     "report",
+				Schedule: "0 * * * *",
+				Command:  &v1alpha2.JobCommand{Command: "report"},
+			}},
+		},
+	}
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-report", Namespace: "prod"},
+		Spec: batchv1.CronJobSpec{
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "test", Command: []string{"report"}}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	mockClient := mockclient.NewMockClient(t)
+	mockClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(
+		func(_ context.Context, key types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+			switch o := obj.(type) {
+			case *v1alpha2.Capsule:
+				capsule.DeepCopyInto(o)
+			case *batchv1.CronJob:
+				cronJob.DeepCopyInto(o)
+			default:
+				return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+			}
+			return nil
+		},
+	)
+	mockClient.EXPECT().Create(mock.Anything, mock.Anything).Return(nil)
+
+	// Overrides are not restricted by the webhook of the job.
+	s := svcjobs.NewService(mockClient, fake.NewSimpleClientset(), logr.Discard())
+	job, err := s.RunJob(context.Background(), "prod", "test", "report", cron_jobs.JobOverrides{
+		Args: []string{"--all"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, cron_jobs.TriggerManual, job.GetAnnotations()["rig.dev/job-trigger"])
+	assert.Equal(t, []string{"--all"}, job.Spec.Template.Spec.Containers[0].Args)
+
+	_, err = s.RunJob(context.Background(), "prod", "test", "cleanup", cron_jobs.JobOverrides{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestFollowJob(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-report-x7k2p", Namespace: "prod"},
+		Status: batchv1.JobStatus{
+			Failed: 1,
+			Conditions: []batchv1.JobCondition{{
+				Type:   batchv1.JobFailed,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+	}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-report-x7k2p-abcde", Namespace: "prod"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "test"}}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "test",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 3}},
+			}},
+		},
+	}
+
+	mockClient := mockclient.NewMockClient(t)
+	mockClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(
+		func(_ context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+			switch o := obj.(type) {
+			case *batchv1.Job:
+				job.DeepCopyInto(o)
+			case *corev1.Pod:
+				pod.DeepCopyInto(o)
+			}
+			return nil
+		},
+	)
+	mockClient.EXPECT().List(mock.Anything, mock.Anything, mock.Anything, mock.Anything).RunAndReturn(
+		func(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+			list.(*corev1.PodList).Items = []corev1.Pod{pod}
+			return nil
+		},
+	)
+
+	s := svcjobs.NewService(mockClient, fake.NewSimpleClientset(), logr.Discard())
+	var logs []string
+	finished, exitCode, err := s.FollowJob(context.Background(), job,
+		func(pod string, _ time.Time, line []byte) error {
+			logs = append(logs, pod+": "+string(line))
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test-report-x7k2p-abcde: fake logs"}, logs)
+	assert.Equal(t, int32(1), finished.Status.Failed)
+	assert.Equal(t, int32(3), exitCode)
+}
@@ -30,6 +30,11 @@ type Service interface {
 	UnregisterCapsule(namespace string, capsule string)
 
 	UpdateStatus(namespace string, capsule string, pluginID uuid.UUID, change *apiplugin.ObjectStatusChange)
+
+	// PipelineReloaded restarts watching the objects of all capsules with the plugins
+	// of the current default pipeline. Statuses reported by the plugins of the replaced
+	// pipeline are kept until the new plugins have reported theirs.
+	PipelineReloaded()
 }
 
 func NewService(
@@ -61,13 +66,37 @@ func (s *service) runForCapsule(ctx context.Context, c *capsuleCache) {
 	p := s.pipeline.GetDefaultPipeline()
 
 	for _, step := range p.Steps() {
+		c.lock.Lock()
 		for _, pluginID := range step.PluginIDs() {
 			c.plugins[pluginID] = false
 		}
+		c.lock.Unlock()
 		go s.runStepForCapsule(ctx, c.capsule, step)
 	}
 }
 
+func (s *service) PipelineReloaded() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, cs := range s.capsules {
+		for _, c := range cs {
+			c.cancel()
+			ctx, cancel := context.WithCancel(context.Background())
+			c.cancel = cancel
+
+			c.lock.Lock()
+			for pluginID := range c.plugins {
+				c.stale[pluginID] = struct{}{}
+			}
+			c.plugins = map[uuid.UUID]bool{}
+			c.lock.Unlock()
+
+			s.runForCapsule(ctx, c)
+		}
+	}
+}
+
 func (s *service) runStepForCapsule(
 	ctx context.Context,
 	capsule *v1alpha2.Capsule,
@@ -152,6 +181,7 @@ func (s *service) RegisterCapsule(namespace string, capsule *v1alpha2.Capsule) {
 		ctx, cancel := context.WithCancel(context.Background())
 		c := &capsuleCache{
 			plugins: map[uuid.UUID]bool{},
+			stale:   map[uuid.UUID]struct{}{},
 			capsule: capsule,
 			cancel:  cancel,
 			objects: map[pipeline.ObjectKey]*objectCache{},
@@ -274,6 +304,9 @@ func (s *service) getCapsule(namespace string, capsule string) *capsuleCache {
 type capsuleCache struct {
 	// This property is owned by the service.
 	plugins map[uuid.UUID]bool
+	// Plugins of replaced pipelines, whose statuses are removed once all
+	// current plugins have reported theirs.
+	stale map[uuid.UUID]struct{}
 
 	lock    sync.RWMutex
 	capsule *v1alpha2.Capsule
@@ -301,6 +334,11 @@ func (c *capsuleCache) update(pluginID uuid.UUID, change *apiplugin.ObjectStatus
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	if _, ok := c.stale[pluginID]; ok {
+		// The plugin belongs to a replaced pipeline.
+		return nil
+	}
+
 	var keys []pipeline.ObjectKey
 	switch v := change.GetChange().(type) {
 	case *apiplugin.ObjectStatusChange_AllObjects_:
@@ -367,7 +405,42 @@ func (c *capsuleCache) update(pluginID uuid.UUID, change *apiplugin.ObjectStatus
 
 	case *apiplugin.ObjectStatusChange_Checkpoint_:
 		c.plugins[pluginID] = true
+		keys = c.removeStale()
+	}
+
+	return keys
+}
+
+// removeStale removes the statuses of stale plugins, if all current plugins are initialized.
+func (c *capsuleCache) removeStale() []pipeline.ObjectKey {
+	if len(c.stale) == 0 {
+		return nil
+	}
+	for _, initialized := range c.plugins {
+		if !initialized {
+			return nil
+		}
+	}
+
+	var keys []pipeline.ObjectKey
+	for key, o := range c.objects {
+		updated := false
+		for pluginID := range c.stale {
+			if _, ok := o.statuses[pluginID]; ok {
+				delete(o.statuses, pluginID)
+				updated = true
+			}
+		}
+		if !updated {
+			continue
+		}
+
+		keys = append(keys, key)
+		if len(o.statuses) == 0 {
+			delete(c.objects, key)
+		}
 	}
+	c.stale = map[uuid.UUID]struct{}{}
 
 	return keys
 }
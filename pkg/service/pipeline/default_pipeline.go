@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/rigdev/rig/pkg/api/config/v1alpha1"
@@ -10,7 +11,9 @@ import (
 	"github.com/rigdev/rig/pkg/scheme"
 	"github.com/rigdev/rig/plugins/capsulesteps/cron_jobs"
 	"github.com/rigdev/rig/plugins/capsulesteps/deployment"
+	"github.com/rigdev/rig/plugins/capsulesteps/rollout"
 	"github.com/rigdev/rig/plugins/capsulesteps/service_account"
+	"go.uber.org/fx"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -21,19 +24,80 @@ useExisting: true
 )
 
 func (s *service) initializePipeline() error {
-	execCtx := plugin.NewExecutionContext(context.Background())
+	dp, err := s.newDefaultPipeline(s.cfg)
+	if err != nil {
+		return err
+	}
 
-	p, err := CreateDefaultPipeline(execCtx, s.client.Scheme(), s.vm, s.cfg, s.pluginManager, s.logger)
+	s.current = dp
+	return nil
+}
+
+func (s *service) ReloadPipeline(cfg *v1alpha1.OperatorConfig, drainTimeout time.Duration) error {
+	// The new plugins are started while the current ones keep serving requests.
+	dp, err := s.newDefaultPipeline(cfg)
 	if err != nil {
-		execCtx.Stop()
 		return err
 	}
 
-	s.pipeline = p
-	s.execCtx = execCtx
+	s.lock.Lock()
+	old := s.current
+	if old != nil {
+		old.replaced.Store(true)
+	}
+	s.current = dp
+	s.lock.Unlock()
+
+	if old == nil {
+		return nil
+	}
+
+	go func() {
+		drained := make(chan struct{})
+		go func() {
+			old.inflight.Wait()
+			close(drained)
+		}()
+
+		select {
+		case <-drained:
+		case <-time.After(drainTimeout):
+			s.logger.Info("timeout draining replaced pipeline, stopping it")
+		}
+		old.execCtx.Stop()
+	}()
+
 	return nil
 }
 
+func (s *service) newDefaultPipeline(cfg *v1alpha1.OperatorConfig) (*defaultPipeline, error) {
+	execCtx := plugin.NewExecutionContext(context.Background())
+
+	p, err := CreateDefaultPipeline(execCtx, s.client.Scheme(), s.vm, cfg, s.pluginManager, s.logger)
+	if err != nil {
+		execCtx.Stop()
+		return nil, err
+	}
+
+	dp := &defaultPipeline{
+		pipeline: p,
+		execCtx:  execCtx,
+	}
+
+	if s.sh != nil {
+		go func() {
+			<-execCtx.Context().Done()
+			if dp.replaced.Load() {
+				return
+			}
+			s.logger.Info("default pipeline plugins terminated, restarting")
+			_ = s.sh.Shutdown(fx.ExitCode(1))
+		}()
+	}
+
+	return dp, nil
+}
+
 func CreateDefaultPipeline(
 	execCtx plugin.ExecutionContext,
 	scheme *runtime.Scheme,
@@ -52,7 +116,13 @@ func CreateDefaultPipeline(
 		pipeline.AddStep(step)
 	}
 
-	for idx, step := range cfg.Pipeline.Steps {
+	order, err := plugin.OrderSteps(cfg.Pipeline.Steps)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, idx := range order {
+		step := cfg.Pipeline.Steps[idx]
 		ps, err := pluginManager.NewStep(execCtx, step, logger, customStepName(idx))
 		if err != nil {
 			return nil, err
@@ -61,6 +131,14 @@ func CreateDefaultPipeline(
 		pipeline.AddStep(ps)
 	}
 
+	// The rollout step must run last, as it splits the final version of the
+	// Deployment into a stable and a canary version.
+	rolloutStep, err := NewCapsulePluginStep(execCtx, rollout.Name, "", pluginManager, logger, true)
+	if err != nil {
+		return nil, err
+	}
+	pipeline.AddStep(rolloutStep)
+
 	return pipeline, nil
 }
 
@@ -145,6 +223,16 @@ func GetDefaultPipelineSteps(
 		steps = append(steps, serviceMonitorStep)
 	}
 
+	if cfg.Pipeline.NetworkPolicyStep.Plugin != "" {
+		networkPolicyStep, err := NewCapsulePluginStep(execCtx, cfg.Pipeline.NetworkPolicyStep.Plugin,
+			cfg.Pipeline.NetworkPolicyStep.Config, pluginManager, logger, false)
+		if err != nil {
+			return nil, err
+		}
+
+		steps = append(steps, networkPolicyStep)
+	}
+
 	steps = append(steps, NewCapsuleExtensionValidationStep(cfg))
 	for name, capsuleStep := range cfg.Pipeline.CapsuleExtensions {
 		if capsuleStep.Plugin != "" {
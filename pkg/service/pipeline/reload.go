@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/rigdev/rig/pkg/api/config/v1alpha1"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/controller/plugin"
+	"golang.org/x/exp/maps"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// MatchedSteps returns the definitions of the steps of the default pipeline of the
+// config, which are run for the capsule. A capsule with the same matched steps in two
+// configs is reconciled the same way by the pipelines of both.
+func MatchedSteps(cfg *v1alpha1.OperatorConfig, capsule *v1alpha2.Capsule) ([]string, error) {
+	var steps []string
+	add := func(kind string, v any) error {
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		steps = append(steps, fmt.Sprintf("%s:%s", kind, bs))
+		return nil
+	}
+
+	// The builtin steps are run for all capsules.
+	if err := add("builtin", v1alpha1.Pipeline{
+		ServiceAccountStep: cfg.Pipeline.ServiceAccountStep,
+		DeploymentStep:     cfg.Pipeline.DeploymentStep,
+		RoutesStep:         cfg.Pipeline.RoutesStep,
+		CronJobsStep:       cfg.Pipeline.CronJobsStep,
+		VPAStep:            cfg.Pipeline.VPAStep,
+		ServiceMonitorStep: cfg.Pipeline.ServiceMonitorStep,
+		NetworkPolicyStep:  cfg.Pipeline.NetworkPolicyStep,
+		ServerSideApply:    cfg.Pipeline.ServerSideApply,
+	}); err != nil {
+		return nil, err
+	}
+
+	names := maps.Keys(capsule.Spec.Extensions)
+	slices.Sort(names)
+	for _, name := range names {
+		// Undeclared extensions fail the validation step.
+		step, ok := cfg.Pipeline.CapsuleExtensions[name]
+		if err := add("extension/"+name, map[string]any{"declared": ok, "step": step}); err != nil {
+			return nil, err
+		}
+	}
+
+	order, err := plugin.OrderSteps(cfg.Pipeline.Steps)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, idx := range order {
+		step := cfg.Pipeline.Steps[idx]
+		matcher, err := plugin.NewMatcher(plugin.MatchFromStep(step))
+		if err != nil {
+			return nil, err
+		}
+		// A condition which fails to evaluate fails the step, so the step is
+		// matched unless the condition is evaluated to false.
+		if ok, err := matcher.MatchCapsule(capsule); err == nil && !ok {
+			continue
+		}
+		if err := add("step", step); err != nil {
+			return nil, err
+		}
+	}
+
+	return steps, nil
+}
+
+// PluginManagerChanged returns true if the configs differ in the custom plugins or
+// sandboxes of the plugin manager. The plugin manager is created when the operator
+// starts, so such changes cannot be applied by ReloadPipeline.
+func PluginManagerChanged(from, to *v1alpha1.OperatorConfig) bool {
+	return !equality.Semantic.DeepEqual(from.Pipeline.CustomPlugins, to.Pipeline.CustomPlugins) ||
+		!equality.Semantic.DeepEqual(from.Pipeline.PluginSandboxes, to.Pipeline.PluginSandboxes)
+}
+
+// RequiresRestart returns true if the configs differ by more than what ReloadPipeline
+// can apply, e.g. the custom plugins or sandboxes of the plugin manager.
+func RequiresRestart(from, to *v1alpha1.OperatorConfig) bool {
+	from, to = from.DeepCopy(), to.DeepCopy()
+	for _, cfg := range []*v1alpha1.OperatorConfig{from, to} {
+		cfg.Pipeline = v1alpha1.Pipeline{
+			CustomPlugins:   cfg.Pipeline.CustomPlugins,
+			PluginSandboxes: cfg.Pipeline.PluginSandboxes,
+		}
+	}
+	return !equality.Semantic.DeepEqual(from, to)
+}
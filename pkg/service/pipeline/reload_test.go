@@ -0,0 +1,105 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/rigdev/rig/pkg/api/config/v1alpha1"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newReloadConfig(steps ...v1alpha1.Step) *v1alpha1.OperatorConfig {
+	cfg := (&v1alpha1.OperatorConfig{}).Default()
+	cfg.Pipeline.Steps = steps
+	cfg.Pipeline.CapsuleExtensions = map[string]v1alpha1.CapsuleStep{
+		"ext": {Plugin: "rigdev.object_template", Config: "kind: Deployment"},
+	}
+	return cfg
+}
+
+func Test_MatchedSteps(t *testing.T) {
+	prod := &v1alpha2.Capsule{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"}}
+	dev := &v1alpha2.Capsule{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "dev"},
+		Spec: v1alpha2.CapsuleSpec{
+			Extensions: map[string]json.RawMessage{"ext": json.RawMessage(`{}`)},
+		},
+	}
+
+	prodStep := v1alpha1.Step{
+		Match:   v1alpha1.CapsuleMatch{Namespaces: []string{"prod"}},
+		Plugins: []v1alpha1.Plugin{{Plugin: "rigdev.annotations", Config: "a: b"}},
+	}
+	changedProdStep := prodStep
+	changedProdStep.Plugins = []v1alpha1.Plugin{{Plugin: "rigdev.annotations", Config: "a: c"}}
+
+	changed := func(from, to *v1alpha1.OperatorConfig, capsule *v1alpha2.Capsule) bool {
+		fromSteps, err := MatchedSteps(from, capsule)
+		require.NoError(t, err)
+		toSteps, err := MatchedSteps(to, capsule)
+		require.NoError(t, err)
+		return !slices.Equal(fromSteps, toSteps)
+	}
+
+	from := newReloadConfig(prodStep)
+	require.False(t, changed(from, newReloadConfig(prodStep), prod))
+	require.True(t, changed(from, newReloadConfig(changedProdStep), prod))
+	require.False(t, changed(from, newReloadConfig(changedProdStep), dev))
+
+	to := newReloadConfig(prodStep)
+	to.Pipeline.CapsuleExtensions["ext"] = v1alpha1.CapsuleStep{}
+	require.True(t, changed(from, to, dev))
+	require.False(t, changed(from, to, prod))
+
+	to = newReloadConfig(prodStep)
+	to.Pipeline.RoutesStep.Plugin = "rigdev.ingress_routes"
+	require.True(t, changed(from, to, dev))
+	require.True(t, changed(from, to, prod))
+
+	// A step whose condition fails to evaluate fails the pipeline of the
+	// capsule, so changes to it reconcile the capsule.
+	condStep := v1alpha1.Step{
+		Match:   v1alpha1.CapsuleMatch{Condition: `capsule.metadata.labels["tier"] == "web"`},
+		Plugins: []v1alpha1.Plugin{{Plugin: "rigdev.annotations", Config: "a: b"}},
+	}
+	changedCondStep := condStep
+	changedCondStep.Plugins = []v1alpha1.Plugin{{Plugin: "rigdev.annotations", Config: "a: c"}}
+	require.True(t, changed(newReloadConfig(condStep), newReloadConfig(changedCondStep), prod))
+
+	_, err := MatchedSteps(newReloadConfig(v1alpha1.Step{Tag: "a", After: []string{"b"}}), prod)
+	require.Error(t, err)
+}
+
+func Test_RequiresRestart(t *testing.T) {
+	from := newReloadConfig()
+
+	to := newReloadConfig(v1alpha1.Step{Plugins: []v1alpha1.Plugin{{Plugin: "rigdev.annotations"}}})
+	to.Pipeline.ServerSideApply = true
+	require.False(t, RequiresRestart(from, to))
+
+	to = newReloadConfig()
+	to.DriftDetection.Enabled = true
+	require.True(t, RequiresRestart(from, to))
+
+	to = newReloadConfig()
+	to.Pipeline.PluginSandboxes = map[string]v1alpha1.PluginSandbox{"rigdev.annotations": {TimeoutSeconds: 5}}
+	require.True(t, RequiresRestart(from, to))
+}
+
+func Test_PluginManagerChanged(t *testing.T) {
+	from := newReloadConfig()
+
+	to := newReloadConfig(v1alpha1.Step{Plugins: []v1alpha1.Plugin{{Plugin: "rigdev.annotations"}}})
+	require.False(t, PluginManagerChanged(from, to))
+
+	to = newReloadConfig()
+	to.Pipeline.PluginSandboxes = map[string]v1alpha1.PluginSandbox{"rigdev.annotations": {TimeoutSeconds: 5}}
+	require.True(t, PluginManagerChanged(from, to))
+
+	to = newReloadConfig()
+	to.Pipeline.CustomPlugins = []v1alpha1.CustomPlugin{{Image: "registry.local/plugins:v2"}}
+	require.True(t, PluginManagerChanged(from, to))
+}
@@ -3,6 +3,9 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/rigdev/rig/pkg/api/config/v1alpha1"
@@ -19,6 +22,13 @@ import (
 
 type Service interface {
 	GetDefaultPipeline() *pipeline.CapsulePipeline
+	// AcquirePipeline returns the default pipeline and a function releasing it. A
+	// pipeline replaced by ReloadPipeline is stopped once all its acquisitions are released.
+	AcquirePipeline() (*pipeline.CapsulePipeline, func())
+	// ReloadPipeline creates a new default pipeline from the config and swaps it in
+	// place of the current one, which is drained and stopped in the background.
+	// The current pipeline is kept if the new one cannot be created.
+	ReloadPipeline(cfg *v1alpha1.OperatorConfig, drainTimeout time.Duration) error
 	DryRun(ctx context.Context,
 		cfg *v1alpha1.OperatorConfig,
 		namespace, capsuleName string,
@@ -53,23 +63,10 @@ func NewService(
 		logger:        logger,
 		pluginManager: pluginManager,
 		vm:            scheme.NewVersionMapper(client),
+		sh:            sh,
 	}
 
-	lc.Append(fx.StartHook(func() error {
-		if err := s.initializePipeline(); err != nil {
-			return err
-		}
-
-		if sh != nil {
-			go func() {
-				<-s.execCtx.Context().Done()
-				s.logger.Info("default pipeline plugins terminated, restarting")
-				_ = sh.Shutdown(fx.ExitCode(1))
-			}()
-		}
-
-		return nil
-	}))
+	lc.Append(fx.StartHook(s.initializePipeline))
 
 	return s
 }
@@ -80,13 +77,33 @@ type service struct {
 	capSvc        capabilities.Service
 	logger        logr.Logger
 	pluginManager *plugin.Manager
-	pipeline      *pipeline.CapsulePipeline
-	execCtx       plugin.ExecutionContext
 	vm            scheme.VersionMapper
+	sh            fx.Shutdowner
+
+	lock    sync.RWMutex
+	current *defaultPipeline
+}
+
+// defaultPipeline is a default pipeline along with the plugins it executes.
+type defaultPipeline struct {
+	pipeline *pipeline.CapsulePipeline
+	execCtx  plugin.ExecutionContext
+	inflight sync.WaitGroup
+	replaced atomic.Bool
 }
 
 func (s *service) GetDefaultPipeline() *pipeline.CapsulePipeline {
-	return s.pipeline
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.current.pipeline
+}
+
+func (s *service) AcquirePipeline() (*pipeline.CapsulePipeline, func()) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	dp := s.current
+	dp.inflight.Add(1)
+	return dp.pipeline, dp.inflight.Done
 }
 
 // DryRun implements Service.
@@ -97,10 +114,11 @@ func (s *service) DryRun(
 	capsuleSpec *v1alpha2.Capsule,
 	opts ...pipeline.CapsuleRequestOption,
 ) (*pipeline.Result, error) {
-	p, capsuleSpec, err := s.setupDryRunPipeline(ctx, cfg, namespace, capsuleName, capsuleSpec)
+	p, capsuleSpec, release, err := s.setupDryRunPipeline(ctx, cfg, namespace, capsuleName, capsuleSpec)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	return p.RunCapsule(ctx, capsuleSpec, s.client, append(opts, pipeline.WithDryRun())...)
 }
 
@@ -109,24 +127,25 @@ func (s *service) DryRunPluginConfig(ctx context.Context,
 	namespace, capsuleName string,
 	capsuleSpec *v1alpha2.Capsule,
 ) (pipeline.PluginConfigResult, error) {
-	p, capsuleSpec, err := s.setupDryRunPipeline(ctx, cfg, namespace, capsuleName, capsuleSpec)
+	p, capsuleSpec, release, err := s.setupDryRunPipeline(ctx, cfg, namespace, capsuleName, capsuleSpec)
 	if err != nil {
 		return pipeline.PluginConfigResult{}, err
 	}
+	defer release()
 
 	return p.ComputeConfig(ctx, capsuleSpec, s.client)
 }
 
 func (s *service) setupDryRunPipeline(
 	ctx context.Context, cfg *v1alpha1.OperatorConfig, namespace, capsuleName string, capsuleSpec *v1alpha2.Capsule,
-) (*pipeline.CapsulePipeline, *v1alpha2.Capsule, error) {
+) (*pipeline.CapsulePipeline, *v1alpha2.Capsule, func(), error) {
 	if capsuleSpec == nil {
 		capsuleSpec = &v1alpha2.Capsule{}
 		if err := errors.FromK8sClient(s.client.Get(ctx, types.NamespacedName{
 			Namespace: namespace,
 			Name:      capsuleName,
 		}, capsuleSpec)); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 	} else {
 		// Load existing status object.
@@ -137,7 +156,7 @@ func (s *service) setupDryRunPipeline(
 		}, currentSpec)); errors.IsNotFound(err) {
 			// Noop.
 		} else if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		} else {
 			capsuleSpec.Status = currentSpec.Status
 			capsuleSpec.UID = currentSpec.GetUID()
@@ -148,32 +167,17 @@ func (s *service) setupDryRunPipeline(
 		capsuleSpec.SetUID(types.UID("dry-run-spec"))
 	}
 
-	var p *pipeline.CapsulePipeline
 	if cfg == nil {
-		p = s.GetDefaultPipeline()
-	} else {
-		execCtx := plugin.NewExecutionContext(ctx)
-
-		steps, err := GetDefaultPipelineSteps(execCtx, cfg, s.pluginManager, s.logger)
-		if err != nil {
-			return nil, nil, err
-		}
-
-		p = pipeline.NewCapsulePipeline(cfg, scheme.New(), s.vm, s.logger)
-		for _, step := range steps {
-			p.AddStep(step)
-		}
-
-		for idx, step := range cfg.Pipeline.Steps {
-			ps, err := s.pluginManager.NewStep(execCtx, step, s.logger, customStepName(idx))
-			if err != nil {
-				return nil, nil, err
-			}
+		p, release := s.AcquirePipeline()
+		return p, capsuleSpec, release, nil
+	}
 
-			p.AddStep(ps)
-		}
+	execCtx := plugin.NewExecutionContext(ctx)
+	p, err := CreateDefaultPipeline(execCtx, scheme.New(), s.vm, cfg, s.pluginManager, s.logger)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-	return p, capsuleSpec, nil
+	return p, capsuleSpec, execCtx.Stop, nil
 }
 
 func customStepName(idx int) string {
@@ -115,30 +115,7 @@ func handleOutboundInner(ctx context.Context, t Tunnel, tunnelID uint64, port ui
 
 		conn = tcpConn
 	} else {
-		t := url.URL{
-			Scheme: "http",
-			Host:   target.Host,
-		}
-		rp := &httputil.ReverseProxy{
-			Rewrite: func(r *httputil.ProxyRequest) {
-				r.SetURL(&t)
-
-				if !target.Options.GetChangeOrigin() {
-					r.Out.Host = r.In.Host
-				}
-
-				for key, value := range target.Options.GetHeaders() {
-					r.Out.Header.Set(key, value)
-				}
-			},
-			ModifyResponse: func(r *http.Response) error {
-				if target.Options.GetAllowOrigin() != "" {
-					r.Header.Set("Access-Control-Allow-Origin", target.Options.GetAllowOrigin())
-				}
-
-				return nil
-			},
-		}
+		rp := NewReverseProxy(target)
 
 		// To server HTTP traffic, we need a listener + client to interact with the http.Server.
 		// The easiest way to accomplish that is to do a net.Pipe, which will generate two
@@ -240,3 +217,32 @@ func (noneAddr) Network() string {
 func (noneAddr) String() string {
 	return "none"
 }
+
+// NewReverseProxy returns a reverse proxy forwarding HTTP requests to the given Target,
+// applying the interface options of the target to requests and responses.
+func NewReverseProxy(target Target) *httputil.ReverseProxy {
+	t := url.URL{
+		Scheme: "http",
+		Host:   target.Host,
+	}
+	return &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(&t)
+
+			if !target.Options.GetChangeOrigin() {
+				r.Out.Host = r.In.Host
+			}
+
+			for key, value := range target.Options.GetHeaders() {
+				r.Out.Header.Set(key, value)
+			}
+		},
+		ModifyResponse: func(r *http.Response) error {
+			if target.Options.GetAllowOrigin() != "" {
+				r.Header.Set("Access-Control-Allow-Origin", target.Options.GetAllowOrigin())
+			}
+
+			return nil
+		},
+	}
+}
@@ -16,6 +16,8 @@ import (
 	"github.com/rigdev/rig/plugins/capsulesteps/cron_jobs"
 	"github.com/rigdev/rig/plugins/capsulesteps/deployment"
 	ingressroutes "github.com/rigdev/rig/plugins/capsulesteps/ingress_routes"
+	"github.com/rigdev/rig/plugins/capsulesteps/network_policy"
+	"github.com/rigdev/rig/plugins/capsulesteps/rollout"
 	"github.com/rigdev/rig/plugins/capsulesteps/service_account"
 	"github.com/rigdev/rig/plugins/capsulesteps/service_monitor"
 	"github.com/rigdev/rig/plugins/capsulesteps/vpa"
@@ -36,6 +38,8 @@ var Plugins = map[string]plugin.Plugin{
 	service_account.Name: &service_account.Plugin{},
 	service_monitor.Name: &service_monitor.Plugin{},
 	vpa.Name:             &vpa.Plugin{},
+	network_policy.Name:  &network_policy.Plugin{},
+	rollout.Name:         &rollout.Plugin{},
 	objectcreate.Name:    &objectcreate.Plugin{},
 	envvarcsi.Name:       &envvarcsi.Plugin{},
 	argorollout.Name:     &argorollout.Plugin{},
@@ -7,6 +7,7 @@ import (
 	"github.com/hashicorp/go-hclog"
 	"github.com/rigdev/rig/pkg/controller/plugin"
 	"github.com/rigdev/rig/pkg/pipeline"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -25,6 +26,14 @@ type Config struct {
 	Kind string `json:"kind,omitempty"`
 	// Name of the object to match. Defaults to Capsule-name.
 	Name string `json:"name,omitempty"`
+	// CapsuleExtension is the name of a Capsule extension, whose value is merged over
+	// this configuration for the Capsules setting it.
+	CapsuleExtension string `json:"capsuleExtension,omitempty"`
+}
+
+// CapsuleExtensionName implements plugin.ExtensibleConfig.
+func (c Config) CapsuleExtensionName() string {
+	return c.CapsuleExtension
 }
 
 type Plugin struct {
@@ -38,6 +47,10 @@ func (p *Plugin) Initialize(req plugin.InitializeRequest) error {
 	return nil
 }
 
+func (p *Plugin) ExtensionSchema() (*apiextensionsv1.JSONSchemaProps, error) {
+	return plugin.ConfigSchema(Config{})
+}
+
 func (p *Plugin) ComputeConfig(ctx context.Context, req pipeline.CapsuleRequest, logger hclog.Logger) (string, error) {
 	return plugin.ParseCapsuleTemplatedConfigToString[Config](p.configBytes, req)
 }
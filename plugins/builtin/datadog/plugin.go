@@ -8,7 +8,7 @@ import (
 	"github.com/rigdev/rig/pkg/controller/plugin"
 	"github.com/rigdev/rig/pkg/errors"
 	"github.com/rigdev/rig/pkg/pipeline"
-	appsv1 "k8s.io/api/apps/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
 
 const Name = "rigdev.datadog"
@@ -23,6 +23,14 @@ type Config struct {
 	LibraryTag LibraryTag `json:"libraryTag,omitempty"`
 	// UnifiedServiceTags configures the values for the Unified Service datadog tags.
 	UnifiedServiceTags UnifiedServiceTags `json:"unifiedServiceTags,omitempty"`
+	// CapsuleExtension is the name of a Capsule extension, whose value is merged over
+	// this configuration for the Capsules setting it.
+	CapsuleExtension string `json:"capsuleExtension,omitempty"`
+}
+
+// CapsuleExtensionName implements plugin.ExtensibleConfig.
+func (c Config) CapsuleExtensionName() string {
+	return c.CapsuleExtension
 }
 
 // LibraryTag defines configuration for which datadog libraries to let the admission controller inject into the pods
@@ -61,6 +69,10 @@ func (d *Plugin) Initialize(req plugin.InitializeRequest) error {
 	return nil
 }
 
+func (d *Plugin) ExtensionSchema() (*apiextensionsv1.JSONSchemaProps, error) {
+	return plugin.ConfigSchema(Config{})
+}
+
 func (p *Plugin) ComputeConfig(ctx context.Context, req pipeline.CapsuleRequest, logger hclog.Logger) (string, error) {
 	return plugin.ParseCapsuleTemplatedConfigToString[Config](p.configBytes, req)
 }
@@ -71,25 +83,25 @@ func (d *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 		return err
 	}
 
-	deployment := &appsv1.Deployment{}
-	if err := req.GetNewInto(deployment); errors.IsNotFound(err) {
+	workload, template, err := pipeline.GetNewWorkload(req)
+	if errors.IsNotFound(err) {
 		return nil
 	} else if err != nil {
 		return err
 	}
 
-	if deployment.Labels == nil {
-		deployment.Labels = map[string]string{}
+	if workload.GetLabels() == nil {
+		workload.SetLabels(map[string]string{})
 	}
-	if deployment.Spec.Template.Labels == nil {
-		deployment.Spec.Template.Labels = map[string]string{}
+	if template.Labels == nil {
+		template.Labels = map[string]string{}
 	}
-	if deployment.Spec.Template.Annotations == nil {
-		deployment.Spec.Template.Annotations = map[string]string{}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
 	}
 
 	if !config.DontAddEnabledAnnotation {
-		deployment.Spec.Template.Labels["admission.datadoghq.com/enabled"] = "true"
+		template.Labels["admission.datadoghq.com/enabled"] = "true"
 	}
 
 	l := config.LibraryTag
@@ -100,7 +112,7 @@ func (d *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 		"admission.datadoghq.com/dotnet-lib.version": l.NET,
 		"admission.datadoghq.com/ruby-lib.version":   l.Ruby,
 	}
-	annotations := deployment.Spec.Template.Annotations
+	annotations := template.Annotations
 	for k, v := range tags {
 		if v == "" {
 			continue
@@ -114,7 +126,7 @@ func (d *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 		"tags.datadoghq.com/service": u.Service,
 		"tags.datadoghq.com/version": u.Version,
 	}
-	labels1, labels2 := deployment.Labels, deployment.Spec.Template.Labels
+	labels1, labels2 := workload.GetLabels(), template.Labels
 	for k, v := range tags {
 		if v == "" {
 			continue
@@ -123,5 +135,5 @@ func (d *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 		labels2[k] = v
 	}
 
-	return req.Set(deployment)
+	return req.Set(workload)
 }
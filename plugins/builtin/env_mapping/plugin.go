@@ -9,7 +9,6 @@ import (
 	"github.com/rigdev/rig/pkg/controller/plugin"
 	"github.com/rigdev/rig/pkg/errors"
 	"github.com/rigdev/rig/pkg/pipeline"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -60,8 +59,8 @@ func (p *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 		return err
 	}
 
-	deployment := &appsv1.Deployment{}
-	if err := req.GetNewInto(deployment); errors.IsNotFound(err) {
+	workload, template, err := pipeline.GetNewWorkload(req)
+	if errors.IsNotFound(err) {
 		return nil
 	} else if err != nil {
 		return err
@@ -73,7 +72,7 @@ func (p *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 			container = req.Capsule().GetName()
 		}
 
-		for i, c := range deployment.Spec.Template.Spec.Containers {
+		for i, c := range template.Spec.Containers {
 			if c.Name != container {
 				continue
 			}
@@ -122,11 +121,11 @@ func (p *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 				c.Env = append(c.Env, envVar)
 			}
 
-			deployment.Spec.Template.Spec.Containers[i] = c
+			template.Spec.Containers[i] = c
 
 			break
 		}
 	}
 
-	return req.Set(deployment)
+	return req.Set(workload)
 }
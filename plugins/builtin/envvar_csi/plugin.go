@@ -12,7 +12,6 @@ import (
 	"github.com/rigdev/rig/pkg/pipeline"
 	"github.com/rigdev/rig/pkg/ptr"
 	"gopkg.in/yaml.v3"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -55,14 +54,14 @@ func (p *Plugin) Run(ctx context.Context, req pipeline.CapsuleRequest, _ hclog.L
 		config.ContainerName = req.Capsule().Name
 	}
 
-	deployment := &appsv1.Deployment{}
-	if err := req.GetNewInto(deployment); err != nil {
+	workload, template, err := pipeline.GetNewWorkload(req)
+	if err != nil {
 		return err
 	}
 	var container *corev1.Container
-	for idx, c := range deployment.Spec.Template.Spec.Containers {
+	for idx, c := range template.Spec.Containers {
 		if c.Name == config.ContainerName {
-			container = &deployment.Spec.Template.Spec.Containers[idx]
+			container = &template.Spec.Containers[idx]
 			break
 		}
 	}
@@ -102,7 +101,7 @@ func (p *Plugin) Run(ctx context.Context, req pipeline.CapsuleRequest, _ hclog.L
 		return err
 	}
 
-	deployment.Spec.Template.Spec.Volumes = append(deployment.Spec.Template.Spec.Volumes, corev1.Volume{
+	template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
 		Name: fmt.Sprintf("csi"),
 		VolumeSource: corev1.VolumeSource{
 			CSI: &corev1.CSIVolumeSource{
@@ -122,7 +121,7 @@ func (p *Plugin) Run(ctx context.Context, req pipeline.CapsuleRequest, _ hclog.L
 		},
 	})
 
-	if err := req.Set(deployment); err != nil {
+	if err := req.Set(workload); err != nil {
 		return err
 	}
 
@@ -11,7 +11,6 @@ import (
 	"github.com/rigdev/rig/pkg/errors"
 	"github.com/rigdev/rig/pkg/pipeline"
 	"github.com/rigdev/rig/pkg/ptr"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 )
@@ -70,18 +69,18 @@ func (p *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 	if err != nil {
 		return err
 	}
-	deployment := &appsv1.Deployment{}
-	if err := req.GetNewInto(deployment); errors.IsNotFound(err) {
+	workload, template, err := pipeline.GetNewWorkload(req)
+	if errors.IsNotFound(err) {
 		return nil
 	} else if err != nil {
 		return err
 	}
 
 	var allnames []string
-	for _, c := range deployment.Spec.Template.Spec.Containers {
+	for _, c := range template.Spec.Containers {
 		allnames = append(allnames, c.Name)
 	}
-	for _, c := range deployment.Spec.Template.Spec.InitContainers {
+	for _, c := range template.Spec.InitContainers {
 		allnames = append(allnames, c.Name)
 	}
 	for _, name := range allnames {
@@ -123,14 +122,14 @@ func (p *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 
 	volume, mounts := pipeline.FilesToVolumes(config.Files)
 	for _, v := range volume {
-		for _, vv := range deployment.Spec.Template.Spec.Volumes {
+		for _, vv := range template.Spec.Volumes {
 			found := false
 			if v.Name == vv.Name {
 				found = true
 				break
 			}
 			if !found {
-				deployment.Spec.Template.Spec.Volumes = append(deployment.Spec.Template.Spec.Volumes, v)
+				template.Spec.Volumes = append(template.Spec.Volumes, v)
 			}
 		}
 	}
@@ -150,7 +149,7 @@ func (p *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 		},
 		RestartPolicy: ptr.New(corev1.ContainerRestartPolicyAlways),
 	}
-	deployment.Spec.Template.Spec.InitContainers = append(deployment.Spec.Template.Spec.InitContainers, container)
+	template.Spec.InitContainers = append(template.Spec.InitContainers, container)
 
-	return req.Set(deployment)
+	return req.Set(workload)
 }
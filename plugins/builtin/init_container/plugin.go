@@ -9,7 +9,6 @@ import (
 	"github.com/rigdev/rig/pkg/controller/plugin"
 	"github.com/rigdev/rig/pkg/errors"
 	"github.com/rigdev/rig/pkg/pipeline"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -47,15 +46,15 @@ func (p *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 		return fmt.Errorf("invalid configuration, no `container` was specified")
 	}
 
-	deployment := &appsv1.Deployment{}
-	if err := req.GetNewInto(deployment); errors.IsNotFound(err) {
+	workload, template, err := pipeline.GetNewWorkload(req)
+	if errors.IsNotFound(err) {
 		return nil
 	} else if err != nil {
 		return err
 	}
 
 	c := *config.Container.DeepCopy()
-	deployment.Spec.Template.Spec.InitContainers = append(deployment.Spec.Template.Spec.InitContainers, c)
+	template.Spec.InitContainers = append(template.Spec.InitContainers, c)
 
-	return req.Set(deployment)
+	return req.Set(workload)
 }
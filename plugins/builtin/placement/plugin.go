@@ -7,8 +7,8 @@ import (
 	"github.com/hashicorp/go-hclog"
 	"github.com/rigdev/rig/pkg/controller/plugin"
 	"github.com/rigdev/rig/pkg/pipeline"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
 
 const Name = "rigdev.placement"
@@ -22,6 +22,14 @@ type Config struct {
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 	// True if a capsule needs a Tag annotation to be run
 	RequireTag bool `json:"requireTag,omitempty"`
+	// CapsuleExtension is the name of a Capsule extension, whose value is merged over
+	// this configuration for the Capsules setting it.
+	CapsuleExtension string `json:"capsuleExtension,omitempty"`
+}
+
+// CapsuleExtensionName implements plugin.ExtensibleConfig.
+func (c Config) CapsuleExtensionName() string {
+	return c.CapsuleExtension
 }
 
 type Plugin struct {
@@ -43,6 +51,10 @@ func (p *Plugin) Initialize(req plugin.InitializeRequest) error {
 	return nil
 }
 
+func (p *Plugin) ExtensionSchema() (*apiextensionsv1.JSONSchemaProps, error) {
+	return plugin.ConfigSchema(Config{})
+}
+
 func (p *Plugin) ComputeConfig(ctx context.Context, req pipeline.CapsuleRequest, logger hclog.Logger) (string, error) {
 	return plugin.ParseCapsuleTemplatedConfigToString[Config](p.configBytes, req)
 }
@@ -58,22 +70,22 @@ func (p *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 		return nil
 	}
 
-	deployment := &appsv1.Deployment{}
-	if err := req.GetNewInto(deployment); err != nil {
+	workload, template, err := pipeline.GetNewWorkload(req)
+	if err != nil {
 		return err
 	}
 
-	selector := deployment.Spec.Template.Spec.NodeSelector
+	selector := template.Spec.NodeSelector
 	if selector == nil {
 		selector = map[string]string{}
 	}
 	for k, v := range p.config.NodeSelector {
 		selector[k] = v
 	}
-	deployment.Spec.Template.Spec.NodeSelector = selector
-	deployment.Spec.Template.Spec.Tolerations = append(deployment.Spec.Template.Spec.Tolerations, p.config.Tolerations...)
+	template.Spec.NodeSelector = selector
+	template.Spec.Tolerations = append(template.Spec.Tolerations, p.config.Tolerations...)
 
-	return req.Set(deployment)
+	return req.Set(workload)
 }
 
 func (p *Plugin) shouldRun(req pipeline.CapsuleRequest) bool {
@@ -10,8 +10,8 @@ import (
 	"github.com/rigdev/rig/pkg/errors"
 	"github.com/rigdev/rig/pkg/pipeline"
 	"github.com/rigdev/rig/pkg/ptr"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
 
 const Name = "rigdev.sidecar"
@@ -21,6 +21,14 @@ const Name = "rigdev.sidecar"
 type Config struct {
 	// Container is the configuration of the sidecar injected into the deployment
 	Container *corev1.Container `json:"container"`
+	// CapsuleExtension is the name of a Capsule extension, whose value is merged over
+	// this configuration for the Capsules setting it.
+	CapsuleExtension string `json:"capsuleExtension,omitempty"`
+}
+
+// CapsuleExtensionName implements plugin.ExtensibleConfig.
+func (c Config) CapsuleExtensionName() string {
+	return c.CapsuleExtension
 }
 
 type Plugin struct {
@@ -34,6 +42,10 @@ func (p *Plugin) Initialize(req plugin.InitializeRequest) error {
 	return nil
 }
 
+func (p *Plugin) ExtensionSchema() (*apiextensionsv1.JSONSchemaProps, error) {
+	return plugin.ConfigSchema(Config{})
+}
+
 func (p *Plugin) ComputeConfig(ctx context.Context, req pipeline.CapsuleRequest, logger hclog.Logger) (string, error) {
 	return plugin.ParseCapsuleTemplatedConfigToString[Config](p.configBytes, req)
 }
@@ -48,8 +60,8 @@ func (p *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 		return fmt.Errorf("invalid configuration, no `container` was specified")
 	}
 
-	deployment := &appsv1.Deployment{}
-	if err := req.GetNewInto(deployment); errors.IsNotFound(err) {
+	workload, template, err := pipeline.GetNewWorkload(req)
+	if errors.IsNotFound(err) {
 		return nil
 	} else if err != nil {
 		return err
@@ -57,7 +69,7 @@ func (p *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 
 	c := *config.Container.DeepCopy()
 	c.RestartPolicy = ptr.New(corev1.ContainerRestartPolicyAlways)
-	deployment.Spec.Template.Spec.InitContainers = append(deployment.Spec.Template.Spec.InitContainers, c)
+	template.Spec.InitContainers = append(template.Spec.InitContainers, c)
 
-	return req.Set(deployment)
+	return req.Set(workload)
 }
@@ -6,14 +6,14 @@ package cron_jobs
 import (
 	"context"
 	"fmt"
-	"net/url"
+	"slices"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
 	"github.com/rigdev/rig/pkg/controller/plugin"
 	"github.com/rigdev/rig/pkg/errors"
 	"github.com/rigdev/rig/pkg/pipeline"
 	"github.com/rigdev/rig/pkg/ptr"
-	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -67,8 +67,8 @@ func (p *Plugin) Run(ctx context.Context, req pipeline.CapsuleRequest, logger hc
 
 func (p *Plugin) createCronJobs(req pipeline.CapsuleRequest) ([]*batchv1.CronJob, error) {
 	var res []*batchv1.CronJob
-	deployment := &appsv1.Deployment{}
-	if err := req.GetNewInto(deployment); errors.IsNotFound(err) {
+	_, podTemplate, err := pipeline.GetNewWorkload(req)
+	if errors.IsNotFound(err) {
 		// TODO(anders): We should support this for command jobs.
 		return nil, nil
 	} else if err != nil {
@@ -78,22 +78,10 @@ func (p *Plugin) createCronJobs(req pipeline.CapsuleRequest) ([]*batchv1.CronJob
 	for _, job := range req.Capsule().Spec.CronJobs {
 		var template corev1.PodTemplateSpec
 		if job.Command != nil {
-			template = *deployment.Spec.Template.DeepCopy()
-			c := template.Spec.Containers[0]
-			if job.Command.Command != "" {
-				c.Command = []string{job.Command.Command}
-			}
-			c.Args = job.Command.Args
-			template.Spec.Containers[0] = c
-			template.Spec.RestartPolicy = corev1.RestartPolicyNever
-
+			template = CommandPodTemplate(req.Capsule(), podTemplate, *job.Command)
 		} else if job.URL != nil {
-			args := []string{"-G", "--fail-with-body"}
-			for k, v := range job.URL.QueryParameters {
-				args = append(args, "-d", fmt.Sprintf("%v=%v", url.QueryEscape(k), url.QueryEscape(v)))
-			}
 			urlString := fmt.Sprintf("http://%s:%v%s", req.Capsule().Name, job.URL.Port, job.URL.Path)
-			args = append(args, urlString)
+			args := append(urlJobArgs(job.URL.QueryParameters), urlString)
 			template = corev1.PodTemplateSpec{
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{{
@@ -144,3 +132,38 @@ func (p *Plugin) createCronJobs(req pipeline.CapsuleRequest) ([]*batchv1.CronJob
 
 	return res, nil
 }
+
+// CommandPodTemplate returns the pod template of a Job running the command in
+// the main container of the capsule, based on the pod template of its
+// workload. The additional containers of the capsule are not run by the Job,
+// as they would keep it from completing.
+func CommandPodTemplate(
+	capsule *v1alpha2.Capsule,
+	podTemplate *corev1.PodTemplateSpec,
+	command v1alpha2.JobCommand,
+) corev1.PodTemplateSpec {
+	template := *podTemplate.DeepCopy()
+	c := template.Spec.Containers[0]
+	if command.Command != "" {
+		c.Command = []string{command.Command}
+	}
+	c.Args = command.Args
+	// Jobs keep the preStop hook and termination grace period of the
+	// capsule, so they are shut down the same way. They don't serve the
+	// interfaces of the capsule, so they are not probed.
+	c.StartupProbe = nil
+	c.LivenessProbe = nil
+	c.ReadinessProbe = nil
+	if storage := capsule.Spec.Storage; storage != nil {
+		// Persistent volumes are claimed per instance of the capsule and
+		// are not available to jobs.
+		c.VolumeMounts = slices.DeleteFunc(c.VolumeMounts, func(m corev1.VolumeMount) bool {
+			return slices.ContainsFunc(storage.Volumes, func(v v1alpha2.PersistentVolume) bool {
+				return v.Name == m.Name
+			})
+		})
+	}
+	template.Spec.Containers = []corev1.Container{c}
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	return template
+}
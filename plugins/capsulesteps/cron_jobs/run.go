@@ -0,0 +1,161 @@
+//nolint:revive
+package cron_jobs
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	"golang.org/x/exp/maps"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	TriggerManual  = "manual"
+	TriggerWebhook = "webhook"
+)
+
+// JobOverrides are the parameters of a single run of a job, overriding the ones
+// of the job in the capsule spec.
+type JobOverrides struct {
+	// Args replaces the arguments of the command of a job running a command.
+	Args []string `json:"args,omitempty"`
+	// Env adds environment variables to a job running a command.
+	Env map[string]string `json:"env,omitempty"`
+	// QueryParameters replaces query parameters of the request of a job calling
+	// a URL, and adds the ones the job doesn't have.
+	QueryParameters map[string]string `json:"queryParameters,omitempty"`
+	// MaxRetries replaces the number of retries of the job.
+	MaxRetries *uint `json:"maxRetries,omitempty"`
+	// TimeoutSeconds replaces the timeout of the job.
+	TimeoutSeconds *uint `json:"timeoutSeconds,omitempty"`
+}
+
+// JobFromCronJob creates a Job running the job of the capsule outside of its
+// schedule, from the template of the CronJob created for it. The Job is owned
+// by the CronJob and gets the labels of the Jobs created by its schedule, so
+// the run is listed among the executions of the job.
+func JobFromCronJob(
+	cronJob *batchv1.CronJob,
+	job v1alpha2.CronJob,
+	trigger string,
+	overrides JobOverrides,
+) (*batchv1.Job, error) {
+	spec := *cronJob.Spec.JobTemplate.Spec.DeepCopy()
+	if len(spec.Template.Spec.Containers) == 0 {
+		return nil, errors.FailedPreconditionErrorf("cron job '%s' has no containers", cronJob.GetName())
+	}
+	c := &spec.Template.Spec.Containers[0]
+
+	if job.URL != nil {
+		if overrides.Args != nil || len(overrides.Env) > 0 {
+			return nil, errors.InvalidArgumentErrorf(
+				"job '%s' calls a URL and can only be given query parameters", job.Name)
+		}
+		if len(overrides.QueryParameters) > 0 && len(c.Args) > 0 {
+			params := maps.Clone(job.URL.QueryParameters)
+			if params == nil {
+				params = map[string]string{}
+			}
+			maps.Copy(params, overrides.QueryParameters)
+			// The URL is the last argument of curl.
+			c.Args = append(urlJobArgs(params), c.Args[len(c.Args)-1])
+		}
+	} else {
+		if len(overrides.QueryParameters) > 0 {
+			return nil, errors.InvalidArgumentErrorf(
+				"job '%s' runs a command and can't be given query parameters", job.Name)
+		}
+		if overrides.Args != nil {
+			c.Args = overrides.Args
+		}
+		for _, k := range sortedKeys(overrides.Env) {
+			c.Env = slices.DeleteFunc(c.Env, func(e corev1.EnvVar) bool { return e.Name == k })
+			c.Env = append(c.Env, corev1.EnvVar{Name: k, Value: overrides.Env[k]})
+		}
+	}
+
+	if overrides.MaxRetries != nil {
+		spec.BackoffLimit = ptr.Convert[uint, int32](overrides.MaxRetries)
+	}
+	if overrides.TimeoutSeconds != nil {
+		spec.ActiveDeadlineSeconds = ptr.Convert[uint, int64](overrides.TimeoutSeconds)
+	}
+
+	annotations := maps.Clone(cronJob.Spec.JobTemplate.GetAnnotations())
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	// Same annotation as set by `kubectl create job --from=cronjob/...`.
+	annotations["cronjob.kubernetes.io/instantiate"] = "manual"
+	annotations[pipeline.AnnotationJobTrigger] = trigger
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: cronJob.GetName() + "-",
+			Namespace:    cronJob.GetNamespace(),
+			Labels:       maps.Clone(cronJob.Spec.JobTemplate.GetLabels()),
+			Annotations:  annotations,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: batchv1.SchemeGroupVersion.String(),
+				Kind:       "CronJob",
+				Name:       cronJob.GetName(),
+				UID:        cronJob.GetUID(),
+				Controller: ptr.New(true),
+			}},
+		},
+		Spec: spec,
+	}, nil
+}
+
+// CheckAllowed returns a PermissionDenied error if the overrides change
+// parameters of the job which are not allowed to be overridden.
+func (o JobOverrides) CheckAllowed(allowed *v1alpha2.JobAllowedOverrides) error {
+	if allowed == nil {
+		allowed = &v1alpha2.JobAllowedOverrides{}
+	}
+
+	if o.Args != nil && !allowed.Args {
+		return errors.PermissionDeniedErrorf("args can't be overridden")
+	}
+	for _, k := range sortedKeys(o.Env) {
+		if !slices.Contains(allowed.Env, k) {
+			return errors.PermissionDeniedErrorf("env variable '%s' can't be overridden", k)
+		}
+	}
+	for _, k := range sortedKeys(o.QueryParameters) {
+		if !slices.Contains(allowed.QueryParameters, k) {
+			return errors.PermissionDeniedErrorf("query parameter '%s' can't be overridden", k)
+		}
+	}
+	if o.MaxRetries != nil && !allowed.MaxRetries {
+		return errors.PermissionDeniedErrorf("maxRetries can't be overridden")
+	}
+	if o.TimeoutSeconds != nil && !allowed.TimeoutSeconds {
+		return errors.PermissionDeniedErrorf("timeoutSeconds can't be overridden")
+	}
+
+	return nil
+}
+
+// urlJobArgs returns the arguments of curl for a job calling a URL with the
+// given query parameters, except for the URL itself.
+func urlJobArgs(queryParameters map[string]string) []string {
+	args := []string{"-G", "--fail-with-body"}
+	for _, k := range sortedKeys(queryParameters) {
+		args = append(args, "-d", fmt.Sprintf("%v=%v", url.QueryEscape(k), url.QueryEscape(queryParameters[k])))
+	}
+	return args
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := maps.Keys(m)
+	slices.Sort(keys)
+	return keys
+}
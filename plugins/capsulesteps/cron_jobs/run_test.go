@@ -0,0 +1,168 @@
+//nolint:revive
+package cron_jobs
+
+import (
+	"testing"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newCronJob(container corev1.Container) *batchv1.CronJob {
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-report",
+			Namespace: "prod",
+			UID:       "uid",
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "0 * * * *",
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						pipeline.LabelCapsule: "test",
+						pipeline.LabelCron:    "report",
+					},
+					Annotations: map[string]string{
+						"rig.dev/rollout": "3",
+					},
+				},
+				Spec: batchv1.JobSpec{
+					BackoffLimit:          ptr.New[int32](2),
+					ActiveDeadlineSeconds: ptr.New[int64](60),
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers:    []corev1.Container{container},
+							RestartPolicy: corev1.RestartPolicyNever,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func Test_JobFromCronJob_command(t *testing.T) {
+	cronJob := newCronJob(corev1.Container{
+		Name:    "test",
+		Image:   "image",
+		Command: []string{"report"},
+		Args:    []string{"--all"},
+		Env:     []corev1.EnvVar{{Name: "LEVEL", Value: "info"}},
+	})
+	spec := v1alpha2.CronJob{
+		Name:     "report",
+		Schedule: "0 * * * *",
+		Command:  &v1alpha2.JobCommand{Command: "report", Args: []string{"--all"}},
+	}
+
+	job, err := JobFromCronJob(cronJob, spec, TriggerManual, JobOverrides{
+		Args:           []string{"--since", "1h"},
+		Env:            map[string]string{"LEVEL": "debug", "DRY_RUN": "true"},
+		MaxRetries:     ptr.New[uint](0),
+		TimeoutSeconds: ptr.New[uint](300),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "test-report-", job.GenerateName)
+	assert.Equal(t, "prod", job.Namespace)
+	assert.Equal(t, map[string]string{
+		pipeline.LabelCapsule: "test",
+		pipeline.LabelCron:    "report",
+	}, job.Labels)
+	assert.Equal(t, map[string]string{
+		"rig.dev/rollout":                   "3",
+		"cronjob.kubernetes.io/instantiate": "manual",
+		pipeline.AnnotationJobTrigger:       TriggerManual,
+	}, job.Annotations)
+	assert.Equal(t, []metav1.OwnerReference{{
+		APIVersion: "batch/v1",
+		Kind:       "CronJob",
+		Name:       "test-report",
+		UID:        "uid",
+		Controller: ptr.New(true),
+	}}, job.OwnerReferences)
+	assert.Equal(t, ptr.New[int32](0), job.Spec.BackoffLimit)
+	assert.Equal(t, ptr.New[int64](300), job.Spec.ActiveDeadlineSeconds)
+
+	c := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, []string{"report"}, c.Command)
+	assert.Equal(t, []string{"--since", "1h"}, c.Args)
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "DRY_RUN", Value: "true"},
+		{Name: "LEVEL", Value: "debug"},
+	}, c.Env)
+
+	// The template of the CronJob is left untouched.
+	assert.Equal(t, []string{"--all"}, cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Args)
+	assert.Equal(t, ptr.New[int32](2), cronJob.Spec.JobTemplate.Spec.BackoffLimit)
+
+	_, err = JobFromCronJob(cronJob, spec, TriggerManual, JobOverrides{
+		QueryParameters: map[string]string{"since": "1h"},
+	})
+	assert.True(t, errors.IsInvalidArgument(err))
+}
+
+func Test_JobFromCronJob_url(t *testing.T) {
+	cronJob := newCronJob(corev1.Container{
+		Name:    "test-report",
+		Image:   "quay.io/curl/curl:latest",
+		Command: []string{"curl"},
+		Args:    []string{"-G", "--fail-with-body", "-d", "all=true", "http://test:8080/report"},
+	})
+	spec := v1alpha2.CronJob{
+		Name:     "report",
+		Schedule: "0 * * * *",
+		URL: &v1alpha2.URL{
+			Port:            8080,
+			Path:            "/report",
+			QueryParameters: map[string]string{"all": "true"},
+		},
+	}
+
+	job, err := JobFromCronJob(cronJob, spec, TriggerWebhook, JobOverrides{
+		QueryParameters: map[string]string{"since": "1h", "format": "csv", "all": "false"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, TriggerWebhook, job.Annotations[pipeline.AnnotationJobTrigger])
+	assert.Equal(t, ptr.New[int32](2), job.Spec.BackoffLimit)
+	assert.Equal(t, ptr.New[int64](60), job.Spec.ActiveDeadlineSeconds)
+	assert.Equal(t, []string{
+		"-G", "--fail-with-body", "-d", "all=false", "-d", "format=csv", "-d", "since=1h", "http://test:8080/report",
+	}, job.Spec.Template.Spec.Containers[0].Args)
+
+	_, err = JobFromCronJob(cronJob, spec, TriggerWebhook, JobOverrides{
+		Args: []string{"--since", "1h"},
+	})
+	assert.True(t, errors.IsInvalidArgument(err))
+}
+
+func Test_JobOverrides_CheckAllowed(t *testing.T) {
+	overrides := JobOverrides{
+		Args: []string{"--since", "1h"},
+		Env:  map[string]string{"LEVEL": "debug"},
+	}
+
+	assert.True(t, errors.IsPermissionDenied(overrides.CheckAllowed(nil)))
+	assert.True(t, errors.IsPermissionDenied(overrides.CheckAllowed(&v1alpha2.JobAllowedOverrides{
+		Args: true,
+		Env:  []string{"DRY_RUN"},
+	})))
+	assert.NoError(t, overrides.CheckAllowed(&v1alpha2.JobAllowedOverrides{
+		Args: true,
+		Env:  []string{"DRY_RUN", "LEVEL"},
+	}))
+
+	assert.NoError(t, JobOverrides{}.CheckAllowed(nil))
+	assert.True(t, errors.IsPermissionDenied(JobOverrides{
+		TimeoutSeconds: ptr.New[uint](300),
+	}.CheckAllowed(&v1alpha2.JobAllowedOverrides{MaxRetries: true})))
+}
@@ -61,7 +61,7 @@ func onJobUpdated(
 		job.Annotations["rig.dev/rollout"], 10, 64,
 	)
 
-	state, finishedAt := getStateAndFinishTimeFromJobExecutionStatus(job)
+	state, finishedAt := JobExecutionState(job)
 	var finishedAtPB *timestamppb.Timestamp
 	if !finishedAt.IsZero() {
 		finishedAtPB = timestamppb.New(finishedAt)
@@ -81,11 +81,16 @@ func onJobUpdated(
 			},
 		}},
 	}
+	if trigger, ok := job.Annotations[pipeline.AnnotationJobTrigger]; ok {
+		info.Properties = map[string]string{"Trigger": trigger}
+	}
 
 	return info
 }
 
-func getStateAndFinishTimeFromJobExecutionStatus(job *batchv1.Job) (apipipeline.JobExecutionState, time.Time) {
+// JobExecutionState returns the state of the Job, and when it finished if it
+// isn't ongoing.
+func JobExecutionState(job *batchv1.Job) (apipipeline.JobExecutionState, time.Time) {
 	if len(job.Status.Conditions) == 0 {
 		return apipipeline.JobExecutionState_JOB_STATE_ONGOING, time.Time{}
 	}
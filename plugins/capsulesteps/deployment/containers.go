@@ -0,0 +1,93 @@
+package deployment
+
+import (
+	"slices"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"golang.org/x/exp/maps"
+	v1 "k8s.io/api/core/v1"
+)
+
+// createContainers creates the additional application containers of the
+// capsule, and the volumes holding their files. The volumes are prefixed with
+// the name of the container, as containers can mount the same ConfigMap or
+// Secret as the main container with different keys.
+func createContainers(req pipeline.CapsuleRequest) ([]v1.Container, []v1.Volume) {
+	var containers []v1.Container
+	var volumes []v1.Volume
+	for _, ac := range req.Capsule().Spec.Containers {
+		c := v1.Container{
+			Name:  ac.Name,
+			Image: ac.Image,
+			Env: []v1.EnvVar{
+				{
+					Name:  "RIG_CAPSULE_NAME",
+					Value: req.Capsule().Name,
+				},
+			},
+			EnvFrom:   pipeline.EnvSources(ac.Env.From),
+			Args:      ac.Args,
+			Resources: makeSidecarResourceRequirements(ac.Resources),
+		}
+
+		if ac.Command != "" {
+			c.Command = []string{ac.Command}
+		}
+
+		keys := maps.Keys(ac.Env.Raw)
+		slices.Sort(keys)
+		for _, k := range keys {
+			c.Env = append(c.Env, v1.EnvVar{
+				Name:  k,
+				Value: ac.Env.Raw[k],
+			})
+		}
+
+		for _, f := range ac.Files {
+			volume, mount := pipeline.FileToVolume(f)
+			if volume.Name == "" {
+				continue
+			}
+			volume.Name = ac.Name + "-" + volume.Name
+			mount.Name = volume.Name
+			volumes = addVolume(volumes, volume)
+			c.VolumeMounts = append(c.VolumeMounts, mount)
+		}
+
+		containers = append(containers, c)
+	}
+
+	return containers, volumes
+}
+
+// addVolume adds a volume of a file to the volumes. If a file of the same
+// ConfigMap or Secret is already mounted, its volume is extended with the key
+// of the file instead.
+func addVolume(volumes []v1.Volume, volume v1.Volume) []v1.Volume {
+	idx := slices.IndexFunc(volumes, func(v v1.Volume) bool { return v.Name == volume.Name })
+	if idx < 0 {
+		return append(volumes, volume)
+	}
+
+	existing := &volumes[idx]
+	switch {
+	case existing.ConfigMap != nil && volume.ConfigMap != nil:
+		existing.ConfigMap.Items = append(existing.ConfigMap.Items, volume.ConfigMap.Items...)
+	case existing.Secret != nil && volume.Secret != nil:
+		existing.Secret.Items = append(existing.Secret.Items, volume.Secret.Items...)
+	}
+	return volumes
+}
+
+// containerInterfaces returns the interfaces of each container of the
+// capsule, keyed by the name of the container.
+func containerInterfaces(capsule *v1alpha2.Capsule) map[string][]v1alpha2.CapsuleInterface {
+	res := map[string][]v1alpha2.CapsuleInterface{
+		capsule.Name: capsule.Spec.Interfaces,
+	}
+	for _, c := range capsule.Spec.Containers {
+		res[c.Name] = c.Interfaces
+	}
+	return res
+}
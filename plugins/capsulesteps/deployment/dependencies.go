@@ -0,0 +1,215 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/pipeline"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// annotationMissingDependencies is set on the workload of a capsule with
+	// the dependencies of the capsule which could not be resolved.
+	annotationMissingDependencies = "rig.dev/missing-dependencies"
+
+	// waitForDependenciesContainer is the name of the init container holding
+	// back instances until the dependencies of the capsule accept connections.
+	waitForDependenciesContainer = "wait-for-dependencies"
+
+	defaultDependencyWaitImage = "busybox:1.36"
+
+	// waitForDependenciesScript is run by the wait-for-dependencies init
+	// container, with the `host:port` of each dependency as arguments.
+	waitForDependenciesScript = `for dep in "$@"; do
+  until nc -z -w 2 "${dep%:*}" "${dep##*:}"; do
+    echo "waiting for $dep"
+    sleep 2
+  done
+done`
+)
+
+// dependency is a dependency of the capsule, resolved to the address of the
+// Service of the capsule depended on.
+type dependency struct {
+	v1alpha2.CapsuleDependency
+	host string
+	port int32
+}
+
+// getDependencies resolves the dependencies of the capsule, by looking up the
+// port of the interface of each capsule depended on. Dependencies which can't
+// be resolved, as the capsule or interface doesn't exist (yet), are skipped
+// and returned as missing, as the capsule is reconciled again when the
+// capsules it depends on change.
+func (p *Plugin) getDependencies(
+	ctx context.Context,
+	req pipeline.CapsuleRequest,
+) ([]dependency, []string, error) {
+	var deps []dependency
+	var missing []string
+	for _, d := range req.Capsule().Spec.Dependencies {
+		if d.Namespace == "" {
+			d.Namespace = req.Capsule().Namespace
+		}
+
+		var capsule v1alpha2.Capsule
+		if err := errors.FromK8sClient(req.Reader().Get(ctx, types.NamespacedName{
+			Namespace: d.Namespace,
+			Name:      d.Capsule,
+		}, &capsule)); errors.IsNotFound(err) {
+			missing = append(missing, fmt.Sprintf("capsule '%s' does not exist in namespace '%s'",
+				d.Capsule, d.Namespace))
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		dep := dependency{
+			CapsuleDependency: d,
+			host:              fmt.Sprintf("%s.%s.svc", d.Capsule, d.Namespace),
+		}
+		for _, inf := range capsule.AllInterfaces() {
+			if inf.Name == d.Interface {
+				dep.port = inf.Port
+			}
+		}
+		if dep.port == 0 {
+			missing = append(missing, fmt.Sprintf("capsule '%s' has no interface '%s'", d.Capsule, d.Interface))
+			continue
+		}
+
+		deps = append(deps, dep)
+	}
+
+	return deps, missing, nil
+}
+
+// setDependencies injects the host and port of the dependencies into the
+// application containers and the sidecars of the deployment, and adds the
+// wait-for-dependencies init container after the sidecars, so sidecars
+// proxying traffic are started first.
+// Missing dependencies are recorded in the annotationMissingDependencies
+// annotation, which is reported as a condition of the deployment.
+func setDependencies(d *appsv1.Deployment, deps []dependency, missing []string, config Config) {
+	if len(missing) > 0 {
+		if d.Annotations == nil {
+			d.Annotations = map[string]string{}
+		}
+		d.Annotations[annotationMissingDependencies] = strings.Join(missing, "; ")
+	}
+
+	if len(deps) == 0 {
+		return
+	}
+
+	spec := &d.Spec.Template.Spec
+	env := dependencyEnv(deps)
+	for i := range spec.Containers {
+		spec.Containers[i].Env = append(spec.Containers[i].Env, env...)
+	}
+	for i, c := range spec.InitContainers {
+		if c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			spec.InitContainers[i].Env = append(spec.InitContainers[i].Env, env...)
+		}
+	}
+	if c := createWaitForDependencies(deps, config.DependencyWaitImage); c != nil {
+		spec.InitContainers = append(spec.InitContainers, *c)
+	}
+}
+
+// dependencyEnv returns the environment variables with the host and port of
+// each dependency, named after the capsule and interface depended on, fx.
+// `USERS_GRPC_HOST` and `USERS_GRPC_PORT`.
+func dependencyEnv(deps []dependency) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	for _, d := range deps {
+		prefix := strings.ToUpper(strings.ReplaceAll(d.Capsule+"_"+d.Interface, "-", "_"))
+		env = append(env,
+			corev1.EnvVar{Name: prefix + "_HOST", Value: d.host},
+			corev1.EnvVar{Name: prefix + "_PORT", Value: fmt.Sprint(d.port)},
+		)
+	}
+	return env
+}
+
+// createWaitForDependencies returns an init container which waits until the
+// dependencies with WaitForReady set accept connections. Connections to a
+// Service are only accepted when it has ready endpoints. Returns nil if no
+// dependency should be waited for.
+func createWaitForDependencies(deps []dependency, image string) *corev1.Container {
+	var addrs []string
+	for _, d := range deps {
+		if d.WaitForReady {
+			addrs = append(addrs, fmt.Sprintf("%s:%d", d.host, d.port))
+		}
+	}
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	if image == "" {
+		image = defaultDependencyWaitImage
+	}
+
+	return &corev1.Container{
+		Name:    waitForDependenciesContainer,
+		Image:   image,
+		Command: []string{"sh", "-c", waitForDependenciesScript, waitForDependenciesContainer},
+		Args:    addrs,
+	}
+}
+
+// makeDependenciesCondition adds a condition to the status of the pod, telling
+// whether it is waiting on the dependencies of the capsule.
+func makeDependenciesCondition(status *apipipeline.ObjectStatusInfo, pod *corev1.Pod) {
+	var container *corev1.Container
+	for _, c := range pod.Spec.InitContainers {
+		if c.Name == waitForDependenciesContainer {
+			container = &c
+			break
+		}
+	}
+	if container == nil {
+		return
+	}
+
+	cond := &apipipeline.ObjectCondition{
+		Name:    "Dependencies",
+		State:   apipipeline.ObjectState_OBJECT_STATE_PENDING,
+		Message: fmt.Sprintf("Waiting on dependencies %s", strings.Join(container.Args, ", ")),
+	}
+	for _, s := range pod.Status.InitContainerStatuses {
+		if s.Name != waitForDependenciesContainer {
+			continue
+		}
+		if t := s.State.Terminated; t != nil && t.ExitCode == 0 {
+			cond.State = apipipeline.ObjectState_OBJECT_STATE_HEALTHY
+			cond.Message = "Dependencies are ready"
+		}
+	}
+
+	status.Conditions = append(status.Conditions, cond)
+}
+
+// makeMissingDependenciesCondition adds a condition to the status of the
+// workload, telling which dependencies of the capsule could not be resolved.
+func makeMissingDependenciesCondition(status *apipipeline.ObjectStatusInfo, workload client.Object) {
+	missing, ok := workload.GetAnnotations()[annotationMissingDependencies]
+	if !ok {
+		return
+	}
+
+	status.Conditions = append(status.Conditions, &apipipeline.ObjectCondition{
+		Name:    "Dependencies",
+		State:   apipipeline.ObjectState_OBJECT_STATE_ERROR,
+		Message: fmt.Sprintf("Missing dependencies: %s", missing),
+	})
+}
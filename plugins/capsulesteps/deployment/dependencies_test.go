@@ -0,0 +1,184 @@
+package deployment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	mockclient "github.com/rigdev/rig/gen/mocks/sigs.k8s.io/controller-runtime/pkg/client"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/rigdev/rig/pkg/scheme"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func Test_setDependencies(t *testing.T) {
+	deps := []dependency{
+		{
+			CapsuleDependency: v1alpha2.CapsuleDependency{Capsule: "user-service", Interface: "grpc"},
+			host:              "user-service.prod.svc",
+			port:              9000,
+		},
+		{
+			CapsuleDependency: v1alpha2.CapsuleDependency{Capsule: "db", Interface: "postgres", WaitForReady: true},
+			host:              "db.data.svc",
+			port:              5432,
+		},
+	}
+
+	d := &appsv1.Deployment{}
+	d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "api"}, {Name: "worker"}}
+	d.Spec.Template.Spec.InitContainers = []corev1.Container{
+		{Name: "proxy", RestartPolicy: ptr.New(corev1.ContainerRestartPolicyAlways)},
+	}
+
+	setDependencies(d, deps, nil, Config{})
+
+	env := []corev1.EnvVar{
+		{Name: "USER_SERVICE_GRPC_HOST", Value: "user-service.prod.svc"},
+		{Name: "USER_SERVICE_GRPC_PORT", Value: "9000"},
+		{Name: "DB_POSTGRES_HOST", Value: "db.data.svc"},
+		{Name: "DB_POSTGRES_PORT", Value: "5432"},
+	}
+	assert.Equal(t, env, d.Spec.Template.Spec.Containers[0].Env)
+	assert.Equal(t, env, d.Spec.Template.Spec.Containers[1].Env)
+
+	inits := d.Spec.Template.Spec.InitContainers
+	assert.Len(t, inits, 2)
+	assert.Equal(t, "proxy", inits[0].Name)
+	assert.Equal(t, env, inits[0].Env)
+	assert.Equal(t, waitForDependenciesContainer, inits[1].Name)
+	assert.Equal(t, defaultDependencyWaitImage, inits[1].Image)
+	assert.Equal(t, []string{"db.data.svc:5432"}, inits[1].Args)
+	assert.Empty(t, inits[1].Env)
+
+	assert.Nil(t, createWaitForDependencies(deps[:1], ""))
+	assert.Equal(t, "registry.local/busybox", createWaitForDependencies(deps, "registry.local/busybox").Image)
+}
+
+func Test_makeDependenciesCondition(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  *corev1.ContainerStatus
+		state   apipipeline.ObjectState
+		message string
+	}{
+		{
+			name:    "not started",
+			state:   apipipeline.ObjectState_OBJECT_STATE_PENDING,
+			message: "Waiting on dependencies db.data.svc:5432",
+		},
+		{
+			name: "waiting",
+			status: &corev1.ContainerStatus{
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			},
+			state:   apipipeline.ObjectState_OBJECT_STATE_PENDING,
+			message: "Waiting on dependencies db.data.svc:5432",
+		},
+		{
+			name: "ready",
+			status: &corev1.ContainerStatus{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}},
+			},
+			state:   apipipeline.ObjectState_OBJECT_STATE_HEALTHY,
+			message: "Dependencies are ready",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{}
+			pod.Spec.InitContainers = []corev1.Container{{
+				Name: waitForDependenciesContainer,
+				Args: []string{"db.data.svc:5432"},
+			}}
+			if tt.status != nil {
+				tt.status.Name = waitForDependenciesContainer
+				pod.Status.InitContainerStatuses = []corev1.ContainerStatus{*tt.status}
+			}
+
+			status := &apipipeline.ObjectStatusInfo{}
+			makeDependenciesCondition(status, pod)
+			assert.Len(t, status.Conditions, 1)
+			assert.Equal(t, tt.state, status.Conditions[0].State)
+			assert.Equal(t, tt.message, status.Conditions[0].Message)
+		})
+	}
+
+	status := &apipipeline.ObjectStatusInfo{}
+	makeDependenciesCondition(status, &corev1.Pod{})
+	assert.Empty(t, status.Conditions)
+}
+
+func Test_getDependencies(t *testing.T) {
+	capsule := &v1alpha2.Capsule{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"},
+		Spec: v1alpha2.CapsuleSpec{
+			Image: "nginx",
+			Dependencies: []v1alpha2.CapsuleDependency{
+				{Capsule: "db", Interface: "postgres", WaitForReady: true},
+				{Capsule: "db", Interface: "admin"},
+				{Capsule: "cache", Interface: "redis", WaitForReady: true},
+			},
+		},
+	}
+	db := &v1alpha2.Capsule{
+		Spec: v1alpha2.CapsuleSpec{
+			Interfaces: []v1alpha2.CapsuleInterface{{Name: "postgres", Port: 5432}},
+		},
+	}
+
+	mockClient := mockclient.NewMockClient(t)
+	mockClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(
+		func(_ context.Context, key types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+			if c, ok := obj.(*v1alpha2.Capsule); ok && key == (types.NamespacedName{Namespace: "prod", Name: "db"}) {
+				db.DeepCopyInto(c)
+				return nil
+			}
+			return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+		},
+	)
+
+	s := scheme.New()
+	p := pipeline.NewCapsulePipeline(nil, s, scheme.NewVersionMapperFromScheme(s), logr.Discard())
+	req := pipeline.NewCapsuleRequest(p, capsule, mockClient, pipeline.WithDryRun())
+
+	deps, missing, err := (&Plugin{}).getDependencies(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, deps, 1)
+	assert.Equal(t, "db.prod.svc", deps[0].host)
+	assert.Equal(t, int32(5432), deps[0].port)
+	assert.Equal(t, []string{
+		"capsule 'db' has no interface 'admin'",
+		"capsule 'cache' does not exist in namespace 'prod'",
+	}, missing)
+
+	d := &appsv1.Deployment{}
+	d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "api"}}
+	setDependencies(d, deps, missing, Config{})
+	assert.Equal(t,
+		"capsule 'db' has no interface 'admin'; capsule 'cache' does not exist in namespace 'prod'",
+		d.Annotations[annotationMissingDependencies],
+	)
+
+	status := &apipipeline.ObjectStatusInfo{}
+	makeMissingDependenciesCondition(status, d)
+	require.Len(t, status.Conditions, 1)
+	assert.Equal(t, apipipeline.ObjectState_OBJECT_STATE_ERROR, status.Conditions[0].State)
+	assert.Equal(t,
+		"Missing dependencies: capsule 'db' has no interface 'admin'; capsule 'cache' does not exist in namespace 'prod'",
+		status.Conditions[0].Message,
+	)
+}
@@ -0,0 +1,135 @@
+package deployment
+
+import (
+	"fmt"
+	"slices"
+
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/controller/plugin"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"golang.org/x/exp/maps"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ExternalSecretGVK is the kind of the objects of the External Secrets Operator,
+// which sync secrets from external secret stores into Secrets.
+var ExternalSecretGVK = schema.GroupVersionKind{
+	Group:   "external-secrets.io",
+	Version: "v1beta1",
+	Kind:    "ExternalSecret",
+}
+
+// setExternalSecrets creates an ExternalSecret for each Secret the capsule
+// references from an external secret store. The ExternalSecret has the name
+// of the Secret it syncs into.
+func (p *Plugin) setExternalSecrets(req pipeline.CapsuleRequest) error {
+	secrets := req.Capsule().ExternalSecrets()
+	names := maps.Keys(secrets)
+	slices.Sort(names)
+	for _, name := range names {
+		es := createExternalSecret(req.Capsule().Namespace, name, secrets[name])
+		if err := req.Set(es); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func createExternalSecret(
+	namespace string,
+	name string,
+	ref *v1alpha2.ExternalSecretReference,
+) *unstructured.Unstructured {
+	es := &unstructured.Unstructured{
+		Object: map[string]any{
+			"spec": map[string]any{
+				"refreshInterval": ref.RefreshInterval().String(),
+				"secretStoreRef": map[string]any{
+					"name": ref.Store,
+					"kind": ref.GetStoreKind(),
+				},
+				"target": map[string]any{
+					"name":           name,
+					"creationPolicy": "Owner",
+				},
+				"dataFrom": []any{
+					map[string]any{
+						"extract": map[string]any{
+							"key": ref.Path,
+						},
+					},
+				},
+			},
+		},
+	}
+	es.SetGroupVersionKind(ExternalSecretGVK)
+	es.SetName(name)
+	es.SetNamespace(namespace)
+	return es
+}
+
+func onExternalSecretUpdated(
+	obj client.Object,
+	_ []*corev1.Event,
+	_ plugin.ObjectWatcher,
+) *apipipeline.ObjectStatusInfo {
+	es := obj.(*unstructured.Unstructured)
+
+	status := &apipipeline.ObjectStatusInfo{
+		Properties: map[string]string{},
+	}
+	dataFrom, _, _ := unstructured.NestedSlice(es.Object, "spec", "dataFrom")
+	for _, d := range dataFrom {
+		if d, ok := d.(map[string]any); ok {
+			if key, ok, _ := unstructured.NestedString(d, "extract", "key"); ok {
+				status.Properties["Path"] = key
+			}
+		}
+	}
+	if store, ok, _ := unstructured.NestedString(es.Object, "spec", "secretStoreRef", "name"); ok {
+		status.Properties["Store"] = store
+	}
+	if refreshed, ok, _ := unstructured.NestedString(es.Object, "status", "refreshTime"); ok && refreshed != "" {
+		status.Properties["Last synced"] = refreshed
+	}
+
+	cond := &apipipeline.ObjectCondition{
+		Name:      "Synced",
+		UpdatedAt: timestamppb.Now(),
+		State:     apipipeline.ObjectState_OBJECT_STATE_PENDING,
+		Message:   "Waiting for the secret to be synced from the secret store",
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(es.Object, "status", "conditions")
+	for _, c := range conditions {
+		c, ok := c.(map[string]any)
+		if !ok || c["type"] != "Ready" {
+			continue
+		}
+
+		message, _ := c["message"].(string)
+		switch c["status"] {
+		case string(corev1.ConditionTrue):
+			cond.State = apipipeline.ObjectState_OBJECT_STATE_HEALTHY
+			cond.Message = "Secret is synced from the secret store"
+		case string(corev1.ConditionFalse):
+			cond.State = apipipeline.ObjectState_OBJECT_STATE_ERROR
+			cond.Message = fmt.Sprintf("Secret could not be synced from the secret store: %s", message)
+		}
+	}
+	status.Conditions = append(status.Conditions, cond)
+
+	return status
+}
+
+func newExternalSecret() client.Object {
+	es := &unstructured.Unstructured{}
+	es.SetGroupVersionKind(ExternalSecretGVK)
+	return es
+}
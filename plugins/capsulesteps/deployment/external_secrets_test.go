@@ -0,0 +1,60 @@
+package deployment
+
+import (
+	"testing"
+
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_onExternalSecretUpdated(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []any
+		state      apipipeline.ObjectState
+		message    string
+	}{
+		{
+			name:    "not yet synced",
+			state:   apipipeline.ObjectState_OBJECT_STATE_PENDING,
+			message: "Waiting for the secret to be synced from the secret store",
+		},
+		{
+			name: "synced",
+			conditions: []any{
+				map[string]any{"type": "Ready", "status": "True", "reason": "SecretSynced"},
+			},
+			state:   apipipeline.ObjectState_OBJECT_STATE_HEALTHY,
+			message: "Secret is synced from the secret store",
+		},
+		{
+			name: "sync failed",
+			conditions: []any{
+				map[string]any{"type": "Ready", "status": "False", "message": "could not get secret data from provider"},
+			},
+			state:   apipipeline.ObjectState_OBJECT_STATE_ERROR,
+			message: "Secret could not be synced from the secret store: could not get secret data from provider",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := createExternalSecret("default", "db", &v1alpha2.ExternalSecretReference{
+				Store: "vault",
+				Path:  "app/db",
+			})
+			if tt.conditions != nil {
+				assert.NoError(t, unstructured.SetNestedSlice(es.Object, tt.conditions, "status", "conditions"))
+			}
+
+			status := onExternalSecretUpdated(es, nil, nil)
+			assert.Equal(t, "vault", status.Properties["Store"])
+			assert.Equal(t, "app/db", status.Properties["Path"])
+			assert.Len(t, status.Conditions, 1)
+			assert.Equal(t, tt.state, status.Conditions[0].State)
+			assert.Equal(t, tt.message, status.Conditions[0].Message)
+		})
+	}
+}
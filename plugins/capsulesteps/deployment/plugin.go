@@ -11,6 +11,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/rigdev/rig/pkg/api/v1alpha2"
@@ -24,6 +25,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -42,7 +44,18 @@ const (
 
 // Configuration for the deployment plugin
 // +kubebuilder:object:root=true
-type Config struct{}
+type Config struct {
+	// DependencyWaitImage is the image of the init container which waits for
+	// the dependencies of a capsule to be ready. The image must have `sh` and
+	// `nc`. Defaults to `busybox:1.36`.
+	DependencyWaitImage string `json:"dependencyWaitImage,omitempty"`
+
+	// DefaultAvailability enables defaults for the availability of capsules
+	// which don't set it. Capsules scheduled to run at least two instances
+	// then get a PodDisruptionBudget allowing one instance to be unavailable
+	// at a time, and prefer to spread their instances across zones and nodes.
+	DefaultAvailability bool `json:"defaultAvailability,omitempty"`
+}
 
 type Plugin struct {
 	configBytes []byte
@@ -58,17 +71,19 @@ func (p *Plugin) ComputeConfig(ctx context.Context, req pipeline.CapsuleRequest,
 }
 
 func (p *Plugin) Run(ctx context.Context, req pipeline.CapsuleRequest, logger hclog.Logger) error {
-	// We do not have any configuration for this step?
-
-	// var config Config
+	var config Config
 	var err error
 	if len(p.configBytes) > 0 {
-		_, err = plugin.ParseTemplatedConfig[Config](p.configBytes, req, plugin.CapsuleStep[Config])
+		config, err = plugin.ParseTemplatedConfig[Config](p.configBytes, req, plugin.CapsuleStep[Config])
 		if err != nil {
 			return err
 		}
 	}
 
+	if err := p.setExternalSecrets(req); err != nil {
+		return err
+	}
+
 	cfgs, err := p.getConfigs(ctx, req)
 	if err != nil {
 		return err
@@ -79,19 +94,23 @@ func (p *Plugin) Run(ctx context.Context, req pipeline.CapsuleRequest, logger hc
 		return err
 	}
 
-	current := &appsv1.Deployment{}
-	if err := req.GetExistingInto(current); errors.IsNotFound(err) {
-		current = nil
-	} else if err != nil {
+	current, err := p.getCurrent(req)
+	if err != nil {
 		return err
 	}
 
-	deployment, err := p.createDeployment(current, req, cfgs, checksums)
+	deps, missingDeps, err := p.getDependencies(ctx, req)
 	if err != nil {
 		return err
 	}
 
-	if len(req.Capsule().Spec.Interfaces) > 0 {
+	deployment, err := p.createDeployment(current, req, config, cfgs, checksums)
+	if err != nil {
+		return err
+	}
+	setDependencies(deployment, deps, missingDeps, config)
+
+	if len(req.Capsule().AllInterfaces()) > 0 {
 		if err := p.handleInterfaces(req, deployment); err != nil {
 			return err
 		}
@@ -101,10 +120,29 @@ func (p *Plugin) Run(ctx context.Context, req pipeline.CapsuleRequest, logger hc
 		}
 	}
 
-	if err := req.Set(deployment); err != nil {
+	if pipeline.IsStateful(req.Capsule()) {
+		if err := req.Set(p.createHeadlessService(req)); err != nil {
+			return err
+		}
+		if err := req.Set(p.createStatefulSet(req, deployment)); err != nil {
+			return err
+		}
+	} else if err := req.Set(deployment); err != nil {
+		return err
+	}
+
+	scale, err := p.getScale(req)
+	if err != nil {
 		return err
 	}
 
+	availability := getAvailability(req.Capsule(), scale.instances.Min, config.DefaultAvailability)
+	if pdb := p.createPodDisruptionBudget(current, req, availability); pdb != nil {
+		if err := req.Set(pdb); err != nil {
+			return err
+		}
+	}
+
 	if ok, err := p.shouldCreateHPA(req); err != nil {
 		return err
 	} else if ok {
@@ -121,9 +159,42 @@ func (p *Plugin) Run(ctx context.Context, req pipeline.CapsuleRequest, logger hc
 	return nil
 }
 
+// getCurrent returns the existing Deployment of the capsule. For stateful
+// capsules, the annotations, replicas and selector of the existing StatefulSet are
+// returned in a Deployment, as these are the only fields read from the current object.
+func (p *Plugin) getCurrent(req pipeline.CapsuleRequest) (*appsv1.Deployment, error) {
+	if !pipeline.IsStateful(req.Capsule()) {
+		current := &appsv1.Deployment{}
+		if err := req.GetExistingInto(current); errors.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return current, nil
+	}
+
+	sts := &appsv1.StatefulSet{}
+	if err := req.GetExistingInto(sts); errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: sts.Annotations,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: sts.Spec.Replicas,
+			Selector: sts.Spec.Selector,
+		},
+	}, nil
+}
+
 func (p *Plugin) createDeployment(
 	current *appsv1.Deployment,
 	req pipeline.CapsuleRequest,
+	config Config,
 	cfgs *configs,
 	checksums checksums,
 ) (*appsv1.Deployment, error) {
@@ -206,6 +277,7 @@ func (p *Plugin) createDeployment(
 	// to it.
 	// Note: Not all system may have the sleep command. In that case, a Kubernetes event may be raised
 	// but the overall result is a no-op.
+	// A preStop hook given in the lifecycle of the Capsule replaces the sleep.
 	var lc *v1.Lifecycle
 	if len(req.Capsule().Spec.Interfaces) > 0 {
 		lc = &v1.Lifecycle{
@@ -216,6 +288,12 @@ func (p *Plugin) createDeployment(
 			},
 		}
 	}
+	capsuleLifecycle := req.Capsule().Spec.Lifecycle
+	if capsuleLifecycle != nil && capsuleLifecycle.PreStop != nil {
+		lc = &v1.Lifecycle{
+			PreStop: createLifecycleHandler(capsuleLifecycle.PreStop),
+		}
+	}
 
 	c := v1.Container{
 		Name:    req.Capsule().Name,
@@ -232,19 +310,29 @@ func (p *Plugin) createDeployment(
 		Args:         req.Capsule().Spec.Args,
 		Lifecycle:    lc,
 	}
+	if capsuleLifecycle != nil && capsuleLifecycle.StartupProbe != nil {
+		c.StartupProbe = createStartupProbe(capsuleLifecycle.StartupProbe)
+	}
 
 	if req.Capsule().Spec.Command != "" {
 		c.Command = []string{req.Capsule().Spec.Command}
 	}
 
-	replicas := ptr.New(int32(req.Capsule().Spec.Scale.Horizontal.Instances.Min))
+	containers, containerVolumes := createContainers(req)
+	volumes = append(volumes, containerVolumes...)
+
+	scale, err := p.getScale(req)
+	if err != nil {
+		return nil, err
+	}
+	ins := scale.instances
+	replicas := ptr.New(int32(ins.Min))
 	hasHPA, err := p.shouldCreateHPA(req)
 	if err != nil {
 		return nil, err
 	}
 	if hasHPA && current != nil && current.Spec.Replicas != nil {
 		cur := uint32(*current.Spec.Replicas)
-		ins := req.Capsule().Spec.Scale.Horizontal.Instances
 		if cur < ins.Min {
 			cur = ins.Min
 		} else if ins.Max != nil && cur > *ins.Max {
@@ -264,13 +352,12 @@ func (p *Plugin) createDeployment(
 			APIVersion: "apps/v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      req.Capsule().Name,
-			Namespace: req.Capsule().Namespace,
+			Name:        req.Capsule().Name,
+			Namespace:   req.Capsule().Namespace,
+			Annotations: map[string]string{},
 		},
 		Spec: appsv1.DeploymentSpec{
-			Selector: &metav1.LabelSelector{
-				MatchLabels: p.getPodsSelector(current, req),
-			},
+			Selector: p.getSelector(current, req),
 			Replicas: replicas,
 			Strategy: appsv1.DeploymentStrategy{
 				Type: strategy,
@@ -281,14 +368,37 @@ func (p *Plugin) createDeployment(
 					Labels:      p.getPodLabels(current, req),
 				},
 				Spec: v1.PodSpec{
-					Containers:   []v1.Container{c},
-					Volumes:      volumes,
-					NodeSelector: req.Capsule().Spec.NodeSelector,
+					InitContainers: createSidecars(req),
+					Containers:     append([]v1.Container{c}, containers...),
+					Volumes:        volumes,
+					NodeSelector:   req.Capsule().Spec.NodeSelector,
+					TopologySpreadConstraints: createTopologySpreadConstraints(
+						getAvailability(req.Capsule(), scale.instances.Min, config.DefaultAvailability),
+						p.getPodsSelector(current, req),
+					),
 				},
 			},
 		},
 	}
 
+	if capsuleLifecycle != nil && capsuleLifecycle.TerminationGracePeriodSeconds != nil {
+		d.Spec.Template.Spec.TerminationGracePeriodSeconds = ptr.New(
+			int64(*capsuleLifecycle.TerminationGracePeriodSeconds),
+		)
+	}
+
+	// Reconcile again when a scale schedule changes the instances, or the
+	// capsule goes idle.
+	if !scale.next.IsZero() {
+		d.Annotations[pipeline.AnnotationReconcileAfter] = scale.next.Format(time.RFC3339)
+	}
+	if !scale.awakeSince.IsZero() {
+		d.Annotations[pipeline.AnnotationAwakeSince] = scale.awakeSince.Format(time.RFC3339)
+	}
+	if req.Capsule().Spec.Scale.Horizontal.ScaleToZero != nil {
+		d.Annotations[pipeline.AnnotationRoutedThroughActivator] = strconv.FormatBool(scale.routedThroughActivator)
+	}
+
 	if cfgs.imagePullSecret != "" {
 		d.Spec.Template.Spec.ImagePullSecrets = []v1.LocalObjectReference{{
 			Name: cfgs.imagePullSecret,
@@ -298,21 +408,131 @@ func (p *Plugin) createDeployment(
 	return d, nil
 }
 
+// createStatefulSet creates a StatefulSet running the same instances as the given
+// Deployment, where each instance claims its own set of the capsule's persistent volumes.
+func (p *Plugin) createStatefulSet(req pipeline.CapsuleRequest, d *appsv1.Deployment) *appsv1.StatefulSet {
+	sts := &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "StatefulSet",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: d.ObjectMeta,
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    d.Spec.Replicas,
+			Selector:    d.Spec.Selector,
+			Template:    d.Spec.Template,
+			ServiceName: headlessServiceName(req.Capsule().Name),
+		},
+	}
+
+	var mounts []v1.VolumeMount
+	for _, pv := range req.Capsule().Spec.Storage.Volumes {
+		pvc := v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: pv.Name,
+				Labels: map[string]string{
+					pipeline.LabelCapsule: req.Capsule().Name,
+				},
+			},
+			Spec: v1.PersistentVolumeClaimSpec{
+				AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+				Resources: v1.VolumeResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceStorage: pv.Size,
+					},
+				},
+			},
+		}
+		if pv.StorageClassName != "" {
+			pvc.Spec.StorageClassName = ptr.New(pv.StorageClassName)
+		}
+		sts.Spec.VolumeClaimTemplates = append(sts.Spec.VolumeClaimTemplates, pvc)
+
+		mounts = append(mounts, v1.VolumeMount{
+			Name:      pv.Name,
+			MountPath: pv.Path,
+		})
+	}
+
+	for i, c := range sts.Spec.Template.Spec.Containers {
+		if c.Name != req.Capsule().Name {
+			continue
+		}
+		c.VolumeMounts = append(c.VolumeMounts, mounts...)
+		sts.Spec.Template.Spec.Containers[i] = c
+	}
+
+	return sts
+}
+
+// createSidecars creates a container for each of the sidecars of the capsule.
+// They are added as init containers with restart policy Always, which makes
+// Kubernetes start them before, and keep them running alongside, the main container.
+func createSidecars(req pipeline.CapsuleRequest) []v1.Container {
+	var containers []v1.Container
+	for _, sc := range req.Capsule().Spec.Sidecars {
+		c := v1.Container{
+			Name:  sc.Name,
+			Image: sc.Image,
+			Env: []v1.EnvVar{
+				{
+					Name:  "RIG_CAPSULE_NAME",
+					Value: req.Capsule().Name,
+				},
+			},
+			EnvFrom:       pipeline.EnvSources(sc.Env.From),
+			Args:          sc.Args,
+			Resources:     makeSidecarResourceRequirements(sc.Resources),
+			RestartPolicy: ptr.New(v1.ContainerRestartPolicyAlways),
+		}
+
+		if sc.Command != "" {
+			c.Command = []string{sc.Command}
+		}
+
+		keys := maps.Keys(sc.Env.Raw)
+		slices.Sort(keys)
+		for _, k := range keys {
+			c.Env = append(c.Env, v1.EnvVar{
+				Name:  k,
+				Value: sc.Env.Raw[k],
+			})
+		}
+
+		if sc.Liveness != nil {
+			c.LivenessProbe = createProbe(sc.Liveness, sc.Liveness.Port)
+		}
+		if sc.Readiness != nil {
+			c.ReadinessProbe = createProbe(sc.Readiness, sc.Readiness.Port)
+		}
+
+		containers = append(containers, c)
+	}
+
+	return containers
+}
+
 func (p *Plugin) handleInterfaces(req pipeline.CapsuleRequest, deployment *appsv1.Deployment) error {
+	interfaces := containerInterfaces(req.Capsule())
 	for i, container := range deployment.Spec.Template.Spec.Containers {
-		if container.Name != req.Capsule().Name {
+		infs, ok := interfaces[container.Name]
+		if !ok || len(infs) == 0 {
 			continue
 		}
 
 		var ports []v1.ContainerPort
-		for _, ni := range req.Capsule().Spec.Interfaces {
+		for _, ni := range infs {
 			ports = append(ports, v1.ContainerPort{
 				Name:          ni.Name,
 				ContainerPort: ni.Port,
 			})
 
 			if ni.Liveness != nil {
-				container.LivenessProbe, container.StartupProbe = createLivenessProbe(ni.Liveness, ni.Port)
+				liveness, startup := createLivenessProbe(ni.Liveness, ni.Port)
+				container.LivenessProbe = liveness
+				if startup != nil {
+					container.StartupProbe = startup
+				}
 			}
 
 			if ni.Readiness != nil {
@@ -339,6 +559,41 @@ func createLivenessProbe(probe *v1alpha2.InterfaceLivenessProbe, port int32) (*v
 	return liveness, startup
 }
 
+func createStartupProbe(probe *v1alpha2.StartupProbe) *v1.Probe {
+	startup := createProbe(probe, probe.Port)
+	if startup == nil {
+		return nil
+	}
+
+	period := probe.PeriodSeconds
+	if period == 0 {
+		period = 10
+	}
+	maxStartup := probe.MaxStartupSeconds
+	if maxStartup == 0 {
+		maxStartup = 300
+	}
+	startup.PeriodSeconds = int32(period)
+	startup.FailureThreshold = int32(math.Ceil(float64(maxStartup) / float64(period)))
+	return startup
+}
+
+func createLifecycleHandler(hook *v1alpha2.LifecycleHook) *v1.LifecycleHandler {
+	if hook.HTTP != nil {
+		return &v1.LifecycleHandler{
+			HTTPGet: &v1.HTTPGetAction{
+				Path: hook.HTTP.Path,
+				Port: intstr.FromInt32(hook.HTTP.Port),
+			},
+		}
+	}
+	return &v1.LifecycleHandler{
+		Exec: &v1.ExecAction{
+			Command: hook.Command,
+		},
+	}
+}
+
 type interfaceProbe interface {
 	GetPath() string
 	GetTCP() bool
@@ -394,7 +649,7 @@ func (p *Plugin) createService(req pipeline.CapsuleRequest) *v1.Service {
 		},
 	}
 
-	for _, inf := range req.Capsule().Spec.Interfaces {
+	for _, inf := range req.Capsule().AllInterfaces() {
 		svc.Spec.Ports = append(svc.Spec.Ports, v1.ServicePort{
 			Name:       inf.Name,
 			Port:       inf.Port,
@@ -405,6 +660,32 @@ func (p *Plugin) createService(req pipeline.CapsuleRequest) *v1.Service {
 	return svc
 }
 
+func headlessServiceName(capsule string) string {
+	return capsule + "-headless"
+}
+
+// createHeadlessService creates the headless Service governing the StatefulSet
+// of a stateful capsule, which gives each instance a stable DNS name of the form
+// `<instance>.<capsule>-headless`. The Service has no ports, as the interfaces
+// of the capsule are served by its regular Service.
+func (p *Plugin) createHeadlessService(req pipeline.CapsuleRequest) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      headlessServiceName(req.Capsule().Name),
+			Namespace: req.Capsule().Namespace,
+			Labels: map[string]string{
+				pipeline.LabelCapsule: req.Capsule().Name,
+			},
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: v1.ClusterIPNone,
+			Selector: map[string]string{
+				pipeline.LabelCapsule: req.Capsule().Name,
+			},
+		},
+	}
+}
+
 func (p *Plugin) getPodLabels(current *appsv1.Deployment, req pipeline.CapsuleRequest) map[string]string {
 	labels := map[string]string{}
 	maps.Copy(labels, p.getPodsSelector(current, req))
@@ -412,20 +693,27 @@ func (p *Plugin) getPodLabels(current *appsv1.Deployment, req pipeline.CapsuleRe
 	return labels
 }
 
-func (p *Plugin) getPodsSelector(current *appsv1.Deployment, req pipeline.CapsuleRequest) map[string]string {
+// getSelector returns the selector of the current workload, as selectors are
+// immutable. This includes expressions added by other steps, fx. the rollout
+// step excluding the pods of a canary.
+func (p *Plugin) getSelector(current *appsv1.Deployment, req pipeline.CapsuleRequest) *metav1.LabelSelector {
 	if current != nil {
-		if s := current.Spec.Selector; s != nil {
-			if len(s.MatchLabels) > 0 && len(s.MatchExpressions) == 0 {
-				return s.MatchLabels
-			}
+		if s := current.Spec.Selector; s != nil && len(s.MatchLabels) > 0 {
+			return s.DeepCopy()
 		}
 	}
 
-	return map[string]string{
-		pipeline.LabelCapsule: req.Capsule().Name,
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			pipeline.LabelCapsule: req.Capsule().Name,
+		},
 	}
 }
 
+func (p *Plugin) getPodsSelector(current *appsv1.Deployment, req pipeline.CapsuleRequest) map[string]string {
+	return p.getSelector(current, req).MatchLabels
+}
+
 func (p *Plugin) getConfigChecksums(req pipeline.CapsuleRequest, cfgs configs) (checksums, error) {
 	sharedEnv, err := configSharedEnvChecksum(cfgs)
 	if err != nil {
@@ -509,19 +797,31 @@ func configAutoEnvChecksum(
 }
 
 func configEnvChecksum(req pipeline.CapsuleRequest, cfgs configs) (string, error) {
-	if len(req.Capsule().Spec.Env.From) == 0 {
+	refs := slices.Clone(req.Capsule().Spec.Env.From)
+	for _, sc := range req.Capsule().Spec.Sidecars {
+		refs = append(refs, sc.Env.From...)
+	}
+	for _, c := range req.Capsule().Spec.Containers {
+		refs = append(refs, c.Env.From...)
+	}
+	if len(refs) == 0 {
 		return "", nil
 	}
 
 	h := sha256.New()
-	for _, e := range req.Capsule().Spec.Env.From {
+	for _, e := range refs {
 		switch e.Kind {
 		case "ConfigMap":
 			if err := hash.ConfigMap(h, cfgs.configMaps[e.Name]); err != nil {
 				return "", err
 			}
 		case "Secret":
-			if err := hash.Secret(h, cfgs.secrets[e.Name]); err != nil {
+			s, ok := cfgs.secrets[e.Name]
+			if !ok {
+				// An external secret which is not yet synced.
+				continue
+			}
+			if err := hash.Secret(h, s); err != nil {
 				return "", err
 			}
 		}
@@ -531,13 +831,17 @@ func configEnvChecksum(req pipeline.CapsuleRequest, cfgs configs) (string, error
 }
 
 func configFilesChecksum(req pipeline.CapsuleRequest, cfgs configs) (string, error) {
-	if len(req.Capsule().Spec.Files) == 0 {
+	files := slices.Clone(req.Capsule().Spec.Files)
+	for _, c := range req.Capsule().Spec.Containers {
+		files = append(files, c.Files...)
+	}
+	if len(files) == 0 {
 		return "", nil
 	}
 
 	referencedKeysBySecretName := map[string]map[string]struct{}{}
 	referencedKeysByConfigMapName := map[string]map[string]struct{}{}
-	for _, f := range req.Capsule().Spec.Files {
+	for _, f := range files {
 		switch f.Ref.Kind {
 		case "ConfigMap":
 			if _, ok := referencedKeysByConfigMapName[f.Ref.Name]; ok {
@@ -564,6 +868,10 @@ func configFilesChecksum(req pipeline.CapsuleRequest, cfgs configs) (string, err
 	slices.Sort(configMapNames)
 	h := sha256.New()
 	for _, name := range secretNames {
+		if _, ok := cfgs.secrets[name]; !ok {
+			// An external secret which is not yet synced.
+			continue
+		}
 		if err := hash.SecretKeys(
 			h,
 			maps.Keys(referencedKeysBySecretName[name]),
@@ -634,20 +942,44 @@ func (p *Plugin) getConfigs(ctx context.Context, req pipeline.CapsuleRequest) (*
 		}
 	}
 
-	// Get envs
+	// Get envs. Secrets synced from an external secret store are not required,
+	// as they don't exist until the first sync.
 	for _, e := range env.From {
-		if err := p.setUsedSource(ctx, req, configs, e.Kind, e.Name, true); err != nil {
+		if err := p.setUsedSource(ctx, req, configs, e.Kind, e.Name, e.External == nil); err != nil {
 			return nil, err
 		}
 	}
 
+	// Get sidecar envs
+	for _, sc := range req.Capsule().Spec.Sidecars {
+		for _, e := range sc.Env.From {
+			if err := p.setUsedSource(ctx, req, configs, e.Kind, e.Name, e.External == nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Get files
 	for _, f := range req.Capsule().Spec.Files {
-		if err := p.setUsedSource(ctx, req, configs, f.Ref.Kind, f.Ref.Name, true); err != nil {
+		if err := p.setUsedSource(ctx, req, configs, f.Ref.Kind, f.Ref.Name, f.Ref.External == nil); err != nil {
 			return nil, err
 		}
 	}
 
+	// Get container envs and files
+	for _, c := range req.Capsule().Spec.Containers {
+		for _, e := range c.Env.From {
+			if err := p.setUsedSource(ctx, req, configs, e.Kind, e.Name, e.External == nil); err != nil {
+				return nil, err
+			}
+		}
+		for _, f := range c.Files {
+			if err := p.setUsedSource(ctx, req, configs, f.Ref.Kind, f.Ref.Name, f.Ref.External == nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if secret := req.Capsule().Annotations[pipeline.AnnotationPullSecret]; secret != "" {
 		configs.imagePullSecret = secret
 		if err := p.setUsedSource(ctx, req, configs, "Secret", secret, true); err != nil {
@@ -717,6 +1049,101 @@ func (p *Plugin) setUsedSource(
 	return nil
 }
 
+// getAvailability returns the availability settings of the capsule. If
+// defaults are enabled, unset fields are defaulted from the minimum number of
+// instances the capsule is currently scheduled to run. Capsules running at
+// least two instances allow one instance to be unavailable at a time and
+// prefer to spread their instances across nodes and zones.
+func getAvailability(capsule *v1alpha2.Capsule, instances uint32, defaults bool) v1alpha2.Availability {
+	var a v1alpha2.Availability
+	if capsule.Spec.Availability != nil {
+		a = *capsule.Spec.Availability
+	}
+
+	highlyAvailable := defaults && instances >= 2
+	defaultSpread := v1alpha2.SpreadNone
+	if highlyAvailable {
+		defaultSpread = v1alpha2.SpreadPreferred
+	}
+	if a.ZoneSpread == "" {
+		a.ZoneSpread = defaultSpread
+	}
+	if a.NodeSpread == "" {
+		a.NodeSpread = defaultSpread
+	}
+	if a.MinAvailable == "" && a.MaxUnavailable == "" && highlyAvailable {
+		a.MaxUnavailable = "1"
+	}
+
+	return a
+}
+
+func createTopologySpreadConstraints(
+	a v1alpha2.Availability,
+	selector map[string]string,
+) []v1.TopologySpreadConstraint {
+	var constraints []v1.TopologySpreadConstraint
+	for _, s := range []struct {
+		key    string
+		policy v1alpha2.SpreadPolicy
+	}{
+		{key: v1.LabelTopologyZone, policy: a.ZoneSpread},
+		{key: v1.LabelHostname, policy: a.NodeSpread},
+	} {
+		var action v1.UnsatisfiableConstraintAction
+		switch s.policy {
+		case v1alpha2.SpreadPreferred:
+			action = v1.ScheduleAnyway
+		case v1alpha2.SpreadRequired:
+			action = v1.DoNotSchedule
+		default:
+			continue
+		}
+
+		constraints = append(constraints, v1.TopologySpreadConstraint{
+			MaxSkew:           1,
+			TopologyKey:       s.key,
+			WhenUnsatisfiable: action,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: selector,
+			},
+		})
+	}
+
+	return constraints
+}
+
+func (p *Plugin) createPodDisruptionBudget(
+	current *appsv1.Deployment,
+	req pipeline.CapsuleRequest,
+	a v1alpha2.Availability,
+) *policyv1.PodDisruptionBudget {
+	if a.MinAvailable == "" && a.MaxUnavailable == "" {
+		return nil
+	}
+
+	pdb := &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PodDisruptionBudget",
+			APIVersion: "policy/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      req.Capsule().Name,
+			Namespace: req.Capsule().Namespace,
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: p.getSelector(current, req),
+		},
+	}
+	if a.MinAvailable != "" {
+		pdb.Spec.MinAvailable = ptr.New(intstr.Parse(a.MinAvailable))
+	} else {
+		pdb.Spec.MaxUnavailable = ptr.New(intstr.Parse(a.MaxUnavailable))
+	}
+
+	return pdb
+}
+
 func (p *Plugin) shouldCreateHPA(req pipeline.CapsuleRequest) (bool, error) {
 	_, res, err := p.createHPA(req)
 	if err != nil {
@@ -737,7 +1164,7 @@ func (p *Plugin) createHPA(req pipeline.CapsuleRequest) (*autoscalingv2.Horizont
 		},
 		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
 			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
-				Kind:       "Deployment",
+				Kind:       workloadKind(req),
 				Name:       req.Capsule().Name,
 				APIVersion: appsv1.SchemeGroupVersion.String(),
 			},
@@ -745,14 +1172,19 @@ func (p *Plugin) createHPA(req pipeline.CapsuleRequest) (*autoscalingv2.Horizont
 	}
 
 	scale := req.Capsule().Spec.Scale.Horizontal
+	state, err := p.getScale(req)
+	if err != nil {
+		return nil, false, err
+	}
+	instances := state.instances
 
-	if scale.Instances.Min == 0 {
+	if instances.Min == 0 {
 		// Cannot have autoscaler going to 0.
 		// TODO We should have some good documentation/userfeedback if min-replicas is set to 0
 		return hpa, false, nil
 	}
 
-	if scale.Instances.Max == nil {
+	if instances.Max == nil {
 		return hpa, false, nil
 	}
 
@@ -830,12 +1262,128 @@ func (p *Plugin) createHPA(req pipeline.CapsuleRequest) (*autoscalingv2.Horizont
 		return hpa, false, nil
 	}
 
-	hpa.Spec.MinReplicas = ptr.New(int32(scale.Instances.Min))
-	hpa.Spec.MaxReplicas = int32(*scale.Instances.Max)
+	hpa.Spec.MinReplicas = ptr.New(int32(instances.Min))
+	hpa.Spec.MaxReplicas = int32(*instances.Max)
 
 	return hpa, true, nil
 }
 
+type scaleState struct {
+	instances v1alpha2.Instances
+	// next is when the instances can change next, or zero if they can't.
+	next time.Time
+	// awakeSince is when a capsule which scales to zero was woken up. It is
+	// zero if the capsule doesn't scale to zero or is asleep.
+	awakeSince time.Time
+	// routedThroughActivator is true if the routes of a capsule which scales
+	// to zero must be sent through the activator.
+	routedThroughActivator bool
+}
+
+// getScale returns the instances of the capsule at the current time, as
+// given by its scale schedules and whether it is asleep.
+func (p *Plugin) getScale(req pipeline.CapsuleRequest) (scaleState, error) {
+	now := req.Now()
+	horizontal := req.Capsule().Spec.Scale.Horizontal
+	ins, next, err := horizontal.InstancesAt(now)
+	if err != nil {
+		return scaleState{}, err
+	}
+
+	if horizontal.ScaleToZero == nil {
+		return scaleState{instances: ins, next: next}, nil
+	}
+
+	current, err := p.getCurrent(req)
+	if err != nil {
+		return scaleState{}, err
+	}
+
+	// A capsule is awake if its workload has been woken up, or is running
+	// instances without having been put to sleep, e.g. if it is new.
+	var awakeSince time.Time
+	if current == nil || current.Spec.Replicas == nil || *current.Spec.Replicas > 0 {
+		awakeSince = now
+	}
+	if current != nil {
+		if t, err := time.Parse(time.RFC3339, current.GetAnnotations()[pipeline.AnnotationAwakeSince]); err == nil {
+			awakeSince = t
+		}
+	}
+	lastRequest, _ := time.Parse(time.RFC3339, req.Capsule().GetAnnotations()[pipeline.AnnotationLastRequest])
+
+	awakeSince, standbyAt, sleepAt := wakeState(horizontal.ScaleToZero, awakeSince, lastRequest, now)
+	if awakeSince.IsZero() {
+		return scaleState{instances: v1alpha2.Instances{}, next: next, routedThroughActivator: true}, nil
+	}
+
+	ins.Min = max(ins.Min, 1)
+	if ins.Max != nil {
+		ins.Max = ptr.New(max(*ins.Max, ins.Min))
+	}
+
+	// The capsule changes state when it goes on standby, and when it goes to
+	// sleep.
+	change := sleepAt
+	if now.Before(standbyAt) {
+		change = standbyAt
+	}
+	if next.IsZero() || change.Before(next) {
+		next = change
+	}
+
+	return scaleState{
+		instances:              ins,
+		next:                   next,
+		awakeSince:             awakeSince,
+		routedThroughActivator: !now.Before(standbyAt),
+	}, nil
+}
+
+// wakeState returns when a capsule which scales to zero was woken up, when it
+// goes on standby and when it goes to sleep, given when it was last awoken and
+// its last request.
+//
+// Requests are sent directly to an awake capsule, so the activator only sees
+// them while the capsule is asleep or on standby. An awake capsule goes on
+// standby when its idle timeout has passed since it was woken up or last saw a
+// request, and its routes are then sent through the activator again. It goes
+// to sleep if it sees no request within another idle timeout. A sleeping
+// capsule is woken up by a request within its idle timeout. Zero times are
+// returned if the capsule is asleep.
+func wakeState(
+	stz *v1alpha2.ScaleToZero,
+	awakeSince, lastRequest, now time.Time,
+) (time.Time, time.Time, time.Time) {
+	idle := stz.IdleTimeout()
+	if awakeSince.IsZero() {
+		if lastRequest.IsZero() || !now.Before(lastRequest.Add(idle)) {
+			return time.Time{}, time.Time{}, time.Time{}
+		}
+		awakeSince = now
+	}
+
+	lastActivity := awakeSince
+	if lastRequest.After(lastActivity) {
+		lastActivity = lastRequest
+	}
+
+	standbyAt := lastActivity.Add(idle)
+	sleepAt := standbyAt.Add(idle)
+	if !now.Before(sleepAt) {
+		return time.Time{}, time.Time{}, time.Time{}
+	}
+
+	return awakeSince, standbyAt, sleepAt
+}
+
+func workloadKind(req pipeline.CapsuleRequest) string {
+	if pipeline.IsStateful(req.Capsule()) {
+		return "StatefulSet"
+	}
+	return "Deployment"
+}
+
 func makeResourceRequirements(capsule *v1alpha2.Capsule) v1.ResourceRequirements {
 	requests := utils.DefaultResources.Requests
 	res := v1.ResourceRequirements{
@@ -846,10 +1394,30 @@ func makeResourceRequirements(capsule *v1alpha2.Capsule) v1.ResourceRequirements
 		Limits: v1.ResourceList{},
 	}
 
-	if capsule.Spec.Scale.Vertical == nil {
-		return res
+	applyVerticalScale(&res, capsule.Spec.Scale.Vertical)
+	return res
+}
+
+// makeSidecarResourceRequirements is like makeResourceRequirements, except
+// that sidecars don't get the default requests of the capsule.
+func makeSidecarResourceRequirements(vertical *v1alpha2.VerticalScale) v1.ResourceRequirements {
+	if vertical == nil {
+		return v1.ResourceRequirements{}
+	}
+
+	res := v1.ResourceRequirements{
+		Requests: v1.ResourceList{},
+		Limits:   v1.ResourceList{},
 	}
-	if c := capsule.Spec.Scale.Vertical.CPU; c != nil {
+	applyVerticalScale(&res, vertical)
+	return res
+}
+
+func applyVerticalScale(res *v1.ResourceRequirements, vertical *v1alpha2.VerticalScale) {
+	if vertical == nil {
+		return
+	}
+	if c := vertical.CPU; c != nil {
 		if c.Request != nil && !c.Request.IsZero() {
 			res.Requests[v1.ResourceCPU] = *c.Request
 		}
@@ -857,7 +1425,7 @@ func makeResourceRequirements(capsule *v1alpha2.Capsule) v1.ResourceRequirements
 			res.Limits[v1.ResourceCPU] = *c.Limit
 		}
 	}
-	if m := capsule.Spec.Scale.Vertical.Memory; m != nil {
+	if m := vertical.Memory; m != nil {
 		if m.Request != nil && !m.Request.IsZero() {
 			res.Requests[v1.ResourceMemory] = *m.Request
 		}
@@ -865,11 +1433,9 @@ func makeResourceRequirements(capsule *v1alpha2.Capsule) v1.ResourceRequirements
 			res.Limits[v1.ResourceMemory] = *m.Limit
 		}
 	}
-	if g := capsule.Spec.Scale.Vertical.GPU; g != nil && !g.Request.IsZero() {
+	if g := vertical.GPU; g != nil && !g.Request.IsZero() {
 		res.Requests["nvidia.com/gpu"] = g.Request
 	}
-
-	return res
 }
 
 type configs struct {
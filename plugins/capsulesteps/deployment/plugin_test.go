@@ -0,0 +1,352 @@
+package deployment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/controller/plugin/plugintest"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func Test_wakeState(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	stz := &v1alpha2.ScaleToZero{IdleSeconds: 600}
+
+	tests := []struct {
+		name         string
+		awakeSince   time.Time
+		lastRequest  time.Time
+		expAwake     time.Time
+		expStandbyAt time.Time
+		expSleepAt   time.Time
+	}{
+		{
+			name: "asleep without requests",
+		},
+		{
+			name:        "asleep with old request",
+			lastRequest: now.Add(-time.Hour),
+		},
+		{
+			name:         "woken up by request",
+			lastRequest:  now.Add(-time.Second),
+			expAwake:     now,
+			expStandbyAt: now.Add(10 * time.Minute),
+			expSleepAt:   now.Add(20 * time.Minute),
+		},
+		{
+			name:         "awake without requests",
+			awakeSince:   now.Add(-time.Minute),
+			expAwake:     now.Add(-time.Minute),
+			expStandbyAt: now.Add(9 * time.Minute),
+			expSleepAt:   now.Add(19 * time.Minute),
+		},
+		{
+			name:         "kept awake by requests",
+			awakeSince:   now.Add(-time.Hour),
+			lastRequest:  now.Add(-5 * time.Minute),
+			expAwake:     now.Add(-time.Hour),
+			expStandbyAt: now.Add(5 * time.Minute),
+			expSleepAt:   now.Add(15 * time.Minute),
+		},
+		{
+			name:         "on standby",
+			awakeSince:   now.Add(-time.Hour),
+			lastRequest:  now.Add(-15 * time.Minute),
+			expAwake:     now.Add(-time.Hour),
+			expStandbyAt: now.Add(-5 * time.Minute),
+			expSleepAt:   now.Add(5 * time.Minute),
+		},
+		{
+			name:        "goes idle",
+			awakeSince:  now.Add(-time.Hour),
+			lastRequest: now.Add(-20 * time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			awake, standbyAt, sleepAt := wakeState(stz, tt.awakeSince, tt.lastRequest, now)
+			assert.Equal(t, tt.expAwake, awake)
+			assert.Equal(t, tt.expStandbyAt, standbyAt)
+			assert.Equal(t, tt.expSleepAt, sleepAt)
+		})
+	}
+}
+
+func Test_createStartupProbe(t *testing.T) {
+	probe := createStartupProbe(&v1alpha2.StartupProbe{Port: 8080, Path: "/health", MaxStartupSeconds: 95})
+	assert.Equal(t, "/health", probe.HTTPGet.Path)
+	assert.Equal(t, int32(10), probe.PeriodSeconds)
+	assert.Equal(t, int32(10), probe.FailureThreshold)
+
+	probe = createStartupProbe(&v1alpha2.StartupProbe{Port: 8080, TCP: true, PeriodSeconds: 5})
+	assert.NotNil(t, probe.TCPSocket)
+	assert.Equal(t, int32(60), probe.FailureThreshold)
+}
+
+func Test_createStatefulSet(t *testing.T) {
+	capsuleYAML := `
+apiVersion: rig.dev/v1alpha2
+kind: Capsule
+metadata:
+  name: db
+  namespace: prod
+spec:
+  image: postgres
+  scale:
+    horizontal:
+      instances:
+        min: 2
+  storage:
+    volumes:
+      - name: data
+        path: /var/lib/postgresql
+        size: 10Gi
+        storageClassName: fast
+      - name: wal
+        path: /var/lib/wal
+        size: 1Gi
+  sidecars:
+    - name: exporter
+      image: exporter
+`
+
+	h, err := plugintest.New(&Plugin{}, capsuleYAML, "")
+	require.NoError(t, err)
+
+	res, err := h.Run(context.Background())
+	require.NoError(t, err)
+
+	var sts *appsv1.StatefulSet
+	var headless *v1.Service
+	for _, o := range res.Objects {
+		switch o := o.(type) {
+		case *appsv1.Deployment:
+			t.Fatal("deployment created for capsule with storage")
+		case *appsv1.StatefulSet:
+			sts = o
+		case *v1.Service:
+			if o.Name == "db-headless" {
+				headless = o
+			}
+		}
+	}
+	require.NotNil(t, sts)
+	require.NotNil(t, headless)
+
+	assert.Equal(t, "db-headless", sts.Spec.ServiceName)
+	assert.Equal(t, v1.ClusterIPNone, headless.Spec.ClusterIP)
+	assert.Equal(t, map[string]string{pipeline.LabelCapsule: "db"}, headless.Spec.Selector)
+	assert.Equal(t, ptr.New(int32(2)), sts.Spec.Replicas)
+	assert.Equal(t, map[string]string{pipeline.LabelCapsule: "db"}, sts.Spec.Selector.MatchLabels)
+
+	require.Len(t, sts.Spec.VolumeClaimTemplates, 2)
+	data := sts.Spec.VolumeClaimTemplates[0]
+	assert.Equal(t, "data", data.Name)
+	assert.Equal(t, map[string]string{pipeline.LabelCapsule: "db"}, data.Labels)
+	assert.Equal(t, []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce}, data.Spec.AccessModes)
+	assert.True(t, resource.MustParse("10Gi").Equal(data.Spec.Resources.Requests[v1.ResourceStorage]))
+	assert.Equal(t, ptr.New("fast"), data.Spec.StorageClassName)
+	wal := sts.Spec.VolumeClaimTemplates[1]
+	assert.Equal(t, "wal", wal.Name)
+	assert.Nil(t, wal.Spec.StorageClassName)
+
+	// Volumes are only mounted in the main container.
+	containers := sts.Spec.Template.Spec.Containers
+	require.Len(t, containers, 1)
+	assert.Equal(t, "db", containers[0].Name)
+	assert.Equal(t, []v1.VolumeMount{
+		{Name: "data", MountPath: "/var/lib/postgresql"},
+		{Name: "wal", MountPath: "/var/lib/wal"},
+	}, containers[0].VolumeMounts)
+	require.Len(t, sts.Spec.Template.Spec.InitContainers, 1)
+	assert.Empty(t, sts.Spec.Template.Spec.InitContainers[0].VolumeMounts)
+}
+
+func Test_createSidecars(t *testing.T) {
+	capsuleYAML := `
+apiVersion: rig.dev/v1alpha2
+kind: Capsule
+metadata:
+  name: api
+  namespace: prod
+spec:
+  image: api
+  sidecars:
+    - name: proxy
+      image: envoy
+      command: envoy
+      args: [-c, /etc/envoy.yaml]
+      env:
+        raw:
+          B: b
+          A: a
+        from:
+          - kind: ConfigMap
+            name: proxy
+      resources:
+        cpu:
+          request: 100m
+        memory:
+          limit: 128Mi
+      liveness:
+        port: 9901
+        path: /ready
+      readiness:
+        port: 9901
+        tcp: true
+`
+
+	h, err := plugintest.New(&Plugin{}, capsuleYAML, "", plugintest.WithExistingObjects(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "proxy", Namespace: "prod"},
+	}))
+	require.NoError(t, err)
+
+	res, err := h.Run(context.Background())
+	require.NoError(t, err)
+
+	var deployment *appsv1.Deployment
+	for _, o := range res.Objects {
+		if d, ok := o.(*appsv1.Deployment); ok {
+			deployment = d
+		}
+	}
+	require.NotNil(t, deployment)
+
+	require.Len(t, deployment.Spec.Template.Spec.Containers, 1)
+	require.Len(t, deployment.Spec.Template.Spec.InitContainers, 1)
+	c := deployment.Spec.Template.Spec.InitContainers[0]
+	assert.Equal(t, "proxy", c.Name)
+	assert.Equal(t, "envoy", c.Image)
+	assert.Equal(t, []string{"envoy"}, c.Command)
+	assert.Equal(t, []string{"-c", "/etc/envoy.yaml"}, c.Args)
+	assert.Equal(t, ptr.New(v1.ContainerRestartPolicyAlways), c.RestartPolicy)
+	assert.Equal(t, []v1.EnvVar{
+		{Name: "RIG_CAPSULE_NAME", Value: "api"},
+		{Name: "A", Value: "a"},
+		{Name: "B", Value: "b"},
+	}, c.Env)
+	assert.Equal(t, []v1.EnvFromSource{{
+		ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "proxy"}},
+	}}, c.EnvFrom)
+	assert.True(t, resource.MustParse("100m").Equal(c.Resources.Requests[v1.ResourceCPU]))
+	assert.True(t, resource.MustParse("128Mi").Equal(c.Resources.Limits[v1.ResourceMemory]))
+	require.NotNil(t, c.LivenessProbe)
+	assert.Equal(t, "/ready", c.LivenessProbe.HTTPGet.Path)
+	require.NotNil(t, c.ReadinessProbe)
+	assert.NotNil(t, c.ReadinessProbe.TCPSocket)
+}
+
+func Test_getAvailability(t *testing.T) {
+	preferred := v1alpha2.Availability{
+		MaxUnavailable: "1",
+		ZoneSpread:     v1alpha2.SpreadPreferred,
+		NodeSpread:     v1alpha2.SpreadPreferred,
+	}
+	none := v1alpha2.Availability{
+		ZoneSpread: v1alpha2.SpreadNone,
+		NodeSpread: v1alpha2.SpreadNone,
+	}
+
+	tests := []struct {
+		name         string
+		availability *v1alpha2.Availability
+		instances    uint32
+		defaults     bool
+		expected     v1alpha2.Availability
+	}{
+		{
+			name:      "defaults disabled",
+			instances: 3,
+			expected:  none,
+		},
+		{
+			name:      "defaults with a single instance",
+			instances: 1,
+			defaults:  true,
+			expected:  none,
+		},
+		{
+			name:      "defaults with multiple instances",
+			instances: 2,
+			defaults:  true,
+			expected:  preferred,
+		},
+		{
+			name:         "set fields are kept",
+			availability: &v1alpha2.Availability{MinAvailable: "50%", NodeSpread: v1alpha2.SpreadRequired},
+			instances:    2,
+			defaults:     true,
+			expected: v1alpha2.Availability{
+				MinAvailable: "50%",
+				ZoneSpread:   v1alpha2.SpreadPreferred,
+				NodeSpread:   v1alpha2.SpreadRequired,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capsule := &v1alpha2.Capsule{Spec: v1alpha2.CapsuleSpec{Availability: tt.availability}}
+			assert.Equal(t, tt.expected, getAvailability(capsule, tt.instances, tt.defaults))
+		})
+	}
+}
+
+func Test_Run_PodDisruptionBudget(t *testing.T) {
+	capsuleYAML := `
+apiVersion: rig.dev/v1alpha2
+kind: Capsule
+metadata:
+  name: api
+  namespace: prod
+spec:
+  image: api
+  scale:
+    horizontal:
+      instances:
+        min: 2
+`
+
+	for _, config := range []string{"", "defaultAvailability: true"} {
+		h, err := plugintest.New(&Plugin{}, capsuleYAML, config)
+		require.NoError(t, err)
+
+		res, err := h.Run(context.Background())
+		require.NoError(t, err)
+
+		var pdb *policyv1.PodDisruptionBudget
+		var deployment *appsv1.Deployment
+		for _, o := range res.Objects {
+			switch o := o.(type) {
+			case *policyv1.PodDisruptionBudget:
+				pdb = o
+			case *appsv1.Deployment:
+				deployment = o
+			}
+		}
+		require.NotNil(t, deployment)
+
+		if config == "" {
+			assert.Nil(t, pdb)
+			assert.Empty(t, deployment.Spec.Template.Spec.TopologySpreadConstraints)
+			continue
+		}
+
+		require.NotNil(t, pdb)
+		assert.Equal(t, ptr.New(intstr.FromInt32(1)), pdb.Spec.MaxUnavailable)
+		assert.Len(t, deployment.Spec.Template.Spec.TopologySpreadConstraints, 2)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -43,6 +44,7 @@ func onPodUpdated(
 	}
 
 	makePlacementCondition(status, pod, events)
+	makeDependenciesCondition(status, pod)
 
 	containers := splitByContainers(status, pod, events)
 
@@ -187,11 +189,15 @@ func makeRunningCondition(pod *corev1.Pod, container containerInfo, watcher plug
 			startedAt = container.status.State.Running.StartedAt.Time
 		}
 
+		// The pod is only ready once all its containers are, so the readiness of
+		// each container of a multi-container capsule is read from its own status.
 		readyCondition := getCondition(pod.Status.Conditions, "Ready")
-		if readyCondition != nil && readyCondition.Status == v1.ConditionTrue {
+		if container.status.Ready {
 			ready.Message = "Instance ready for traffic"
 			ready.State = apipipeline.ObjectState_OBJECT_STATE_HEALTHY
-			ready.UpdatedAt = timestamppb.New(readyCondition.LastTransitionTime.Time)
+			if readyCondition != nil && readyCondition.Status == v1.ConditionTrue {
+				ready.UpdatedAt = timestamppb.New(readyCondition.LastTransitionTime.Time)
+			}
 		} else {
 			unhealthyEvent := getEventWithPrefix(container.events, "Unhealthy", "Readiness ")
 			if unhealthyEvent != nil {
@@ -489,7 +495,162 @@ func onDeploymentUpdated(
 	objectWatcher plugin.ObjectWatcher,
 ) *apipipeline.ObjectStatusInfo {
 	dep := obj.(*appsv1.Deployment)
-	return OnPodTemplatedUpdated(dep.Spec.Template, objectWatcher)
+	status := OnPodTemplatedUpdated(dep.Spec.Template, objectWatcher)
+	makeMissingDependenciesCondition(status, dep)
+	makeRolloutCondition(status, dep)
+	return status
+}
+
+// makeRolloutCondition reports the progress of a canary or blue/green rollout
+// on the canary Deployment running the new version of the capsule.
+func makeRolloutCondition(status *apipipeline.ObjectStatusInfo, dep *appsv1.Deployment) {
+	annotations := dep.GetAnnotations()
+	stepValue, ok := annotations[pipeline.AnnotationRolloutStep]
+	if !ok {
+		return
+	}
+
+	step, _ := strconv.Atoi(stepValue)
+	steps, _ := strconv.Atoi(annotations[pipeline.AnnotationRolloutSteps])
+	weight := annotations[pipeline.AnnotationRolloutWeight]
+
+	status.Properties["Rollout step"] = fmt.Sprintf("%d/%d", step+1, steps)
+	status.Properties["Traffic weight"] = weight + "%"
+
+	cond := &apipipeline.ObjectCondition{
+		Name:    "Rollout",
+		State:   apipipeline.ObjectState_OBJECT_STATE_PENDING,
+		Message: fmt.Sprintf("Step %d of %d, %s%% of traffic: waiting for all instances to be ready", step+1, steps, weight),
+	}
+
+	readySince, err := time.Parse(time.RFC3339, annotations[pipeline.AnnotationRolloutReadySince])
+	if err == nil {
+		cond.UpdatedAt = timestamppb.New(readySince)
+		cond.Message = fmt.Sprintf("Step %d of %d, %s%% of traffic: instances are ready", step+1, steps, weight)
+		if next, err := time.Parse(time.RFC3339, annotations[pipeline.AnnotationReconcileAfter]); err == nil {
+			cond.Message += fmt.Sprintf(", moving on at %s", next.Format(time.RFC3339))
+		}
+	}
+
+	status.Conditions = append(status.Conditions, cond)
+}
+
+func onStatefulSetUpdated(
+	obj client.Object,
+	_ []*corev1.Event,
+	objectWatcher plugin.ObjectWatcher,
+) *apipipeline.ObjectStatusInfo {
+	sts := obj.(*appsv1.StatefulSet)
+	if len(sts.Spec.VolumeClaimTemplates) > 0 {
+		objectWatcher.WatchSecondaryByLabels(
+			labels.SelectorFromSet(sts.Spec.Selector.MatchLabels),
+			&corev1.PersistentVolumeClaim{},
+			onPersistentVolumeClaimUpdated,
+		)
+	}
+	status := OnPodTemplatedUpdated(sts.Spec.Template, objectWatcher)
+	makeMissingDependenciesCondition(status, sts)
+	return status
+}
+
+func onPersistentVolumeClaimUpdated(
+	obj client.Object,
+	events []*corev1.Event,
+	_ plugin.ObjectWatcher,
+) *apipipeline.ObjectStatusInfo {
+	pvc := obj.(*corev1.PersistentVolumeClaim)
+
+	status := &apipipeline.ObjectStatusInfo{
+		Properties: map[string]string{},
+	}
+	if q, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		status.Properties["Size"] = q.String()
+	}
+	if pvc.Spec.StorageClassName != nil {
+		status.Properties["Storage class"] = *pvc.Spec.StorageClassName
+	}
+	if pvc.Spec.VolumeName != "" {
+		status.Properties["Volume"] = pvc.Spec.VolumeName
+	}
+
+	cond := &apipipeline.ObjectCondition{
+		Name:      "Volume binding",
+		UpdatedAt: timestamppb.Now(),
+	}
+	switch pvc.Status.Phase {
+	case corev1.ClaimBound:
+		cond.State = apipipeline.ObjectState_OBJECT_STATE_HEALTHY
+		cond.Message = "Volume is bound"
+	case corev1.ClaimLost:
+		cond.State = apipipeline.ObjectState_OBJECT_STATE_ERROR
+		cond.Message = "Bound volume no longer exists"
+	default:
+		cond.State = apipipeline.ObjectState_OBJECT_STATE_PENDING
+		cond.Message = "Waiting for volume to be bound"
+
+		var latest *corev1.Event
+		for _, e := range events {
+			if e.Type != corev1.EventTypeWarning {
+				continue
+			}
+			if latest == nil || timestampFromEvent(e).AsTime().After(timestampFromEvent(latest).AsTime()) {
+				latest = e
+			}
+		}
+		if latest != nil {
+			cond.Message = latest.Message
+			cond.UpdatedAt = timestampFromEvent(latest)
+			if latest.Reason == "ProvisioningFailed" {
+				cond.State = apipipeline.ObjectState_OBJECT_STATE_ERROR
+			}
+		}
+	}
+	status.Conditions = append(status.Conditions, cond)
+
+	return status
+}
+
+func onPodDisruptionBudgetUpdated(
+	obj client.Object,
+	_ []*corev1.Event,
+	_ plugin.ObjectWatcher,
+) *apipipeline.ObjectStatusInfo {
+	pdb := obj.(*policyv1.PodDisruptionBudget)
+
+	status := &apipipeline.ObjectStatusInfo{
+		Properties: map[string]string{
+			"Disruptions allowed": strconv.Itoa(int(pdb.Status.DisruptionsAllowed)),
+			"Healthy instances":   fmt.Sprintf("%d/%d", pdb.Status.CurrentHealthy, pdb.Status.ExpectedPods),
+		},
+	}
+	if pdb.Spec.MinAvailable != nil {
+		status.Properties["Min available"] = pdb.Spec.MinAvailable.String()
+	}
+	if pdb.Spec.MaxUnavailable != nil {
+		status.Properties["Max unavailable"] = pdb.Spec.MaxUnavailable.String()
+	}
+
+	cond := &apipipeline.ObjectCondition{
+		Name:      "Disruption budget",
+		UpdatedAt: timestamppb.Now(),
+	}
+	switch {
+	case pdb.Status.ObservedGeneration < pdb.GetGeneration():
+		cond.State = apipipeline.ObjectState_OBJECT_STATE_PENDING
+		cond.Message = "Waiting for the budget to be observed"
+	case pdb.Status.DisruptionsAllowed == 0:
+		cond.State = apipipeline.ObjectState_OBJECT_STATE_PENDING
+		cond.Message = fmt.Sprintf(
+			"No disruptions allowed, %d of %d required instances are healthy",
+			pdb.Status.CurrentHealthy, pdb.Status.DesiredHealthy,
+		)
+	default:
+		cond.State = apipipeline.ObjectState_OBJECT_STATE_HEALTHY
+		cond.Message = fmt.Sprintf("%d disruptions allowed", pdb.Status.DisruptionsAllowed)
+	}
+	status.Conditions = append(status.Conditions, cond)
+
+	return status
 }
 
 func OnPodTemplatedUpdated(
@@ -582,6 +743,13 @@ func onServiceUpdated(
 ) *apipipeline.ObjectStatusInfo {
 	svc := obj.(*corev1.Service)
 
+	// The interfaces of the capsule are reported by its main Service.
+	if svc.GetLabels()[pipeline.LabelRolloutTrack] == pipeline.RolloutTrackCanary {
+		return &apipipeline.ObjectStatusInfo{
+			Properties: map[string]string{},
+		}
+	}
+
 	var platformStatuses []*apipipeline.PlatformObjectStatus
 	for _, p := range svc.Spec.Ports {
 		platformStatuses = append(platformStatuses, &apipipeline.PlatformObjectStatus{
@@ -601,12 +769,24 @@ func onServiceUpdated(
 }
 
 func (p *Plugin) WatchObjectStatus(ctx context.Context, watcher plugin.CapsuleWatcher) error {
+	// The External Secrets Operator is optional, so ExternalSecrets are only
+	// watched if their CRD is installed.
+	hasExternalSecrets, err := watcher.HasKind(newExternalSecret())
+	if err != nil {
+		return err
+	}
+
 	errChan := make(chan error, 1)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	go runWatch(ctx, watcher, &corev1.Service{}, onServiceUpdated, errChan)
 	go runWatch(ctx, watcher, &appsv1.Deployment{}, onDeploymentUpdated, errChan)
+	go runWatch(ctx, watcher, &appsv1.StatefulSet{}, onStatefulSetUpdated, errChan)
+	go runWatch(ctx, watcher, &policyv1.PodDisruptionBudget{}, onPodDisruptionBudgetUpdated, errChan)
+	if hasExternalSecrets {
+		go runWatch(ctx, watcher, newExternalSecret(), onExternalSecretUpdated, errChan)
+	}
 
 	select {
 	case err := <-errChan:
@@ -3,7 +3,12 @@ package deployment
 import (
 	"testing"
 
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func Test_containerNameFromEventFieldPath(t *testing.T) {
@@ -16,3 +21,77 @@ func Test_containerNameFromEventFieldPath(t *testing.T) {
 	assert.Equal(t, containerNameFromEventFieldPath("null"), "")
 	assert.Equal(t, containerNameFromEventFieldPath(""), "")
 }
+
+func Test_onPodDisruptionBudgetUpdated(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  policyv1.PodDisruptionBudgetStatus
+		state   apipipeline.ObjectState
+		message string
+	}{
+		{
+			name: "disruptions allowed",
+			status: policyv1.PodDisruptionBudgetStatus{
+				DisruptionsAllowed: 1, CurrentHealthy: 3, DesiredHealthy: 2, ExpectedPods: 3,
+			},
+			state:   apipipeline.ObjectState_OBJECT_STATE_HEALTHY,
+			message: "1 disruptions allowed",
+		},
+		{
+			name: "no disruptions allowed",
+			status: policyv1.PodDisruptionBudgetStatus{
+				CurrentHealthy: 1, DesiredHealthy: 2, ExpectedPods: 3,
+			},
+			state:   apipipeline.ObjectState_OBJECT_STATE_PENDING,
+			message: "No disruptions allowed, 1 of 2 required instances are healthy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdb := &policyv1.PodDisruptionBudget{
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					MaxUnavailable: ptr.New(intstr.FromInt32(1)),
+				},
+				Status: tt.status,
+			}
+
+			status := onPodDisruptionBudgetUpdated(pdb, nil, nil)
+			assert.Equal(t, "1", status.Properties["Max unavailable"])
+			assert.Len(t, status.Conditions, 1)
+			assert.Equal(t, tt.state, status.Conditions[0].State)
+			assert.Equal(t, tt.message, status.Conditions[0].Message)
+		})
+	}
+}
+
+func Test_onPodUpdated_containers(t *testing.T) {
+	pod := &corev1.Pod{}
+	pod.Spec.Containers = []corev1.Container{
+		{Name: "api", ReadinessProbe: &corev1.Probe{}},
+		{Name: "worker", ReadinessProbe: &corev1.Probe{}},
+	}
+	pod.Status.Conditions = []corev1.PodCondition{
+		{Type: corev1.PodInitialized, Status: corev1.ConditionTrue},
+		{Type: corev1.PodReady, Status: corev1.ConditionFalse},
+	}
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{Name: "api", Ready: true, State: running, ImageID: "api@sha256:1"},
+		{Name: "worker", State: running, ImageID: "worker@sha256:2"},
+	}
+
+	status := onPodUpdated(pod, nil, nil)
+	assert.Len(t, status.SubObjects, 2)
+
+	readiness := map[string]apipipeline.ObjectState{}
+	for _, sub := range status.SubObjects {
+		assert.Equal(t, apipipeline.ObjectState_OBJECT_STATE_HEALTHY,
+			getObjectCondition(sub.Conditions, "Running").GetState())
+		readiness[sub.Name] = getObjectCondition(sub.Conditions, "Readiness").GetState()
+	}
+	assert.Equal(t, map[string]apipipeline.ObjectState{
+		"api":    apipipeline.ObjectState_OBJECT_STATE_HEALTHY,
+		"worker": apipipeline.ObjectState_OBJECT_STATE_PENDING,
+	}, readiness)
+}
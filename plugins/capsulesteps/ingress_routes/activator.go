@@ -0,0 +1,128 @@
+//nolint:revive
+package ingress_routes
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func scalesToZero(req pipeline.CapsuleRequest) bool {
+	return req.Capsule().Spec.Scale.Horizontal.ScaleToZero != nil
+}
+
+func activatorServiceName(req pipeline.CapsuleRequest) string {
+	return fmt.Sprintf("%s-activator", req.Capsule().Name)
+}
+
+// routedThroughActivator returns true if the routes of the capsule must be sent
+// through the activator instead of to the Service of the capsule, as set on the
+// workload of the capsule by the deployment step. Routes are sent through the
+// activator if the workload doesn't say otherwise.
+func routedThroughActivator(req pipeline.CapsuleRequest) (bool, error) {
+	if !scalesToZero(req) {
+		return false, nil
+	}
+
+	workload, _, err := pipeline.GetNewWorkload(req)
+	if errors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	routed, err := strconv.ParseBool(workload.GetAnnotations()[pipeline.AnnotationRoutedThroughActivator])
+	if err != nil {
+		return true, nil
+	}
+	return routed, nil
+}
+
+// activatorAddress returns the host and port of the configured activator.
+func activatorAddress(cfg Config) (string, int32, error) {
+	if cfg.Activator == "" {
+		return "", 0, errors.New("an activator must be configured to scale capsules to zero")
+	}
+
+	host, port, err := net.SplitHostPort(cfg.Activator)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid activator address: %w", err)
+	}
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid activator port: %w", err)
+	}
+
+	return host, int32(portNumber), nil
+}
+
+// createActivatorService creates an ExternalName Service resolving to the
+// activator, which the Ingresses of a capsule scaling to zero use as backend
+// instead of the Service of the capsule, as Ingresses can't refer to Services
+// in other namespaces. The ports of the interfaces are all mapped to the port
+// of the activator.
+func createActivatorService(req pipeline.CapsuleRequest, cfg Config) error {
+	host, port, err := activatorAddress(cfg)
+	if err != nil {
+		return err
+	}
+
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      activatorServiceName(req),
+			Namespace: req.Capsule().Namespace,
+			Labels: map[string]string{
+				pipeline.LabelCapsule: req.Capsule().Name,
+			},
+		},
+		Spec: v1.ServiceSpec{
+			Type:         v1.ServiceTypeExternalName,
+			ExternalName: host,
+		},
+	}
+
+	for _, inf := range req.Capsule().AllInterfaces() {
+		svc.Spec.Ports = append(svc.Spec.Ports, v1.ServicePort{
+			Name:       inf.Name,
+			Port:       inf.Port,
+			TargetPort: intstr.FromInt32(port),
+		})
+	}
+
+	return req.Set(svc)
+}
+
+// activatorBackendRef returns a reference to the Service of the activator, which
+// the Gateway API routes of a capsule scaling to zero use as backend instead of
+// the Service of the capsule. The activator must be addressed by the DNS name of
+// its Service, `<service>.<namespace>.svc.cluster.local`, and its namespace must
+// have a ReferenceGrant allowing routes in the namespace of the capsule to refer
+// to it.
+func activatorBackendRef(cfg Config) (gatewayv1.BackendObjectReference, error) {
+	host, port, err := activatorAddress(cfg)
+	if err != nil {
+		return gatewayv1.BackendObjectReference{}, err
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 || len(labels) > 2 && labels[2] != "svc" {
+		return gatewayv1.BackendObjectReference{}, fmt.Errorf(
+			"activator address '%s' must be the DNS name of a Service when using the Gateway API", host,
+		)
+	}
+
+	return gatewayv1.BackendObjectReference{
+		Name:      gatewayv1.ObjectName(labels[0]),
+		Namespace: ptr.New(gatewayv1.Namespace(labels[1])),
+		Port:      ptr.New(gatewayv1.PortNumber(port)),
+	}, nil
+}
@@ -0,0 +1,194 @@
+//nolint:revive
+package ingress_routes
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rigdev/rig/pkg/controller/plugin/plugintest"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const scaleToZeroCapsuleYAML = `
+apiVersion: rig.dev/v1alpha2
+kind: Capsule
+metadata:
+  name: api
+  namespace: prod
+spec:
+  image: nginx
+  scale:
+    horizontal:
+      instances:
+        min: 1
+      scaleToZero:
+        idleSeconds: 600
+  interfaces:
+    - name: http
+      port: 8080
+      routes:
+        - id: public
+          host: api.example.com
+`
+
+func Test_Run_ScaleToZero(t *testing.T) {
+	config := `
+ingressClassName: nginx
+disableTLS: true
+activator: rig-operator-activator.rig-system.svc.cluster.local:8080
+`
+
+	tests := []struct {
+		name     string
+		workload []client.Object
+		backend  string
+	}{
+		{
+			name: "routed through activator while asleep or on standby",
+			workload: []client.Object{newWorkload(map[string]string{
+				pipeline.AnnotationRoutedThroughActivator: "true",
+			})},
+			backend: "api-activator",
+		},
+		{
+			name: "routed to capsule while awake",
+			workload: []client.Object{newWorkload(map[string]string{
+				pipeline.AnnotationRoutedThroughActivator: "false",
+			})},
+			backend: "api",
+		},
+		{
+			name:     "routed through activator without workload",
+			workload: nil,
+			backend:  "api-activator",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := plugintest.New(&Plugin{}, scaleToZeroCapsuleYAML, config,
+				plugintest.WithNewObjects(tt.workload...),
+			)
+			require.NoError(t, err)
+
+			res, err := h.Run(context.Background())
+			require.NoError(t, err)
+
+			var backend string
+			var activator *v1.Service
+			for _, o := range res.Objects {
+				switch o := o.(type) {
+				case *netv1.Ingress:
+					backend = o.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name
+					require.NotContains(t, o.Annotations, "nginx.ingress.kubernetes.io/configuration-snippet")
+				case *v1.Service:
+					activator = o
+				}
+			}
+			require.Equal(t, tt.backend, backend)
+
+			// The Service of the activator is kept while the capsule is awake.
+			require.NotNil(t, activator)
+			require.Equal(t, "api-activator", activator.Name)
+			require.Equal(t, v1.ServiceTypeExternalName, activator.Spec.Type)
+			require.Equal(t, "rig-operator-activator.rig-system.svc.cluster.local", activator.Spec.ExternalName)
+		})
+	}
+}
+
+func newWorkload(annotations map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api",
+			Namespace:   "prod",
+			Annotations: annotations,
+		},
+	}
+}
+
+func Test_Run_ScaleToZero_Gateway(t *testing.T) {
+	config := `
+gateway:
+  name: public
+activator: %s
+`
+
+	tests := []struct {
+		name      string
+		activator string
+		workload  []client.Object
+		backend   gatewayv1.BackendObjectReference
+		err       string
+	}{
+		{
+			name:      "routed through activator in its namespace",
+			activator: "rig-operator-activator.rig-system.svc.cluster.local:8080",
+			workload: []client.Object{newWorkload(map[string]string{
+				pipeline.AnnotationRoutedThroughActivator: "true",
+			})},
+			backend: gatewayv1.BackendObjectReference{
+				Name:      "rig-operator-activator",
+				Namespace: ptr.New(gatewayv1.Namespace("rig-system")),
+				Port:      ptr.New(gatewayv1.PortNumber(8080)),
+			},
+		},
+		{
+			name:      "routed to capsule while awake",
+			activator: "rig-operator-activator.rig-system:8080",
+			workload: []client.Object{newWorkload(map[string]string{
+				pipeline.AnnotationRoutedThroughActivator: "false",
+			})},
+			backend: gatewayv1.BackendObjectReference{
+				Name: "api",
+				Port: ptr.New(gatewayv1.PortNumber(8080)),
+			},
+		},
+		{
+			name:      "activator not addressed by its service",
+			activator: "activator.example.com:8080",
+			err:       "activator address 'activator.example.com' must be the DNS name of a Service",
+		},
+		{
+			name:      "activator without namespace",
+			activator: "rig-operator-activator:8080",
+			err:       "activator address 'rig-operator-activator' must be the DNS name of a Service",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := plugintest.New(&Plugin{}, scaleToZeroCapsuleYAML, fmt.Sprintf(config, tt.activator),
+				plugintest.WithNewObjects(tt.workload...),
+			)
+			require.NoError(t, err)
+
+			res, err := h.Run(context.Background())
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			var route *gatewayv1.HTTPRoute
+			for _, o := range res.Objects {
+				switch o := o.(type) {
+				case *gatewayv1.HTTPRoute:
+					route = o
+				case *v1.Service:
+					t.Fatalf("service %s created for gateway routes", o.Name)
+				}
+			}
+			require.NotNil(t, route)
+			require.Equal(t, tt.backend, route.Spec.Rules[0].BackendRefs[0].BackendObjectReference)
+		})
+	}
+}
@@ -0,0 +1,433 @@
+//nolint:revive
+package ingress_routes
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// AnnotationGRPCRoute can be set on a route to create a GRPCRoute instead of
+// an HTTPRoute, when the plugin is configured to use the Gateway API.
+const AnnotationGRPCRoute = "plugin.rig.dev/grpc-route"
+
+// GatewayConfig specifies the Gateway that routes are attached to.
+type GatewayConfig struct {
+	// Name of the Gateway.
+	Name string `json:"name"`
+
+	// Namespace of the Gateway. If empty, the Gateway is expected to be in the
+	// namespace of the capsule.
+	Namespace string `json:"namespace,omitempty"`
+
+	// SectionName is the name of the listener of the Gateway to attach to. If
+	// empty, the routes are attached to all listeners of the Gateway.
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+func (p *Plugin) createGatewayRoutes(req pipeline.CapsuleRequest, cfg Config) ([]client.Object, error) {
+	var routes []client.Object
+	routed, err := routedThroughActivator(req)
+	if err != nil {
+		return nil, err
+	}
+	var activatorRef gatewayv1.BackendObjectReference
+	if scalesToZero(req) {
+		if activatorRef, err = activatorBackendRef(cfg); err != nil {
+			return nil, err
+		}
+	}
+	for _, inf := range req.Capsule().AllInterfaces() {
+		for _, route := range GetRoutes(inf) {
+			meta := metav1.ObjectMeta{
+				Name:        getRouteName(req, route),
+				Namespace:   req.Capsule().Namespace,
+				Annotations: map[string]string{},
+				Labels: map[string]string{
+					pipeline.RigDevInterfaceLabel: inf.Name,
+				},
+			}
+			maps.Copy(meta.Annotations, cfg.Annotations)
+			maps.Copy(meta.Annotations, route.Annotations)
+
+			backendRef := gatewayv1.BackendRef{
+				BackendObjectReference: gatewayv1.BackendObjectReference{
+					Name: gatewayv1.ObjectName(req.Capsule().Name),
+					Port: ptr.New(gatewayv1.PortNumber(inf.Port)),
+				},
+			}
+			if routed {
+				backendRef.BackendObjectReference = activatorRef
+			}
+
+			var hostnames []gatewayv1.Hostname
+			if route.Host != "" {
+				hostnames = append(hostnames, gatewayv1.Hostname(route.Host))
+			}
+
+			if isGRPC, _ := strconv.ParseBool(route.Annotations[AnnotationGRPCRoute]); isGRPC {
+				if scalesToZero(req) {
+					return nil, fmt.Errorf("route %s: gRPC routes are not supported when scaling to zero", route.ID)
+				}
+				rule, err := createGRPCRouteRule(route)
+				if err != nil {
+					return nil, err
+				}
+				rule.BackendRefs = []gatewayv1.GRPCBackendRef{{BackendRef: backendRef}}
+
+				routes = append(routes, &gatewayv1.GRPCRoute{
+					ObjectMeta: meta,
+					Spec: gatewayv1.GRPCRouteSpec{
+						CommonRouteSpec: createCommonRouteSpec(cfg.Gateway),
+						Hostnames:       hostnames,
+						Rules:           []gatewayv1.GRPCRouteRule{rule},
+					},
+				})
+				continue
+			}
+
+			rules, err := createHTTPRouteRules(route, backendRef)
+			if err != nil {
+				return nil, err
+			}
+
+			routes = append(routes, &gatewayv1.HTTPRoute{
+				ObjectMeta: meta,
+				Spec: gatewayv1.HTTPRouteSpec{
+					CommonRouteSpec: createCommonRouteSpec(cfg.Gateway),
+					Hostnames:       hostnames,
+					Rules:           rules,
+				},
+			})
+		}
+	}
+
+	return routes, nil
+}
+
+func createCommonRouteSpec(cfg *GatewayConfig) gatewayv1.CommonRouteSpec {
+	parent := gatewayv1.ParentReference{
+		Group: ptr.New(gatewayv1.Group(gatewayv1.GroupName)),
+		Kind:  ptr.New(gatewayv1.Kind("Gateway")),
+		Name:  gatewayv1.ObjectName(cfg.Name),
+	}
+	if cfg.Namespace != "" {
+		parent.Namespace = ptr.New(gatewayv1.Namespace(cfg.Namespace))
+	}
+	if cfg.SectionName != "" {
+		parent.SectionName = ptr.New(gatewayv1.SectionName(cfg.SectionName))
+	}
+
+	return gatewayv1.CommonRouteSpec{
+		ParentRefs: []gatewayv1.ParentReference{parent},
+	}
+}
+
+const (
+	// maxHTTPRouteMatches is the maximum number of matches of a rule of an
+	// HTTPRoute allowed by the Gateway API.
+	maxHTTPRouteMatches = 8
+	// maxHTTPRouteRules is the maximum number of rules of an HTTPRoute allowed
+	// by the Gateway API.
+	maxHTTPRouteRules = 16
+)
+
+// createHTTPRouteRules creates the rules of the HTTPRoute of a route. The
+// matches of the route are split across rules, which share the filters and
+// backend, as each rule can only hold a limited number of matches.
+func createHTTPRouteRules(
+	route v1alpha2.HostRoute,
+	backendRef gatewayv1.BackendRef,
+) ([]gatewayv1.HTTPRouteRule, error) {
+	matches := createHTTPRouteMatches(route)
+	if len(matches) > maxHTTPRouteMatches*maxHTTPRouteRules {
+		return nil, fmt.Errorf(
+			"route %s: %d combinations of paths and methods exceed the maximum of %d",
+			route.ID, len(matches), maxHTTPRouteMatches*maxHTTPRouteRules,
+		)
+	}
+
+	filters := createHTTPRouteFilters(route)
+
+	var rules []gatewayv1.HTTPRouteRule
+	for chunk := range slices.Chunk(matches, maxHTTPRouteMatches) {
+		rules = append(rules, gatewayv1.HTTPRouteRule{
+			Matches:     chunk,
+			Filters:     filters,
+			BackendRefs: []gatewayv1.HTTPBackendRef{{BackendRef: backendRef}},
+		})
+	}
+	return rules, nil
+}
+
+// createHTTPRouteMatches creates a match for each combination of path and
+// method of the route. The header and query parameter matches of the route
+// are part of all of them.
+func createHTTPRouteMatches(route v1alpha2.HostRoute) []gatewayv1.HTTPRouteMatch {
+	paths := route.Paths
+	if len(paths) == 0 {
+		paths = []v1alpha2.HTTPPathRoute{{Path: "/", Match: v1alpha2.PathPrefix}}
+	}
+
+	var headers []gatewayv1.HTTPHeaderMatch
+	for _, h := range route.Headers {
+		mt := gatewayv1.HeaderMatchExact
+		if h.Match == v1alpha2.ValueRegularExpression {
+			mt = gatewayv1.HeaderMatchRegularExpression
+		}
+		headers = append(headers, gatewayv1.HTTPHeaderMatch{
+			Type:  ptr.New(mt),
+			Name:  gatewayv1.HTTPHeaderName(h.Name),
+			Value: h.Value,
+		})
+	}
+
+	var queryParams []gatewayv1.HTTPQueryParamMatch
+	for _, q := range route.QueryParameters {
+		mt := gatewayv1.QueryParamMatchExact
+		if q.Match == v1alpha2.ValueRegularExpression {
+			mt = gatewayv1.QueryParamMatchRegularExpression
+		}
+		queryParams = append(queryParams, gatewayv1.HTTPQueryParamMatch{
+			Type:  ptr.New(mt),
+			Name:  gatewayv1.HTTPHeaderName(q.Name),
+			Value: q.Value,
+		})
+	}
+
+	var methods []*gatewayv1.HTTPMethod
+	for _, m := range route.Methods {
+		methods = append(methods, ptr.New(gatewayv1.HTTPMethod(m)))
+	}
+	if len(methods) == 0 {
+		methods = []*gatewayv1.HTTPMethod{nil}
+	}
+
+	var matches []gatewayv1.HTTPRouteMatch
+	for _, path := range paths {
+		var pt gatewayv1.PathMatchType
+		switch path.Match {
+		case v1alpha2.RegularExpression:
+			pt = gatewayv1.PathMatchRegularExpression
+		case v1alpha2.Exact:
+			pt = gatewayv1.PathMatchExact
+		default:
+			pt = gatewayv1.PathMatchPathPrefix
+		}
+
+		for _, method := range methods {
+			matches = append(matches, gatewayv1.HTTPRouteMatch{
+				Path: &gatewayv1.HTTPPathMatch{
+					Type:  ptr.New(pt),
+					Value: ptr.New(path.Path),
+				},
+				Headers:     headers,
+				QueryParams: queryParams,
+				Method:      method,
+			})
+		}
+	}
+
+	return matches
+}
+
+func createHTTPRouteFilters(route v1alpha2.HostRoute) []gatewayv1.HTTPRouteFilter {
+	var filters []gatewayv1.HTTPRouteFilter
+	if f := createHeaderFilter(route.RequestHeaders); f != nil {
+		filters = append(filters, gatewayv1.HTTPRouteFilter{
+			Type:                  gatewayv1.HTTPRouteFilterRequestHeaderModifier,
+			RequestHeaderModifier: f,
+		})
+	}
+	if f := createHeaderFilter(route.ResponseHeaders); f != nil {
+		filters = append(filters, gatewayv1.HTTPRouteFilter{
+			Type:                   gatewayv1.HTTPRouteFilterResponseHeaderModifier,
+			ResponseHeaderModifier: f,
+		})
+	}
+	if route.RewritePathPrefix != "" {
+		filters = append(filters, gatewayv1.HTTPRouteFilter{
+			Type: gatewayv1.HTTPRouteFilterURLRewrite,
+			URLRewrite: &gatewayv1.HTTPURLRewriteFilter{
+				Path: &gatewayv1.HTTPPathModifier{
+					Type:               gatewayv1.PrefixMatchHTTPPathModifier,
+					ReplacePrefixMatch: ptr.New(route.RewritePathPrefix),
+				},
+			},
+		})
+	}
+	return filters
+}
+
+func createHeaderFilter(m *v1alpha2.HeaderModifier) *gatewayv1.HTTPHeaderFilter {
+	if m == nil {
+		return nil
+	}
+
+	toHeaders := func(values map[string]string) []gatewayv1.HTTPHeader {
+		var headers []gatewayv1.HTTPHeader
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			headers = append(headers, gatewayv1.HTTPHeader{
+				Name:  gatewayv1.HTTPHeaderName(name),
+				Value: values[name],
+			})
+		}
+		return headers
+	}
+
+	return &gatewayv1.HTTPHeaderFilter{
+		Set:    toHeaders(m.Set),
+		Add:    toHeaders(m.Add),
+		Remove: m.Remove,
+	}
+}
+
+func createGRPCRouteRule(route v1alpha2.HostRoute) (gatewayv1.GRPCRouteRule, error) {
+	if len(route.Methods) > 0 {
+		return gatewayv1.GRPCRouteRule{}, fmt.Errorf("route %s: methods are not supported for gRPC routes", route.ID)
+	}
+	if len(route.QueryParameters) > 0 {
+		return gatewayv1.GRPCRouteRule{}, fmt.Errorf(
+			"route %s: query parameters are not supported for gRPC routes", route.ID,
+		)
+	}
+	if route.RewritePathPrefix != "" {
+		return gatewayv1.GRPCRouteRule{}, fmt.Errorf(
+			"route %s: path rewrites are not supported for gRPC routes", route.ID,
+		)
+	}
+
+	var headers []gatewayv1.GRPCHeaderMatch
+	for _, h := range route.Headers {
+		mt := gatewayv1.HeaderMatchExact
+		if h.Match == v1alpha2.ValueRegularExpression {
+			mt = gatewayv1.HeaderMatchRegularExpression
+		}
+		headers = append(headers, gatewayv1.GRPCHeaderMatch{
+			Type:  ptr.New(mt),
+			Name:  gatewayv1.GRPCHeaderName(h.Name),
+			Value: h.Value,
+		})
+	}
+
+	matches := createGRPCRouteMatches(route.Paths)
+	if len(matches) == 0 && len(headers) > 0 {
+		matches = []gatewayv1.GRPCRouteMatch{{}}
+	}
+	for i := range matches {
+		matches[i].Headers = headers
+	}
+
+	rule := gatewayv1.GRPCRouteRule{
+		Matches: matches,
+	}
+	if f := createHeaderFilter(route.RequestHeaders); f != nil {
+		rule.Filters = append(rule.Filters, gatewayv1.GRPCRouteFilter{
+			Type:                  gatewayv1.GRPCRouteFilterRequestHeaderModifier,
+			RequestHeaderModifier: f,
+		})
+	}
+	if f := createHeaderFilter(route.ResponseHeaders); f != nil {
+		rule.Filters = append(rule.Filters, gatewayv1.GRPCRouteFilter{
+			Type:                   gatewayv1.GRPCRouteFilterResponseHeaderModifier,
+			ResponseHeaderModifier: f,
+		})
+	}
+
+	return rule, nil
+}
+
+// createGRPCRouteMatches maps paths of the form `/<service>/<method>` to gRPC
+// method matches. A path prefix only matches on the service.
+func createGRPCRouteMatches(paths []v1alpha2.HTTPPathRoute) []gatewayv1.GRPCRouteMatch {
+	var matches []gatewayv1.GRPCRouteMatch
+	for _, path := range paths {
+		service, method, _ := strings.Cut(strings.Trim(path.Path, "/"), "/")
+		if service == "" {
+			// Matches all services.
+			return nil
+		}
+
+		mt := gatewayv1.GRPCMethodMatchExact
+		switch path.Match {
+		case v1alpha2.RegularExpression:
+			mt = gatewayv1.GRPCMethodMatchRegularExpression
+		case v1alpha2.Exact:
+			// Matches the method exactly, or the service if no method is given.
+		default:
+			// A prefix matches all methods of the service.
+			method = ""
+		}
+
+		m := &gatewayv1.GRPCMethodMatch{
+			Type:    ptr.New(mt),
+			Service: ptr.New(service),
+		}
+		if method != "" {
+			m.Method = ptr.New(method)
+		}
+		matches = append(matches, gatewayv1.GRPCRouteMatch{Method: m})
+	}
+
+	return matches
+}
+
+// SetCanaryBackends splits the traffic of an HTTPRoute or GRPCRoute between
+// the capsule service and the canary service, sending the given weight (in
+// percent) to the canary. False is returned for all other objects.
+func SetCanaryBackends(route client.Object, capsuleName, canaryServiceName string, weight uint32) bool {
+	split := func(ref gatewayv1.BackendRef) (gatewayv1.BackendRef, gatewayv1.BackendRef, bool) {
+		if string(ref.Name) != capsuleName || ref.Kind != nil && *ref.Kind != "Service" {
+			return ref, ref, false
+		}
+
+		stable := *ref.DeepCopy()
+		stable.Weight = ptr.New(int32(100 - weight))
+		canary := *ref.DeepCopy()
+		canary.Name = gatewayv1.ObjectName(canaryServiceName)
+		canary.Weight = ptr.New(int32(weight))
+		return stable, canary, true
+	}
+
+	switch r := route.(type) {
+	case *gatewayv1.HTTPRoute:
+		for i, rule := range r.Spec.Rules {
+			var refs []gatewayv1.HTTPBackendRef
+			for _, ref := range rule.BackendRefs {
+				stable, canary, ok := split(ref.BackendRef)
+				ref.BackendRef = stable
+				refs = append(refs, ref)
+				if ok {
+					refs = append(refs, gatewayv1.HTTPBackendRef{BackendRef: canary, Filters: ref.Filters})
+				}
+			}
+			r.Spec.Rules[i].BackendRefs = refs
+		}
+		return true
+	case *gatewayv1.GRPCRoute:
+		for i, rule := range r.Spec.Rules {
+			var refs []gatewayv1.GRPCBackendRef
+			for _, ref := range rule.BackendRefs {
+				stable, canary, ok := split(ref.BackendRef)
+				ref.BackendRef = stable
+				refs = append(refs, ref)
+				if ok {
+					refs = append(refs, gatewayv1.GRPCBackendRef{BackendRef: canary, Filters: ref.Filters})
+				}
+			}
+			r.Spec.Rules[i].BackendRefs = refs
+		}
+		return true
+	default:
+		return false
+	}
+}
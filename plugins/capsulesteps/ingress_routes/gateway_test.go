@@ -0,0 +1,335 @@
+//nolint:revive
+package ingress_routes
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/controller/plugin/plugintest"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func Test_Run_GatewayRoutes(t *testing.T) {
+	config := `
+gateway:
+  name: public
+  namespace: gateways
+  sectionName: https
+`
+
+	parentRefs := []gatewayv1.ParentReference{{
+		Group:       ptr.New(gatewayv1.Group(gatewayv1.GroupName)),
+		Kind:        ptr.New(gatewayv1.Kind("Gateway")),
+		Name:        "public",
+		Namespace:   ptr.New(gatewayv1.Namespace("gateways")),
+		SectionName: ptr.New(gatewayv1.SectionName("https")),
+	}}
+	backendRef := gatewayv1.BackendRef{
+		BackendObjectReference: gatewayv1.BackendObjectReference{
+			Name: "api",
+			Port: ptr.New(gatewayv1.PortNumber(8080)),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		route    string
+		expected client.Object
+		err      string
+	}{
+		{
+			name: "all paths",
+			route: `
+          host: api.example.com
+`,
+			expected: &gatewayv1.HTTPRoute{
+				Spec: gatewayv1.HTTPRouteSpec{
+					CommonRouteSpec: gatewayv1.CommonRouteSpec{ParentRefs: parentRefs},
+					Hostnames:       []gatewayv1.Hostname{"api.example.com"},
+					Rules: []gatewayv1.HTTPRouteRule{{
+						Matches: []gatewayv1.HTTPRouteMatch{{
+							Path: &gatewayv1.HTTPPathMatch{
+								Type:  ptr.New(gatewayv1.PathMatchPathPrefix),
+								Value: ptr.New("/"),
+							},
+						}},
+						BackendRefs: []gatewayv1.HTTPBackendRef{{BackendRef: backendRef}},
+					}},
+				},
+			},
+		},
+		{
+			name: "matches and filters",
+			route: `
+          host: api.example.com
+          paths:
+            - path: /v1
+            - path: /health
+              match: Exact
+          methods: [GET, POST]
+          headers:
+            - name: X-Beta
+              value: "true"
+          queryParameters:
+            - name: version
+              value: "v[0-9]+"
+              match: RegularExpression
+          requestHeaders:
+            set:
+              X-Forwarded-Prefix: /api
+            remove: [X-Debug]
+          responseHeaders:
+            add:
+              X-Served-By: rig
+          rewritePathPrefix: /
+`,
+			expected: &gatewayv1.HTTPRoute{
+				Spec: gatewayv1.HTTPRouteSpec{
+					CommonRouteSpec: gatewayv1.CommonRouteSpec{ParentRefs: parentRefs},
+					Hostnames:       []gatewayv1.Hostname{"api.example.com"},
+					Rules: []gatewayv1.HTTPRouteRule{{
+						Matches: []gatewayv1.HTTPRouteMatch{
+							httpRouteMatch(gatewayv1.PathMatchPathPrefix, "/v1", gatewayv1.HTTPMethodGet),
+							httpRouteMatch(gatewayv1.PathMatchPathPrefix, "/v1", gatewayv1.HTTPMethodPost),
+							httpRouteMatch(gatewayv1.PathMatchExact, "/health", gatewayv1.HTTPMethodGet),
+							httpRouteMatch(gatewayv1.PathMatchExact, "/health", gatewayv1.HTTPMethodPost),
+						},
+						Filters: []gatewayv1.HTTPRouteFilter{
+							{
+								Type: gatewayv1.HTTPRouteFilterRequestHeaderModifier,
+								RequestHeaderModifier: &gatewayv1.HTTPHeaderFilter{
+									Set:    []gatewayv1.HTTPHeader{{Name: "X-Forwarded-Prefix", Value: "/api"}},
+									Remove: []string{"X-Debug"},
+								},
+							},
+							{
+								Type: gatewayv1.HTTPRouteFilterResponseHeaderModifier,
+								ResponseHeaderModifier: &gatewayv1.HTTPHeaderFilter{
+									Add: []gatewayv1.HTTPHeader{{Name: "X-Served-By", Value: "rig"}},
+								},
+							},
+							{
+								Type: gatewayv1.HTTPRouteFilterURLRewrite,
+								URLRewrite: &gatewayv1.HTTPURLRewriteFilter{
+									Path: &gatewayv1.HTTPPathModifier{
+										Type:               gatewayv1.PrefixMatchHTTPPathModifier,
+										ReplacePrefixMatch: ptr.New("/"),
+									},
+								},
+							},
+						},
+						BackendRefs: []gatewayv1.HTTPBackendRef{{BackendRef: backendRef}},
+					}},
+				},
+			},
+		},
+		{
+			name: "grpc route",
+			route: `
+          host: grpc.example.com
+          annotations:
+            plugin.rig.dev/grpc-route: "true"
+          paths:
+            - path: /api.v1.Users
+            - path: /api.v1.Projects/Get
+              match: Exact
+          headers:
+            - name: X-Tenant
+              value: "[a-z]+"
+              match: RegularExpression
+`,
+			expected: &gatewayv1.GRPCRoute{
+				Spec: gatewayv1.GRPCRouteSpec{
+					CommonRouteSpec: gatewayv1.CommonRouteSpec{ParentRefs: parentRefs},
+					Hostnames:       []gatewayv1.Hostname{"grpc.example.com"},
+					Rules: []gatewayv1.GRPCRouteRule{{
+						Matches: []gatewayv1.GRPCRouteMatch{
+							{
+								Method: &gatewayv1.GRPCMethodMatch{
+									Type:    ptr.New(gatewayv1.GRPCMethodMatchExact),
+									Service: ptr.New("api.v1.Users"),
+								},
+								Headers: []gatewayv1.GRPCHeaderMatch{grpcTenantHeader},
+							},
+							{
+								Method: &gatewayv1.GRPCMethodMatch{
+									Type:    ptr.New(gatewayv1.GRPCMethodMatchExact),
+									Service: ptr.New("api.v1.Projects"),
+									Method:  ptr.New("Get"),
+								},
+								Headers: []gatewayv1.GRPCHeaderMatch{grpcTenantHeader},
+							},
+						},
+						BackendRefs: []gatewayv1.GRPCBackendRef{{BackendRef: backendRef}},
+					}},
+				},
+			},
+		},
+		{
+			name: "grpc route matching all services on headers",
+			route: `
+          host: grpc.example.com
+          annotations:
+            plugin.rig.dev/grpc-route: "true"
+          headers:
+            - name: X-Tenant
+              value: "[a-z]+"
+              match: RegularExpression
+`,
+			expected: &gatewayv1.GRPCRoute{
+				Spec: gatewayv1.GRPCRouteSpec{
+					CommonRouteSpec: gatewayv1.CommonRouteSpec{ParentRefs: parentRefs},
+					Hostnames:       []gatewayv1.Hostname{"grpc.example.com"},
+					Rules: []gatewayv1.GRPCRouteRule{{
+						Matches: []gatewayv1.GRPCRouteMatch{{
+							Headers: []gatewayv1.GRPCHeaderMatch{grpcTenantHeader},
+						}},
+						BackendRefs: []gatewayv1.GRPCBackendRef{{BackendRef: backendRef}},
+					}},
+				},
+			},
+		},
+		{
+			name: "grpc route with methods",
+			route: `
+          host: grpc.example.com
+          annotations:
+            plugin.rig.dev/grpc-route: "true"
+          methods: [POST]
+`,
+			err: "route public: methods are not supported for gRPC routes",
+		},
+		{
+			name: "grpc route with path rewrite",
+			route: `
+          host: grpc.example.com
+          annotations:
+            plugin.rig.dev/grpc-route: "true"
+          rewritePathPrefix: /
+`,
+			err: "route public: path rewrites are not supported for gRPC routes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capsuleYAML := `
+apiVersion: rig.dev/v1alpha2
+kind: Capsule
+metadata:
+  name: api
+  namespace: prod
+spec:
+  image: nginx
+  interfaces:
+    - name: http
+      port: 8080
+      routes:
+        - id: public
+` + tt.route
+
+			h, err := plugintest.New(&Plugin{}, capsuleYAML, config)
+			require.NoError(t, err)
+
+			res, err := h.Run(context.Background())
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			var routes []client.Object
+			for _, o := range res.Objects {
+				switch o.(type) {
+				case *gatewayv1.HTTPRoute, *gatewayv1.GRPCRoute:
+					routes = append(routes, o)
+				}
+			}
+			require.Len(t, routes, 1)
+
+			route := routes[0]
+			require.Equal(t, "api-public", route.GetName())
+			require.Equal(t, "prod", route.GetNamespace())
+			require.Equal(t, "http", route.GetLabels()[pipeline.RigDevInterfaceLabel])
+			switch r := route.(type) {
+			case *gatewayv1.HTTPRoute:
+				require.IsType(t, tt.expected, r)
+				require.Equal(t, tt.expected.(*gatewayv1.HTTPRoute).Spec, r.Spec)
+			case *gatewayv1.GRPCRoute:
+				require.IsType(t, tt.expected, r)
+				require.Equal(t, tt.expected.(*gatewayv1.GRPCRoute).Spec, r.Spec)
+			}
+		})
+	}
+}
+
+var grpcTenantHeader = gatewayv1.GRPCHeaderMatch{
+	Type:  ptr.New(gatewayv1.HeaderMatchRegularExpression),
+	Name:  "X-Tenant",
+	Value: "[a-z]+",
+}
+
+func httpRouteMatch(
+	pathType gatewayv1.PathMatchType,
+	path string,
+	method gatewayv1.HTTPMethod,
+) gatewayv1.HTTPRouteMatch {
+	return gatewayv1.HTTPRouteMatch{
+		Path: &gatewayv1.HTTPPathMatch{
+			Type:  ptr.New(pathType),
+			Value: ptr.New(path),
+		},
+		Headers: []gatewayv1.HTTPHeaderMatch{{
+			Type:  ptr.New(gatewayv1.HeaderMatchExact),
+			Name:  "X-Beta",
+			Value: "true",
+		}},
+		QueryParams: []gatewayv1.HTTPQueryParamMatch{{
+			Type:  ptr.New(gatewayv1.QueryParamMatchRegularExpression),
+			Name:  "version",
+			Value: "v[0-9]+",
+		}},
+		Method: ptr.New(method),
+	}
+}
+
+func Test_createHTTPRouteRules(t *testing.T) {
+	backendRef := gatewayv1.BackendRef{
+		BackendObjectReference: gatewayv1.BackendObjectReference{Name: "api"},
+	}
+	route := func(paths int, methods ...string) v1alpha2.HostRoute {
+		r := v1alpha2.HostRoute{ID: "public", Methods: methods}
+		for i := range paths {
+			r.Paths = append(r.Paths, v1alpha2.HTTPPathRoute{Path: fmt.Sprintf("/v%d", i)})
+		}
+		return r
+	}
+
+	rules, err := createHTTPRouteRules(route(1), backendRef)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Len(t, rules[0].Matches, 1)
+
+	rules, err = createHTTPRouteRules(route(5, "GET", "POST"), backendRef)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Len(t, rules[0].Matches, 8)
+	require.Len(t, rules[1].Matches, 2)
+	require.Equal(t, "/v4", *rules[1].Matches[0].Path.Value)
+	for _, rule := range rules {
+		require.Equal(t, []gatewayv1.HTTPBackendRef{{BackendRef: backendRef}}, rule.BackendRefs)
+	}
+
+	rules, err = createHTTPRouteRules(route(64, "GET", "POST"), backendRef)
+	require.NoError(t, err)
+	require.Len(t, rules, 16)
+
+	_, err = createHTTPRouteRules(route(65, "GET", "POST"), backendRef)
+	require.EqualError(t, err, "route public: 130 combinations of paths and methods exceed the maximum of 128")
+}
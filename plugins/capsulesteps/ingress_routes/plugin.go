@@ -7,7 +7,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 
 	cmv1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	cmmetav1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
@@ -16,11 +20,11 @@ import (
 	"github.com/rigdev/rig/pkg/controller/plugin"
 	"github.com/rigdev/rig/pkg/pipeline"
 	"github.com/rigdev/rig/pkg/ptr"
-	"golang.org/x/exp/maps"
 	v1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 const Name = "rigdev.ingress_routes"
@@ -48,6 +52,17 @@ type Config struct {
 
 	// Annotations to be added to all ingress resources created.
 	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Gateway, if set, makes the plugin create Gateway API HTTPRoutes and
+	// GRPCRoutes attached to the given Gateway, instead of Ingress resources.
+	// TLS is then handled by the listeners of the Gateway.
+	Gateway *GatewayConfig `json:"gateway,omitempty"`
+
+	// Activator is the address, as `host:port`, of the activator which the
+	// routes of capsules scaling to zero are sent through. The activator wakes
+	// up sleeping capsules and holds requests until they are ready. With the
+	// Gateway API, the host must be the DNS name of the Service of the activator.
+	Activator string `json:"activator,omitempty"`
 }
 
 type Plugin struct {
@@ -63,6 +78,9 @@ func (p *Plugin) Initialize(req plugin.InitializeRequest) error {
 	if err := cmv1.AddToScheme(req.Scheme()); err != nil {
 		return err
 	}
+	if err := gatewayv1.Install(req.Scheme()); err != nil {
+		return err
+	}
 	p.configBytes = req.Config
 
 	if len(p.configBytes) > 0 {
@@ -83,7 +101,28 @@ func (p *Plugin) Run(_ context.Context, req pipeline.CapsuleRequest, _ hclog.Log
 		}
 	}
 
-	if capsuleHasIngress(req) {
+	if capsuleHasIngress(req) && scalesToZero(req) && config.Gateway == nil {
+		if err := createActivatorService(req, config); err != nil {
+			return err
+		}
+	}
+
+	if capsuleHasIngress(req) && config.Gateway != nil {
+		if config.Gateway.Name == "" {
+			return errors.New("gateway name is required when using gateway routes")
+		}
+
+		routes, err := p.createGatewayRoutes(req, config)
+		if err != nil {
+			return err
+		}
+
+		for _, route := range routes {
+			if err := req.Set(route); err != nil {
+				return err
+			}
+		}
+	} else if capsuleHasIngress(req) {
 		if !ingressIsSupported(p.config) {
 			return errors.New("ingress is not supported. Either disable TLS or set a cluster issuer")
 		}
@@ -120,7 +159,7 @@ func ingressIsSupported(cfg Config) bool {
 }
 
 func capsuleHasIngress(req pipeline.CapsuleRequest) bool {
-	for _, inf := range req.Capsule().Spec.Interfaces {
+	for _, inf := range req.Capsule().AllInterfaces() {
 		if (inf.Public != nil && inf.Public.Ingress != nil) || (len(inf.Routes) > 0) {
 			return true
 		}
@@ -128,7 +167,9 @@ func capsuleHasIngress(req pipeline.CapsuleRequest) bool {
 	return false
 }
 
-func getRoutes(inf v1alpha2.CapsuleInterface) []v1alpha2.HostRoute {
+// GetRoutes returns the routes of the interface, including the route of its
+// deprecated public ingress.
+func GetRoutes(inf v1alpha2.CapsuleInterface) []v1alpha2.HostRoute {
 	routes := inf.Routes
 	if inf.Public != nil && inf.Public.Ingress != nil {
 		paths := []v1alpha2.HTTPPathRoute{}
@@ -155,8 +196,8 @@ func getRouteName(req pipeline.CapsuleRequest, route v1alpha2.HostRoute) string
 func (p *Plugin) createCertificate(req pipeline.CapsuleRequest, cfg Config) []*cmv1.Certificate {
 	var crts []*cmv1.Certificate
 
-	for _, inf := range req.Capsule().Spec.Interfaces {
-		for _, route := range getRoutes(inf) {
+	for _, inf := range req.Capsule().AllInterfaces() {
+		for _, route := range GetRoutes(inf) {
 			name := getRouteName(req, route)
 
 			crt := &cmv1.Certificate{
@@ -187,9 +228,13 @@ func (p *Plugin) createCertificate(req pipeline.CapsuleRequest, cfg Config) []*c
 
 func (p *Plugin) createIngresses(req pipeline.CapsuleRequest, cfg Config) ([]*netv1.Ingress, error) {
 	albServiceCreated := false
+	routed, err := routedThroughActivator(req)
+	if err != nil {
+		return nil, err
+	}
 	var ingresses []*netv1.Ingress
-	for _, inf := range req.Capsule().Spec.Interfaces {
-		for _, route := range getRoutes(inf) {
+	for _, inf := range req.Capsule().AllInterfaces() {
+		for _, route := range GetRoutes(inf) {
 			name := getRouteName(req, route)
 			ing := createBasicIngress(req, cfg, name, inf.Name)
 			rule := netv1.IngressRule{
@@ -206,6 +251,30 @@ func (p *Plugin) createIngresses(req pipeline.CapsuleRequest, cfg Config) ([]*ne
 
 			serviceName := req.Capsule().Name
 
+			rewrite := false
+			if cfg.IngressClassName == "nginx" {
+				if err := setNginxRouteOptions(ing, route); err != nil {
+					return nil, err
+				}
+				rewrite = route.RewritePathPrefix != ""
+			} else if hasRouteOptions(route) {
+				return nil, fmt.Errorf(
+					"route %s: header, method and query parameter matching, header modifiers and path rewrites "+
+						"are only supported by the nginx ingress controller and the Gateway API", route.ID,
+				)
+			}
+
+			if scalesToZero(req) {
+				if cfg.IngressClassName != "nginx" {
+					return nil, errors.New(
+						"scale to zero is only supported by the nginx ingress controller and the Gateway API",
+					)
+				}
+				if routed {
+					serviceName = activatorServiceName(req)
+				}
+			}
+
 			switch cfg.IngressClassName {
 			case "alb":
 				targetType := ing.Annotations["alb.ingress.kubernetes.io/target-type"]
@@ -223,13 +292,17 @@ func (p *Plugin) createIngresses(req pipeline.CapsuleRequest, cfg Config) ([]*ne
 
 			if len(route.Paths) == 0 {
 				pathType := netv1.PathTypePrefix
-				if useImplementationSpecific {
+				path := "/"
+				if useImplementationSpecific || rewrite {
 					pathType = netv1.PathTypeImplementationSpecific
 				}
+				if rewrite {
+					path = nginxRewritePath(path)
+				}
 				rule.IngressRuleValue.HTTP.Paths = []netv1.HTTPIngressPath{
 					{
 						PathType: ptr.New(pathType),
-						Path:     "/",
+						Path:     path,
 						Backend: netv1.IngressBackend{
 							Service: &netv1.IngressServiceBackend{
 								Name: serviceName,
@@ -258,7 +331,11 @@ func (p *Plugin) createIngresses(req pipeline.CapsuleRequest, cfg Config) ([]*ne
 					default:
 						pt = ptr.New(netv1.PathTypePrefix)
 					}
-					if useImplementationSpecific {
+					p := path.Path
+					if rewrite {
+						p = nginxRewritePath(p)
+					}
+					if useImplementationSpecific || rewrite {
 						pt = ptr.New(netv1.PathTypeImplementationSpecific)
 					}
 
@@ -266,7 +343,7 @@ func (p *Plugin) createIngresses(req pipeline.CapsuleRequest, cfg Config) ([]*ne
 						rule.IngressRuleValue.HTTP.Paths,
 						netv1.HTTPIngressPath{
 							PathType: pt,
-							Path:     path.Path,
+							Path:     p,
 							Backend: netv1.IngressBackend{
 								Service: &netv1.IngressServiceBackend{
 									Name: serviceName,
@@ -297,6 +374,112 @@ func (p *Plugin) createIngresses(req pipeline.CapsuleRequest, cfg Config) ([]*ne
 	return ingresses, nil
 }
 
+func hasRouteOptions(route v1alpha2.HostRoute) bool {
+	return len(route.Headers) > 0 || len(route.Methods) > 0 || len(route.QueryParameters) > 0 ||
+		route.RequestHeaders != nil || route.ResponseHeaders != nil || route.RewritePathPrefix != ""
+}
+
+var nginxVariableRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// setNginxRouteOptions implements the matching and header modification of the
+// route through a configuration snippet, and path rewrites through a rewrite
+// target. The nginx ingress controller cannot fall through to other routes,
+// so requests not matching the headers, methods or query parameters of the
+// route are rejected.
+func setNginxRouteOptions(ing *netv1.Ingress, route v1alpha2.HostRoute) error {
+	var lines []string
+
+	if len(route.Methods) > 0 {
+		lines = append(lines, fmt.Sprintf(
+			"if ($request_method !~ ^(%s)$) { return 405; }", strings.Join(route.Methods, "|"),
+		))
+	}
+	for _, h := range route.Headers {
+		variable := "$http_" + strings.ToLower(strings.ReplaceAll(h.Name, "-", "_"))
+		lines = append(lines, nginxMatchCondition(variable, h.Value, h.Match))
+	}
+	for _, q := range route.QueryParameters {
+		if !nginxVariableRegexp.MatchString(q.Name) {
+			return fmt.Errorf(
+				"route %s: query parameter %q is not supported by the nginx ingress controller", route.ID, q.Name,
+			)
+		}
+		lines = append(lines, nginxMatchCondition("$arg_"+q.Name, q.Value, q.Match))
+	}
+
+	if m := route.RequestHeaders; m != nil {
+		if len(m.Add) > 0 {
+			return fmt.Errorf(
+				"route %s: adding request headers is not supported by the nginx ingress controller, use set instead",
+				route.ID,
+			)
+		}
+		for _, name := range slices.Sorted(maps.Keys(m.Set)) {
+			lines = append(lines, fmt.Sprintf("proxy_set_header %s %s;", name, nginxQuote(m.Set[name])))
+		}
+		for _, name := range m.Remove {
+			lines = append(lines, fmt.Sprintf("proxy_set_header %s \"\";", name))
+		}
+	}
+
+	if m := route.ResponseHeaders; m != nil {
+		for _, name := range slices.Sorted(maps.Keys(m.Set)) {
+			lines = append(lines, fmt.Sprintf("more_set_headers %s;", nginxQuote(name+": "+m.Set[name])))
+		}
+		for _, name := range slices.Sorted(maps.Keys(m.Add)) {
+			lines = append(lines, fmt.Sprintf("add_header %s %s always;", name, nginxQuote(m.Add[name])))
+		}
+		for _, name := range m.Remove {
+			lines = append(lines, fmt.Sprintf("more_clear_headers %s;", nginxQuote(name)))
+		}
+	}
+
+	appendNginxSnippet(ing, lines)
+
+	if route.RewritePathPrefix != "" {
+		ing.Annotations["nginx.ingress.kubernetes.io/use-regex"] = "true"
+		ing.Annotations["nginx.ingress.kubernetes.io/rewrite-target"] =
+			strings.TrimSuffix(route.RewritePathPrefix, "/") + "/$2"
+	}
+
+	return nil
+}
+
+func appendNginxSnippet(ing *netv1.Ingress, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	const key = "nginx.ingress.kubernetes.io/configuration-snippet"
+	if snippet := ing.Annotations[key]; snippet != "" {
+		lines = append([]string{strings.TrimSuffix(snippet, "\n")}, lines...)
+	}
+	ing.Annotations[key] = strings.Join(lines, "\n") + "\n"
+}
+
+func nginxMatchCondition(variable, value string, match v1alpha2.ValueMatchType) string {
+	op := "!="
+	if match == v1alpha2.ValueRegularExpression {
+		op = "!~"
+	}
+	return fmt.Sprintf("if (%s %s %s) { return 404; }", variable, op, nginxQuote(value))
+}
+
+func nginxQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// nginxRewritePath turns a path prefix into a regular expression capturing
+// the rest of the path as `$2`, which is used by the rewrite target.
+func nginxRewritePath(prefix string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		// Paths must be absolute, the empty group keeps the rest at `$2`.
+		return "/()(.*)"
+	}
+	return prefix + "(/|$)(.*)"
+}
+
 func createBasicIngress(req pipeline.CapsuleRequest, cfg Config, name, interfaceName string) *netv1.Ingress {
 	var ingressClassName *string
 	if cfg.IngressClassName != "" {
@@ -347,7 +530,7 @@ func createAlbService(req pipeline.CapsuleRequest) error {
 		},
 	}
 
-	for _, inf := range req.Capsule().Spec.Interfaces {
+	for _, inf := range req.Capsule().AllInterfaces() {
 		albService.Spec.Ports = append(albService.Spec.Ports, v1.ServicePort{
 			Name:       inf.Name,
 			Port:       inf.Port,
@@ -356,3 +539,55 @@ func createAlbService(req pipeline.CapsuleRequest) error {
 	}
 	return req.Set(albService)
 }
+
+// CanaryIngress returns a copy of the given ingress, sending the given weight
+// (in percent) of its traffic to the canary service instead of the capsule
+// service. Weighted ingresses are only supported by the nginx ingress
+// controller, for all other ingresses false is returned.
+func CanaryIngress(
+	ing *netv1.Ingress,
+	capsuleName, canaryServiceName string,
+	weight uint32,
+) (*netv1.Ingress, bool) {
+	if !isNginxIngress(ing) {
+		return nil, false
+	}
+
+	canary := ing.DeepCopy()
+	canary.SetName(fmt.Sprintf("%s-%s", ing.GetName(), pipeline.RolloutTrackCanary))
+	canary.SetResourceVersion("")
+	canary.SetUID("")
+	if canary.Labels == nil {
+		canary.Labels = map[string]string{}
+	}
+	canary.Labels[pipeline.LabelRolloutTrack] = pipeline.RolloutTrackCanary
+
+	// TLS and certificates are handled by the main ingress.
+	canary.Spec.TLS = nil
+	delete(canary.Annotations, "cert-manager.io/cluster-issuer")
+	if canary.Annotations == nil {
+		canary.Annotations = map[string]string{}
+	}
+	canary.Annotations["nginx.ingress.kubernetes.io/canary"] = "true"
+	canary.Annotations["nginx.ingress.kubernetes.io/canary-weight"] = strconv.FormatUint(uint64(weight), 10)
+
+	for _, rule := range canary.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for i, path := range rule.HTTP.Paths {
+			if path.Backend.Service != nil && path.Backend.Service.Name == capsuleName {
+				rule.HTTP.Paths[i].Backend.Service.Name = canaryServiceName
+			}
+		}
+	}
+
+	return canary, true
+}
+
+func isNginxIngress(ing *netv1.Ingress) bool {
+	if ing.Spec.IngressClassName != nil {
+		return *ing.Spec.IngressClassName == "nginx"
+	}
+	return ing.Annotations["kubernetes.io/ingress.class"] == "nginx"
+}
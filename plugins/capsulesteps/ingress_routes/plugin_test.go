@@ -0,0 +1,229 @@
+//nolint:revive
+package ingress_routes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rigdev/rig/pkg/controller/plugin/plugintest"
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/stretchr/testify/require"
+	netv1 "k8s.io/api/networking/v1"
+)
+
+func Test_Run_Ingress(t *testing.T) {
+	tests := []struct {
+		name             string
+		ingressClassName string
+		route            string
+		paths            []netv1.HTTPIngressPath
+		annotations      map[string]string
+		err              string
+	}{
+		{
+			name: "all paths",
+			route: `
+          host: api.example.com
+`,
+			paths: []netv1.HTTPIngressPath{{
+				Path:     "/",
+				PathType: ptr.New(netv1.PathTypePrefix),
+				Backend:  backend("api", "http"),
+			}},
+			annotations: map[string]string{},
+		},
+		{
+			name: "path match types",
+			route: `
+          host: api.example.com
+          paths:
+            - path: /v1
+            - path: /health
+              match: Exact
+            - path: /v[0-9]+/users
+              match: RegularExpression
+`,
+			paths: []netv1.HTTPIngressPath{
+				{Path: "/v1", PathType: ptr.New(netv1.PathTypePrefix), Backend: backend("api", "http")},
+				{Path: "/health", PathType: ptr.New(netv1.PathTypeExact), Backend: backend("api", "http")},
+				{
+					Path:     "/v[0-9]+/users",
+					PathType: ptr.New(netv1.PathTypeImplementationSpecific),
+					Backend:  backend("api", "http"),
+				},
+			},
+			annotations: map[string]string{"nginx.ingress.kubernetes.io/use-regex": "true"},
+		},
+		{
+			name: "header match",
+			route: `
+          host: api.example.com
+          headers:
+            - name: X-Beta
+              value: "true"
+            - name: X-Version
+              value: v[0-9]+
+              match: RegularExpression
+`,
+			paths: []netv1.HTTPIngressPath{{
+				Path:     "/",
+				PathType: ptr.New(netv1.PathTypePrefix),
+				Backend:  backend("api", "http"),
+			}},
+			annotations: map[string]string{
+				"nginx.ingress.kubernetes.io/configuration-snippet": "if ($http_x_beta != \"true\") { return 404; }\n" +
+					"if ($http_x_version !~ \"v[0-9]+\") { return 404; }\n",
+			},
+		},
+		{
+			name: "method and query parameter match",
+			route: `
+          host: api.example.com
+          methods: [GET, HEAD]
+          queryParameters:
+            - name: debug
+              value: "1"
+`,
+			paths: []netv1.HTTPIngressPath{{
+				Path:     "/",
+				PathType: ptr.New(netv1.PathTypePrefix),
+				Backend:  backend("api", "http"),
+			}},
+			annotations: map[string]string{
+				"nginx.ingress.kubernetes.io/configuration-snippet": "if ($request_method !~ ^(GET|HEAD)$) { return 405; }\n" +
+					"if ($arg_debug != \"1\") { return 404; }\n",
+			},
+		},
+		{
+			name: "unsupported query parameter",
+			route: `
+          host: api.example.com
+          queryParameters:
+            - name: filter[name]
+              value: a
+`,
+			err: `route public: query parameter "filter[name]" is not supported by the nginx ingress controller`,
+		},
+		{
+			name: "header modifiers",
+			route: `
+          host: api.example.com
+          requestHeaders:
+            set:
+              X-Forwarded-Prefix: /api
+            remove: [X-Debug]
+          responseHeaders:
+            add:
+              Cache-Control: no-cache
+`,
+			paths: []netv1.HTTPIngressPath{{
+				Path:     "/",
+				PathType: ptr.New(netv1.PathTypePrefix),
+				Backend:  backend("api", "http"),
+			}},
+			annotations: map[string]string{
+				"nginx.ingress.kubernetes.io/configuration-snippet": "proxy_set_header X-Forwarded-Prefix \"/api\";\n" +
+					"proxy_set_header X-Debug \"\";\n" +
+					"add_header Cache-Control \"no-cache\" always;\n",
+			},
+		},
+		{
+			name: "adding request headers",
+			route: `
+          host: api.example.com
+          requestHeaders:
+            add:
+              X-Debug: "1"
+`,
+			err: "route public: adding request headers is not supported by the nginx ingress controller, use set instead",
+		},
+		{
+			name: "path rewrite",
+			route: `
+          host: api.example.com
+          paths:
+            - path: /api
+          rewritePathPrefix: /
+`,
+			paths: []netv1.HTTPIngressPath{{
+				Path:     "/api(/|$)(.*)",
+				PathType: ptr.New(netv1.PathTypeImplementationSpecific),
+				Backend:  backend("api", "http"),
+			}},
+			annotations: map[string]string{
+				"nginx.ingress.kubernetes.io/use-regex":      "true",
+				"nginx.ingress.kubernetes.io/rewrite-target": "/$2",
+			},
+		},
+		{
+			name:             "route options without nginx",
+			ingressClassName: "alb",
+			route: `
+          host: api.example.com
+          methods: [GET]
+`,
+			err: "route public: header, method and query parameter matching, header modifiers and path rewrites " +
+				"are only supported by the nginx ingress controller and the Gateway API",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capsuleYAML := `
+apiVersion: rig.dev/v1alpha2
+kind: Capsule
+metadata:
+  name: api
+  namespace: prod
+spec:
+  image: nginx
+  interfaces:
+    - name: http
+      port: 8080
+      routes:
+        - id: public
+` + tt.route
+
+			ingressClassName := tt.ingressClassName
+			if ingressClassName == "" {
+				ingressClassName = "nginx"
+			}
+			config := "ingressClassName: " + ingressClassName + "\ndisableTLS: true\n"
+
+			h, err := plugintest.New(&Plugin{}, capsuleYAML, config)
+			require.NoError(t, err)
+
+			res, err := h.Run(context.Background())
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			var ingresses []*netv1.Ingress
+			for _, o := range res.Objects {
+				if ing, ok := o.(*netv1.Ingress); ok {
+					ingresses = append(ingresses, ing)
+				}
+			}
+			require.Len(t, ingresses, 1)
+
+			ing := ingresses[0]
+			require.Equal(t, "api-public", ing.Name)
+			require.Equal(t, ptr.New("nginx"), ing.Spec.IngressClassName)
+			require.Equal(t, tt.annotations, ing.Annotations)
+			require.Len(t, ing.Spec.Rules, 1)
+			require.Equal(t, "api.example.com", ing.Spec.Rules[0].Host)
+			require.Equal(t, tt.paths, ing.Spec.Rules[0].HTTP.Paths)
+		})
+	}
+}
+
+func backend(service, port string) netv1.IngressBackend {
+	return netv1.IngressBackend{
+		Service: &netv1.IngressServiceBackend{
+			Name: service,
+			Port: netv1.ServiceBackendPort{Name: port},
+		},
+	}
+}
@@ -14,8 +14,11 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 	corev1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 func toIngressStatus(ingress *netv1.Ingress) *apipipeline.ObjectStatusInfo {
@@ -56,6 +59,13 @@ func toIngressStatus(ingress *netv1.Ingress) *apipipeline.ObjectStatusInfo {
 	parts := strings.Split(ingress.GetName(), "-")
 	routeID := parts[len(parts)-1]
 	status.Conditions = append(status.Conditions, lbCondition)
+
+	// Canary ingresses are a weighted copy of the ingress of a route, and not a route by themselves.
+	if ingress.GetLabels()[pipeline.LabelRolloutTrack] == pipeline.RolloutTrackCanary {
+		status.Properties["Canary weight"] = ingress.GetAnnotations()["nginx.ingress.kubernetes.io/canary-weight"] + "%"
+		return status
+	}
+
 	status.PlatformStatus = append(status.PlatformStatus, &apipipeline.PlatformObjectStatus{
 		Name: routeID,
 		Kind: &apipipeline.PlatformObjectStatus_Route{
@@ -164,6 +174,136 @@ func (p *Plugin) onIngressUpdated(
 	return toIngressStatus(ingress)
 }
 
+func onHTTPRouteUpdated(
+	obj client.Object,
+	_ []*corev1.Event,
+	_ plugin.ObjectWatcher,
+) *apipipeline.ObjectStatusInfo {
+	route := obj.(*gatewayv1.HTTPRoute)
+	return toGatewayRouteStatus(route, route.Spec.Hostnames, route.Status.RouteStatus)
+}
+
+func onGRPCRouteUpdated(
+	obj client.Object,
+	_ []*corev1.Event,
+	_ plugin.ObjectWatcher,
+) *apipipeline.ObjectStatusInfo {
+	route := obj.(*gatewayv1.GRPCRoute)
+	return toGatewayRouteStatus(route, route.Spec.Hostnames, route.Status.RouteStatus)
+}
+
+func toGatewayRouteStatus(
+	route client.Object,
+	hostnames []gatewayv1.Hostname,
+	routeStatus gatewayv1.RouteStatus,
+) *apipipeline.ObjectStatusInfo {
+	status := &apipipeline.ObjectStatusInfo{
+		Properties: map[string]string{},
+	}
+
+	var hosts []string
+	for _, h := range hostnames {
+		hosts = append(hosts, string(h))
+	}
+	status.Properties["Hosts"] = strings.Join(hosts, ", ")
+
+	// The conditions are reported by the controller of each Gateway the route is attached to.
+	for _, parent := range routeStatus.Parents {
+		status.Conditions = append(status.Conditions,
+			gatewayRouteCondition(parent, gatewayv1.RouteConditionAccepted, "Accepted"),
+			gatewayRouteCondition(parent, gatewayv1.RouteConditionResolvedRefs, "Resolved refs"),
+		)
+	}
+	if len(routeStatus.Parents) == 0 {
+		status.Conditions = append(status.Conditions, &apipipeline.ObjectCondition{
+			Name:    "Accepted",
+			State:   apipipeline.ObjectState_OBJECT_STATE_PENDING,
+			Message: "Waiting for the route to be accepted by the Gateway",
+		})
+	}
+
+	var host string
+	if len(hosts) > 0 {
+		host = hosts[0]
+	}
+	parts := strings.Split(route.GetName(), "-")
+	routeID := parts[len(parts)-1]
+	status.PlatformStatus = append(status.PlatformStatus, &apipipeline.PlatformObjectStatus{
+		Name: routeID,
+		Kind: &apipipeline.PlatformObjectStatus_Route{
+			Route: &apipipeline.RouteStatus{
+				Id:            routeID,
+				Host:          host,
+				InterfaceName: route.GetLabels()[pipeline.RigDevInterfaceLabel],
+			},
+		},
+	})
+
+	return status
+}
+
+func gatewayRouteCondition(
+	parent gatewayv1.RouteParentStatus,
+	conditionType gatewayv1.RouteConditionType,
+	name string,
+) *apipipeline.ObjectCondition {
+	if len(parent.ParentRef.Name) > 0 {
+		name = fmt.Sprintf("%s by %s", name, parent.ParentRef.Name)
+	}
+
+	cond := &apipipeline.ObjectCondition{
+		Name:  name,
+		State: apipipeline.ObjectState_OBJECT_STATE_PENDING,
+	}
+
+	c := meta.FindStatusCondition(parent.Conditions, string(conditionType))
+	if c == nil {
+		cond.Message = "Waiting for the Gateway to report status"
+		return cond
+	}
+
+	cond.Message = c.Message
+	cond.UpdatedAt = timestamppb.New(c.LastTransitionTime.Time)
+	switch c.Status {
+	case metav1.ConditionTrue:
+		cond.State = apipipeline.ObjectState_OBJECT_STATE_HEALTHY
+	case metav1.ConditionFalse:
+		cond.State = apipipeline.ObjectState_OBJECT_STATE_ERROR
+		if cond.Message == "" {
+			cond.Message = c.Reason
+		}
+	}
+
+	return cond
+}
+
 func (p *Plugin) WatchObjectStatus(ctx context.Context, watcher plugin.CapsuleWatcher) error {
-	return watcher.WatchPrimary(ctx, &netv1.Ingress{}, p.onIngressUpdated)
+	if p.config.Gateway == nil {
+		return watcher.WatchPrimary(ctx, &netv1.Ingress{}, p.onIngressUpdated)
+	}
+
+	errChan := make(chan error, 1)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go runWatch(ctx, watcher, &gatewayv1.HTTPRoute{}, onHTTPRouteUpdated, errChan)
+	go runWatch(ctx, watcher, &gatewayv1.GRPCRoute{}, onGRPCRouteUpdated, errChan)
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func runWatch(ctx context.Context,
+	watcher plugin.CapsuleWatcher,
+	obj client.Object,
+	cb plugin.WatchCallback,
+	errChan chan error,
+) {
+	if err := watcher.WatchPrimary(ctx, obj, cb); err != nil {
+		errChan <- err
+	}
 }
@@ -0,0 +1,133 @@
+//nolint:revive
+package ingress_routes
+
+import (
+	"testing"
+	"time"
+
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func Test_onGatewayRouteUpdated(t *testing.T) {
+	transitioned := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	parent := func(conditions ...metav1.Condition) gatewayv1.RouteParentStatus {
+		return gatewayv1.RouteParentStatus{
+			ParentRef:  gatewayv1.ParentReference{Name: "public"},
+			Conditions: conditions,
+		}
+	}
+	routeStatus := &apipipeline.PlatformObjectStatus{
+		Name: "public",
+		Kind: &apipipeline.PlatformObjectStatus_Route{
+			Route: &apipipeline.RouteStatus{
+				Id:            "public",
+				Host:          "api.example.com",
+				InterfaceName: "http",
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		parents    []gatewayv1.RouteParentStatus
+		conditions []*apipipeline.ObjectCondition
+	}{
+		{
+			name: "not attached",
+			conditions: []*apipipeline.ObjectCondition{{
+				Name:    "Accepted",
+				State:   apipipeline.ObjectState_OBJECT_STATE_PENDING,
+				Message: "Waiting for the route to be accepted by the Gateway",
+			}},
+		},
+		{
+			name:    "waiting for gateway",
+			parents: []gatewayv1.RouteParentStatus{parent()},
+			conditions: []*apipipeline.ObjectCondition{
+				{
+					Name:    "Accepted by public",
+					State:   apipipeline.ObjectState_OBJECT_STATE_PENDING,
+					Message: "Waiting for the Gateway to report status",
+				},
+				{
+					Name:    "Resolved refs by public",
+					State:   apipipeline.ObjectState_OBJECT_STATE_PENDING,
+					Message: "Waiting for the Gateway to report status",
+				},
+			},
+		},
+		{
+			name: "accepted with unresolved refs",
+			parents: []gatewayv1.RouteParentStatus{parent(
+				metav1.Condition{
+					Type:               string(gatewayv1.RouteConditionAccepted),
+					Status:             metav1.ConditionTrue,
+					Message:            "Route is accepted",
+					LastTransitionTime: transitioned,
+				},
+				metav1.Condition{
+					Type:               string(gatewayv1.RouteConditionResolvedRefs),
+					Status:             metav1.ConditionFalse,
+					Reason:             string(gatewayv1.RouteReasonBackendNotFound),
+					LastTransitionTime: transitioned,
+				},
+			)},
+			conditions: []*apipipeline.ObjectCondition{
+				{
+					Name:      "Accepted by public",
+					State:     apipipeline.ObjectState_OBJECT_STATE_HEALTHY,
+					Message:   "Route is accepted",
+					UpdatedAt: timestamppb.New(transitioned.Time),
+				},
+				{
+					Name:      "Resolved refs by public",
+					State:     apipipeline.ObjectState_OBJECT_STATE_ERROR,
+					Message:   string(gatewayv1.RouteReasonBackendNotFound),
+					UpdatedAt: timestamppb.New(transitioned.Time),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := metav1.ObjectMeta{
+				Name:      "api-public",
+				Namespace: "prod",
+				Labels:    map[string]string{pipeline.RigDevInterfaceLabel: "http"},
+			}
+			hostnames := []gatewayv1.Hostname{"api.example.com"}
+			expected := &apipipeline.ObjectStatusInfo{
+				Properties:     map[string]string{"Hosts": "api.example.com"},
+				Conditions:     tt.conditions,
+				PlatformStatus: []*apipipeline.PlatformObjectStatus{routeStatus},
+			}
+
+			httpRoute := &gatewayv1.HTTPRoute{
+				ObjectMeta: meta,
+				Spec:       gatewayv1.HTTPRouteSpec{Hostnames: hostnames},
+				Status: gatewayv1.HTTPRouteStatus{
+					RouteStatus: gatewayv1.RouteStatus{Parents: tt.parents},
+				},
+			}
+			status := onHTTPRouteUpdated(httpRoute, nil, nil)
+			require.True(t, proto.Equal(expected, status), "HTTPRoute status: %v", status)
+
+			grpcRoute := &gatewayv1.GRPCRoute{
+				ObjectMeta: meta,
+				Spec:       gatewayv1.GRPCRouteSpec{Hostnames: hostnames},
+				Status: gatewayv1.GRPCRouteStatus{
+					RouteStatus: gatewayv1.RouteStatus{Parents: tt.parents},
+				},
+			}
+			status = onGRPCRouteUpdated(grpcRoute, nil, nil)
+			require.True(t, proto.Equal(expected, status), "GRPCRoute status: %v", status)
+		})
+	}
+}
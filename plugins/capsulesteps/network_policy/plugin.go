@@ -0,0 +1,283 @@
+// +groupName=plugins.rig.dev -- Only used for config doc generation
+//
+//nolint:revive
+package network_policy
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/controller/plugin"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	Name = "rigdev.network_policy"
+
+	// labelNamespaceName is set by Kubernetes on all namespaces.
+	labelNamespaceName = "kubernetes.io/metadata.name"
+
+	defaultIngressControllerNamespace = "ingress-nginx"
+	defaultDNSNamespace               = "kube-system"
+)
+
+var (
+	defaultDNSPodLabels       = map[string]string{"k8s-app": "kube-dns"}
+	defaultActivatorPodLabels = map[string]string{"app.kubernetes.io/name": "rig-operator-activator"}
+)
+
+// Configuration for the network policy plugin
+// +kubebuilder:object:root=true
+type Config struct {
+	// IngressController are the pods of the ingress controller, or gateway,
+	// which can connect to public interfaces and interfaces with routes.
+	// Defaults to all pods in the `ingress-nginx` namespace.
+	IngressController *Peer `json:"ingressController,omitempty"`
+
+	// Activator are the pods of the activator, which can connect to public
+	// interfaces and interfaces with routes of capsules scaling to zero.
+	// Defaults to pods with the label
+	// `app.kubernetes.io/name: rig-operator-activator` in all namespaces.
+	Activator *Peer `json:"activator,omitempty"`
+
+	// AllowFrom are peers which can connect to the interfaces of all
+	// capsules, fx. a Prometheus scraping the capsules.
+	AllowFrom []Peer `json:"allowFrom,omitempty"`
+
+	// Egress, if enabled, also restricts the traffic from capsules. Capsules
+	// can then only connect to the cluster DNS, the peers of the egress
+	// config, the capsules and namespaces they allow and the capsules they
+	// depend on.
+	Egress EgressConfig `json:"egress,omitempty"`
+}
+
+// EgressConfig configures the egress rules of the network policies.
+type EgressConfig struct {
+	// Enabled restricts the egress traffic of capsules.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// DNS are the pods of the cluster DNS, which capsules can connect to on
+	// port 53. Defaults to pods with the label `k8s-app: kube-dns` in the
+	// `kube-system` namespace.
+	DNS *Peer `json:"dns,omitempty"`
+
+	// AllowTo are peers which all capsules can connect to, fx. the CIDR of
+	// an external database.
+	AllowTo []Peer `json:"allowTo,omitempty"`
+}
+
+// Peer is a set of pods, or a block of IP addresses.
+type Peer struct {
+	// Namespace of the pods. If empty, pods in all namespaces are matched.
+	Namespace string `json:"namespace,omitempty"`
+
+	// PodLabels the pods must have. If empty, all pods of the namespace are
+	// matched.
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+
+	// CIDR is a block of IP addresses, fx. `10.0.0.0/16`. If set, the
+	// namespace and pod labels are ignored.
+	CIDR string `json:"cidr,omitempty"`
+}
+
+type Plugin struct {
+	plugin.NoWatchObjectStatus
+
+	configBytes []byte
+}
+
+func (p *Plugin) Initialize(req plugin.InitializeRequest) error {
+	p.configBytes = req.Config
+	return nil
+}
+
+func (p *Plugin) ComputeConfig(ctx context.Context, req pipeline.CapsuleRequest, logger hclog.Logger) (string, error) {
+	return plugin.ParseCapsuleTemplatedConfigToString[Config](p.configBytes, req)
+}
+
+func (p *Plugin) Run(ctx context.Context, req pipeline.CapsuleRequest, logger hclog.Logger) error {
+	var cfg Config
+	if len(p.configBytes) > 0 {
+		var err error
+		cfg, err = plugin.ParseTemplatedConfig[Config](p.configBytes, req, plugin.CapsuleStep[Config])
+		if err != nil {
+			return err
+		}
+	}
+
+	return req.Set(createNetworkPolicy(req.Capsule(), cfg))
+}
+
+func createNetworkPolicy(capsule *v1alpha2.Capsule, cfg Config) *netv1.NetworkPolicy {
+	np := &netv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "NetworkPolicy",
+			APIVersion: netv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      capsule.Name,
+			Namespace: capsule.Namespace,
+			Labels: map[string]string{
+				pipeline.LabelCapsule: capsule.Name,
+			},
+		},
+		Spec: netv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					pipeline.LabelCapsule: capsule.Name,
+				},
+			},
+			PolicyTypes: []netv1.PolicyType{netv1.PolicyTypeIngress},
+			// Without rules, all ingress traffic is denied.
+			Ingress: ingressRules(capsule, cfg),
+		},
+	}
+
+	if cfg.Egress.Enabled {
+		np.Spec.PolicyTypes = append(np.Spec.PolicyTypes, netv1.PolicyTypeEgress)
+		np.Spec.Egress = egressRules(capsule, cfg)
+	}
+
+	return np
+}
+
+func ingressRules(capsule *v1alpha2.Capsule, cfg Config) []netv1.NetworkPolicyIngressRule {
+	var all, public, loadBalancer []netv1.NetworkPolicyPort
+	for _, inf := range capsule.AllInterfaces() {
+		port := tcpPort(inf.Port)
+		all = append(all, port)
+		switch {
+		case inf.Public != nil && inf.Public.LoadBalancer != nil:
+			loadBalancer = append(loadBalancer, port)
+		case inf.Public != nil && inf.Public.Ingress != nil, len(inf.Routes) > 0:
+			public = append(public, port)
+		}
+	}
+	if len(all) == 0 {
+		return nil
+	}
+
+	var rules []netv1.NetworkPolicyIngressRule
+
+	var from []netv1.NetworkPolicyPeer
+	for _, peer := range cfg.AllowFrom {
+		from = append(from, peer.toK8s())
+	}
+	if capsule.Spec.NetworkPolicy != nil {
+		for _, peer := range capsule.Spec.NetworkPolicy.AllowFrom {
+			from = append(from, capsulePeer(capsule, peer))
+		}
+	}
+	if len(from) > 0 {
+		rules = append(rules, netv1.NetworkPolicyIngressRule{Ports: all, From: from})
+	}
+
+	if len(public) > 0 {
+		ingressController := Peer{Namespace: defaultIngressControllerNamespace}
+		if cfg.IngressController != nil {
+			ingressController = *cfg.IngressController
+		}
+		from := []netv1.NetworkPolicyPeer{ingressController.toK8s()}
+
+		// Requests to capsules scaling to zero are sent through the activator.
+		if capsule.Spec.Scale.Horizontal.ScaleToZero != nil {
+			activator := Peer{PodLabels: defaultActivatorPodLabels}
+			if cfg.Activator != nil {
+				activator = *cfg.Activator
+			}
+			from = append(from, activator.toK8s())
+		}
+
+		rules = append(rules, netv1.NetworkPolicyIngressRule{
+			Ports: public,
+			From:  from,
+		})
+	}
+
+	if len(loadBalancer) > 0 {
+		// Traffic through load balancers comes from outside the cluster, so
+		// it is allowed from everywhere.
+		rules = append(rules, netv1.NetworkPolicyIngressRule{Ports: loadBalancer})
+	}
+
+	return rules
+}
+
+func egressRules(capsule *v1alpha2.Capsule, cfg Config) []netv1.NetworkPolicyEgressRule {
+	dns := Peer{Namespace: defaultDNSNamespace, PodLabels: defaultDNSPodLabels}
+	if cfg.Egress.DNS != nil {
+		dns = *cfg.Egress.DNS
+	}
+
+	rules := []netv1.NetworkPolicyEgressRule{{
+		Ports: []netv1.NetworkPolicyPort{
+			{Protocol: ptr.New(v1.ProtocolUDP), Port: ptr.New(intstr.FromInt32(53))},
+			{Protocol: ptr.New(v1.ProtocolTCP), Port: ptr.New(intstr.FromInt32(53))},
+		},
+		To: []netv1.NetworkPolicyPeer{dns.toK8s()},
+	}}
+
+	var to []netv1.NetworkPolicyPeer
+	for _, peer := range cfg.Egress.AllowTo {
+		to = append(to, peer.toK8s())
+	}
+	if capsule.Spec.NetworkPolicy != nil {
+		for _, peer := range capsule.Spec.NetworkPolicy.AllowTo {
+			to = append(to, capsulePeer(capsule, peer))
+		}
+	}
+	for _, dep := range capsule.Spec.Dependencies {
+		to = append(to, capsulePeer(capsule, v1alpha2.NetworkPeer{Capsule: dep.Capsule, Namespace: dep.Namespace}))
+	}
+	if len(to) > 0 {
+		rules = append(rules, netv1.NetworkPolicyEgressRule{To: to})
+	}
+
+	return rules
+}
+
+// capsulePeer returns the pods of a peer of the capsule, where the namespace
+// defaults to the namespace of the capsule.
+func capsulePeer(capsule *v1alpha2.Capsule, peer v1alpha2.NetworkPeer) netv1.NetworkPolicyPeer {
+	p := Peer{Namespace: peer.Namespace}
+	if p.Namespace == "" {
+		p.Namespace = capsule.Namespace
+	}
+	if peer.Capsule != "" {
+		p.PodLabels = map[string]string{pipeline.LabelCapsule: peer.Capsule}
+	}
+	return p.toK8s()
+}
+
+func (p Peer) toK8s() netv1.NetworkPolicyPeer {
+	if p.CIDR != "" {
+		return netv1.NetworkPolicyPeer{IPBlock: &netv1.IPBlock{CIDR: p.CIDR}}
+	}
+
+	// An empty namespace selector matches all namespaces, whereas a nil one
+	// only matches the namespace of the policy.
+	peer := netv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{},
+		PodSelector:       &metav1.LabelSelector{},
+	}
+	if p.Namespace != "" {
+		peer.NamespaceSelector.MatchLabels = map[string]string{labelNamespaceName: p.Namespace}
+	}
+	if len(p.PodLabels) > 0 {
+		peer.PodSelector.MatchLabels = p.PodLabels
+	}
+	return peer
+}
+
+func tcpPort(port int32) netv1.NetworkPolicyPort {
+	return netv1.NetworkPolicyPort{
+		Protocol: ptr.New(v1.ProtocolTCP),
+		Port:     ptr.New(intstr.FromInt32(port)),
+	}
+}
@@ -0,0 +1,130 @@
+//nolint:revive
+package network_policy
+
+import (
+	"testing"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func namespacePeer(namespace string, podLabels map[string]string) netv1.NetworkPolicyPeer {
+	return netv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{labelNamespaceName: namespace}},
+		PodSelector:       &metav1.LabelSelector{MatchLabels: podLabels},
+	}
+}
+
+func Test_createNetworkPolicy(t *testing.T) {
+	capsule := &v1alpha2.Capsule{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"},
+		Spec: v1alpha2.CapsuleSpec{
+			Interfaces: []v1alpha2.CapsuleInterface{
+				{Name: "grpc", Port: 9000},
+				{Name: "http", Port: 8080, Public: &v1alpha2.CapsulePublicInterface{
+					Ingress: &v1alpha2.CapsuleInterfaceIngress{Host: "api.example.com"},
+				}},
+				{Name: "tcp", Port: 5000, Public: &v1alpha2.CapsulePublicInterface{
+					LoadBalancer: &v1alpha2.CapsuleInterfaceLoadBalancer{Port: 5000},
+				}},
+			},
+			NetworkPolicy: &v1alpha2.NetworkPolicy{
+				AllowFrom: []v1alpha2.NetworkPeer{{Capsule: "frontend"}, {Namespace: "jobs"}},
+				AllowTo:   []v1alpha2.NetworkPeer{{Capsule: "db", Namespace: "data"}},
+			},
+			Dependencies: []v1alpha2.CapsuleDependency{{Capsule: "users", Interface: "grpc"}},
+		},
+	}
+
+	allPorts := []netv1.NetworkPolicyPort{tcpPort(9000), tcpPort(8080), tcpPort(5000)}
+
+	t.Run("ingress only", func(t *testing.T) {
+		np := createNetworkPolicy(capsule, Config{
+			AllowFrom: []Peer{{Namespace: "monitoring", PodLabels: map[string]string{"app": "prometheus"}}},
+		})
+
+		require.Equal(t, "api", np.Name)
+		require.Equal(t, map[string]string{pipeline.LabelCapsule: "api"}, np.Spec.PodSelector.MatchLabels)
+		require.Equal(t, []netv1.PolicyType{netv1.PolicyTypeIngress}, np.Spec.PolicyTypes)
+		require.Empty(t, np.Spec.Egress)
+		require.Equal(t, []netv1.NetworkPolicyIngressRule{
+			{
+				Ports: allPorts,
+				From: []netv1.NetworkPolicyPeer{
+					namespacePeer("monitoring", map[string]string{"app": "prometheus"}),
+					namespacePeer("prod", map[string]string{pipeline.LabelCapsule: "frontend"}),
+					namespacePeer("jobs", nil),
+				},
+			},
+			{
+				Ports: []netv1.NetworkPolicyPort{tcpPort(8080)},
+				From:  []netv1.NetworkPolicyPeer{namespacePeer(defaultIngressControllerNamespace, nil)},
+			},
+			{
+				Ports: []netv1.NetworkPolicyPort{tcpPort(5000)},
+			},
+		}, np.Spec.Ingress)
+	})
+
+	t.Run("egress", func(t *testing.T) {
+		np := createNetworkPolicy(capsule, Config{
+			Egress: EgressConfig{
+				Enabled: true,
+				AllowTo: []Peer{{CIDR: "10.0.0.0/16"}},
+			},
+		})
+
+		require.Equal(t, []netv1.PolicyType{netv1.PolicyTypeIngress, netv1.PolicyTypeEgress}, np.Spec.PolicyTypes)
+		require.Equal(t, []netv1.NetworkPolicyEgressRule{
+			{
+				Ports: []netv1.NetworkPolicyPort{
+					{Protocol: ptr.New(v1.ProtocolUDP), Port: ptr.New(intstr.FromInt32(53))},
+					{Protocol: ptr.New(v1.ProtocolTCP), Port: ptr.New(intstr.FromInt32(53))},
+				},
+				To: []netv1.NetworkPolicyPeer{namespacePeer(defaultDNSNamespace, defaultDNSPodLabels)},
+			},
+			{
+				To: []netv1.NetworkPolicyPeer{
+					{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.0/16"}},
+					namespacePeer("data", map[string]string{pipeline.LabelCapsule: "db"}),
+					namespacePeer("prod", map[string]string{pipeline.LabelCapsule: "users"}),
+				},
+			},
+		}, np.Spec.Egress)
+	})
+
+	t.Run("scale to zero", func(t *testing.T) {
+		stz := capsule.DeepCopy()
+		stz.Spec.Scale.Horizontal.ScaleToZero = &v1alpha2.ScaleToZero{IdleSeconds: 600}
+
+		np := createNetworkPolicy(stz, Config{})
+		require.Equal(t, netv1.NetworkPolicyIngressRule{
+			Ports: []netv1.NetworkPolicyPort{tcpPort(8080)},
+			From: []netv1.NetworkPolicyPeer{
+				namespacePeer(defaultIngressControllerNamespace, nil),
+				{
+					NamespaceSelector: &metav1.LabelSelector{},
+					PodSelector:       &metav1.LabelSelector{MatchLabels: defaultActivatorPodLabels},
+				},
+			},
+		}, np.Spec.Ingress[1])
+
+		np = createNetworkPolicy(stz, Config{Activator: &Peer{Namespace: "rig-system"}})
+		require.Equal(t, namespacePeer("rig-system", nil), np.Spec.Ingress[1].From[1])
+	})
+
+	t.Run("no interfaces", func(t *testing.T) {
+		np := createNetworkPolicy(&v1alpha2.Capsule{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "prod"},
+		}, Config{AllowFrom: []Peer{{Namespace: "monitoring"}}})
+
+		require.Equal(t, []netv1.PolicyType{netv1.PolicyTypeIngress}, np.Spec.PolicyTypes)
+		require.Empty(t, np.Spec.Ingress)
+	})
+}
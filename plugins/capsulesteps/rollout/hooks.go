@@ -0,0 +1,242 @@
+package rollout
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/rigdev/rig/plugins/capsulesteps/cron_jobs"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	preRolloutHook  = "pre-rollout"
+	postRolloutHook = "post-rollout"
+)
+
+type hookState int
+
+const (
+	hookMissing hookState = iota
+	hookRunning
+	hookSucceeded
+	hookFailed
+)
+
+// setRolloutHooks runs the rollout hooks of the capsule for the pod template
+// of the new Deployment. Until the pre-rollout hook of the pod template has
+// succeeded, the Deployment is held back on its current version, and true is
+// returned. The post-rollout hook is run once the Deployment running the pod
+// template is ready.
+func setRolloutHooks(
+	req pipeline.CapsuleRequest,
+	deployment *appsv1.Deployment,
+	current *appsv1.Deployment,
+	logger hclog.Logger,
+) (bool, error) {
+	hooks := req.Capsule().Spec.RolloutHooks
+
+	templateHash, err := hashTemplate(&deployment.Spec.Template)
+	if err != nil {
+		return false, err
+	}
+
+	var currentHash string
+	if current != nil {
+		currentHash = current.GetAnnotations()[pipeline.AnnotationRolloutHookTemplateHash]
+	}
+
+	if err := keepRunningHooks(req); err != nil {
+		return false, err
+	}
+
+	if hooks.PreRollout != nil {
+		job, state, err := getHookJob(
+			req, createHookJob(req.Capsule(), deployment, preRolloutHook, hooks.PreRollout, templateHash),
+		)
+		if err != nil {
+			return false, err
+		}
+
+		// Once the new version is rolled out, the Job is kept for as long as it
+		// exists, but is not run again.
+		if currentHash != templateHash || state != hookMissing {
+			if err := req.Set(job); err != nil {
+				return false, err
+			}
+		}
+
+		if currentHash != templateHash && state != hookSucceeded {
+			if state == hookFailed {
+				logger.Info("pre-rollout hook failed", "template_hash", templateHash, "job", job.GetName())
+			} else {
+				logger.Info("waiting on pre-rollout hook", "template_hash", templateHash, "job", job.GetName())
+			}
+			return true, holdDeployment(req, deployment, current)
+		}
+	}
+
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	deployment.Annotations[pipeline.AnnotationRolloutHookTemplateHash] = templateHash
+
+	if hooks.PostRollout != nil && currentHash == templateHash {
+		job, state, err := getHookJob(
+			req, createHookJob(req.Capsule(), deployment, postRolloutHook, hooks.PostRollout, templateHash),
+		)
+		if err != nil {
+			return false, err
+		}
+
+		if state != hookMissing || isReady(current) {
+			if err := req.Set(job); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// holdDeployment keeps the Deployment on the pod template of the current
+// version. If the capsule has no current version, the Deployment is not
+// created yet.
+func holdDeployment(req pipeline.CapsuleRequest, deployment *appsv1.Deployment, current *appsv1.Deployment) error {
+	if current == nil {
+		return req.Delete(appsv1.SchemeGroupVersion.WithKind("Deployment"), deployment.GetName())
+	}
+
+	keepCurrentTemplate(deployment, current)
+	return req.Set(deployment)
+}
+
+// keepCurrentTemplate sets the pod template of the current Deployment on the
+// new Deployment, together with the annotations describing the template.
+func keepCurrentTemplate(deployment *appsv1.Deployment, current *appsv1.Deployment) {
+	deployment.Spec.Template = current.Spec.Template
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	for _, a := range []string{
+		pipeline.AnnotationRolloutTemplateHash,
+		pipeline.AnnotationRolloutHookTemplateHash,
+	} {
+		if v, ok := current.GetAnnotations()[a]; ok {
+			deployment.Annotations[a] = v
+		} else {
+			delete(deployment.Annotations, a)
+		}
+	}
+}
+
+// createHookJob creates the Job of a rollout hook for the pod template with
+// the given hash. The hash is part of the name of the Job, as the pod
+// template of a Job cannot be changed.
+func createHookJob(
+	capsule *v1alpha2.Capsule,
+	deployment *appsv1.Deployment,
+	name string,
+	hook *v1alpha2.RolloutHook,
+	templateHash string,
+) *batchv1.Job {
+	// The pods of the Job don't get the labels of the instances of the
+	// capsule, so they are not selected by its Service.
+	template := cron_jobs.CommandPodTemplate(capsule, &deployment.Spec.Template, hook.Command)
+	template.Labels = map[string]string{
+		pipeline.LabelRolloutHook: name,
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s-%s", capsule.Name, name, templateHash[:8]),
+			Namespace: capsule.Namespace,
+			Labels: map[string]string{
+				pipeline.LabelCapsule:     capsule.Name,
+				pipeline.LabelRolloutHook: name,
+			},
+			Annotations: map[string]string{
+				pipeline.AnnotationRolloutHookTemplateHash: templateHash,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			ActiveDeadlineSeconds: ptr.Convert[uint, int64](hook.TimeoutSeconds),
+			BackoffLimit:          ptr.Convert[uint, int32](hook.MaxRetries),
+			Template:              template,
+		},
+	}
+}
+
+// keepRunningHooks keeps the existing Jobs of rollout hooks which are still
+// running, so a hook of a previous version isn't stopped midway when a newer
+// version is rolled out. The Jobs are deleted once they have finished.
+func keepRunningHooks(req pipeline.CapsuleRequest) error {
+	jobs, err := pipeline.ListExisting(req, &batchv1.Job{})
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if _, ok := job.GetLabels()[pipeline.LabelRolloutHook]; !ok || jobState(job) != hookRunning {
+			continue
+		}
+
+		if err := req.Set(keepJob(job)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getHookJob returns the Job of a rollout hook, together with its state. If the
+// Job already exists, the existing Job is returned instead of the given one,
+// as the selector and pod template of a Job can't be changed after it's
+// created.
+func getHookJob(req pipeline.CapsuleRequest, job *batchv1.Job) (*batchv1.Job, hookState, error) {
+	existing := &batchv1.Job{}
+	existing.SetName(job.GetName())
+	if err := req.GetExistingInto(existing); errors.IsNotFound(err) {
+		return job, hookMissing, nil
+	} else if err != nil {
+		return nil, hookMissing, err
+	}
+
+	return keepJob(existing), jobState(existing), nil
+}
+
+// keepJob returns a copy of an existing Job to be set on the request, keeping
+// the spec defaulted by the API server.
+func keepJob(job *batchv1.Job) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        job.GetName(),
+			Namespace:   job.GetNamespace(),
+			Labels:      job.GetLabels(),
+			Annotations: job.GetAnnotations(),
+		},
+		Spec: *job.Spec.DeepCopy(),
+	}
+}
+
+func jobState(job *batchv1.Job) hookState {
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return hookSucceeded
+		case batchv1.JobFailed:
+			return hookFailed
+		}
+	}
+
+	return hookRunning
+}
@@ -0,0 +1,204 @@
+package rollout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rigdev/rig/pkg/controller/plugin/plugintest"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const hooksCapsuleYAML = `
+apiVersion: rig.dev/v1alpha2
+kind: Capsule
+metadata:
+  name: name
+  namespace: namespace
+spec:
+  image: nginx:2
+  rolloutHooks:
+    preRollout:
+      command:
+        command: migrate
+        args: ["up"]
+    postRollout:
+      command:
+        command: warm-cache
+`
+
+func Test_Run_RolloutHooks(t *testing.T) {
+	newDeploy := newDeployment("name", "namespace", 2)
+	newDeploy.Spec.Template.Spec.Containers[0].Image = "nginx:2"
+	newHash, err := hashTemplate(&newDeploy.Spec.Template)
+	require.NoError(t, err)
+
+	currentDeploy := newDeployment("name", "namespace", 2)
+	currentDeploy.Annotations = map[string]string{pipeline.AnnotationRolloutHookTemplateHash: "0123456789abcdef"}
+	currentDeploy.Status = appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2}
+
+	readyDeploy := newDeploy.DeepCopy()
+	readyDeploy.Annotations = map[string]string{pipeline.AnnotationRolloutHookTemplateHash: newHash}
+	readyDeploy.Status = currentDeploy.Status
+
+	preJob := "name-pre-rollout-" + newHash[:8]
+	postJob := "name-post-rollout-" + newHash[:8]
+
+	tests := []struct {
+		name     string
+		existing []client.Object
+		image    string
+		jobs     []string
+	}{
+		{
+			name:  "first rollout waits on pre-rollout hook",
+			jobs:  []string{preJob},
+			image: "",
+		},
+		{
+			name:     "new version waits on pre-rollout hook",
+			existing: []client.Object{currentDeploy},
+			jobs:     []string{preJob},
+			image:    "nginx",
+		},
+		{
+			name:     "failed pre-rollout hook keeps current version",
+			existing: []client.Object{currentDeploy, newJob(preJob, batchv1.JobFailed)},
+			jobs:     []string{preJob},
+			image:    "nginx",
+		},
+		{
+			name:     "succeeded pre-rollout hook rolls out new version",
+			existing: []client.Object{currentDeploy, newJob(preJob, batchv1.JobComplete)},
+			jobs:     []string{preJob},
+			image:    "nginx:2",
+		},
+		{
+			name:     "ready new version runs post-rollout hook",
+			existing: []client.Object{readyDeploy, newJob(preJob, batchv1.JobComplete)},
+			jobs:     []string{postJob, preJob},
+			image:    "nginx:2",
+		},
+		{
+			name:     "finished hooks are not run again",
+			existing: []client.Object{readyDeploy, newJob(postJob, batchv1.JobComplete)},
+			jobs:     []string{postJob},
+			image:    "nginx:2",
+		},
+		{
+			name: "running hook of previous version is kept",
+			existing: []client.Object{
+				currentDeploy, newJob("name-pre-rollout-01234567", ""), newJob("name-post-rollout-01234567", ""),
+			},
+			jobs:  []string{"name-post-rollout-01234567", "name-pre-rollout-01234567", preJob},
+			image: "nginx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := plugintest.New(&Plugin{}, hooksCapsuleYAML, "",
+				plugintest.WithNewObjects(newDeploy),
+				plugintest.WithExistingObjects(tt.existing...),
+			)
+			require.NoError(t, err)
+
+			res, err := h.Run(context.Background())
+			require.NoError(t, err)
+
+			var image string
+			var jobs []string
+			for _, o := range res.Objects {
+				switch o := o.(type) {
+				case *appsv1.Deployment:
+					image = o.Spec.Template.Spec.Containers[0].Image
+				case *batchv1.Job:
+					jobs = append(jobs, o.GetName())
+				}
+			}
+			require.Equal(t, tt.image, image)
+			require.Equal(t, tt.jobs, jobs)
+		})
+	}
+}
+
+func Test_Run_RolloutHooks_ExistingJob(t *testing.T) {
+	newDeploy := newDeployment("name", "namespace", 2)
+	newDeploy.Spec.Template.Spec.Containers[0].Image = "nginx:2"
+	newHash, err := hashTemplate(&newDeploy.Spec.Template)
+	require.NoError(t, err)
+
+	currentDeploy := newDeployment("name", "namespace", 2)
+	currentDeploy.Annotations = map[string]string{pipeline.AnnotationRolloutHookTemplateHash: "0123456789abcdef"}
+
+	// The API server sets the selector of a Job and labels its pod template
+	// with it when the Job is created. Both are immutable.
+	labels := map[string]string{
+		pipeline.LabelRolloutHook:            preRolloutHook,
+		"batch.kubernetes.io/job-name":       "name-pre-rollout-" + newHash[:8],
+		"batch.kubernetes.io/controller-uid": "8c4f7a29-6f4e-4b8b-a1b4-3c2f6b5d9e10",
+	}
+	existing := newJob("name-pre-rollout-"+newHash[:8], "")
+	existing.SetNamespace("namespace")
+	existing.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{
+		"batch.kubernetes.io/controller-uid": "8c4f7a29-6f4e-4b8b-a1b4-3c2f6b5d9e10",
+	}}
+	existing.Spec.Template.Labels = labels
+
+	h, err := plugintest.New(&Plugin{}, hooksCapsuleYAML, "",
+		plugintest.WithNewObjects(newDeploy),
+		plugintest.WithExistingObjects(currentDeploy, existing),
+	)
+	require.NoError(t, err)
+
+	res, err := h.Run(context.Background())
+	require.NoError(t, err)
+
+	var job *batchv1.Job
+	for _, o := range res.Objects {
+		if j, ok := o.(*batchv1.Job); ok {
+			job = j
+		}
+	}
+	require.NotNil(t, job)
+	require.Equal(t, existing.Spec.Selector, job.Spec.Selector)
+	require.Equal(t, labels, job.Spec.Template.Labels)
+}
+
+func Test_createHookJob(t *testing.T) {
+	h, err := plugintest.New(&Plugin{}, hooksCapsuleYAML, "")
+	require.NoError(t, err)
+	capsule := h.Capsule()
+
+	deploy := newDeployment("name", "namespace", 2)
+	deploy.Spec.Template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{}
+	deploy.Spec.Template.Spec.Containers = append(deploy.Spec.Template.Spec.Containers, corev1.Container{
+		Name: "worker",
+	})
+
+	job := createHookJob(capsule, deploy, preRolloutHook, capsule.Spec.RolloutHooks.PreRollout, "0123456789abcdef")
+	require.Equal(t, "name-pre-rollout-01234567", job.GetName())
+	require.Equal(t, map[string]string{pipeline.LabelRolloutHook: preRolloutHook}, job.Spec.Template.Labels)
+
+	containers := job.Spec.Template.Spec.Containers
+	require.Len(t, containers, 1)
+	require.Equal(t, []string{"migrate"}, containers[0].Command)
+	require.Equal(t, []string{"up"}, containers[0].Args)
+	require.Nil(t, containers[0].ReadinessProbe)
+	require.Equal(t, corev1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+}
+
+func newJob(name string, condition batchv1.JobConditionType) *batchv1.Job {
+	job := &batchv1.Job{}
+	job.SetName(name)
+	job.SetLabels(map[string]string{pipeline.LabelRolloutHook: preRolloutHook})
+	if condition != "" {
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+	}
+	return job
+}
@@ -0,0 +1,391 @@
+// +groupName=plugins.rig.dev -- Only used for config doc generation
+//
+//nolint:revive
+package rollout
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/controller/plugin"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/pipeline"
+	ingressroutes "github.com/rigdev/rig/plugins/capsulesteps/ingress_routes"
+	"golang.org/x/exp/maps"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	Name = "rigdev.rollout"
+)
+
+// Configuration for the rollout plugin
+// +kubebuilder:object:root=true
+type Config struct{}
+
+type Plugin struct {
+	configBytes []byte
+}
+
+func (p *Plugin) Initialize(req plugin.InitializeRequest) error {
+	if err := gatewayv1.Install(req.Scheme()); err != nil {
+		return err
+	}
+	p.configBytes = req.Config
+	return nil
+}
+
+func (p *Plugin) ComputeConfig(ctx context.Context, req pipeline.CapsuleRequest, logger hclog.Logger) (string, error) {
+	return plugin.ParseCapsuleTemplatedConfigToString[Config](p.configBytes, req)
+}
+
+func (p *Plugin) Run(ctx context.Context, req pipeline.CapsuleRequest, logger hclog.Logger) error {
+	capsule := req.Capsule()
+	if (capsule.Spec.Rollout == nil && capsule.Spec.RolloutHooks == nil) || pipeline.IsStateful(capsule) {
+		return nil
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := req.GetNewInto(deployment); errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	current := &appsv1.Deployment{}
+	if err := req.GetExistingInto(current); errors.IsNotFound(err) {
+		current = nil
+	} else if err != nil {
+		return err
+	}
+
+	if capsule.Spec.RolloutHooks != nil {
+		held, err := setRolloutHooks(req, deployment, current, logger)
+		if err != nil || held {
+			return err
+		}
+	}
+
+	if capsule.Spec.Rollout == nil {
+		return req.Set(deployment)
+	}
+
+	// The template is hashed with the label of the stable track, which is
+	// only added to the pods of the Deployment when they are replaced anyway.
+	labelled := deployment.Spec.Template.DeepCopy()
+	setTrackLabel(labelled)
+	templateHash, err := hashTemplate(labelled)
+	if err != nil {
+		return err
+	}
+
+	setStableSelector(deployment)
+	if err := setStablePodDisruptionBudget(req, deployment); err != nil {
+		return err
+	}
+
+	// The existing Deployment is adopted as the stable version if it has not
+	// been rolled out by this step before. Otherwise a rollout is only needed
+	// if the pod template has changed.
+	var stableHash string
+	if current != nil {
+		stableHash = current.GetAnnotations()[pipeline.AnnotationRolloutTemplateHash]
+	}
+	if stableHash == "" || stableHash == templateHash {
+		// Enabling rollouts doesn't restart the instances of the capsule.
+		if current == nil || current.Spec.Template.Labels[pipeline.LabelRolloutTrack] != "" {
+			setTrackLabel(&deployment.Spec.Template)
+		}
+		return setDeployment(req, deployment, templateHash)
+	}
+
+	steps := getSteps(capsule.Spec.Rollout)
+	state, err := getState(req, templateHash)
+	if err != nil {
+		return err
+	}
+
+	now := req.Now()
+	if state.ready {
+		if state.readySince.IsZero() {
+			state.readySince = now
+		}
+		pause := time.Duration(steps[state.step].PauseSeconds) * time.Second
+		if !now.Before(state.readySince.Add(pause)) {
+			state.step++
+			state.readySince = now
+		}
+	}
+
+	if state.step >= len(steps) {
+		// Promote the canary by rolling out the new version in the stable
+		// Deployment. The canary objects are deleted as they are not set.
+		logger.Info("promoting canary", "template_hash", templateHash)
+		setTrackLabel(&deployment.Spec.Template)
+		return setDeployment(req, deployment, templateHash)
+	}
+
+	canary := createCanaryDeployment(deployment, capsule, steps, state, templateHash)
+
+	// Keep running the stable version in the Deployment of the capsule. Its
+	// pods are labelled with the stable track, so the Service of the capsule
+	// can exclude the canary. This restarts the stable pods once, during the
+	// first rollout of a capsule which didn't have rollouts enabled.
+	keepCurrentTemplate(deployment, current)
+	setTrackLabel(&deployment.Spec.Template)
+	if err := setDeployment(req, deployment, stableHash); err != nil {
+		return err
+	}
+
+	if err := req.Set(canary); err != nil {
+		return err
+	}
+
+	return setServicesAndIngresses(req, capsule, steps[state.step].Weight)
+}
+
+// setTrackLabel labels the pods of the template with the stable track.
+func setTrackLabel(template *v1.PodTemplateSpec) {
+	if template.Labels == nil {
+		template.Labels = map[string]string{}
+	}
+	template.Labels[pipeline.LabelRolloutTrack] = pipeline.RolloutTrackStable
+}
+
+// setStableSelector sets the selector of the stable Deployment, so the pods
+// of the canary are not counted as pods of the stable version, fx. by the
+// HorizontalPodAutoscaler of the capsule. Deployments created before rollouts
+// were enabled are replaced by the pipeline, as selectors are immutable,
+// which keeps their pods running.
+func setStableSelector(deployment *appsv1.Deployment) {
+	deployment.Spec.Selector = pipeline.StableSelector(deployment.Spec.Selector)
+}
+
+// setStablePodDisruptionBudget makes the PodDisruptionBudget of the capsule,
+// if any, select the pods of the stable version only.
+func setStablePodDisruptionBudget(req pipeline.CapsuleRequest, deployment *appsv1.Deployment) error {
+	pdb := &policyv1.PodDisruptionBudget{}
+	if err := req.GetNewInto(pdb); errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	pdb.Spec.Selector = deployment.Spec.Selector.DeepCopy()
+	return req.Set(pdb)
+}
+
+func setDeployment(req pipeline.CapsuleRequest, deployment *appsv1.Deployment, templateHash string) error {
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	deployment.Annotations[pipeline.AnnotationRolloutTemplateHash] = templateHash
+	return req.Set(deployment)
+}
+
+// getSteps returns the rollout strategy as a list of canary steps. A
+// blue/green rollout is a single step where the new version gets no traffic.
+func getSteps(strategy *v1alpha2.RolloutStrategy) []v1alpha2.CanaryStep {
+	if strategy.Canary != nil {
+		return strategy.Canary.Steps
+	}
+
+	var delay uint32
+	if strategy.BlueGreen != nil {
+		delay = strategy.BlueGreen.PromotionDelaySeconds
+	}
+	return []v1alpha2.CanaryStep{{PauseSeconds: delay}}
+}
+
+type rolloutState struct {
+	step       int
+	ready      bool
+	readySince time.Time
+}
+
+// getState reads the state of the rollout of the given pod template from the
+// existing canary Deployment. A canary running another version of the pod
+// template is replaced, starting the rollout over.
+func getState(req pipeline.CapsuleRequest, templateHash string) (rolloutState, error) {
+	canary := &appsv1.Deployment{}
+	canary.SetName(canaryName(req.Capsule()))
+	if err := req.GetExistingInto(canary); errors.IsNotFound(err) {
+		return rolloutState{}, nil
+	} else if err != nil {
+		return rolloutState{}, err
+	}
+
+	annotations := canary.GetAnnotations()
+	if annotations[pipeline.AnnotationRolloutTemplateHash] != templateHash {
+		return rolloutState{}, nil
+	}
+
+	state := rolloutState{
+		ready: isReady(canary),
+	}
+	state.step, _ = strconv.Atoi(annotations[pipeline.AnnotationRolloutStep])
+	if state.ready {
+		state.readySince, _ = time.Parse(time.RFC3339, annotations[pipeline.AnnotationRolloutReadySince])
+	}
+
+	return state, nil
+}
+
+func isReady(d *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+
+	return d.Status.ObservedGeneration >= d.GetGeneration() &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.ReadyReplicas == replicas &&
+		d.Status.Replicas == replicas
+}
+
+func createCanaryDeployment(
+	deployment *appsv1.Deployment,
+	capsule *v1alpha2.Capsule,
+	steps []v1alpha2.CanaryStep,
+	state rolloutState,
+	templateHash string,
+) *appsv1.Deployment {
+	canary := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      canaryName(capsule),
+			Namespace: capsule.Namespace,
+			Labels:    maps.Clone(deployment.Labels),
+			Annotations: map[string]string{
+				pipeline.AnnotationRolloutTemplateHash: templateHash,
+				pipeline.AnnotationRolloutStep:         strconv.Itoa(state.step),
+				pipeline.AnnotationRolloutSteps:        strconv.Itoa(len(steps)),
+				pipeline.AnnotationRolloutWeight:       strconv.FormatUint(uint64(steps[state.step].Weight), 10),
+			},
+		},
+		Spec: *deployment.Spec.DeepCopy(),
+	}
+
+	if state.ready {
+		pause := time.Duration(steps[state.step].PauseSeconds) * time.Second
+		canary.Annotations[pipeline.AnnotationRolloutReadySince] = state.readySince.Format(time.RFC3339)
+		canary.Annotations[pipeline.AnnotationReconcileAfter] = state.readySince.Add(pause).Format(time.RFC3339)
+	}
+
+	canary.Spec.Template.Labels[pipeline.LabelRolloutTrack] = pipeline.RolloutTrackCanary
+	canary.Spec.Selector = &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			pipeline.LabelCapsule:      capsule.Name,
+			pipeline.LabelRolloutTrack: pipeline.RolloutTrackCanary,
+		},
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	if capsule.Spec.Rollout.Canary != nil {
+		weight := float64(steps[state.step].Weight) / 100
+		replicas = max(int32(math.Ceil(float64(replicas)*weight)), 1)
+	}
+	canary.Spec.Replicas = &replicas
+
+	return canary
+}
+
+// setServicesAndIngresses creates a Service for the canary and weighted
+// copies of the ingresses of the capsule, and splits the backends of its
+// Gateway API routes. The Service of the capsule only sends traffic to the
+// stable version, so the traffic of the routes is split exactly by the weight
+// of the step, while traffic sent directly to the Service isn't split.
+func setServicesAndIngresses(req pipeline.CapsuleRequest, capsule *v1alpha2.Capsule, weight uint32) error {
+	svc := &v1.Service{}
+	if err := req.GetNewInto(svc); errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	canarySvc := svc.DeepCopy()
+	canarySvc.SetName(canaryName(capsule))
+	canarySvc.Spec.Selector = map[string]string{
+		pipeline.LabelCapsule:      capsule.Name,
+		pipeline.LabelRolloutTrack: pipeline.RolloutTrackCanary,
+	}
+	if canarySvc.Labels == nil {
+		canarySvc.Labels = map[string]string{}
+	}
+	canarySvc.Labels[pipeline.LabelRolloutTrack] = pipeline.RolloutTrackCanary
+	if err := req.Set(canarySvc); err != nil {
+		return err
+	}
+
+	svc.Spec.Selector[pipeline.LabelRolloutTrack] = pipeline.RolloutTrackStable
+	if err := req.Set(svc); err != nil {
+		return err
+	}
+
+	ingresses, err := pipeline.ListNew(req, &netv1.Ingress{})
+	if err != nil {
+		return err
+	}
+
+	for _, ing := range ingresses {
+		if canary, ok := ingressroutes.CanaryIngress(ing, capsule.Name, canarySvc.GetName(), weight); ok {
+			if err := req.Set(canary); err != nil {
+				return err
+			}
+		}
+	}
+
+	httpRoutes, err := pipeline.ListNew(req, &gatewayv1.HTTPRoute{})
+	if err != nil {
+		return err
+	}
+	grpcRoutes, err := pipeline.ListNew(req, &gatewayv1.GRPCRoute{})
+	if err != nil {
+		return err
+	}
+
+	var routes []client.Object
+	for _, r := range httpRoutes {
+		routes = append(routes, r)
+	}
+	for _, r := range grpcRoutes {
+		routes = append(routes, r)
+	}
+	for _, r := range routes {
+		if ingressroutes.SetCanaryBackends(r, capsule.Name, canarySvc.GetName(), weight) {
+			if err := req.Set(r); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func canaryName(capsule *v1alpha2.Capsule) string {
+	return fmt.Sprintf("%s-%s", capsule.Name, pipeline.RolloutTrackCanary)
+}
+
+func hashTemplate(template *v1.PodTemplateSpec) (string, error) {
+	bs, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(bs))[:16], nil
+}
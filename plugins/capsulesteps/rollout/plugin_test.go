@@ -0,0 +1,206 @@
+package rollout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-hclog"
+	"github.com/rigdev/rig/pkg/api/v1alpha2"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/rigdev/rig/pkg/scheme"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func newDeployment(name, namespace string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.New(replicas),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					pipeline.LabelCapsule: name,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						pipeline.LabelCapsule: name,
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  name,
+						Image: "nginx",
+					}},
+				},
+			},
+		},
+	}
+}
+
+func Test_Run_NoExistingDeployment(t *testing.T) {
+	name, namespace := "name", "namespace"
+
+	vm := scheme.NewVersionMapperFromScheme(scheme.New())
+	pipe := pipeline.NewCapsulePipeline(nil, scheme.New(), vm, logr.FromContextOrDiscard(context.Background()))
+	req := pipeline.NewCapsuleRequest(pipe, &v1alpha2.Capsule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: v1alpha2.CapsuleSpec{
+			Rollout: &v1alpha2.RolloutStrategy{
+				Canary: &v1alpha2.CanaryStrategy{Steps: []v1alpha2.CanaryStep{{Weight: 10}}},
+			},
+		},
+	}, nil)
+	require.NoError(t, req.Set(newDeployment(name, namespace, 3)))
+
+	plug := Plugin{}
+	require.NoError(t, plug.Run(context.Background(), req, hclog.Default()))
+
+	deploy := &appsv1.Deployment{}
+	require.NoError(t, req.GetNewInto(deploy))
+	require.Equal(t, pipeline.RolloutTrackStable, deploy.Spec.Template.Labels[pipeline.LabelRolloutTrack])
+	require.Equal(t, pipeline.StableSelector(newDeployment(name, namespace, 3).Spec.Selector), deploy.Spec.Selector)
+	require.NotEmpty(t, deploy.Annotations[pipeline.AnnotationRolloutTemplateHash])
+
+	canary := &appsv1.Deployment{}
+	canary.SetName(name + "-canary")
+	require.Error(t, req.GetNewInto(canary))
+}
+
+func Test_createCanaryDeployment(t *testing.T) {
+	name, namespace := "name", "namespace"
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		rollout          *v1alpha2.RolloutStrategy
+		replicas         int32
+		state            rolloutState
+		expectedReplicas int32
+		expectedWeight   string
+		reconcileAfter   string
+	}{
+		{
+			name: "canary rounds replicas up",
+			rollout: &v1alpha2.RolloutStrategy{Canary: &v1alpha2.CanaryStrategy{Steps: []v1alpha2.CanaryStep{
+				{Weight: 10}, {Weight: 50},
+			}}},
+			replicas:         5,
+			expectedReplicas: 1,
+			expectedWeight:   "10",
+		},
+		{
+			name: "canary at second step",
+			rollout: &v1alpha2.RolloutStrategy{Canary: &v1alpha2.CanaryStrategy{Steps: []v1alpha2.CanaryStep{
+				{Weight: 10}, {Weight: 50, PauseSeconds: 60},
+			}}},
+			replicas:         5,
+			state:            rolloutState{step: 1, ready: true, readySince: now},
+			expectedReplicas: 3,
+			expectedWeight:   "50",
+			reconcileAfter:   "2024-01-01T12:01:00Z",
+		},
+		{
+			name:             "blue/green runs all replicas",
+			rollout:          &v1alpha2.RolloutStrategy{BlueGreen: &v1alpha2.BlueGreenStrategy{}},
+			replicas:         5,
+			expectedReplicas: 5,
+			expectedWeight:   "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capsule := &v1alpha2.Capsule{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Spec:       v1alpha2.CapsuleSpec{Rollout: tt.rollout},
+			}
+			steps := getSteps(tt.rollout)
+
+			canary := createCanaryDeployment(
+				newDeployment(name, namespace, tt.replicas), capsule, steps, tt.state, "hash",
+			)
+			require.Equal(t, "name-canary", canary.GetName())
+			require.Equal(t, tt.expectedReplicas, *canary.Spec.Replicas)
+			require.Equal(t, tt.expectedWeight, canary.Annotations[pipeline.AnnotationRolloutWeight])
+			require.Equal(t, tt.reconcileAfter, canary.Annotations[pipeline.AnnotationReconcileAfter])
+			require.Equal(t, map[string]string{
+				pipeline.LabelCapsule:      name,
+				pipeline.LabelRolloutTrack: pipeline.RolloutTrackCanary,
+			}, canary.Spec.Selector.MatchLabels)
+			require.Equal(t, pipeline.RolloutTrackCanary, canary.Spec.Template.Labels[pipeline.LabelRolloutTrack])
+		})
+	}
+}
+
+func Test_setServicesAndIngresses(t *testing.T) {
+	name, namespace := "name", "namespace"
+
+	for _, strategy := range []v1alpha2.RolloutStrategy{
+		{Canary: &v1alpha2.CanaryStrategy{Steps: []v1alpha2.CanaryStep{{Weight: 10}}}},
+		{BlueGreen: &v1alpha2.BlueGreenStrategy{}},
+	} {
+		capsule := &v1alpha2.Capsule{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: v1alpha2.CapsuleSpec{
+				Rollout: &strategy,
+			},
+		}
+
+		vm := scheme.NewVersionMapperFromScheme(scheme.New())
+		pipe := pipeline.NewCapsulePipeline(nil, scheme.New(), vm, logr.FromContextOrDiscard(context.Background()))
+		req := pipeline.NewCapsuleRequest(pipe, capsule, nil)
+		require.NoError(t, gatewayv1.Install(req.Scheme()))
+		require.NoError(t, req.Set(&corev1.Service{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Service",
+				APIVersion: "v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{pipeline.LabelCapsule: name},
+			},
+		}))
+
+		require.NoError(t, setServicesAndIngresses(req, capsule, 10))
+
+		svc := &corev1.Service{}
+		svc.SetName(name)
+		require.NoError(t, req.GetNewInto(svc))
+		require.Equal(t, map[string]string{
+			pipeline.LabelCapsule:      name,
+			pipeline.LabelRolloutTrack: pipeline.RolloutTrackStable,
+		}, svc.Spec.Selector)
+
+		canarySvc := &corev1.Service{}
+		canarySvc.SetName(name + "-canary")
+		require.NoError(t, req.GetNewInto(canarySvc))
+		require.Equal(t, map[string]string{
+			pipeline.LabelCapsule:      name,
+			pipeline.LabelRolloutTrack: pipeline.RolloutTrackCanary,
+		}, canarySvc.Spec.Selector)
+	}
+}
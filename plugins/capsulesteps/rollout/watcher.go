@@ -0,0 +1,86 @@
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	"github.com/rigdev/rig/pkg/controller/plugin"
+	"github.com/rigdev/rig/pkg/pipeline"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (p *Plugin) WatchObjectStatus(ctx context.Context, watcher plugin.CapsuleWatcher) error {
+	return watcher.WatchPrimary(ctx, &batchv1.Job{}, onHookJobUpdated)
+}
+
+// onHookJobUpdated reports the progress of the Job of a rollout hook, as a
+// condition named after the hook.
+func onHookJobUpdated(
+	obj client.Object,
+	_ []*corev1.Event,
+	_ plugin.ObjectWatcher,
+) *apipipeline.ObjectStatusInfo {
+	job := obj.(*batchv1.Job)
+	hook, ok := job.GetLabels()[pipeline.LabelRolloutHook]
+	if !ok {
+		return nil
+	}
+
+	status := &apipipeline.ObjectStatusInfo{
+		Properties: map[string]string{
+			"Retries": fmt.Sprint(job.Status.Failed),
+		},
+	}
+	if cs := job.Spec.Template.Spec.Containers; len(cs) > 0 {
+		status.Properties["Command"] = strings.Join(slices.Concat(cs[0].Command, cs[0].Args), " ")
+	}
+
+	cond := &apipipeline.ObjectCondition{
+		Name:      hookConditionName(hook),
+		State:     apipipeline.ObjectState_OBJECT_STATE_PENDING,
+		Message:   "Running",
+		UpdatedAt: timestamppb.New(job.GetCreationTimestamp().Time),
+	}
+	if hook == preRolloutHook {
+		cond.Message = "Running, the new version is rolled out when it succeeds"
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			cond.State = apipipeline.ObjectState_OBJECT_STATE_HEALTHY
+			cond.Message = "Succeeded"
+		case batchv1.JobFailed:
+			cond.State = apipipeline.ObjectState_OBJECT_STATE_ERROR
+			cond.Message = fmt.Sprintf("Failed: %s", c.Message)
+			if hook == preRolloutHook {
+				cond.Message += ", the new version is not rolled out"
+			}
+		default:
+			continue
+		}
+		cond.UpdatedAt = timestamppb.New(c.LastTransitionTime.Time)
+	}
+	status.Conditions = append(status.Conditions, cond)
+
+	return status
+}
+
+func hookConditionName(hook string) string {
+	switch hook {
+	case preRolloutHook:
+		return "Pre-rollout hook"
+	case postRolloutHook:
+		return "Post-rollout hook"
+	default:
+		return hook
+	}
+}
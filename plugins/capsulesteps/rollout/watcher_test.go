@@ -0,0 +1,58 @@
+package rollout
+
+import (
+	"testing"
+
+	apipipeline "github.com/rigdev/rig-go-api/operator/api/v1/pipeline"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_onHookJobUpdated(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition batchv1.JobCondition
+		state     apipipeline.ObjectState
+		message   string
+	}{
+		{
+			name:    "running",
+			state:   apipipeline.ObjectState_OBJECT_STATE_PENDING,
+			message: "Running, the new version is rolled out when it succeeds",
+		},
+		{
+			name:      "succeeded",
+			condition: batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+			state:     apipipeline.ObjectState_OBJECT_STATE_HEALTHY,
+			message:   "Succeeded",
+		},
+		{
+			name: "failed",
+			condition: batchv1.JobCondition{
+				Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "Job has reached the specified backoff limit",
+			},
+			state:   apipipeline.ObjectState_OBJECT_STATE_ERROR,
+			message: "Failed: Job has reached the specified backoff limit, the new version is not rolled out",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := newJob("name-pre-rollout-01234567", "")
+			job.Spec.Template.Spec.Containers = []corev1.Container{{Command: []string{"migrate"}, Args: []string{"up"}}}
+			if tt.condition.Type != "" {
+				job.Status.Conditions = []batchv1.JobCondition{tt.condition}
+			}
+
+			status := onHookJobUpdated(job, nil, nil)
+			require.Equal(t, "migrate up", status.Properties["Command"])
+			require.Len(t, status.Conditions, 1)
+			require.Equal(t, "Pre-rollout hook", status.Conditions[0].Name)
+			require.Equal(t, tt.state, status.Conditions[0].State)
+			require.Equal(t, tt.message, status.Conditions[0].Message)
+		})
+	}
+
+	require.Nil(t, onHookJobUpdated(&batchv1.Job{}, nil, nil))
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/rigdev/rig/pkg/controller/plugin"
 	"github.com/rigdev/rig/pkg/errors"
 	"github.com/rigdev/rig/pkg/pipeline"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -89,14 +88,14 @@ func (p *Plugin) Run(ctx context.Context, req pipeline.CapsuleRequest, logger hc
 		return nil
 	}
 
-	deploy := &appsv1.Deployment{}
-	if err := req.GetNewInto(deploy); err != nil {
+	workload, template, err := pipeline.GetNewWorkload(req)
+	if err != nil {
 		return err
 	}
 
-	deploy.Spec.Template.Spec.ServiceAccountName = name
+	template.Spec.ServiceAccountName = name
 
-	return req.Set(deploy)
+	return req.Set(workload)
 }
 
 func (s *Plugin) createServiceAccount(